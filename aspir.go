@@ -1,7 +1,13 @@
 package pir
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math"
 
 	"github.com/ncw/gmp"
 	"github.com/sachaservan/paillier"
@@ -170,6 +176,46 @@ func AuthProve(state *AuthQueryPrivateState, chalToken *ChalToken) (*ProofToken,
 	return &ProofToken{selToken, chal2, proof, queryBit, r, s}, nil
 }
 
+// VerifyOwnProof is a client-side self-check of the proof token produced by AuthProve
+// run before sending the proof to the server (analogous to EncryptedQuery.SelfCheck).
+// It checks that the proof is for one of the client's auth tokens, that the challenge minus
+// the auth token is a nested encryption of zero, that the re-randomized challenge T is the
+// nested encryption of zero under the recorded randomness (R, S), and that the DDLEQ proof
+// verifies; i.e., it returns false if AuthCheck would reject an honest challenge
+func VerifyOwnProof(state *AuthQueryPrivateState, chalToken *ChalToken, proofToken *ProofToken) bool {
+
+	sk := state.Sk
+	pk := &sk.PublicKey
+
+	var chal, authToken *paillier.Ciphertext
+	switch proofToken.QBit {
+	case 0:
+		chal, authToken = chalToken.Token0, state.AuthToken0
+	case 1:
+		chal, authToken = chalToken.Token1, state.AuthToken1
+	default:
+		return false
+	}
+
+	if proofToken.AuthToken == nil || proofToken.AuthToken.C.Cmp(authToken.C) != 0 {
+		return false
+	}
+
+	ct1 := sk.NestedSub(chal, authToken)
+	if sk.NestedDecrypt(ct1).Cmp(gmp.NewInt(0)) != 0 {
+		return false
+	}
+
+	// re-derive T from the recorded randomness
+	expected := pk.EncryptWithRAtLevel(gmp.NewInt(0), proofToken.R, paillier.EncLevelOne)
+	expected = pk.EncryptWithRAtLevel(expected.C, proofToken.S, paillier.EncLevelTwo)
+	if proofToken.T == nil || proofToken.P == nil || expected.C.Cmp(proofToken.T.C) != 0 {
+		return false
+	}
+
+	return pk.VerifyDDLEQProof(ct1, proofToken.T, proofToken.P)
+}
+
 // AuthCheck verifies the proof provided by the client and outputs True if and only if the proof is valid
 func AuthCheck(pk *paillier.PublicKey, query *AuthenticatedEncryptedQuery, chalToken *ChalToken, proofToken *ProofToken) bool {
 
@@ -183,13 +229,19 @@ func AuthCheck(pk *paillier.PublicKey, query *AuthenticatedEncryptedQuery, chalT
 		comm = query.AuthTokenComm1
 	}
 
-	// perform the subtraction and check commitment
-	ct1 = pk.NestedSub(ct1, proofToken.AuthToken)
-	if !comm.CheckOpen(ct1.C) {
+	// the auth token must be the one committed to in the query
+	// (otherwise the client could derive it from the challenge)
+	if proofToken.AuthToken == nil || !comm.CheckOpen(proofToken.AuthToken.C) {
 		return false
 	}
 
+	// perform the subtraction
+	ct1 = pk.NestedSub(ct1, proofToken.AuthToken)
+
 	ct2 := proofToken.T
+	if ct2 == nil || proofToken.P == nil {
+		return false
+	}
 
 	// make sure that ct2 is a re-encryption of ct1
 	if !pk.VerifyDDLEQProof(ct1, ct2, proofToken.P) {
@@ -208,6 +260,164 @@ func AuthCheck(pk *paillier.PublicKey, query *AuthenticatedEncryptedQuery, chalT
 	return true
 }
 
+// AuthTranscriptDigest returns the digest of the transcript of an ASPIR authentication
+// (see TranscriptDigest), e.g., for the server to log the authentications it checked.
+// The proof token may be nil for the transcript before the client's proof
+func AuthTranscriptDigest(query *AuthenticatedEncryptedQuery, chalToken *ChalToken, proofToken *ProofToken) []byte {
+
+	if proofToken == nil {
+		return TranscriptDigest(query, chalToken)
+	}
+
+	return TranscriptDigest(query, chalToken, proofToken)
+}
+
+// AuthenticatedResponse is the combined response to an AuthenticatedEncryptedQuery
+// containing the results of both queries (one of which is the null query)
+// along with the challenge token (see AuthenticatedRetrieve)
+type AuthenticatedResponse struct {
+	Result0 *DoublyEncryptedQueryResult
+	Result1 *DoublyEncryptedQueryResult
+	Chal    *ChalToken
+}
+
+// AuthenticatedRetrieve answers both queries of the authenticated query over dataDB
+// and issues the challenge over keyDB in a single response, such that the client can
+// recover the data and prove knowledge of the key (see RecoverAuthenticated) and the
+// server can run AuthCheck after one additional round.
+//
+// The server answers the real and the null query alike, so it still does not learn
+// which query is real. Note that the data is released before the proof is checked:
+// a failed AuthCheck identifies an unauthorized retrieval after the fact (e.g., for
+// auditing or revoking the client) rather than preventing it, so clients for which
+// the check must gate access should use the multi-round flow
+func AuthenticatedRetrieve(
+	secparam int,
+	dataDB, keyDB *Database,
+	query *AuthenticatedEncryptedQuery,
+	nprocs int) (*AuthenticatedResponse, error) {
+
+	res0, err := dataDB.PrivateDoublyEncryptedQuery(query.Query0, nprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	res1, err := dataDB.PrivateDoublyEncryptedQuery(query.Query1, nprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	chalToken, err := GenerateAuthChalForQuery(secparam, keyDB, query, nprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthenticatedResponse{Result0: res0, Result1: res1, Chal: chalToken}, nil
+}
+
+// RecoverAuthenticated recovers the data retrieved by the real query of the
+// combined response and generates the proof token for the challenge (see AuthProve)
+func RecoverAuthenticated(state *AuthQueryPrivateState, res *AuthenticatedResponse) ([]*Slot, *ProofToken, error) {
+
+	real := res.Result0
+	if state.Bit == 1 {
+		real = res.Result1
+	}
+
+	slots, err := RecoverDoublyEncrypted(real, state.Sk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proofToken, err := AuthProve(state, res.Chal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return slots, proofToken, nil
+}
+
+// BuildKeyDBFromData builds an ASPIR key database aligned with dataDB (group size 1)
+// where the auth key of each item is derived from the item's data (see DeriveAuthKey)
+// such that a client proves that it knows the content of the retrieved item
+func BuildKeyDBFromData(dataDB *Database, keyBytes int) *Database {
+	return BuildKeyDBFromDataWithGroupSize(dataDB, keyBytes, 1)
+}
+
+// BuildKeyDBFromDataWithGroupSize builds an ASPIR key database with one key
+// for each group of groupSize adjacent slots in dataDB (i.e., ceil(DBSize/groupSize) keys)
+// where the key of the group at index i is derived from the data in the group
+func BuildKeyDBFromDataWithGroupSize(dataDB *Database, keyBytes, groupSize int) *Database {
+
+	checkGroupSize(groupSize)
+
+	numKeys := int(math.Ceil(float64(dataDB.DBSize) / float64(groupSize)))
+
+	keyDB := NewDatabase()
+	keyDB.Slots = make([]*Slot, numKeys)
+	keyDB.SlotBytes = keyBytes
+	keyDB.DBSize = numKeys
+
+	for i := 0; i < numKeys; i++ {
+		start := i * groupSize
+		end := int(math.Min(float64(start+groupSize), float64(len(dataDB.Slots))))
+		keyDB.Slots[i] = DeriveAuthKey(keyBytes, dataDB.Slots[start:end]...)
+	}
+
+	return keyDB
+}
+
+// ValidateASPIRAlignment returns an error unless keyDB has exactly one key per group of
+// groupSize slots of dataDB (i.e., ceil(dataDB.DBSize/groupSize) keys) and the default
+// dimensions of the queries over dataDB (see NewAuthenticatedQuery) map each group
+// to its key when GenerateAuthChalForQuery views keyDB with DBWidth/groupSize columns
+func ValidateASPIRAlignment(dataDB, keyDB *Database, groupSize int) error {
+
+	if groupSize <= 0 {
+		return errors.New("invalid group size")
+	}
+
+	numGroups := int(math.Ceil(float64(dataDB.DBSize) / float64(groupSize)))
+	if keyDB.DBSize != numGroups {
+		return fmt.Errorf("key database has %v keys but the data database has %v groups of %v slots",
+			keyDB.DBSize, numGroups, groupSize)
+	}
+
+	if len(keyDB.Slots) < keyDB.DBSize {
+		return errors.New("key database is missing keys")
+	}
+
+	height := int(math.Ceil(math.Sqrt(float64(dataDB.DBSize))))
+	width, height := dataDB.GetDimentionsForDatabase(height, groupSize)
+	if width%groupSize != 0 || (width/groupSize)*height < keyDB.DBSize {
+		return fmt.Errorf("query dimensions %v x %v do not cover the key database", width, height)
+	}
+
+	return nil
+}
+
+// DeriveAuthKey derives a keyBytes auth key from the data in the slots
+// by hashing the data (with a counter to expand the digest if needed)
+func DeriveAuthKey(keyBytes int, slots ...*Slot) *Slot {
+
+	key := make([]byte, 0, keyBytes+sha256.Size)
+	for counter := uint32(0); len(key) < keyBytes; counter++ {
+		h := sha256.New()
+
+		ctr := make([]byte, 4)
+		binary.BigEndian.PutUint32(ctr, counter)
+		h.Write(ctr)
+
+		for _, slot := range slots {
+			h.Write(slot.Data)
+		}
+
+		key = h.Sum(key)
+	}
+
+	return NewSlot(key[:keyBytes])
+}
+
 /*
  Secret shared DPF variant of ASPIR
 */
@@ -216,6 +426,18 @@ func AuthCheck(pk *paillier.PublicKey, query *AuthenticatedEncryptedQuery, chalT
 // used to authenticate two-server PIR queries
 type AuditTokenShare struct {
 	T *Slot
+
+	// digest of the database the server answered the query from (optional, see AttachDigest)
+	DigestCommitment []byte
+}
+
+// AttachDigest commits the audit share to the database that the server answers
+// the query from (the Merkle root of the database with one slot per row, see Digest).
+// When all servers attach their digest, CheckAudit detects servers that answer
+// from a different database than their peers (split-view attacks).
+// Computing the digest takes time linear in the size of the database
+func (audit *AuditTokenShare) AttachDigest(db *Database) {
+	audit.DigestCommitment = db.Digest(1, db.DBSize)
 }
 
 // AuthTokenShare is a share of the key associated with the queried item
@@ -242,12 +464,57 @@ func NewAuthTokenSharesForKey(authKey *Slot, numShares uint) []*AuthTokenShare {
 	return shares
 }
 
+// ValidateAuthQueryShare returns an error if the share cannot be audited against the key database db:
+// the domain of the share's DPF key must match the height of the key database (with group size 1)
+// and the auth token share must have the slot size of the key database.
+// A share generated for other dimensions would otherwise produce a meaningless audit token
+func (db *Database) ValidateAuthQueryShare(share *AuthenticatedQueryShare) error {
+
+	if share.QueryShare == nil || share.AuthToken == nil || share.AuthToken.T == nil {
+		return errors.New("incomplete authenticated query share")
+	}
+
+	if len(share.AuthToken.T.Data) != db.SlotBytes {
+		return fmt.Errorf("auth token has %v bytes but key database slots have %v bytes",
+			len(share.AuthToken.T.Data), db.SlotBytes)
+	}
+
+	// domain of the key database expanded with group size 1
+	numBits := uint(math.Log2(float64(db.DBSize)) + 1)
+	if share.IsKeywordBased {
+		numBits = uint(KeywordBits)
+
+		if len(db.Keywords) < db.DBSize {
+			return errors.New("keyword query share for a key database without keywords")
+		}
+	}
+
+	if share.IsTwoParty {
+		if share.KeyTwoParty == nil {
+			return errors.New("missing DPF key in query share")
+		}
+
+		if uint(len(share.KeyTwoParty.CW)) != numBits {
+			return fmt.Errorf("query share has a domain of %v bits but the key database requires %v bits",
+				len(share.KeyTwoParty.CW), numBits)
+		}
+	} else if share.KeyMultiParty == nil {
+		return errors.New("missing DPF key in query share")
+	}
+
+	return nil
+}
+
 // GenerateAuditForSharedQuery generates an audit share that is sent to the other server(s)
 func GenerateAuditForSharedQuery(
 	keyDB *Database,
 	query *AuthenticatedQueryShare,
 	nprocs int) (*AuditTokenShare, error) {
 
+	if err := keyDB.ValidateAuthQueryShare(query); err != nil {
+		return nil, err
+	}
+
 	oldGroupSize := query.GroupSize
 	query.GroupSize = 1 // key database has group size 1
 	bits := keyDB.ExpandSharedQuery(query.QueryShare, nprocs)
@@ -256,6 +523,36 @@ func GenerateAuditForSharedQuery(
 	return GenerateAuditForSharedQueryWithExpandedBits(keyDB, query, bits, nprocs)
 }
 
+// GenerateAuditForSharedQueryContext generates an audit share that is sent to the other server(s)
+// and returns ctx.Err() promptly if the context is cancelled during the expansion or the scan
+// (e.g., to bound the total audit time across servers)
+func GenerateAuditForSharedQueryContext(
+	ctx context.Context,
+	keyDB *Database,
+	query *AuthenticatedQueryShare,
+	nprocs int) (*AuditTokenShare, error) {
+
+	if err := keyDB.ValidateAuthQueryShare(query); err != nil {
+		return nil, err
+	}
+
+	oldGroupSize := query.GroupSize
+	query.GroupSize = 1 // key database has group size 1
+	defer func() { query.GroupSize = oldGroupSize }()
+
+	bits, err := keyDB.ExpandSharedQueryContext(ctx, query.QueryShare, nprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := keyDB.PrivateSecretSharedQueryWithExpandedBitsContext(ctx, query.QueryShare, bits, nprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuditTokenShare(res, query)
+}
+
 // GenerateAuditForSharedQueryWithExpandedBits generates an audit share that is sent to the other server(s)
 // using the expanded DPF bits provided to it
 func GenerateAuditForSharedQueryWithExpandedBits(
@@ -269,18 +566,34 @@ func GenerateAuditForSharedQueryWithExpandedBits(
 		return nil, err
 	}
 
+	return newAuditTokenShare(res, query)
+}
+
+// newAuditTokenShare masks the retrieved key share with the auth token share
+func newAuditTokenShare(res *SecretSharedQueryResult, query *AuthenticatedQueryShare) (*AuditTokenShare, error) {
+
 	if len(res.Shares) != 1 {
 		return nil, errors.New("Invalid challenge ciphertext result")
 	}
 
 	keySlotShare := res.Shares[0]
 	XorSlots(keySlotShare, query.AuthToken.T)
-	return &AuditTokenShare{keySlotShare}, nil
+	return &AuditTokenShare{T: keySlotShare}, nil
 }
 
 // CheckAudit outputs True of all provided audit tokens xor to zero
+// and, if any audit token carries a DigestCommitment, all audit tokens
+// carry the same DigestCommitment
 func CheckAudit(auditTokens ...*AuditTokenShare) bool {
 
+	for _, tok := range auditTokens {
+		if tok.DigestCommitment != nil || auditTokens[0].DigestCommitment != nil {
+			if !bytes.Equal(tok.DigestCommitment, auditTokens[0].DigestCommitment) {
+				return false
+			}
+		}
+	}
+
 	res := NewEmptySlot(len(auditTokens[0].T.Data))
 	for _, tok := range auditTokens {
 		XorSlots(res, tok.T)
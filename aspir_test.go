@@ -1,10 +1,15 @@
 package pir
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ncw/gmp"
 	"github.com/sachaservan/paillier"
 )
 
@@ -56,6 +61,156 @@ func TestASPIR(t *testing.T) {
 	}
 }
 
+// run with 'go test -v -run TestASPIRSoundness' to see log outputs.
+func TestASPIRSoundness(t *testing.T) {
+	secbytes := StatisticalSecurityBytes // statistical secuirity parameter for proof soundness
+	nprocs := 1
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		keydb := BuildKeyDBFromDataWithGroupSize(db, secbytes, groupSize)
+
+		for i := 0; i < NumTrials; i++ {
+			qIndex := rand.Intn(db.DBSize)
+
+			// auth key that does not match the key of the retrieved group
+			wrongKey := NewRandomSlot(secbytes)
+			if wrongKey.Equal(keydb.Slots[qIndex/groupSize]) {
+				continue
+			}
+
+			authQuery, state := db.NewAuthenticatedQuery(sk, groupSize, qIndex, wrongKey)
+
+			chalToken, err := GenerateAuthChalForQuery(secbytes, keydb, authQuery, nprocs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the client can only prove the null query (which retrieves nothing)
+			proofToken, err := AuthProve(state, chalToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proofToken.QBit == state.Bit {
+				t.Fatalf("Proof generated for the real query with a wrong auth key (group size %v)\n", groupSize)
+			}
+
+			if !AuthCheck(pk, authQuery, chalToken, proofToken) {
+				t.Fatalf("ASPIR proof for the null query failed")
+			}
+
+			nullQuery := authQuery.Query0
+			if proofToken.QBit == 1 {
+				nullQuery = authQuery.Query1
+			}
+
+			res, err := db.PrivateDoublyEncryptedQuery(nullQuery, nprocs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !res.IsNullResult(sk) {
+				t.Fatalf("Proven query retrieved data with a wrong auth key (group size %v)\n", groupSize)
+			}
+
+			// claiming the real query with the same proof fails
+			forged := *proofToken
+			forged.QBit = state.Bit
+			forged.AuthToken = state.AuthToken0
+			if state.Bit == 1 {
+				forged.AuthToken = state.AuthToken1
+			}
+
+			if AuthCheck(pk, authQuery, chalToken, &forged) {
+				t.Fatalf("ASPIR proof succeeded with a wrong auth key (group size %v)\n", groupSize)
+			}
+
+			// substituting an auth token derived from the challenge (i.e., from the
+			// key in the key database) for the committed auth token fails
+			forgedState := *state
+			if state.Bit == 0 {
+				forgedState.AuthToken0 = pk.Encrypt(sk.NestedDecrypt(chalToken.Token0))
+			} else {
+				forgedState.AuthToken1 = pk.Encrypt(sk.NestedDecrypt(chalToken.Token1))
+			}
+
+			forgedProof, err := AuthProve(&forgedState, chalToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if forgedProof.QBit == state.Bit && AuthCheck(pk, authQuery, chalToken, forgedProof) {
+				t.Fatalf("ASPIR proof succeeded with an auth token that does not match the commitment (group size %v)\n", groupSize)
+			}
+		}
+	}
+}
+
+func TestVerifyOwnProof(t *testing.T) {
+	secbytes := StatisticalSecurityBytes
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, secbytes)
+	keydb := GenerateRandomDB(TestDBSize, secbytes)
+	qIndex := rand.Intn(keydb.DBSize)
+
+	authQuery, state := db.NewAuthenticatedQuery(sk, 1, qIndex, keydb.Slots[qIndex])
+
+	chalToken, err := GenerateAuthChalForQuery(secbytes, keydb, authQuery, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofToken, err := AuthProve(state, chalToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !VerifyOwnProof(state, chalToken, proofToken) {
+		t.Fatalf("Self-check rejected a valid proof\n")
+	}
+
+	if !AuthCheck(pk, authQuery, chalToken, proofToken) {
+		t.Fatalf("ASPIR proof failed")
+	}
+
+	// corrupted randomness is caught before the proof is sent
+	proofToken.S = new(gmp.Int).Add(proofToken.S, gmp.NewInt(1))
+	if VerifyOwnProof(state, chalToken, proofToken) {
+		t.Fatalf("Self-check accepted a proof with corrupted randomness\n")
+	}
+}
+
+func TestValidateASPIRAlignment(t *testing.T) {
+
+	db := GenerateRandomDB(TestDBSize, StatisticalSecurityBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		keydb := BuildKeyDBFromDataWithGroupSize(db, StatisticalSecurityBytes, groupSize)
+		if err := ValidateASPIRAlignment(db, keydb, groupSize); err != nil {
+			t.Fatalf("Aligned key database was rejected: %v\n", err)
+		}
+	}
+
+	// one key per slot rather than per group of 3 slots
+	keydb := GenerateRandomDB(TestDBSize, StatisticalSecurityBytes)
+	if err := ValidateASPIRAlignment(db, keydb, 3); err == nil {
+		t.Fatalf("Misaligned key database was accepted\n")
+	}
+
+	// floor(DBSize/groupSize) keys miss the last (partial) group
+	keydb = GenerateRandomDB(TestDBSize/3, StatisticalSecurityBytes)
+	if err := ValidateASPIRAlignment(db, keydb, 3); err == nil {
+		t.Fatalf("Key database without a key for the last group was accepted\n")
+	}
+}
+
 // run with 'go test -v -run TestSharedASPIRCompleteness' to see log outputs.
 func TestSharedASPIRCompleteness(t *testing.T) {
 
@@ -83,6 +238,50 @@ func TestSharedASPIRCompleteness(t *testing.T) {
 	}
 }
 
+func TestSharedASPIRDigestAgreement(t *testing.T) {
+
+	secbytes := StatisticalSecurityBytes
+
+	dbs := []*Database{GenerateRandomDB(TestDBSize, SlotBytes), GenerateRandomDB(TestDBSize, SlotBytes)}
+	copy(dbs[1].Slots, dbs[0].Slots)
+
+	keydb := GenerateRandomDB(TestDBSize, secbytes)
+
+	index := rand.Intn(TestDBSize)
+	queryShares := keydb.NewAuthenticatedIndexQueryShares(index, keydb.Slots[index], 1, 2)
+
+	audit := func() []*AuditTokenShare {
+		audits := make([]*AuditTokenShare, 2)
+		for i := range audits {
+			var err error
+			audits[i], err = GenerateAuditForSharedQuery(keydb, queryShares[i], 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			audits[i].AttachDigest(dbs[i])
+		}
+		return audits
+	}
+
+	if !CheckAudit(audit()...) {
+		t.Fatalf("Audit failed for servers with the same database\n")
+	}
+
+	// the second server answers from a tampered database
+	dbs[1].Slots[rand.Intn(TestDBSize)] = NewRandomSlot(SlotBytes)
+
+	if CheckAudit(audit()...) {
+		t.Fatalf("Audit did not detect the tampered database\n")
+	}
+
+	// a server that does not commit to a digest is also rejected
+	audits := audit()
+	audits[1].DigestCommitment = nil
+	if CheckAudit(audits...) {
+		t.Fatalf("Audit accepted a missing digest commitment\n")
+	}
+}
+
 // run with 'go test -v -run TestSharedASPIRSoundness' to see log outputs.
 func TestSharedASPIRSoundness(t *testing.T) {
 
@@ -154,3 +353,352 @@ func BenchmarkProve(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkProveWithContext(b *testing.B) {
+	secbytes := StatisticalSecurityBytes // statistical secuirity parameter for proof soundness
+
+	sk, _ := paillier.KeyGen(1024)
+	keydb := GenerateRandomDB(BenchmarkDBSize, secbytes)
+
+	ctx := NewProverContext(sk)
+
+	authKey := keydb.Slots[0]
+	authQuery, state := keydb.DBMetadata.NewAuthenticatedQuery(sk, 1, 0, authKey)
+
+	chalToken, _ := GenerateAuthChalForQuery(secbytes, keydb, authQuery, 1)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := AuthProveWithContext(ctx, state, chalToken)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestAuthProveWithContext(t *testing.T) {
+	setup()
+
+	secbytes := StatisticalSecurityBytes
+
+	sk, pk := paillier.KeyGen(128)
+	ctx := NewProverContext(sk)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		keydb := BuildKeyDBFromDataWithGroupSize(db, secbytes, groupSize)
+
+		index := rand.Intn(TestDBSize)
+		authQuery, state := db.NewAuthenticatedQuery(sk, groupSize, index, keydb.Slots[index/groupSize])
+
+		chalToken, err := GenerateAuthChalForQuery(secbytes, keydb, authQuery, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proofToken, err := AuthProveWithContext(ctx, state, chalToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if proofToken.QBit != state.Bit || !AuthCheck(pk, authQuery, chalToken, proofToken) {
+			t.Fatalf("ASPIR proof generated with the context failed\n")
+		}
+
+		// proving the same challenge again never reuses the randomness
+		again, err := AuthProveWithContext(ctx, state, chalToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if again.T.C.Cmp(proofToken.T.C) == 0 || !AuthCheck(pk, authQuery, chalToken, again) {
+			t.Fatalf("Proving the same challenge again failed or reused the randomness\n")
+		}
+	}
+
+	// states of other keys are rejected
+	otherSk, _ := paillier.KeyGen(128)
+	_, state := db.NewAuthenticatedQuery(otherSk, 1, 0, NewRandomSlot(secbytes))
+	if _, err := AuthProveWithContext(ctx, state, &ChalToken{state.AuthToken0, state.AuthToken1, secbytes}); err == nil {
+		t.Fatalf("Context accepted a state generated with a different key\n")
+	}
+}
+
+func TestSharedASPIRContext(t *testing.T) {
+
+	secbytes := StatisticalSecurityBytes // statistical secuirity parameter for proof soundness
+
+	keydb := GenerateRandomDB(TestDBSize, secbytes) // get secparam in bytes
+
+	index := rand.Intn(TestDBSize)
+	authKey := keydb.Slots[index]
+	queryShares := keydb.NewAuthenticatedIndexQueryShares(index, authKey, 1, 2)
+
+	// completes when the context is not cancelled
+	audits := make([]*AuditTokenShare, 2)
+	for i := range audits {
+		audit, err := GenerateAuditForSharedQueryContext(context.Background(), keydb, queryShares[i], 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audits[i] = audit
+	}
+
+	if !CheckAudit(audits...) {
+		t.Fatalf("Secret shared ASPIR proof failed")
+	}
+
+	// returns immediately when the context is already cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	audit, err := GenerateAuditForSharedQueryContext(ctx, keydb, queryShares[0], 1)
+	if err != context.Canceled || audit != nil {
+		t.Fatalf("Expected %v, got %v\n", context.Canceled, err)
+	}
+
+	// cancel mid-expansion: the context is cancelled after the expansion checked it a few times
+	ctx = &countdownContext{Context: context.Background(), remaining: TestDBSize / 2}
+
+	audit, err = GenerateAuditForSharedQueryContext(ctx, keydb, queryShares[0], NumProcsForQuery)
+	if err != context.Canceled || audit != nil {
+		t.Fatalf("Expected %v, got %v\n", context.Canceled, err)
+	}
+
+	if queryShares[0].GroupSize != 1 {
+		t.Fatalf("Query group size was not restored")
+	}
+}
+
+func TestValidateAuthQueryShare(t *testing.T) {
+
+	keydb := GenerateRandomDB(TestDBSize, StatisticalSecurityBytes)
+
+	index := rand.Intn(TestDBSize)
+	queryShares := keydb.NewAuthenticatedIndexQueryShares(index, keydb.Slots[index], 1, 2)
+	if err := keydb.ValidateAuthQueryShare(queryShares[0]); err != nil {
+		t.Fatalf("Valid share was rejected: %v\n", err)
+	}
+
+	// share generated for a group size that does not match the key database
+	queryShares = keydb.NewAuthenticatedIndexQueryShares(0, keydb.Slots[0], 4, 2)
+	if audit, err := GenerateAuditForSharedQuery(keydb, queryShares[0], 1); err == nil || audit != nil {
+		t.Fatalf("Audit was generated for a share with a mismatched domain\n")
+	}
+
+	// auth token that does not match the key database slots
+	queryShares = keydb.NewAuthenticatedIndexQueryShares(index, NewRandomSlot(StatisticalSecurityBytes+1), 1, 2)
+	if audit, err := GenerateAuditForSharedQuery(keydb, queryShares[0], 1); err == nil || audit != nil {
+		t.Fatalf("Audit was generated for a share with a mismatched auth token\n")
+	}
+}
+
+// run with 'go test -v -run TestASPIRWithKeysDerivedFromData' to see log outputs.
+func TestASPIRWithKeysDerivedFromData(t *testing.T) {
+	secbytes := StatisticalSecurityBytes // statistical secuirity parameter for proof soundness
+	nprocs := 1
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		keydb := BuildKeyDBFromDataWithGroupSize(db, secbytes, groupSize)
+		if keydb.DBSize != int(math.Ceil(float64(db.DBSize)/float64(groupSize))) {
+			t.Fatalf("Key database has %v keys for group size %v\n", keydb.DBSize, groupSize)
+		}
+
+		for i := 0; i < NumTrials; i++ {
+			qIndex := rand.Intn(db.DBSize)
+
+			// the client derives the auth key from the content of the group
+			start := (qIndex / groupSize) * groupSize
+			end := int(math.Min(float64(start+groupSize), float64(db.DBSize)))
+			authKey := DeriveAuthKey(secbytes, db.Slots[start:end]...)
+
+			if !authKey.Equal(keydb.Slots[qIndex/groupSize]) {
+				t.Fatalf("Derived auth key does not match the key database")
+			}
+
+			authQuery, state := db.NewAuthenticatedQuery(sk, groupSize, qIndex, authKey)
+
+			chalToken, err := GenerateAuthChalForQuery(secbytes, keydb, authQuery, nprocs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			proofToken, err := AuthProve(state, chalToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the proof must be for the real query (not the null query)
+			if proofToken.QBit != state.Bit {
+				t.Fatalf("Proof generated for the null query")
+			}
+
+			if !AuthCheck(pk, authQuery, chalToken, proofToken) {
+				t.Fatalf("ASPIR proof failed")
+			}
+		}
+	}
+
+	// secret shared variant (group size 1)
+	keydb := BuildKeyDBFromData(db, secbytes)
+	for i := 0; i < NumTrials; i++ {
+		index := rand.Intn(db.DBSize)
+
+		authKey := DeriveAuthKey(secbytes, db.Slots[index])
+		queryShares := keydb.NewAuthenticatedIndexQueryShares(index, authKey, 1, 2)
+
+		audits := make([]*AuditTokenShare, 2)
+		audits[0], _ = GenerateAuditForSharedQuery(keydb, queryShares[0], 1)
+		audits[1], _ = GenerateAuditForSharedQuery(keydb, queryShares[1], 1)
+
+		if !CheckAudit(audits...) {
+			t.Fatalf("Secret shared ASPIR proof failed")
+		}
+	}
+}
+
+// run with 'go test -run=XXX -bench BenchmarkASPIREndToEnd' to see the per-phase breakdown.
+func BenchmarkASPIREndToEnd(b *testing.B) {
+	for _, keyBits := range []int{512, 1024} {
+		b.Run(fmt.Sprintf("KeyBits=%v", keyBits), func(b *testing.B) {
+			benchmarkASPIREndToEnd(b, keyBits)
+		})
+	}
+}
+
+func benchmarkASPIREndToEnd(b *testing.B, keyBits int) {
+	secbytes := StatisticalSecurityBytes // statistical secuirity parameter for proof soundness
+	nprocs := NumProcsForQuery
+
+	sk, pk := paillier.KeyGen(keyBits)
+	db := GenerateRandomDB(BenchmarkDBSize, SlotBytes)
+	keydb := BuildKeyDBFromData(db, secbytes)
+
+	var queryTime, chalTime, proveTime, checkTime, answerTime, recoverTime time.Duration
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		index := rand.Intn(db.DBSize)
+		authKey := keydb.Slots[index]
+
+		start := time.Now()
+		authQuery, state := db.NewAuthenticatedQuery(sk, 1, index, authKey)
+		queryTime += time.Since(start)
+
+		start = time.Now()
+		chalToken, err := GenerateAuthChalForQuery(secbytes, keydb, authQuery, nprocs)
+		if err != nil {
+			panic(err)
+		}
+		chalTime += time.Since(start)
+
+		start = time.Now()
+		proofToken, err := AuthProve(state, chalToken)
+		if err != nil {
+			panic(err)
+		}
+		proveTime += time.Since(start)
+
+		start = time.Now()
+		if !AuthCheck(pk, authQuery, chalToken, proofToken) {
+			panic("ASPIR proof failed")
+		}
+		checkTime += time.Since(start)
+
+		// the server answers both queries since it does not know which one is real
+		start = time.Now()
+		res0, err := db.PrivateDoublyEncryptedQuery(authQuery.Query0, nprocs)
+		if err != nil {
+			panic(err)
+		}
+		res1, err := db.PrivateDoublyEncryptedQuery(authQuery.Query1, nprocs)
+		if err != nil {
+			panic(err)
+		}
+		answerTime += time.Since(start)
+
+		start = time.Now()
+		res := res0
+		if state.Bit == 1 {
+			res = res1
+		}
+		if _, err := RecoverDoublyEncrypted(res, sk); err != nil {
+			panic(err)
+		}
+		recoverTime += time.Since(start)
+	}
+
+	b.StopTimer()
+
+	n := float64(b.N)
+	b.ReportMetric(float64(queryTime.Nanoseconds())/n, "query-ns/op")
+	b.ReportMetric(float64(chalTime.Nanoseconds())/n, "challenge-ns/op")
+	b.ReportMetric(float64(proveTime.Nanoseconds())/n, "prove-ns/op")
+	b.ReportMetric(float64(checkTime.Nanoseconds())/n, "check-ns/op")
+	b.ReportMetric(float64(answerTime.Nanoseconds())/n, "answer-ns/op")
+	b.ReportMetric(float64(recoverTime.Nanoseconds())/n, "recover-ns/op")
+}
+
+func TestAuthenticatedRetrieve(t *testing.T) {
+	setup()
+
+	secbytes := StatisticalSecurityBytes
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		keydb := BuildKeyDBFromDataWithGroupSize(db, secbytes, groupSize)
+		if err := ValidateASPIRAlignment(db, keydb, groupSize); err != nil {
+			t.Fatal(err)
+		}
+
+		index := rand.Intn(TestDBSize)
+		authQuery, state := db.NewAuthenticatedQuery(sk, groupSize, index, keydb.Slots[index/groupSize])
+
+		res, err := AuthenticatedRetrieve(secbytes, db, keydb, authQuery, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots, proofToken, err := RecoverAuthenticated(state, res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checkGroup(t, db, groupSize, index, slots)
+
+		if !AuthCheck(pk, authQuery, res.Chal, proofToken) {
+			t.Fatalf("ASPIR proof failed for the combined response\n")
+		}
+	}
+}
+
+// countdownContext is a context that reports cancellation
+// once Err has been called remaining times
+type countdownContext struct {
+	context.Context
+	mu        sync.Mutex
+	remaining int
+}
+
+func (ctx *countdownContext) Err() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if ctx.remaining <= 0 {
+		return context.Canceled
+	}
+
+	ctx.remaining--
+	return nil
+}
@@ -0,0 +1,214 @@
+package pir
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+// BenchmarkConfig is one configuration of a benchmark matrix (see RunBenchmarkMatrix)
+type BenchmarkConfig struct {
+	Variant   QueryVariant
+	DBSize    int
+	SlotBytes int
+	NumProcs  int
+	KeyBits   int // size of the Paillier modulus (ignored by the secret-shared variant)
+
+	// seed of the source of the queried index such that runs are reproducible
+	Seed int64
+}
+
+// BenchmarkResult is the measurement of one configuration.
+// NsPerOp is the server time to answer one query (per server for the secret-shared variant)
+// and UploadBytes and DownloadBytes are the sizes of the encoded queries and responses
+// (summed over the servers for the secret-shared variant)
+type BenchmarkResult struct {
+	Variant       string `json:"variant"`
+	DBSize        int    `json:"db_size"`
+	SlotBytes     int    `json:"slot_bytes"`
+	NumProcs      int    `json:"nprocs"`
+	KeyBits       int    `json:"key_bits,omitempty"`
+	Seed          int64  `json:"seed"`
+	Iterations    int    `json:"iterations"`
+	NsPerOp       int64  `json:"ns_per_op"`
+	UploadBytes   int    `json:"upload_bytes"`
+	DownloadBytes int    `json:"download_bytes"`
+}
+
+// BenchmarkReport collects benchmark results and writes each result
+// as a JSON line to its writer (if any) as soon as it is added
+type BenchmarkReport struct {
+	Results []*BenchmarkResult
+	w       io.Writer
+}
+
+// NewBenchmarkReport returns a report that writes JSON lines to w (which can be nil)
+func NewBenchmarkReport(w io.Writer) *BenchmarkReport {
+	return &BenchmarkReport{w: w}
+}
+
+// Add records the result and writes it to the report's writer
+func (report *BenchmarkReport) Add(res *BenchmarkResult) error {
+
+	report.Results = append(report.Results, res)
+
+	if report.w == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	_, err = report.w.Write(append(line, '\n'))
+	return err
+}
+
+// RunBenchmarkMatrix measures every configuration over a random database
+// (answering iterations queries for a random index) and adds the results to the report
+func RunBenchmarkMatrix(configs []*BenchmarkConfig, iterations int, report *BenchmarkReport) error {
+
+	if iterations <= 0 {
+		return errors.New("number of iterations must be positive")
+	}
+
+	for _, config := range configs {
+		res, err := runBenchmark(config, iterations)
+		if err != nil {
+			return err
+		}
+
+		if err := report.Add(res); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runBenchmark measures a single configuration
+func runBenchmark(config *BenchmarkConfig, iterations int) (*BenchmarkResult, error) {
+
+	if config.DBSize <= 0 || config.SlotBytes <= 0 {
+		return nil, errors.New("invalid database size")
+	}
+
+	nprocs := config.NumProcs
+	if nprocs <= 0 {
+		nprocs = 1
+	}
+
+	res := &BenchmarkResult{
+		Variant:    config.Variant.String(),
+		DBSize:     config.DBSize,
+		SlotBytes:  config.SlotBytes,
+		NumProcs:   nprocs,
+		Seed:       config.Seed,
+		Iterations: iterations,
+	}
+
+	db := GenerateRandomDB(config.DBSize, config.SlotBytes)
+	index := rand.New(rand.NewSource(config.Seed)).Intn(config.DBSize)
+
+	var pk *paillier.PublicKey
+	if config.Variant != SecretSharedVariant {
+		res.KeyBits = config.KeyBits
+		if res.KeyBits <= 0 {
+			res.KeyBits = CostModelKeyBits
+		}
+		_, pk = paillier.KeyGen(res.KeyBits)
+	}
+
+	var elapsed time.Duration
+	for it := 0; it < iterations; it++ {
+
+		var upload, download int
+
+		switch config.Variant {
+		case SecretSharedVariant:
+			for _, share := range db.NewIndexQueryShares(index, 1, 2) {
+				start := time.Now()
+				shareRes, err := db.PrivateSecretSharedQuery(share, nprocs)
+				elapsed += time.Since(start) / 2 // per server
+				if err != nil {
+					return nil, err
+				}
+
+				shareUpload, err := encodedSize(share)
+				if err != nil {
+					return nil, err
+				}
+
+				shareDownload, err := encodedSize(shareRes)
+				if err != nil {
+					return nil, err
+				}
+
+				upload += shareUpload
+				download += shareDownload
+			}
+
+		case EncryptedVariant:
+			width, _ := db.encryptedQueryDimentions(pk, 1)
+			query := db.NewEncryptedQuery(pk, 1, index/width)
+
+			start := time.Now()
+			encRes, err := db.PrivateEncryptedQuery(query, nprocs)
+			elapsed += time.Since(start)
+			if err != nil {
+				return nil, err
+			}
+
+			if upload, err = encodedSize(query); err != nil {
+				return nil, err
+			}
+
+			if download, err = encodedSize(encRes); err != nil {
+				return nil, err
+			}
+
+		case DoublyEncryptedVariant:
+			query := db.NewDoublyEncryptedQuery(pk, 1, index)
+
+			start := time.Now()
+			dres, err := db.PrivateDoublyEncryptedQuery(query, nprocs)
+			elapsed += time.Since(start)
+			if err != nil {
+				return nil, err
+			}
+
+			if upload, err = encodedSize(query); err != nil {
+				return nil, err
+			}
+
+			if download, err = encodedSize(dres); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, errors.New("unknown query variant")
+		}
+
+		res.UploadBytes, res.DownloadBytes = upload, download
+	}
+
+	res.NsPerOp = elapsed.Nanoseconds() / int64(iterations)
+
+	return res, nil
+}
+
+// encodedSize returns the size of the binary encoding of v
+func encodedSize(v interface{ MarshalBinary() ([]byte, error) }) (int, error) {
+
+	data, err := v.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
@@ -0,0 +1,57 @@
+package pir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBenchmarkReport(t *testing.T) {
+	setup()
+
+	var buf bytes.Buffer
+	report := NewBenchmarkReport(&buf)
+
+	configs := []*BenchmarkConfig{
+		{Variant: SecretSharedVariant, DBSize: TestDBSize, SlotBytes: SlotBytes, NumProcs: NumProcsForQuery},
+		{Variant: EncryptedVariant, DBSize: TestDBSize, SlotBytes: SlotBytes, NumProcs: NumProcsForQuery, KeyBits: 128},
+		{Variant: DoublyEncryptedVariant, DBSize: TestDBSize, SlotBytes: SlotBytes, NumProcs: NumProcsForQuery, KeyBits: 128},
+	}
+
+	if err := RunBenchmarkMatrix(configs, 2, report); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	numLines := 0
+	for scanner.Scan() {
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v\n", scanner.Text(), err)
+		}
+
+		for _, field := range []string{"variant", "db_size", "slot_bytes", "nprocs", "seed", "iterations", "ns_per_op", "upload_bytes", "download_bytes"} {
+			if _, ok := fields[field]; !ok {
+				t.Fatalf("JSON line %q is missing field %v\n", scanner.Text(), field)
+			}
+		}
+
+		config := configs[numLines]
+		if fields["variant"] != config.Variant.String() || fields["db_size"] != float64(config.DBSize) {
+			t.Fatalf("JSON line %q does not match the configuration\n", scanner.Text())
+		}
+
+		numLines++
+	}
+
+	if numLines != len(configs) || len(report.Results) != len(configs) {
+		t.Fatalf("Report has %v lines and %v results, expected %v\n", numLines, len(report.Results), len(configs))
+	}
+
+	for _, res := range report.Results {
+		if res.NsPerOp <= 0 || res.UploadBytes <= 0 || res.DownloadBytes <= 0 {
+			t.Fatalf("Result %+v is missing measurements\n", res)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package pir
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// BloomFilter is a public Bloom filter over the keywords of the
+// occupied slots of a keyword database. A client checks the filter
+// before issuing a keyword query and skips keywords that are definitely absent
+// (saving a round trip) without revealing anything to the server
+type BloomFilter struct {
+	Bits      []byte
+	NumBits   int
+	NumHashes int
+}
+
+// OccupancyBitmap returns a bitmap where bit i is set if slot i is non-empty.
+// The bitmap is public metadata; setting it as db.Occupancy lets the server
+// skip empty slots during the scan independently of the query
+func (db *Database) OccupancyBitmap() []byte {
+
+	bitmap := make([]byte, (db.DBSize+7)/8)
+	for i := 0; i < db.DBSize && i < len(db.Slots); i++ {
+		if !db.Slots[i].IsEmpty() {
+			bitmap[i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	return bitmap
+}
+
+// isOccupied returns false if the occupancy bitmap shows that the slot at index is empty
+// or that index is outside of the bitmap (and true if the database does not have an occupancy bitmap)
+func (db *Database) isOccupied(index int) bool {
+
+	if db.Occupancy == nil {
+		return true
+	}
+
+	if index < 0 || index/8 >= len(db.Occupancy) {
+		return false
+	}
+
+	return db.Occupancy[index/8]&(1<<uint(index%8)) != 0
+}
+
+// setOccupied updates the occupancy bitmap (if any) for the slot at index
+func (db *Database) setOccupied(index int, slot *Slot) {
+
+	if db.Occupancy == nil {
+		return
+	}
+
+	for len(db.Occupancy)*8 <= index {
+		db.Occupancy = append(db.Occupancy, 0)
+	}
+
+	if slot.IsEmpty() {
+		db.Occupancy[index/8] &^= 1 << uint(index%8)
+	} else {
+		db.Occupancy[index/8] |= 1 << uint(index%8)
+	}
+}
+
+// NewKeywordBloomFilter returns a Bloom filter of numBits bits using numHashes hash functions
+// over the keywords of the non-empty slots of the database
+func (db *Database) NewKeywordBloomFilter(numBits, numHashes int) *BloomFilter {
+
+	bf := &BloomFilter{
+		Bits:      make([]byte, (numBits+7)/8),
+		NumBits:   numBits,
+		NumHashes: numHashes,
+	}
+
+	for i, keyword := range db.Keywords {
+		if i < len(db.Slots) && !db.Slots[i].IsEmpty() {
+			bf.Add(int(keyword))
+		}
+	}
+
+	return bf
+}
+
+// Add inserts the keyword into the filter
+func (bf *BloomFilter) Add(keyword int) {
+	for _, pos := range bf.positions(keyword) {
+		bf.Bits[pos/8] |= 1 << uint(pos%8)
+	}
+}
+
+// MightContain returns false if the keyword is definitely not in the database
+// and true if it might be (with a false positive rate depending on the filter size)
+func (bf *BloomFilter) MightContain(keyword int) bool {
+
+	for _, pos := range bf.positions(keyword) {
+		if bf.Bits[pos/8]&(1<<uint(pos%8)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// positions returns the bits of the filter for the keyword
+// using double hashing over the digest of the keyword
+func (bf *BloomFilter) positions(keyword int) []int {
+
+	keywordBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(keywordBytes, uint64(keyword))
+	digest := sha256.Sum256(keywordBytes)
+
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16])
+
+	positions := make([]int, bf.NumHashes)
+	for i := range positions {
+		positions[i] = int((h1 + uint64(i)*h2) % uint64(bf.NumBits))
+	}
+
+	return positions
+}
@@ -0,0 +1,103 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestBloomFilterPrefilter(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	// sparse keyword database where only a few slots are occupied
+	db := GenerateEmptyDB(TestDBSize, SlotBytes)
+	keywords := make([]uint, TestDBSize)
+	for i, keyword := range rand.Perm(TestDBSize) {
+		keywords[i] = uint(keyword)
+	}
+	db.SetKeywords(keywords)
+
+	occupied := rand.Perm(TestDBSize)[:10]
+	for _, i := range occupied {
+		db.Slots[i] = NewRandomSlot(SlotBytes)
+	}
+
+	db.Occupancy = db.OccupancyBitmap()
+	bf := db.NewKeywordBloomFilter(1024, 4)
+
+	// present keywords are never skipped and still retrieve
+	for _, i := range occupied {
+		keyword := int(keywords[i])
+		if !bf.MightContain(keyword) {
+			t.Fatalf("Bloom filter does not contain keyword %v\n", keyword)
+		}
+
+		shares, err := db.NewKeywordQueryShares(keyword, 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resA, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := db.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Recover([]*SecretSharedQueryResult{resA, resB})
+		if !db.Slots[i].Equal(res[0]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[i], res[0])
+		}
+	}
+
+	// most absent keywords can be skipped by the client
+	skipped := 0
+	for i := range db.Slots {
+		if db.Slots[i].IsEmpty() && !bf.MightContain(int(keywords[i])) {
+			skipped++
+		}
+	}
+
+	if skipped < (TestDBSize-len(occupied))*9/10 {
+		t.Fatalf("Only %v of %v absent keywords can be skipped\n", skipped, TestDBSize-len(occupied))
+	}
+
+	// the encrypted scan skips the empty slots
+	db.OpCounter = NewOpCounter()
+	for _, i := range occupied {
+		query := db.NewEncryptedQueryWithDimentions(pk, 1, TestDBSize, 1, i)
+		res := encryptedQueryRowWithQuery(t, db, sk, query, i)
+		if !db.Slots[i].Equal(res[0]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[i], res[0])
+		}
+	}
+
+	if _, constMults := db.OpCounter.Totals(); constMults > uint64(len(occupied)*len(occupied)) {
+		t.Fatalf("Scan performed %v multiplications over %v occupied slots\n", constMults, len(occupied))
+	}
+}
+
+func TestOccupancyBitmapBounds(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.Occupancy = db.OccupancyBitmap()
+
+	for _, index := range []int{-1, TestDBSize + 8, 1 << 20} {
+		if db.isOccupied(index) {
+			t.Fatalf("Slot %v outside of the bitmap is occupied\n", index)
+		}
+	}
+
+	// a bitmap shorter than the database does not cause an out of range read
+	db.Occupancy = db.Occupancy[:1]
+	if db.isOccupied(TestDBSize - 1) {
+		t.Fatalf("Slot outside of a short bitmap is occupied\n")
+	}
+}
@@ -0,0 +1,97 @@
+package pir
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+// ZeroTestFunc maps each ciphertext to a fresh encryption of 1 if it
+// decrypts to zero and to a fresh encryption of 0 otherwise.
+// Paillier is only additively homomorphic, so testing an encrypted value
+// for equality requires the party holding the secret key (see NewZeroTest)
+type ZeroTestFunc func(cts []*paillier.Ciphertext) ([]*paillier.Ciphertext, error)
+
+// NewEncryptedQueryFromEncryptedIndex generates an encrypted PIR query
+// from an encryption of the row index (under the client's key) such that a third party
+// (e.g., a broker) can build the query without learning the index.
+// The broker computes Enc(r_j * (index - j)) for each row j using fresh random r_j,
+// which decrypts to zero only at the index and to a random value elsewhere,
+// and obtains the one-hot EBits vector by sending these to zeroTest.
+//
+// Cost: O(height) homomorphic operations for the broker and
+// O(height) decryptions and encryptions for the key holder (in one round).
+// The height is adjusted to fit the database as done by GetDimentionsForDatabase
+func (dbmd *DBMetadata) NewEncryptedQueryFromEncryptedIndex(
+	pk *paillier.PublicKey,
+	encryptedIndex *paillier.Ciphertext,
+	height, groupSize int,
+	zeroTest ZeroTestFunc) (*EncryptedQuery, error) {
+
+	width, height := dbmd.GetDimentionsForDatabase(height, groupSize)
+
+	diffs := make([]*paillier.Ciphertext, height)
+	for j := 0; j < height; j++ {
+
+		// encryption of -j (mod N)
+		negJ := new(gmp.Int).Sub(pk.N, gmp.NewInt(int64(j)))
+		negJ.Mod(negJ, pk.N)
+
+		diff := pk.Add(encryptedIndex, pk.Encrypt(negJ))
+		diffs[j] = pk.ConstMult(diff, randomNonZeroMod(pk.N))
+	}
+
+	ebits, err := zeroTest(diffs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ebits) != height {
+		return nil, errors.New("zero test returned an incorrect number of ciphertexts")
+	}
+
+	return &EncryptedQuery{
+		Pk:        pk,
+		EBits:     ebits,
+		GroupSize: groupSize,
+		DBWidth:   width,
+		DBHeight:  height,
+	}, nil
+}
+
+// NewZeroTest returns the ZeroTestFunc run by the key holder.
+// The key holder only learns the position of the zero, which is
+// the index it encrypted in the first place
+func NewZeroTest(sk *paillier.SecretKey) ZeroTestFunc {
+	return func(cts []*paillier.Ciphertext) ([]*paillier.Ciphertext, error) {
+
+		zero := gmp.NewInt(0)
+		res := make([]*paillier.Ciphertext, len(cts))
+		for i, ct := range cts {
+			if sk.Decrypt(ct).Cmp(zero) == 0 {
+				res[i] = sk.PublicKey.EncryptOne()
+			} else {
+				res[i] = sk.PublicKey.EncryptZero()
+			}
+		}
+
+		return res, nil
+	}
+}
+
+// randomNonZeroMod returns a uniformly random value in [1, n)
+func randomNonZeroMod(n *gmp.Int) *gmp.Int {
+
+	max := new(big.Int).SetBytes(n.Bytes())
+	max.Sub(max, big.NewInt(1))
+
+	r, err := rand.Int(randomSource, max)
+	if err != nil {
+		panic(err)
+	}
+
+	return new(gmp.Int).SetBytes(r.Add(r, big.NewInt(1)).Bytes())
+}
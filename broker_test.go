@@ -0,0 +1,42 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+// run with 'go test -v -run TestEncryptedQueryFromEncryptedIndex' to see log outputs.
+func TestEncryptedQueryFromEncryptedIndex(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+
+		for i := 0; i < NumTrials; i++ {
+			qIndex := rand.Intn(dimHeight)
+
+			// client encrypts the index and hands it to the broker
+			encryptedIndex := pk.Encrypt(gmp.NewInt(int64(qIndex)))
+
+			// broker builds the query with the help of the key holder
+			query, err := db.NewEncryptedQueryFromEncryptedIndex(pk, encryptedIndex, TestDBHeight, groupSize, NewZeroTest(sk))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if query.DBHeight != dimHeight {
+				t.Fatalf("Query has height %v, expected %v\n", query.DBHeight, dimHeight)
+			}
+
+			encryptedQueryRowWithQuery(t, db, sk, query, qIndex)
+		}
+	}
+}
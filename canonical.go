@@ -0,0 +1,272 @@
+package pir
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+// CanonicalMessage is a message with a canonical encoding, i.e., a byte encoding
+// that is a function of the message alone (see CanonicalBytes). Unlike the wire formats
+// (see wireversion.go), the canonical encoding is not versioned or compressed and never
+// changes, so commitments and transcript digests computed over it are reproducible
+// across versions of the package and across implementations in other languages.
+//
+// Every canonical encoding starts with the tag of the message type (a length-prefixed
+// string) followed by the fields in declaration order, where
+// integers and booleans are 8-byte big-endian values, byte strings are
+// prefixed with their 8-byte big-endian length, big integers are byte strings
+// holding their minimal big-endian magnitude (nil encodes as 0), and ciphertexts
+// are their encryption level followed by their value
+type CanonicalMessage interface {
+	CanonicalBytes() []byte
+}
+
+// TranscriptDigest returns the SHA-256 digest of the canonical encodings of the messages
+// (each prefixed with its length), e.g., to bind a proof to the protocol messages
+func TranscriptDigest(msgs ...CanonicalMessage) []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("pir transcript")
+	for _, msg := range msgs {
+		w.writeBytes(msg.CanonicalBytes())
+	}
+
+	digest := sha256.Sum256(w.buf)
+	return digest[:]
+}
+
+// CanonicalBytes returns the canonical encoding of the query share (see CanonicalMessage).
+// The compression of the share is a property of its transport and is not encoded,
+// and a share without its DPF key encodes an empty key
+func (query *QueryShare) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("QueryShare")
+	writeQueryShare(w, query)
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the encrypted query (see CanonicalMessage)
+// including the modulus of its public key and its proof (if any)
+func (query *EncryptedQuery) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("EncryptedQuery")
+	w.writeEncryptedQuery(query)
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the doubly encrypted query (see CanonicalMessage)
+func (query *DoublyEncryptedQuery) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("DoublyEncryptedQuery")
+	w.writeEncryptedQuery(query.Row)
+	w.writeEncryptedQuery(query.Col)
+
+	w.writeBool(query.ColumnMask != nil)
+	w.writeUint(uint64(len(query.ColumnMask)))
+	for _, b := range query.ColumnMask {
+		w.writeBool(b)
+	}
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the commitment (see CanonicalMessage)
+func (c *ROCommitment) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("ROCommitment")
+	w.writeBytes(c.HashBytes)
+	w.writeInt(c.R)
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the authenticated query (see CanonicalMessage)
+func (query *AuthenticatedEncryptedQuery) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("AuthenticatedEncryptedQuery")
+	w.writeBytes(query.Query0.CanonicalBytes())
+	w.writeBytes(query.Query1.CanonicalBytes())
+	w.writeBytes(query.AuthTokenComm0.CanonicalBytes())
+	w.writeBytes(query.AuthTokenComm1.CanonicalBytes())
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the challenge token (see CanonicalMessage)
+func (chal *ChalToken) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("ChalToken")
+	w.writeCiphertext(chal.Token0)
+	w.writeCiphertext(chal.Token1)
+	w.writeUint(uint64(chal.SecParam))
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the proof token (see CanonicalMessage)
+// including its DDLEQ proof (if any) in the encoding of MarshalProto
+func (proof *ProofToken) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("ProofToken")
+	w.writeCiphertext(proof.AuthToken)
+	w.writeCiphertext(proof.T)
+	w.writeUint(uint64(proof.QBit))
+	w.writeInt(proof.R)
+	w.writeInt(proof.S)
+
+	w.writeBool(proof.P != nil)
+	if proof.P != nil {
+		data, err := marshalDDLEQProof(proof.P)
+		if err != nil {
+			panic(err)
+		}
+		w.writeBytes(data)
+	}
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the auth token share (see CanonicalMessage)
+func (share *AuthTokenShare) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("AuthTokenShare")
+	w.writeBytes(share.T.Data)
+
+	return w.buf
+}
+
+// CanonicalBytes returns the canonical encoding of the audit token share (see CanonicalMessage)
+func (share *AuditTokenShare) CanonicalBytes() []byte {
+
+	w := &canonicalWriter{}
+	w.writeTag("AuditTokenShare")
+	w.writeBytes(share.T.Data)
+	w.writeBool(share.DigestCommitment != nil)
+	w.writeBytes(share.DigestCommitment)
+
+	return w.buf
+}
+
+// canonicalWriter appends fields to a buffer in the canonical encoding (see CanonicalMessage)
+type canonicalWriter struct {
+	buf []byte
+}
+
+func (w *canonicalWriter) writeUint(v uint64) {
+	w.buf = binary.BigEndian.AppendUint64(w.buf, v)
+}
+
+func (w *canonicalWriter) writeBool(b bool) {
+	if b {
+		w.writeUint(1)
+	} else {
+		w.writeUint(0)
+	}
+}
+
+func (w *canonicalWriter) writeBytes(b []byte) {
+	w.writeUint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *canonicalWriter) writeBytesList(bs [][]byte) {
+	w.writeUint(uint64(len(bs)))
+	for _, b := range bs {
+		w.writeBytes(b)
+	}
+}
+
+func (w *canonicalWriter) writeTag(tag string) {
+	w.writeBytes([]byte(tag))
+}
+
+func (w *canonicalWriter) writeInt(v *gmp.Int) {
+	if v == nil {
+		w.writeBytes(nil)
+	} else {
+		w.writeBytes(v.Bytes())
+	}
+}
+
+func (w *canonicalWriter) writeRegion(region *GroupRegion) {
+	w.writeBool(region != nil)
+	if region != nil {
+		w.writeUint(uint64(region.Start))
+		w.writeUint(uint64(region.End))
+		w.writeUint(uint64(region.GroupSize))
+	}
+}
+
+func (w *canonicalWriter) writeCiphertext(ct *paillier.Ciphertext) {
+	if ct == nil {
+		w.writeUint(0)
+		w.writeInt(nil)
+	} else {
+		w.writeUint(uint64(ct.Level))
+		w.writeInt(ct.C)
+	}
+}
+
+// writeEncryptedQuery writes the fields of the query, the modulus of its public key, and its proof
+func (w *canonicalWriter) writeEncryptedQuery(query *EncryptedQuery) {
+
+	var n *gmp.Int
+	if query.Pk != nil {
+		n = query.Pk.N
+	}
+	w.writeInt(n)
+
+	w.writeUint(uint64(query.GroupSize))
+	w.writeUint(uint64(query.DBWidth))
+	w.writeUint(uint64(query.DBHeight))
+
+	w.writeUint(uint64(len(query.EBits)))
+	for _, ct := range query.EBits {
+		w.writeCiphertext(ct)
+	}
+
+	w.writeRegion(query.Region)
+
+	proof := query.Proof
+	w.writeBool(proof != nil)
+	if proof == nil {
+		return
+	}
+
+	w.writeUint(uint64(len(proof.BitCommitments)))
+	for i := range proof.BitCommitments {
+		for m := 0; m < 2; m++ {
+			w.writeCiphertext(proof.BitCommitments[i][m])
+		}
+	}
+
+	w.writeUint(uint64(len(proof.BitChallenges)))
+	for i := range proof.BitChallenges {
+		for m := 0; m < 2; m++ {
+			w.writeInt(proof.BitChallenges[i][m])
+		}
+	}
+
+	w.writeUint(uint64(len(proof.BitResponses)))
+	for i := range proof.BitResponses {
+		for m := 0; m < 2; m++ {
+			w.writeInt(proof.BitResponses[i][m])
+		}
+	}
+
+	w.writeCiphertext(proof.SumCommitment)
+	w.writeInt(proof.SumResponse)
+}
@@ -0,0 +1,171 @@
+package pir
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+func TestCanonicalBytesFormat(t *testing.T) {
+
+	chal := &ChalToken{
+		Token0:   &paillier.Ciphertext{C: gmp.NewInt(0x0102), Level: paillier.EncLevelTwo},
+		Token1:   &paillier.Ciphertext{C: gmp.NewInt(3), Level: paillier.EncLevelTwo},
+		SecParam: 8,
+	}
+
+	// the canonical encoding is fixed (see CanonicalMessage)
+	expected := "0000000000000009" + hex.EncodeToString([]byte("ChalToken")) +
+		"0000000000000002" + "0000000000000002" + "0102" +
+		"0000000000000002" + "0000000000000001" + "03" +
+		"0000000000000008"
+
+	if hex.EncodeToString(chal.CanonicalBytes()) != expected {
+		t.Fatalf("Unexpected canonical encoding %x\n", chal.CanonicalBytes())
+	}
+
+	// the random oracle hashes length-prefixed values
+	if bytes.Equal(RandomOracleDigest(gmp.NewInt(1), gmp.NewInt(0x0203)), RandomOracleDigest(gmp.NewInt(0x0102), gmp.NewInt(3))) {
+		t.Fatalf("Random oracle digest is ambiguous\n")
+	}
+}
+
+func TestCanonicalBytesEncryptedQuery(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		query := db.NewDoublyEncryptedQuery(pk, groupSize, TestDBSize/2)
+
+		// the encoding survives a round trip through every wire format version
+		for _, version := range SupportedWireVersions() {
+			data, err := MarshalBinaryVersion(query, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := UnmarshalDoublyEncryptedQuery(data, pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(query.CanonicalBytes(), decoded.CanonicalBytes()) {
+				t.Fatalf("Canonical encoding changed after decoding version %v\n", version)
+			}
+		}
+
+		// the encoding depends on every ciphertext
+		other := *query.Row
+		other.EBits = append([]*paillier.Ciphertext{}, query.Row.EBits...)
+		other.EBits[0] = pk.Encrypt(gmp.NewInt(1))
+		if bytes.Equal(query.Row.CanonicalBytes(), other.CanonicalBytes()) {
+			t.Fatalf("Canonical encoding does not depend on the ciphertexts\n")
+		}
+
+	}
+}
+
+func TestAuthTranscriptDigest(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	keydb := GenerateRandomDB(TestDBSize, StatisticalSecurityBytes)
+
+	authQuery, state := db.NewAuthenticatedQuery(sk, 1, 7, keydb.Slots[7])
+	chalToken, err := GenerateAuthChalForQuery(StatisticalSecurityBytes, keydb, authQuery, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofToken, err := AuthProve(state, chalToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !AuthCheck(pk, authQuery, chalToken, proofToken) {
+		t.Fatalf("ASPIR proof failed\n")
+	}
+
+	digest := AuthTranscriptDigest(authQuery, chalToken, proofToken)
+	if !bytes.Equal(digest, TranscriptDigest(authQuery, chalToken, proofToken)) {
+		t.Fatalf("Transcript digest is not the digest of the messages\n")
+	}
+
+	if !bytes.Equal(AuthTranscriptDigest(authQuery, chalToken, nil), TranscriptDigest(authQuery, chalToken)) {
+		t.Fatalf("Transcript digest before the proof is not the digest of the query and challenge\n")
+	}
+
+	if bytes.Equal(digest, AuthTranscriptDigest(authQuery, chalToken, nil)) {
+		t.Fatalf("Transcript digest does not depend on the proof\n")
+	}
+
+	// the digest is the same after a round trip through the wire encodings
+	data, err := proofToken.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &ProofToken{}
+	if err := decoded.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(digest, AuthTranscriptDigest(authQuery, chalToken, decoded)) {
+		t.Fatalf("Transcript digest changed after a protobuf round trip\n")
+	}
+
+	// the digest depends on every field of the proof token
+	otherProof, err := AuthProve(state, chalToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, forge := range map[string]func(p *ProofToken){
+		"auth token": func(p *ProofToken) { p.AuthToken = otherAuthToken(state, proofToken) },
+		"T":          func(p *ProofToken) { p.T = otherProof.T },
+		"query bit":  func(p *ProofToken) { p.QBit = 1 - p.QBit },
+		"R":          func(p *ProofToken) { p.R = otherProof.R },
+		"S":          func(p *ProofToken) { p.S = otherProof.S },
+		"proof":      func(p *ProofToken) { p.P = otherProof.P },
+		"no proof":   func(p *ProofToken) { p.P = nil },
+	} {
+		forged := *proofToken
+		forge(&forged)
+		if bytes.Equal(digest, AuthTranscriptDigest(authQuery, chalToken, &forged)) {
+			t.Fatalf("Transcript digest does not depend on the %v of the proof token\n", name)
+		}
+	}
+
+	// and on the query and challenge
+	otherChal := *chalToken
+	otherChal.SecParam++
+	if bytes.Equal(digest, AuthTranscriptDigest(authQuery, &otherChal, proofToken)) {
+		t.Fatalf("Transcript digest does not depend on the challenge\n")
+	}
+
+	otherQuery := *authQuery
+	otherQuery.Query0, otherQuery.Query1 = authQuery.Query1, authQuery.Query0
+	if bytes.Equal(digest, AuthTranscriptDigest(&otherQuery, chalToken, proofToken)) {
+		t.Fatalf("Transcript digest does not depend on the query\n")
+	}
+
+	// the messages are length-prefixed, so moving bytes between them changes the digest
+	if bytes.Equal(digest, TranscriptDigest(chalToken, authQuery, proofToken)) {
+		t.Fatalf("Transcript digest does not depend on the order of the messages\n")
+	}
+}
+
+// otherAuthToken returns the auth token of the state that the proof is not for
+func otherAuthToken(state *AuthQueryPrivateState, proofToken *ProofToken) *paillier.Ciphertext {
+	if proofToken.QBit == 0 {
+		return state.AuthToken1
+	}
+	return state.AuthToken0
+}
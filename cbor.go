@@ -0,0 +1,591 @@
+package pir
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir/dpf"
+)
+
+// This file contains CBOR (RFC 8949) encodings of queries and results for
+// bandwidth-sensitive deployments (e.g., mobile clients, where the upload of
+// encrypted queries dominates the cost). Each struct is encoded as a CBOR array
+// of its fields in a fixed order (no field names), ciphertexts as [level, bytes]
+// with the big-endian bytes of the ciphertext, and absent optional fields as null:
+//
+//	QueryShare:                 [is_two_party, is_keyword_based, share_number, group_size,
+//	                             [prf_key...], dpf_key, null / [start, end, group_size], prefix_bits]
+//	SecretSharedQueryResult:    [slot_bytes, [share...]]
+//	EncryptedQuery:             [group_size, db_width, db_height, [ciphertext...],
+//	                             null / [start, end, group_size]] (the region is new in WireVersion3)
+//	DoublyEncryptedQuery:       [row, col, null / [bool...]]
+//	EncryptedQueryResult:       [slot_bytes, num_bytes_per_ciphertext, [[ciphertext...]...]]
+//	DoublyEncryptedQueryResult: [slot_bytes, num_bytes_per_ciphertext, group_size,
+//	                             null / [bool...], [[ciphertext...]...]]
+//
+// The encoding of a message is the array [version, message] where version is the
+// wire format version (see wireversion.go). Only definite-length items are produced
+// and accepted. As with the other encodings, the Paillier public key (and the query
+// proof) is not part of the encoding
+
+var errMalformedCBOR = errors.New("malformed CBOR encoding")
+
+// CBOR major types
+const (
+	cborUint  = 0
+	cborBytes = 2
+	cborArray = 4
+	cborOther = 7
+)
+
+// CBOR simple values (major type 7)
+const (
+	cborFalse = 20
+	cborTrue  = 21
+	cborNull  = 22
+)
+
+// MarshalCBOR encodes the query share
+// in the current wire format version (see MarshalCBORVersion)
+func (query *QueryShare) MarshalCBOR() ([]byte, error) {
+	return MarshalCBORVersion(query, CurrentWireVersion)
+}
+
+func (query *QueryShare) encodeCBOR(w *cborWriter) {
+	w.writeArray(8)
+	if err := writeQueryShare(w, query); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// UnmarshalCBOR decodes a query share encoded with MarshalCBOR
+func (query *QueryShare) UnmarshalCBOR(data []byte) error {
+
+	r := newCBORReader(data)
+	r.readArrayOf(8)
+
+	res := &QueryShare{}
+	res.IsTwoParty = r.readBool()
+	res.IsKeywordBased = r.readBool()
+	res.ShareNumber = uint(r.readUint())
+	res.GroupSize = int(r.readUint())
+
+	res.PrfKeys = make([]*dpf.PrfKey, r.readArray())
+	for i := range res.PrfKeys {
+		res.PrfKeys[i] = &dpf.PrfKey{Bytes: r.readBytes()}
+	}
+
+	keyBytes := r.readBytes()
+
+	res.Region = r.readRegion()
+	res.PrefixBits = uint(r.readUint())
+
+	if err := r.done(); err != nil {
+		return err
+	}
+
+	if err := res.setDPFKey(keyBytes); err != nil {
+		return err
+	}
+
+	*query = *res
+	return nil
+}
+
+// MarshalCBOR encodes the result share
+// in the current wire format version (see MarshalCBORVersion)
+func (res *SecretSharedQueryResult) MarshalCBOR() ([]byte, error) {
+	return MarshalCBORVersion(res, CurrentWireVersion)
+}
+
+func (res *SecretSharedQueryResult) encodeCBOR(w *cborWriter) {
+	w.writeArray(2)
+	w.writeUint(uint64(res.SlotBytes))
+	w.writeArray(len(res.Shares))
+	for _, share := range res.Shares {
+		w.writeBytes(share.Data)
+	}
+}
+
+// UnmarshalCBOR decodes a result share encoded with MarshalCBOR
+func (res *SecretSharedQueryResult) UnmarshalCBOR(data []byte) error {
+
+	r := newCBORReader(data)
+	r.readArrayOf(2)
+	slotBytes := int(r.readUint())
+	shares := make([]*Slot, r.readArray())
+	for i := range shares {
+		shares[i] = NewSlot(r.readBytes())
+	}
+
+	if err := r.done(); err != nil {
+		return err
+	}
+
+	res.SlotBytes = slotBytes
+	res.Shares = shares
+	return nil
+}
+
+// MarshalCBOR encodes the encrypted query (without the public key and the query proof)
+// in the current wire format version (see MarshalCBORVersion)
+func (query *EncryptedQuery) MarshalCBOR() ([]byte, error) {
+	return MarshalCBORVersion(query, CurrentWireVersion)
+}
+
+func (query *EncryptedQuery) encodeCBOR(w *cborWriter) {
+	w.writeEncryptedQuery(query)
+}
+
+// UnmarshalEncryptedQueryCBOR decodes an encrypted query encoded with MarshalCBOR
+// that is encrypted under pk
+func UnmarshalEncryptedQueryCBOR(data []byte, pk *paillier.PublicKey) (*EncryptedQuery, error) {
+
+	r := newCBORReader(data)
+	query := r.readEncryptedQuery(pk)
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// MarshalCBOR encodes the doubly encrypted query (without the public key)
+// in the current wire format version (see MarshalCBORVersion)
+func (query *DoublyEncryptedQuery) MarshalCBOR() ([]byte, error) {
+	return MarshalCBORVersion(query, CurrentWireVersion)
+}
+
+func (query *DoublyEncryptedQuery) encodeCBOR(w *cborWriter) {
+	w.writeArray(3)
+	w.writeEncryptedQuery(query.Row)
+	w.writeEncryptedQuery(query.Col)
+	w.writeColumnMask(query.ColumnMask)
+}
+
+// UnmarshalDoublyEncryptedQueryCBOR decodes a doubly encrypted query encoded with
+// MarshalCBOR that is encrypted under pk
+func UnmarshalDoublyEncryptedQueryCBOR(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQuery, error) {
+
+	r := newCBORReader(data)
+	r.readArrayOf(3)
+	query := &DoublyEncryptedQuery{}
+	query.Row = r.readEncryptedQuery(pk)
+	query.Col = r.readEncryptedQuery(pk)
+	query.ColumnMask = r.readColumnMask()
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// MarshalCBOR encodes the encrypted result (without the public key)
+// in the current wire format version (see MarshalCBORVersion)
+func (res *EncryptedQueryResult) MarshalCBOR() ([]byte, error) {
+	return MarshalCBORVersion(res, CurrentWireVersion)
+}
+
+func (res *EncryptedQueryResult) encodeCBOR(w *cborWriter) {
+	w.writeArray(3)
+	w.writeUint(uint64(res.SlotBytes))
+	w.writeUint(uint64(res.NumBytesPerCiphertext))
+	w.writeArray(len(res.Slots))
+	for _, eslot := range res.Slots {
+		w.writeCiphertexts(eslot.Cts)
+	}
+}
+
+// UnmarshalEncryptedQueryResultCBOR decodes an encrypted result encoded with
+// MarshalCBOR that is encrypted under pk
+func UnmarshalEncryptedQueryResultCBOR(data []byte, pk *paillier.PublicKey) (*EncryptedQueryResult, error) {
+
+	r := newCBORReader(data)
+	r.readArrayOf(3)
+	res := &EncryptedQueryResult{Pk: pk}
+	res.SlotBytes = int(r.readUint())
+	res.NumBytesPerCiphertext = int(r.readUint())
+	res.Slots = make([]*EncryptedSlot, r.readArray())
+	for i := range res.Slots {
+		res.Slots[i] = &EncryptedSlot{Cts: r.readCiphertexts()}
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// MarshalCBOR encodes the doubly encrypted result (without the public key)
+// in the current wire format version (see MarshalCBORVersion)
+func (res *DoublyEncryptedQueryResult) MarshalCBOR() ([]byte, error) {
+	return MarshalCBORVersion(res, CurrentWireVersion)
+}
+
+func (res *DoublyEncryptedQueryResult) encodeCBOR(w *cborWriter) {
+	w.writeArray(5)
+	w.writeUint(uint64(res.SlotBytes))
+	w.writeUint(uint64(res.NumBytesPerCiphertext))
+	w.writeUint(uint64(res.GroupSize))
+	w.writeColumnMask(res.ColumnMask)
+	w.writeArray(len(res.Slots))
+	for _, slot := range res.Slots {
+		w.writeCiphertexts(slot.Cts)
+	}
+}
+
+// UnmarshalDoublyEncryptedQueryResultCBOR decodes a doubly encrypted result encoded
+// with MarshalCBOR that is encrypted under pk
+func UnmarshalDoublyEncryptedQueryResultCBOR(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQueryResult, error) {
+
+	r := newCBORReader(data)
+	r.readArrayOf(5)
+	res := &DoublyEncryptedQueryResult{Pk: pk}
+	res.SlotBytes = int(r.readUint())
+	res.NumBytesPerCiphertext = int(r.readUint())
+	res.GroupSize = int(r.readUint())
+	res.ColumnMask = r.readColumnMask()
+	res.Slots = make([]*DoublyEncryptedSlot, r.readArray())
+	for i := range res.Slots {
+		res.Slots[i] = &DoublyEncryptedSlot{Cts: r.readCiphertexts()}
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// cborWriter appends CBOR data items to a buffer
+// in the layout of the given wire format version
+// and records the first error encountered
+type cborWriter struct {
+	buf     []byte
+	version uint
+	err     error
+}
+
+// writeHead writes the initial byte (and argument) of a data item
+func (w *cborWriter) writeHead(major byte, n uint64) {
+
+	switch {
+	case n < 24:
+		w.buf = append(w.buf, major<<5|byte(n))
+	case n <= 0xff:
+		w.buf = append(w.buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		w.buf = append(w.buf, major<<5|25)
+		w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(n))
+	case n <= 0xffffffff:
+		w.buf = append(w.buf, major<<5|26)
+		w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(n))
+	default:
+		w.buf = append(w.buf, major<<5|27)
+		w.buf = binary.BigEndian.AppendUint64(w.buf, n)
+	}
+}
+
+func (w *cborWriter) writeUint(v uint64) {
+	w.writeHead(cborUint, v)
+}
+
+func (w *cborWriter) writeBool(b bool) {
+	if b {
+		w.writeHead(cborOther, cborTrue)
+	} else {
+		w.writeHead(cborOther, cborFalse)
+	}
+}
+
+func (w *cborWriter) writeNull() {
+	w.writeHead(cborOther, cborNull)
+}
+
+func (w *cborWriter) writeBytes(b []byte) {
+	w.writeHead(cborBytes, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *cborWriter) writeArray(n int) {
+	w.writeHead(cborArray, uint64(n))
+}
+
+func (w *cborWriter) writeBytesList(bs [][]byte) {
+	w.writeArray(len(bs))
+	for _, b := range bs {
+		w.writeBytes(b)
+	}
+}
+
+func (w *cborWriter) writeCiphertexts(cts []*paillier.Ciphertext) {
+	w.writeArray(len(cts))
+	for _, ct := range cts {
+		w.writeArray(2)
+		w.writeUint(uint64(ct.Level))
+		w.writeBytes(ct.C.Bytes())
+	}
+}
+
+func (w *cborWriter) writeEncryptedQuery(query *EncryptedQuery) {
+
+	if w.version >= WireVersion3 {
+		w.writeArray(5)
+	} else {
+		w.writeArray(4)
+	}
+
+	w.writeUint(uint64(query.GroupSize))
+	w.writeUint(uint64(query.DBWidth))
+	w.writeUint(uint64(query.DBHeight))
+	w.writeCiphertexts(query.EBits)
+
+	if w.version >= WireVersion3 {
+		w.writeRegion(query.Region)
+	} else if query.Region != nil && w.err == nil {
+		w.err = ErrFieldNotInWireVersion
+	}
+}
+
+func (w *cborWriter) writeRegion(region *GroupRegion) {
+
+	if region == nil {
+		w.writeNull()
+		return
+	}
+
+	w.writeArray(3)
+	w.writeUint(uint64(region.Start))
+	w.writeUint(uint64(region.End))
+	w.writeUint(uint64(region.GroupSize))
+}
+
+func (w *cborWriter) writeColumnMask(mask []bool) {
+
+	if mask == nil {
+		w.writeNull()
+		return
+	}
+
+	w.writeArray(len(mask))
+	for _, b := range mask {
+		w.writeBool(b)
+	}
+}
+
+// cborReader reads the data items written by cborWriter
+// and records the first error encountered
+type cborReader struct {
+	buf     []byte
+	err     error
+	version uint // wire format version of the encoding
+}
+
+// newCBORReader returns a reader over the message of the encoding after reading
+// its wire format version (ErrUnsupportedWireVersion if it is not supported)
+func newCBORReader(data []byte) *cborReader {
+
+	r := &cborReader{buf: data}
+	r.readArrayOf(2)
+	r.version = uint(r.readUint())
+	if r.err == nil && !IsSupportedWireVersion(r.version) {
+		r.err = ErrUnsupportedWireVersion
+	}
+
+	return r
+}
+
+// readHead reads the initial byte (and argument) of a data item
+func (r *cborReader) readHead() (byte, uint64) {
+
+	if r.err != nil {
+		return 0, 0
+	}
+
+	if len(r.buf) == 0 {
+		r.err = errMalformedCBOR
+		return 0, 0
+	}
+
+	major, info := r.buf[0]>>5, r.buf[0]&0x1f
+	r.buf = r.buf[1:]
+
+	if info < 24 {
+		return major, uint64(info)
+	}
+
+	size := 0
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default: // indefinite lengths and reserved values
+		r.err = errMalformedCBOR
+		return 0, 0
+	}
+
+	if len(r.buf) < size {
+		r.err = errMalformedCBOR
+		return 0, 0
+	}
+
+	var n uint64
+	for _, b := range r.buf[:size] {
+		n = n<<8 | uint64(b)
+	}
+	r.buf = r.buf[size:]
+
+	return major, n
+}
+
+// expect reads the head of a data item of the given major type and returns its argument
+func (r *cborReader) expect(major byte) uint64 {
+
+	m, n := r.readHead()
+	if r.err == nil && m != major {
+		r.err = errMalformedCBOR
+		return 0
+	}
+
+	return n
+}
+
+func (r *cborReader) readUint() uint64 {
+	return r.expect(cborUint)
+}
+
+func (r *cborReader) readBool() bool {
+
+	v := r.expect(cborOther)
+	if r.err == nil && v != cborTrue && v != cborFalse {
+		r.err = errMalformedCBOR
+	}
+
+	return v == cborTrue
+}
+
+// readNull consumes a null item and returns true if the next item is null
+func (r *cborReader) readNull() bool {
+
+	if r.err != nil || len(r.buf) == 0 || r.buf[0] != cborOther<<5|cborNull {
+		return false
+	}
+
+	r.buf = r.buf[1:]
+	return true
+}
+
+func (r *cborReader) readBytes() []byte {
+
+	n := r.expect(cborBytes)
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = errMalformedCBOR
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	b := append([]byte{}, r.buf[:n]...)
+	r.buf = r.buf[n:]
+	return b
+}
+
+// readArray reads the head of an array (whose elements are each encoded with at least one byte)
+func (r *cborReader) readArray() int {
+
+	n := r.expect(cborArray)
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = errMalformedCBOR
+		return 0
+	}
+
+	return int(n)
+}
+
+// readArrayOf reads the head of an array of exactly n elements
+func (r *cborReader) readArrayOf(n int) {
+	if r.readArray() != n && r.err == nil {
+		r.err = errMalformedCBOR
+	}
+}
+
+func (r *cborReader) readCiphertexts() []*paillier.Ciphertext {
+
+	cts := make([]*paillier.Ciphertext, r.readArray())
+	for i := range cts {
+		r.readArrayOf(2)
+		level := paillier.EncryptionLevel(r.readUint())
+		c := new(gmp.Int).SetBytes(r.readBytes())
+		cts[i] = &paillier.Ciphertext{C: c, Level: level}
+	}
+
+	return cts
+}
+
+func (r *cborReader) readEncryptedQuery(pk *paillier.PublicKey) *EncryptedQuery {
+
+	if r.version >= WireVersion3 {
+		r.readArrayOf(5)
+	} else {
+		r.readArrayOf(4)
+	}
+
+	query := &EncryptedQuery{Pk: pk}
+	query.GroupSize = int(r.readUint())
+	query.DBWidth = int(r.readUint())
+	query.DBHeight = int(r.readUint())
+	query.EBits = r.readCiphertexts()
+
+	if r.version >= WireVersion3 {
+		query.Region = r.readRegion()
+	}
+
+	return query
+}
+
+func (r *cborReader) readRegion() *GroupRegion {
+
+	if r.readNull() {
+		return nil
+	}
+
+	r.readArrayOf(3)
+	return &GroupRegion{
+		Start:     int(r.readUint()),
+		End:       int(r.readUint()),
+		GroupSize: int(r.readUint()),
+	}
+}
+
+func (r *cborReader) readColumnMask() []bool {
+
+	if r.readNull() {
+		return nil
+	}
+
+	mask := make([]bool, r.readArray())
+	for i := range mask {
+		mask[i] = r.readBool()
+	}
+
+	return mask
+}
+
+func (r *cborReader) done() error {
+
+	if r.err == nil && len(r.buf) != 0 {
+		r.err = errMalformedCBOR
+	}
+
+	return r.err
+}
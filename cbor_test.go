@@ -0,0 +1,266 @@
+package pir
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestSecretSharedQueryCBOR(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+
+		resShares := make([]*SecretSharedQueryResult, 2)
+		for j, share := range db.NewIndexQueryShares(index, 1, 2) {
+			data, err := share.MarshalCBOR()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded := &QueryShare{}
+			if err := decoded.UnmarshalCBOR(data); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := db.PrivateSecretSharedQuery(decoded, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err = res.MarshalCBOR()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resShares[j] = &SecretSharedQueryResult{}
+			if err := resShares[j].UnmarshalCBOR(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res := Recover(resShares)
+		if !db.Slots[index].Equal(res[0]) {
+			t.Fatalf("Incorrect result after CBOR round trip: expected %v, got %v\n", db.Slots[index], res[0])
+		}
+	}
+}
+
+func TestEncryptedQueryCBOR(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	width, height := db.encryptedQueryDimentions(pk, 1)
+	row := rand.Intn(height)
+
+	data, err := db.NewEncryptedQuery(pk, 1, row).MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := UnmarshalEncryptedQueryCBOR(data, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = res.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = UnmarshalEncryptedQueryResultCBOR(data, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := RecoverEncrypted(res, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j := 0; j < width && row*width+j < db.DBSize; j++ {
+		if !db.Slots[row*width+j].Equal(slots[j]) {
+			t.Fatalf("Incorrect result after CBOR round trip: expected %v, got %v\n", db.Slots[row*width+j], slots[j])
+		}
+	}
+}
+
+func TestDoublyEncryptedQueryCBOR(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+
+		// every other group member (nil mask for group size 1)
+		var mask []bool
+		if groupSize > 1 {
+			mask = make([]bool, groupSize)
+			for j := range mask {
+				mask[j] = j%2 == 0
+			}
+		}
+
+		data, err := db.NewDoublyEncryptedQueryWithColumnMask(pk, groupSize, index, mask).MarshalCBOR()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, err := UnmarshalDoublyEncryptedQueryCBOR(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err = res.MarshalCBOR()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err = UnmarshalDoublyEncryptedQueryResultCBOR(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots, err := RecoverDoublyEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// only the unmasked group members are returned (in order)
+		start := (index / groupSize) * groupSize
+		j := 0
+		for member := 0; member < groupSize; member++ {
+			if mask != nil && !mask[member] {
+				continue
+			}
+
+			expected := NewEmptySlot(SlotBytes)
+			if start+member < db.DBSize {
+				expected = db.Slots[start+member]
+			}
+
+			if !expected.Equal(slots[j]) {
+				t.Fatalf("Incorrect result after CBOR round trip: expected %v, got %v\n", expected, slots[j])
+			}
+			j++
+		}
+	}
+}
+
+func TestCBORWireFormat(t *testing.T) {
+
+	// [1, [3, [h'010203']]] (RFC 8949 diagnostic notation) for wire format version 1
+	expected := []byte{0x82, 0x01, 0x82, 0x03, 0x81, 0x43, 0x01, 0x02, 0x03}
+
+	res := &SecretSharedQueryResult{SlotBytes: 3, Shares: []*Slot{NewSlot([]byte{1, 2, 3})}}
+	data, err := MarshalCBORVersion(res, WireVersion1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("Unexpected CBOR encoding: expected %x, got %x\n", expected, data)
+	}
+
+	// non-preferred (but valid) argument sizes are accepted
+	decoded := &SecretSharedQueryResult{}
+	if err := decoded.UnmarshalCBOR([]byte{0x82, 0x01, 0x82, 0x19, 0x00, 0x03, 0x81, 0x58, 0x03, 0x01, 0x02, 0x03}); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.SlotBytes != 3 || len(decoded.Shares) != 1 || !decoded.Shares[0].Equal(res.Shares[0]) {
+		t.Fatalf("Incorrect CBOR decoding: %v\n", decoded)
+	}
+
+	for _, malformed := range [][]byte{
+		expected[:len(expected)-1],                                   // truncated
+		append(append([]byte{}, expected...), 0x00),                  // trailing bytes
+		{0x82, 0x01, 0x9f, 0x03, 0x81, 0x43, 0x01, 0x02, 0x03, 0xff}, // indefinite length
+		{0x82, 0x01, 0x82, 0x03, 0x81, 0x03},                         // wrong type
+	} {
+		if err := decoded.UnmarshalCBOR(malformed); err == nil {
+			t.Fatalf("Decoded malformed CBOR %x\n", malformed)
+		}
+	}
+}
+
+func TestCBORSmallerThanJSON(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	query := db.NewEncryptedQuery(pk, 1, 0)
+
+	cborData, err := query.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonData, err := json.Marshal(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cborData) >= len(jsonData) {
+		t.Fatalf("CBOR encoding (%v bytes) is not smaller than JSON (%v bytes)\n", len(cborData), len(jsonData))
+	}
+}
+
+// benchmarkEncodingSize reports the size of the encoding of an encrypted query
+// (over a database of BenchmarkDBSize slots) as the "bytes/query" metric
+func benchmarkEncodingSize(b *testing.B, encode func(query *EncryptedQuery) ([]byte, error)) {
+	setup()
+
+	_, pk := paillier.KeyGen(1024)
+	db := GenerateRandomDB(BenchmarkDBSize, SlotBytes)
+	query := db.NewEncryptedQuery(pk, 1, 0)
+
+	b.ResetTimer()
+
+	var size int
+	for i := 0; i < b.N; i++ {
+		data, err := encode(query)
+		if err != nil {
+			panic(err)
+		}
+		size = len(data)
+	}
+
+	b.ReportMetric(float64(size), "bytes/query")
+}
+
+func BenchmarkEncryptedQuerySizeBinary(b *testing.B) {
+	benchmarkEncodingSize(b, func(query *EncryptedQuery) ([]byte, error) { return query.MarshalBinary() })
+}
+
+func BenchmarkEncryptedQuerySizeCBOR(b *testing.B) {
+	benchmarkEncodingSize(b, func(query *EncryptedQuery) ([]byte, error) { return query.MarshalCBOR() })
+}
+
+func BenchmarkEncryptedQuerySizeProto(b *testing.B) {
+	benchmarkEncodingSize(b, func(query *EncryptedQuery) ([]byte, error) { return query.MarshalProto() })
+}
+
+func BenchmarkEncryptedQuerySizeJSON(b *testing.B) {
+	benchmarkEncodingSize(b, func(query *EncryptedQuery) ([]byte, error) { return json.Marshal(query) })
+}
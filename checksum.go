@@ -0,0 +1,68 @@
+package pir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// ChecksumBytes is the size of the checksum appended to each slot of a checksummed database
+const ChecksumBytes = 8
+
+// ErrChecksumMismatch is returned when a recovered slot does not match its checksum
+var ErrChecksumMismatch = errors.New("recovered slot does not match its checksum")
+
+// NewChecksummedDatabase returns a copy of the database where each slot
+// has a checksum of its data appended (ChecksumBytes bytes).
+// The checksum is a truncated SHA-256 digest rather than a CRC: a CRC is linear
+// so a server could XOR a valid (data, CRC) pair into its share without detection,
+// whereas the digest cannot be matched without knowing the slot data
+func NewChecksummedDatabase(db *Database) *Database {
+
+	checked := NewDatabase()
+	checked.SlotBytes = db.SlotBytes + ChecksumBytes
+	checked.DBSize = db.DBSize
+	checked.Keywords = db.Keywords
+	checked.Slots = make([]*Slot, len(db.Slots))
+
+	for i, slot := range db.Slots {
+		data := make([]byte, 0, checked.SlotBytes)
+		data = append(data, slot.Data...)
+		data = append(data, slotChecksum(slot.Data)...)
+		checked.Slots[i] = NewSlot(data)
+	}
+
+	return checked
+}
+
+// RecoverChecked combines the result shares of a query to a checksummed database
+// (see NewChecksummedDatabase) and returns the slots without their checksum.
+// Returns ErrChecksumMismatch if any slot is corrupted.
+// All-zero slots are accepted as they are returned for group members
+// past the end of the database
+func RecoverChecked(resShares []*SecretSharedQueryResult) ([]*Slot, error) {
+
+	if resShares[0].SlotBytes < ChecksumBytes {
+		return nil, errors.New("result slots are too small to contain a checksum")
+	}
+
+	slots := Recover(resShares)
+	dataBytes := resShares[0].SlotBytes - ChecksumBytes
+
+	res := make([]*Slot, len(slots))
+	for i, slot := range slots {
+		data := slot.Data[:dataBytes]
+		if !slot.IsEmpty() && !bytes.Equal(slot.Data[dataBytes:], slotChecksum(data)) {
+			return nil, ErrChecksumMismatch
+		}
+
+		res[i] = NewSlot(data)
+	}
+
+	return res, nil
+}
+
+func slotChecksum(data []byte) []byte {
+	digest := sha256.Sum256(data)
+	return digest[:ChecksumBytes]
+}
@@ -0,0 +1,41 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRecoverChecked(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	checked := NewChecksummedDatabase(db)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+		shares := checked.NewIndexQueryShares(index/groupSize, groupSize, 2)
+
+		resA, err := checked.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := checked.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := RecoverChecked([]*SecretSharedQueryResult{resA, resB})
+		if err != nil {
+			t.Fatalf("Failed to recover uncorrupted result: %v\n", err)
+		}
+
+		checkGroup(t, db, groupSize, index, res)
+
+		// flipping a bit in one share is detected
+		resB.Shares[index%groupSize].Data[rand.Intn(checked.SlotBytes)] ^= 1 << uint(rand.Intn(8))
+		if _, err := RecoverChecked([]*SecretSharedQueryResult{resA, resB}); err != ErrChecksumMismatch {
+			t.Fatalf("Corrupted share was not detected (err = %v)\n", err)
+		}
+	}
+}
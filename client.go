@@ -0,0 +1,173 @@
+package pir
+
+import (
+	"errors"
+
+	"github.com/sachaservan/paillier"
+)
+
+// EncryptedQueryFunc sends an encrypted query to the server and returns the result
+type EncryptedQueryFunc func(query *EncryptedQuery) (*EncryptedQueryResult, error)
+
+// DownloadFunc downloads every slot of the database from the server (trivial PIR)
+type DownloadFunc func() ([]*Slot, error)
+
+// Client retrieves groups of slots from a deployment of one or more servers
+// holding copies of the database described by DBMetadata.
+// With PreferSecretShared set and two servers, Query uses secret-shared
+// PIR (one query share per server, sent with Shared); otherwise it falls back to
+// single-server PIR with an encrypted query under Sk (sent with Encrypted).
+// The two-party DPF is the only one supported, so deployments with more than
+// two servers also use encrypted queries.
+//
+// With AllowTrivialDownload set, Query downloads the entire database (with Download)
+// instead whenever that is cheaper than the estimated communication of the private
+// query (see UsesTrivialDownload), which is the case for tiny databases.
+// Downloading everything does not reveal the index to the server (the download
+// is the same for every index) but the server learns that the client downloaded
+// the database rather than querying it, so the fallback is only used when the
+// client explicitly allows it
+type Client struct {
+	DBMetadata
+	GroupSize  int // number of adjacent slots retrieved by Query
+	NumServers int
+
+	PreferSecretShared bool
+	Shared             SharedQueryFunc // sends the query shares to the servers (in order)
+
+	Sk        *paillier.SecretKey
+	Encrypted EncryptedQueryFunc // sends the encrypted query to the (single) server
+
+	AllowTrivialDownload bool
+	Download             DownloadFunc // downloads the database from a server
+}
+
+// UsesSecretShared returns true if Query uses secret-shared PIR
+// given the configured number of servers
+func (c *Client) UsesSecretShared() bool {
+	return c.PreferSecretShared && c.NumServers == 2 && c.Shared != nil
+}
+
+// UsesTrivialDownload returns true if Query downloads the entire database:
+// the client allows it and the size of the database is at most the estimated
+// upload and download of the private query (see EstimateQueryCost)
+func (c *Client) UsesTrivialDownload() bool {
+
+	if !c.AllowTrivialDownload || c.Download == nil {
+		return false
+	}
+
+	var cost QueryCost
+	if c.UsesSecretShared() {
+		cost = EstimateQueryCost(SecretSharedVariant, c.DBSize, c.SlotBytes, 0)
+	} else {
+		keyBits := CostModelKeyBits
+		if c.Sk != nil {
+			keyBits = c.Sk.PublicKey.N.BitLen()
+		}
+		cost = EstimateQueryCost(EncryptedVariant, c.DBSize, c.SlotBytes, keyBits)
+	}
+
+	return c.DBSize*c.SlotBytes <= cost.UploadBytes+cost.DownloadBytes
+}
+
+// Query retrieves the group of GroupSize slots containing the slot at index
+// using secret-shared or encrypted PIR or by downloading the database (see Client).
+// If the database has a layout, the group has the group size of the index's region
+func (c *Client) Query(index int) ([]*Slot, error) {
+
+	groupSize := c.GroupSize
+	if groupSize <= 0 {
+		groupSize = 1
+	}
+
+	if index < 0 || index >= c.DBSize {
+		return nil, errors.New("index outside of the database")
+	}
+
+	// with a layout, the group size is the one of the index's region
+	// and the queries are over the window of the region
+	region, _ := c.layoutRegion(index)
+	if region != nil {
+		groupSize = region.GroupSize
+	}
+
+	if c.UsesTrivialDownload() {
+		slots, err := c.Download()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(slots) != c.DBSize {
+			return nil, errors.New("downloaded database does not match the database size")
+		}
+
+		// the last group (of the region) is padded with empty slots (as the results of the PIR queries)
+		start, end := 0, len(slots)
+		if region != nil {
+			start, end = region.Start, region.End
+		}
+
+		group := make([]*Slot, groupSize)
+		for j := range group {
+			if slotIndex := start + (index-start)/groupSize*groupSize + j; slotIndex < end {
+				group[j] = slots[slotIndex]
+			} else {
+				group[j] = c.EmptySlot()
+			}
+		}
+
+		return group, nil
+	}
+
+	if c.UsesSecretShared() {
+		var shares []*QueryShare
+		if region != nil {
+			var err error
+			if shares, err = c.NewLayoutIndexQueryShares(index, uint(c.NumServers)); err != nil {
+				return nil, err
+			}
+		} else {
+			shares = c.NewIndexQueryShares(index/groupSize, groupSize, uint(c.NumServers))
+		}
+
+		resShares, err := c.Shared(shares)
+		if err != nil {
+			return nil, err
+		}
+
+		return RecoverN(resShares, c.NumServers)
+	}
+
+	if c.Sk == nil || c.Encrypted == nil {
+		return nil, errors.New("client is not configured for encrypted queries")
+	}
+
+	pk := &c.Sk.PublicKey
+	var query *EncryptedQuery
+	if region != nil {
+		query = c.NewEncryptedQuery(pk, groupSize, index)
+	} else {
+		width, height := c.encryptedQueryDimentions(pk, groupSize)
+		query = c.NewEncryptedQueryWithDimentions(pk, width, height, groupSize, index/width)
+	}
+	width := query.DBWidth
+
+	res, err := c.Encrypted(query)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := RecoverEncrypted(res, c.Sk)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(slots) != width {
+		return nil, errors.New("result does not match the query width")
+	}
+
+	// the result is the entire row; return the group containing index
+	start := query.LayoutRowOffset(index) / groupSize * groupSize
+	return slots[start : start+groupSize], nil
+}
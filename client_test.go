@@ -0,0 +1,117 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestClientQuery(t *testing.T) {
+	setup()
+
+	sk, _ := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for _, numServers := range []int{1, 2, 3} {
+		for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+			endpoints := make([]*ServerEndpoint, numServers)
+			for i := range endpoints {
+				endpoints[i] = &ServerEndpoint{Server: db, NumProcs: NumProcsForQuery}
+			}
+
+			numEncrypted := 0
+			client := &Client{
+				DBMetadata:         db.DBMetadata,
+				GroupSize:          groupSize,
+				NumServers:         numServers,
+				PreferSecretShared: true,
+				Shared:             NewSharedQueryFunc(endpoints),
+				Sk:                 sk,
+				Encrypted: func(query *EncryptedQuery) (*EncryptedQueryResult, error) {
+					numEncrypted++
+					return db.PrivateEncryptedQuery(query, NumProcsForQuery)
+				},
+			}
+
+			if client.UsesSecretShared() != (numServers == 2) {
+				t.Fatalf("Client with %v servers picked the wrong query type\n", numServers)
+			}
+
+			index := rand.Intn(TestDBSize)
+			slots, err := client.Query(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if (numEncrypted == 1) != (numServers != 2) {
+				t.Fatalf("Client with %v servers sent %v encrypted queries\n", numServers, numEncrypted)
+			}
+
+			checkGroup(t, db, groupSize, index, slots)
+		}
+	}
+}
+
+func TestClientTrivialDownload(t *testing.T) {
+	setup()
+
+	sk, _ := paillier.KeyGen(128)
+
+	for _, dbSize := range []int{4, TestDBSize} {
+		db := GenerateRandomDB(dbSize, SlotBytes)
+
+		for _, numServers := range []int{1, 2} {
+			for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+				endpoints := make([]*ServerEndpoint, numServers)
+				for i := range endpoints {
+					endpoints[i] = &ServerEndpoint{Server: db, NumProcs: NumProcsForQuery}
+				}
+
+				numDownloads := 0
+				client := &Client{
+					DBMetadata:         db.DBMetadata,
+					GroupSize:          groupSize,
+					NumServers:         numServers,
+					PreferSecretShared: true,
+					Shared:             NewSharedQueryFunc(endpoints),
+					Sk:                 sk,
+					Encrypted: func(query *EncryptedQuery) (*EncryptedQueryResult, error) {
+						return db.PrivateEncryptedQuery(query, NumProcsForQuery)
+					},
+					Download: func() ([]*Slot, error) {
+						numDownloads++
+						return db.Slots, nil
+					},
+				}
+
+				// the fallback requires an explicit opt-in
+				if client.UsesTrivialDownload() {
+					t.Fatalf("Client downloads the database without allowing it\n")
+				}
+
+				client.AllowTrivialDownload = true
+
+				// downloading 4 slots is cheaper than any private query but
+				// downloading TestDBSize slots is not
+				if client.UsesTrivialDownload() != (dbSize == 4) {
+					t.Fatalf("Client with %v servers picked the wrong path for %v slots\n", numServers, dbSize)
+				}
+
+				index := rand.Intn(dbSize)
+				slots, err := client.Query(index)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if (numDownloads == 1) != (dbSize == 4) {
+					t.Fatalf("Client downloaded the database %v times for %v slots\n", numDownloads, dbSize)
+				}
+
+				checkGroup(t, db, groupSize, index, slots)
+			}
+		}
+	}
+}
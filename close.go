@@ -0,0 +1,33 @@
+package pir
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrDatabaseClosed is returned when a query is processed by a closed database
+var ErrDatabaseClosed = errors.New("database is closed")
+
+// Close releases the slots (and the slot store and occupancy bitmap) held by the database such that long-running
+// servers do not hold on to databases they no longer serve.
+// Queries processed after the database is closed return ErrDatabaseClosed.
+// Close must not be called while queries are in progress and is idempotent
+func (db *Database) Close() error {
+
+	if !atomic.CompareAndSwapInt32(&db.closed, 0, 1) {
+		return nil
+	}
+
+	db.Slots = nil
+	db.Keywords = nil
+	db.ColKeys = nil
+	db.Store = nil
+	db.Occupancy = nil
+
+	return nil
+}
+
+// IsClosed returns true if Close was called on the database
+func (db *Database) IsClosed() bool {
+	return atomic.LoadInt32(&db.closed) == 1
+}
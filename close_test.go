@@ -0,0 +1,49 @@
+package pir
+
+import (
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestQueryAfterClose(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	shares := db.NewIndexQueryShares(0, 1, 2)
+	query := db.NewEncryptedQuery(pk, 1, 0)
+	doublyQuery := db.NewDoublyEncryptedQuery(pk, 1, 0)
+	db.BuildSlotStore()
+	db.Occupancy = db.OccupancyBitmap()
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !db.IsClosed() || db.Slots != nil || db.Store != nil || db.Occupancy != nil {
+		t.Fatal("Database did not release its slots")
+	}
+
+	if _, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery); err != ErrDatabaseClosed {
+		t.Fatalf("Expected %v, got %v\n", ErrDatabaseClosed, err)
+	}
+
+	if _, err := db.PrivateEncryptedQuery(query, NumProcsForQuery); err != ErrDatabaseClosed {
+		t.Fatalf("Expected %v, got %v\n", ErrDatabaseClosed, err)
+	}
+
+	if _, err := db.PrivateDoublyEncryptedQuery(doublyQuery, NumProcsForQuery); err != ErrDatabaseClosed {
+		t.Fatalf("Expected %v, got %v\n", ErrDatabaseClosed, err)
+	}
+
+	if _, err := db.PrivateEncryptedQueryInRange(query, 0, query.DBHeight, NumProcsForQuery); err != ErrDatabaseClosed {
+		t.Fatalf("Expected %v, got %v\n", ErrDatabaseClosed, err)
+	}
+
+	// closing twice is a no-op
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+// Command pir-testvectors writes deterministic test vectors of the pir package
+// (see pir.GenerateTestVectors) as JSON such that implementations in other
+// languages can check their compatibility with the package.
+//
+// Usage:
+//
+//	pir-testvectors -seed "vectors" -dbsize 1024 -slotbytes 3 -groupsize 1 -index 7 -out vectors.json
+//
+// The primes of the Paillier key are derived from the seed as well,
+// so the same flags always give the same vectors (including the primes)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sachaservan/pir"
+)
+
+func main() {
+
+	seed := flag.String("seed", "pir test vectors", "seed of the randomness")
+	dbSize := flag.Int("dbsize", 1<<10, "number of slots of the database")
+	slotBytes := flag.Int("slotbytes", 3, "size of each slot in bytes")
+	groupSize := flag.Int("groupsize", 1, "number of slots retrieved by each query")
+	index := flag.Int("index", 0, "index of the slot retrieved by the queries")
+	keyBits := flag.Int("keybits", 1024, "size of the Paillier modulus in bits")
+	out := flag.String("out", "", "output file (standard output if empty)")
+	flag.Parse()
+
+	if err := run(*seed, *dbSize, *slotBytes, *groupSize, *index, *keyBits, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "pir-testvectors: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(seed string, dbSize, slotBytes, groupSize, index, keyBits int, out string) error {
+
+	tv, err := pir.GenerateTestVectors(&pir.TestVectorConfig{
+		Seed:      []byte(seed),
+		DBSize:    dbSize,
+		SlotBytes: slotBytes,
+		GroupSize: groupSize,
+		Index:     index,
+		KeyBits:   keyBits,
+	})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return pir.WriteTestVectors(w, tv)
+}
@@ -0,0 +1,136 @@
+package pir
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ColumnOrQueryShare is a share of a query that privately tests whether
+// any of a set of rows has a non-zero flag in a column of the database.
+//
+// The servers can only compute (shares of) the XOR of the selected flags,
+// which is zero if the flags cancel out. To compute the OR, the query consists of
+// several trials where each trial selects a random subset of the rows: if some flag is
+// non-zero, the XOR over a random subset is non-zero with probability at least 1/2,
+// so the OR is computed correctly except with probability 2^-numTrials.
+// Each trial has one DPF key per row of the set (rows outside of the subset
+// are mapped outside of the database), so the servers only learn the size of the set
+type ColumnOrQueryShare struct {
+	Trials    [][]*QueryShare
+	GroupSize int // width of the rows; the column is an offset within the row
+}
+
+// NewColumnOrQueryShares generates two-party query shares that test whether
+// any of the rows has a non-zero flag (see ColumnOrQueryShare).
+// The database is viewed as rows of groupSize slots (as in NewIndexQueryShares)
+func (dbmd *DBMetadata) NewColumnOrQueryShares(rows []int, groupSize int, numTrials int) []*ColumnOrQueryShare {
+
+	checkGroupSize(groupSize)
+
+	dimHeight := int(math.Ceil(float64(dbmd.DBSize) / float64(groupSize)))
+
+	// num bits to represent the index (and the dummy index dimHeight)
+	numBits := uint(math.Log2(float64(dimHeight)) + 1)
+
+	// duplicate rows would cancel out
+	set := make([]int, 0, len(rows))
+	seen := make(map[int]bool)
+	for _, row := range rows {
+		if row < 0 || row >= dimHeight {
+			panic("requesting row outside of domain")
+		}
+
+		if !seen[row] {
+			seen[row] = true
+			set = append(set, row)
+		}
+	}
+
+	shares := make([]*ColumnOrQueryShare, 2)
+	for i := range shares {
+		shares[i] = &ColumnOrQueryShare{
+			Trials:    make([][]*QueryShare, numTrials),
+			GroupSize: groupSize,
+		}
+	}
+
+	for t := 0; t < numTrials; t++ {
+		for i := range shares {
+			shares[i].Trials[t] = make([]*QueryShare, len(set))
+		}
+
+		for j, row := range set {
+			key := row
+			if randomBit() == 0 {
+				key = dimHeight // selects no row of the database
+			}
+
+			keyShares := newDPFQueryShares(uint(key), numBits, groupSize, 2)
+			for i := range shares {
+				shares[i].Trials[t][j] = keyShares[i]
+			}
+		}
+	}
+
+	return shares
+}
+
+// PrivateColumnOrQuery returns, for each trial of the query, a share of the XOR
+// of the slots at offset column of the rows selected by the trial
+func (db *Database) PrivateColumnOrQuery(query *ColumnOrQueryShare, column int, nprocs int) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if query.GroupSize <= 0 || column < 0 || column >= query.GroupSize {
+		return nil, errors.New("column outside of the row")
+	}
+
+	dimHeight := int(math.Ceil(float64(db.DBSize) / float64(query.GroupSize)))
+
+	results := make([]*Slot, len(query.Trials))
+	for t, trial := range query.Trials {
+
+		// the selection of the trial is the XOR of the selections of its DPF keys
+		selected := make([]bool, dimHeight)
+		for _, share := range trial {
+			if share.GroupSize != query.GroupSize {
+				return nil, errors.New("invalid group size provided in query")
+			}
+
+			bits, err := db.ExpandSharedQueryContext(context.Background(), share, nprocs)
+			if err != nil {
+				return nil, err
+			}
+
+			for row := range selected {
+				selected[row] = selected[row] != bits[row]
+			}
+		}
+
+		results[t] = NewEmptySlot(db.SlotBytes)
+		for row, sel := range selected {
+			slotIndex := row*query.GroupSize + column
+			if sel && slotIndex < len(db.Slots) {
+				XorSlots(results[t], db.Slots[slotIndex])
+			}
+		}
+	}
+
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: results}, nil
+}
+
+// RecoverColumnOr combines the result shares of a column OR query and
+// returns true if any of the queried rows has a non-zero flag
+func RecoverColumnOr(resShares []*SecretSharedQueryResult) bool {
+
+	for _, slot := range Recover(resShares) {
+		if !slot.IsEmpty() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestColumnOrQuery(t *testing.T) {
+	setup()
+
+	groupSize := 4
+	numRows := TestDBSize / groupSize
+
+	// sparse flags in each column
+	db := GenerateEmptyDB(TestDBSize, 1)
+	for i := 0; i < TestDBSize/16; i++ {
+		db.Slots[rand.Intn(TestDBSize)].Data[0] = byte(rand.Intn(255) + 1)
+	}
+
+	for trial := 0; trial < NumTrials; trial++ {
+		column := rand.Intn(groupSize)
+		rows := rand.Perm(numRows)[:5]
+
+		expected := false
+		for _, row := range rows {
+			if !db.Slots[row*groupSize+column].IsEmpty() {
+				expected = true
+			}
+		}
+
+		shares := db.NewColumnOrQueryShares(rows, groupSize, 20)
+
+		resA, err := db.PrivateColumnOrQuery(shares[0], column, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := db.PrivateColumnOrQuery(shares[1], column, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res := RecoverColumnOr([]*SecretSharedQueryResult{resA, resB}); res != expected {
+			t.Fatalf("Column OR is incorrect. %v != %v\n", res, expected)
+		}
+	}
+
+	// a set whose flags cancel out under XOR
+	db.Slots[0].Data[0] = 7
+	db.Slots[groupSize].Data[0] = 7
+
+	shares := db.NewColumnOrQueryShares([]int{0, 1}, groupSize, 20)
+	resA, _ := db.PrivateColumnOrQuery(shares[0], 0, NumProcsForQuery)
+	resB, _ := db.PrivateColumnOrQuery(shares[1], 0, NumProcsForQuery)
+
+	if !RecoverColumnOr([]*SecretSharedQueryResult{resA, resB}) {
+		t.Fatal("Column OR of cancelling flags is incorrect")
+	}
+}
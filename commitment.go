@@ -3,7 +3,6 @@ package pir
 import (
 	"bytes"
 	"crypto/sha256"
-	"math/rand"
 
 	"github.com/ncw/gmp"
 )
@@ -17,9 +16,10 @@ type ROCommitment struct {
 }
 
 // Commit uses the random oracle to generate a commitment
+// (over the canonical encoding of the value and the randomness, see RandomOracleDigest)
 func Commit(value *gmp.Int) *ROCommitment {
 	rBytes := make([]byte, 32)
-	rand.Read(rBytes)
+	randomBytes(rBytes)
 	r := new(gmp.Int).SetBytes(rBytes)
 	comm := &ROCommitment{
 		HashBytes: RandomOracleDigest(value, r),
@@ -37,18 +37,19 @@ func (c *ROCommitment) CheckOpen(value *gmp.Int) bool {
 	return bytes.Equal(hash1, hash2)
 }
 
-// RandomOracleDigest returns the digest of all the input bytes
-// using SHA 256 to model a random oracle
+// RandomOracleDigest returns the digest of the canonical encoding of the values
+// (see CanonicalMessage) using SHA 256 to model a random oracle.
+// The values are length-prefixed such that distinct sequences of values
+// (e.g., (1, 23) and (12, 3)) never hash the same bytes
 func RandomOracleDigest(values ...*gmp.Int) []byte {
 
-	hashData := make([]byte, 0)
-	for i, b := range values {
-		if i == 0 {
-			continue
-		}
-		hashData = append(hashData, b.Bytes()...)
+	w := &canonicalWriter{}
+	w.writeTag("RandomOracle")
+	w.writeUint(uint64(len(values)))
+	for _, v := range values {
+		w.writeInt(v)
 	}
 
-	res := sha256.Sum256(hashData)
+	res := sha256.Sum256(w.buf)
 	return res[:]
 }
@@ -0,0 +1,129 @@
+package pir
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// Compression is the compression applied to the binary encoding of a message
+// (see the Compression field of the result types and of QueryShare).
+//
+// Shares and ciphertexts are indistinguishable from random bytes and do not compress,
+// regardless of the contents of the database. Compression pays off for messages that
+// carry plaintext or structured data: the results of non-private queries
+// (see PlaintextIndexQuery) over sparse or low-entropy databases and, to a small
+// extent, the framing of DPF keys. The Compression fields are only applied by
+// the binary encodings from WireVersion2 on (messages encoded in an older
+// version, and CBOR, protobuf and JSON encodings, are never compressed)
+type Compression byte
+
+const (
+	// CompressionNone leaves the encoding as is
+	CompressionNone Compression = iota
+
+	// CompressionGzip compresses the encoding with gzip (RFC 1952)
+	CompressionGzip
+)
+
+// ErrUnsupportedCompression is returned when decoding a message compressed
+// with an unknown algorithm
+var ErrUnsupportedCompression = errors.New("unsupported compression")
+
+// MaxDecompressedBytes bounds the size of a decompressed message such that
+// a malicious peer cannot exhaust the memory of the receiver with a small message
+// (a compression bomb). Receivers of larger messages (e.g., plaintext results over
+// large databases) can raise it before decoding
+var MaxDecompressedBytes = 16 << 20
+
+// ErrDecompressedTooLarge is returned when a decompressed message exceeds MaxDecompressedBytes
+var ErrDecompressedTooLarge = errors.New("decompressed message exceeds the maximum size")
+
+// compress compresses the data with the algorithm
+func compress(c Compression, data []byte) ([]byte, error) {
+
+	switch c {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		z := gzip.NewWriter(&buf)
+		if _, err := z.Write(data); err != nil {
+			return nil, err
+		}
+		if err := z.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+}
+
+// decompress reverses compress
+func decompress(c Compression, data []byte) ([]byte, error) {
+
+	switch c {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		z, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		out, err := io.ReadAll(io.LimitReader(z, int64(MaxDecompressedBytes)+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(out) > MaxDecompressedBytes {
+			return nil, ErrDecompressedTooLarge
+		}
+
+		return out, nil
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+}
+
+// compressedWriter returns a writer that compresses what is written to w
+// (and must be closed to flush the compressed data)
+func compressedWriter(c Compression, w io.Writer) (io.WriteCloser, error) {
+
+	switch c {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// compressionOf returns the compression flag of the message
+// (CompressionNone for the messages without one)
+func compressionOf(msg WireMessage) Compression {
+
+	switch m := msg.(type) {
+	case *QueryShare:
+		return m.Compression
+	case *SecretSharedQueryResult:
+		return m.Compression
+	case *EncryptedQueryResult:
+		return m.Compression
+	case *DoublyEncryptedQueryResult:
+		return m.Compression
+	default:
+		return CompressionNone
+	}
+}
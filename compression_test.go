@@ -0,0 +1,258 @@
+package pir
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+// generateSparseDB returns a database of size slots where only one in
+// sparsity slots is non-empty (and random)
+func generateSparseDB(size, numBytes, sparsity int) *Database {
+
+	db := GenerateEmptyDB(size, numBytes)
+	for i := 0; i < size; i += sparsity {
+		db.Slots[i] = NewRandomSlot(numBytes)
+	}
+
+	return db
+}
+
+func TestCompressedResults(t *testing.T) {
+	setup()
+
+	db := generateSparseDB(TestDBSize, SlotBytes, 8)
+
+	for _, c := range []Compression{CompressionNone, CompressionGzip} {
+		for i := 0; i < NumQueries; i++ {
+			index := rand.Intn(TestDBSize)
+
+			resShares := make([]*SecretSharedQueryResult, 2)
+			for j, share := range db.NewIndexQueryShares(index, 1, 2) {
+				share.Compression = c
+
+				data, err := share.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				decoded := &QueryShare{}
+				if err := decoded.UnmarshalBinary(data); err != nil {
+					t.Fatal(err)
+				}
+
+				if decoded.Compression != c {
+					t.Fatalf("Decoded compression %v, expected %v\n", decoded.Compression, c)
+				}
+
+				res, err := db.PrivateSecretSharedQuery(decoded, NumProcsForQuery)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Compression = c
+
+				data, err = res.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				resShares[j] = &SecretSharedQueryResult{}
+				if err := resShares[j].UnmarshalBinary(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			res := Recover(resShares)
+			if !db.Slots[index].Equal(res[0]) {
+				t.Fatalf("Incorrect result with compression %v: expected %v, got %v\n", c, db.Slots[index], res[0])
+			}
+		}
+	}
+}
+
+func TestCompressedEncryptedResults(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	width, height := db.encryptedQueryDimentions(pk, 1)
+	row := rand.Intn(height)
+
+	res, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, 1, row), NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Compression = CompressionGzip
+
+	data, err := res.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the streamed encoding is the same as MarshalBinary
+	var buf bytes.Buffer
+	if err := WriteEncryptedQueryResult(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+
+	streamed, err := ReadEncryptedQueryResult(&buf, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := UnmarshalEncryptedQueryResult(data, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*EncryptedQueryResult{decoded, streamed} {
+		if r.Compression != CompressionGzip {
+			t.Fatalf("Decoded compression %v, expected gzip\n", r.Compression)
+		}
+
+		slots, err := RecoverEncrypted(r, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j := 0; j < width && row*width+j < db.DBSize; j++ {
+			if !db.Slots[row*width+j].Equal(slots[j]) {
+				t.Fatalf("Incorrect compressed result: expected %v, got %v\n", db.Slots[row*width+j], slots[j])
+			}
+		}
+	}
+}
+
+func TestCompressionOlderVersion(t *testing.T) {
+	setup()
+
+	res := &SecretSharedQueryResult{
+		SlotBytes:   SlotBytes,
+		Shares:      []*Slot{NewEmptySlot(SlotBytes)},
+		Compression: CompressionGzip,
+	}
+
+	// messages encoded for an older peer are not compressed
+	data, err := MarshalBinaryVersion(res, WireVersion1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &SecretSharedQueryResult{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Compression != CompressionNone || !decoded.Shares[0].Equal(res.Shares[0]) {
+		t.Fatalf("Incorrect decoding of a version 1 result\n")
+	}
+
+	res.Compression = Compression(42)
+	if _, err := res.MarshalBinary(); err != ErrUnsupportedCompression {
+		t.Fatalf("Expected ErrUnsupportedCompression, got %v\n", err)
+	}
+}
+
+func TestCompressionBomb(t *testing.T) {
+	setup()
+
+	// a result of empty slots compresses to a tiny fraction of its size
+	res := &SecretSharedQueryResult{
+		SlotBytes:   1 << 10,
+		Shares:      make([]*Slot, 1<<10),
+		Compression: CompressionGzip,
+	}
+	for i := range res.Shares {
+		res.Shares[i] = NewEmptySlot(res.SlotBytes)
+	}
+
+	data, err := res.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(limit int) { MaxDecompressedBytes = limit }(MaxDecompressedBytes)
+
+	MaxDecompressedBytes = 1 << 19
+	if err := (&SecretSharedQueryResult{}).UnmarshalBinary(data); err != ErrDecompressedTooLarge {
+		t.Fatalf("Expected ErrDecompressedTooLarge for %v compressed bytes, got %v\n", len(data), err)
+	}
+
+	MaxDecompressedBytes = 1 << 21
+	if err := (&SecretSharedQueryResult{}).UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchmarkCompressedSize reports the size of the binary encoding of the response
+// to a query over a sparse database (one in sparsity slots is non-empty) without
+// and with compression as the "bytes/response" and "gzip-bytes/response" metrics.
+// answer returns the response and its compression flag
+func benchmarkCompressedSize(b *testing.B, sparsity int, answer func(db *Database) (WireMessage, *Compression)) {
+	setup()
+
+	db := generateSparseDB(TestDBSize, 1<<10, sparsity)
+	msg, compression := answer(db)
+
+	b.ResetTimer()
+
+	var plain, compressed []byte
+	var err error
+	for i := 0; i < b.N; i++ {
+		*compression = CompressionNone
+		plain, err = MarshalBinaryVersion(msg, CurrentWireVersion)
+		if err != nil {
+			panic(err)
+		}
+
+		*compression = CompressionGzip
+		compressed, err = MarshalBinaryVersion(msg, CurrentWireVersion)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	b.ReportMetric(float64(len(plain)), "bytes/response")
+	b.ReportMetric(float64(len(compressed)), "gzip-bytes/response")
+}
+
+func BenchmarkCompressedPlaintextResponseSparse(b *testing.B) {
+	benchmarkCompressedSize(b, 8, func(db *Database) (WireMessage, *Compression) {
+		res, err := db.PlaintextIndexQuery(NewPlaintextIndexQuery(1)) // empty slot
+		if err != nil {
+			panic(err)
+		}
+		return res, &res.Compression
+	})
+}
+
+func BenchmarkCompressedSecretSharedResponseSparse(b *testing.B) {
+	benchmarkCompressedSize(b, 8, func(db *Database) (WireMessage, *Compression) {
+		res, err := db.PrivateSecretSharedQuery(db.NewIndexQueryShares(1, 1, 2)[0], NumProcsForQuery)
+		if err != nil {
+			panic(err)
+		}
+		return res, &res.Compression
+	})
+}
+
+func BenchmarkCompressedEncryptedResponseSparse(b *testing.B) {
+	benchmarkCompressedSize(b, 8, func(db *Database) (WireMessage, *Compression) {
+		_, pk := paillier.KeyGen(1024)
+		res, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, 1, 0), NumProcsForQuery)
+		if err != nil {
+			panic(err)
+		}
+		return res, &res.Compression
+	})
+}
+
+func BenchmarkCompressedQueryShare(b *testing.B) {
+	benchmarkCompressedSize(b, 8, func(db *Database) (WireMessage, *Compression) {
+		share := db.NewIndexQueryShares(1, 1, 2)[0]
+		return share, &share.Compression
+	})
+}
@@ -0,0 +1,66 @@
+package pir
+
+import (
+	"errors"
+	"sync"
+)
+
+// SharedQueryServer answers secret shared PIR queries
+// (both Database and Server implement it)
+type SharedQueryServer interface {
+	PrivateSecretSharedQuery(query *QueryShare, nprocs int) (*SecretSharedQueryResult, error)
+}
+
+// ServerEndpoint is a server along with the number of
+// workers it should use to process a query share
+type ServerEndpoint struct {
+	Server   SharedQueryServer
+	NumProcs int
+}
+
+// DispatchSharedQuery sends query share i to endpoint i using the
+// number of workers configured for that endpoint and gathers the result shares
+// (in the order of the endpoints).
+// The shares are processed concurrently, so the total time is that of
+// the slowest server rather than the sum across servers
+func DispatchSharedQuery(endpoints []*ServerEndpoint, shares []*QueryShare) ([]*SecretSharedQueryResult, error) {
+
+	if len(endpoints) != len(shares) {
+		return nil, errors.New("number of query shares does not match the number of servers")
+	}
+
+	resShares := make([]*SecretSharedQueryResult, len(shares))
+	errs := make([]error, len(shares))
+
+	var wg sync.WaitGroup
+	for i := range shares {
+		nprocs := endpoints[i].NumProcs
+		if nprocs <= 0 {
+			nprocs = 1
+		}
+
+		wg.Add(1)
+		go func(i, nprocs int) {
+			defer wg.Done()
+			resShares[i], errs[i] = endpoints[i].Server.PrivateSecretSharedQuery(shares[i], nprocs)
+		}(i, nprocs)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resShares, nil
+}
+
+// NewSharedQueryFunc returns a SharedQueryFunc that dispatches
+// the shares to the endpoints using DispatchSharedQuery
+func NewSharedQueryFunc(endpoints []*ServerEndpoint) SharedQueryFunc {
+	return func(shares []*QueryShare) ([]*SecretSharedQueryResult, error) {
+		return DispatchSharedQuery(endpoints, shares)
+	}
+}
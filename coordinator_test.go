@@ -0,0 +1,72 @@
+package pir
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// mockServer answers queries after a delay and records the number of workers it was asked to use
+type mockServer struct {
+	db       *Database
+	delay    time.Duration
+	numProcs int
+	fail     bool
+}
+
+func (s *mockServer) PrivateSecretSharedQuery(query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+	time.Sleep(s.delay)
+	s.numProcs = nprocs
+
+	if s.fail {
+		return nil, errors.New("server unavailable")
+	}
+
+	return s.db.PrivateSecretSharedQuery(query, nprocs)
+}
+
+func TestDispatchSharedQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	fast := &mockServer{db: db}
+	slow := &mockServer{db: db, delay: 10 * time.Millisecond}
+
+	// the slow server responds last but its share must still be combined in order
+	endpoints := []*ServerEndpoint{
+		{Server: slow, NumProcs: 1},
+		{Server: fast, NumProcs: 8},
+	}
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(db.DBSize / groupSize)
+		shares := db.NewIndexQueryShares(index, groupSize, 2)
+
+		resShares, err := DispatchSharedQuery(endpoints, shares)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Recover(resShares)
+		for j := 0; j < groupSize; j++ {
+			if !db.Slots[index*groupSize+j].Equal(res[j]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index*groupSize+j], res[j])
+			}
+		}
+
+		if slow.numProcs != 1 || fast.numProcs != 8 {
+			t.Fatalf("Servers used %v and %v workers, expected 1 and 8\n", slow.numProcs, fast.numProcs)
+		}
+	}
+
+	if _, err := DispatchSharedQuery(endpoints[:1], db.NewIndexQueryShares(0, 1, 2)); err == nil {
+		t.Fatal("Dispatched query shares to the wrong number of servers")
+	}
+
+	slow.fail = true
+	if _, err := DispatchSharedQuery(endpoints, db.NewIndexQueryShares(0, 1, 2)); err == nil {
+		t.Fatal("Did not report the error of a failing server")
+	}
+}
@@ -0,0 +1,83 @@
+package pir
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sachaservan/pir/dpf"
+)
+
+// CountQueryResult is a server's additive share of the number of rows matching a keyword
+type CountQueryResult struct {
+	Share int
+}
+
+// PrivateKeywordCountQuery returns a share of the number of rows of the database whose
+// keyword matches the keyword of the query (generated with NewKeywordQueryShares
+// or NewKeywordPrefixQuery).
+//
+// The selection bits used by PrivateSecretSharedQuery are XOR shares, and the XOR of the
+// match indicators only reveals the parity of the count. The count instead uses the
+// additive output of the two-party DPF: the integer outputs of the two servers sum to one
+// at the keyword and to zero elsewhere, so summing the outputs over all rows yields additive
+// shares of the count. This requires two-party (additive) keys; multi-party keys are XOR
+// shares and are rejected
+func (db *Database) PrivateKeywordCountQuery(query *QueryShare, nprocs int) (*CountQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if !query.IsKeywordBased {
+		return nil, errors.New("count queries must be keyword based")
+	}
+
+	if !query.IsTwoParty {
+		return nil, errors.New("count queries require two-party (additive) DPF keys")
+	}
+
+	if len(db.Keywords) < db.DBSize {
+		return nil, errors.New("database does not have keywords")
+	}
+
+	if nprocs <= 0 {
+		nprocs = 1
+	}
+
+	sums := make([]int, nprocs)
+	rows := rowChunks(db.DBSize, nprocs, 0)
+
+	var wg sync.WaitGroup
+	for i := range rows {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// each worker evaluates the DPF with its own buffers
+			pf := dpf.ServerInitialize(query.PrfKeys, queryDomainBits(query, db.DBSize))
+			for row := rows[i][0]; row < rows[i][1]; row++ {
+				sums[i] += pf.Evaluate2P(query.ShareNumber, query.KeyTwoParty, db.queryKey(query, row))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	res := &CountQueryResult{}
+	for _, sum := range sums {
+		res.Share += sum
+	}
+
+	return res, nil
+}
+
+// RecoverCount combines the shares of a count query
+func RecoverCount(resShares []*CountQueryResult) int {
+
+	count := 0
+	for _, res := range resShares {
+		count += res.Share
+	}
+
+	return count
+}
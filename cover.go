@@ -0,0 +1,247 @@
+package pir
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+// ErrSchedulerStopped is returned for queries submitted to a stopped CoverTrafficScheduler
+var ErrSchedulerStopped = errors.New("cover traffic scheduler is stopped")
+
+// ErrCoverShapeMismatch is returned for real queries submitted to a CoverTrafficScheduler
+// that do not have the shape of its null queries (and would stand out among them)
+var ErrCoverShapeMismatch = errors.New("query does not have the shape of the cover queries")
+
+// DoublyEncryptedQueryFunc sends a doubly encrypted query to the server and returns the result
+type DoublyEncryptedQueryFunc func(query *DoublyEncryptedQuery) (*DoublyEncryptedQueryResult, error)
+
+// CoverTrafficScheduler sends exactly one doubly encrypted query per interval.
+// Real queries submitted with Query are sent at the next tick; when no real
+// query is pending, a fresh null query (see NewDoublyEncryptedNullQuery) is sent instead,
+// so an observer of the client sees a uniform stream of indistinguishable queries
+// regardless of when (or how often) the client actually queries.
+// Real queries submitted faster than the rate are queued (and delayed), and
+// real queries whose shape (dimensions, group size, public key, region, and column mask)
+// differs from that of the null queries are rejected.
+// The results and errors of null queries are discarded
+type CoverTrafficScheduler struct {
+	Interval time.Duration
+
+	dbmd      *DBMetadata
+	pk        *paillier.PublicKey
+	groupSize int
+	send      DoublyEncryptedQueryFunc
+
+	mu       sync.Mutex
+	pending  []*scheduledQuery
+	paused   bool
+	running  bool
+	stop     chan struct{}
+	done     chan struct{}
+	numReal  int
+	numCover int
+
+	// next null query (only accessed by the background goroutine once started)
+	nextCover *DoublyEncryptedQuery
+
+	// ticks of the scheduler (a time.Ticker with period Interval unless set by tests)
+	ticks <-chan time.Time
+}
+
+type scheduledQuery struct {
+	query *DoublyEncryptedQuery
+	res   *DoublyEncryptedQueryResult
+	err   error
+	done  chan struct{}
+}
+
+// NewCoverTrafficScheduler returns a scheduler that sends one query per interval using send.
+// Null queries are generated for the database dbmd under pk with the group size of the real queries
+func NewCoverTrafficScheduler(
+	dbmd *DBMetadata,
+	pk *paillier.PublicKey,
+	groupSize int,
+	interval time.Duration,
+	send DoublyEncryptedQueryFunc) *CoverTrafficScheduler {
+
+	checkGroupSize(groupSize)
+
+	return &CoverTrafficScheduler{
+		Interval:  interval,
+		dbmd:      dbmd,
+		pk:        pk,
+		groupSize: groupSize,
+		send:      send,
+		nextCover: dbmd.NewDoublyEncryptedNullQuery(pk, groupSize),
+	}
+}
+
+// Start starts sending queries in the background (no-op if the scheduler is running)
+func (s *CoverTrafficScheduler) Start() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+
+	s.running = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go s.run(s.stop, s.done)
+}
+
+// Stop stops sending queries and fails the pending real queries with ErrSchedulerStopped
+func (s *CoverTrafficScheduler) Stop() {
+
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+
+	s.running = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, q := range s.pending {
+		q.err = ErrSchedulerStopped
+		close(q.done)
+	}
+	s.pending = nil
+}
+
+// Pause stops sending queries (real and null) until Resume is called.
+// Real queries submitted while paused are sent after resuming
+func (s *CoverTrafficScheduler) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = true
+}
+
+// Resume resumes sending queries after Pause
+func (s *CoverTrafficScheduler) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+// Query sends the real query at the next tick of the scheduler
+// and blocks until its result is available.
+// It returns ErrCoverShapeMismatch if the query does not have the shape of the null queries
+func (s *CoverTrafficScheduler) Query(query *DoublyEncryptedQuery) (*DoublyEncryptedQueryResult, error) {
+
+	if !s.hasCoverShape(query) {
+		return nil, ErrCoverShapeMismatch
+	}
+
+	q := &scheduledQuery{query: query, done: make(chan struct{})}
+
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil, ErrSchedulerStopped
+	}
+	s.pending = append(s.pending, q)
+	s.mu.Unlock()
+
+	<-q.done
+	return q.res, q.err
+}
+
+// NumSent returns the number of real and null queries sent so far
+func (s *CoverTrafficScheduler) NumSent() (real, cover int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.numReal, s.numCover
+}
+
+// hasCoverShape returns true if the query has the (public) shape of the null queries
+func (s *CoverTrafficScheduler) hasCoverShape(query *DoublyEncryptedQuery) bool {
+
+	if query == nil || query.Row == nil || query.Col == nil || query.ColumnMask != nil {
+		return false
+	}
+
+	sameShape := func(a *EncryptedQuery, width, height, numBits int) bool {
+		return a.Pk != nil && a.Pk.N.Cmp(s.pk.N) == 0 &&
+			a.GroupSize == s.groupSize && a.Region == nil &&
+			a.DBWidth == width && a.DBHeight == height && len(a.EBits) == numBits
+	}
+
+	// same dimensions as NewDoublyEncryptedNullQuery
+	height := int(math.Ceil(math.Sqrt(float64(s.dbmd.DBSize))))
+	width, height := s.dbmd.GetDimentionsForDatabase(height, s.groupSize)
+
+	return sameShape(query.Row, width, height, height) &&
+		sameShape(query.Col, width, 1, width/s.groupSize)
+}
+
+func (s *CoverTrafficScheduler) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticks := s.ticks
+	if ticks == nil {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticks:
+			s.tick()
+		}
+	}
+}
+
+// tick sends the next pending real query or a null query
+func (s *CoverTrafficScheduler) tick() {
+
+	s.mu.Lock()
+	if s.paused {
+		s.mu.Unlock()
+		return
+	}
+
+	var q *scheduledQuery
+	if len(s.pending) > 0 {
+		q = s.pending[0]
+		s.pending = s.pending[1:]
+		s.numReal++
+	} else {
+		s.numCover++
+	}
+	s.mu.Unlock()
+
+	if q != nil {
+		q.res, q.err = s.send(q.query)
+		close(q.done)
+	} else {
+		if s.nextCover == nil {
+			s.nextCover = s.dbmd.NewDoublyEncryptedNullQuery(s.pk, s.groupSize)
+		}
+		s.send(s.nextCover)
+		s.nextCover = nil
+	}
+
+	// every null query must be fresh (re-sending the same ciphertexts would stand out),
+	// so the next one is generated ahead of time to be ready by the next tick
+	if s.nextCover == nil {
+		s.nextCover = s.dbmd.NewDoublyEncryptedNullQuery(s.pk, s.groupSize)
+	}
+}
@@ -0,0 +1,174 @@
+package pir
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+// newManualScheduler returns a scheduler that ticks when the test sends on the returned channel
+// (each send returns once the scheduler has picked up the tick and finished the previous one)
+func newManualScheduler(db *Database, pk *paillier.PublicKey, send DoublyEncryptedQueryFunc) (*CoverTrafficScheduler, chan time.Time) {
+
+	ticks := make(chan time.Time)
+	scheduler := NewCoverTrafficScheduler(&db.DBMetadata, pk, 1, time.Hour, send)
+	scheduler.ticks = ticks
+
+	return scheduler, ticks
+}
+
+func TestCoverTrafficRate(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	numTicks := 10
+	query := db.NewDoublyEncryptedQuery(pk, 1, 0)
+
+	for _, numReal := range []int{0, 5, 2 * numTicks} {
+
+		var mu sync.Mutex
+		numSent := 0
+		send := func(query *DoublyEncryptedQuery) (*DoublyEncryptedQueryResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			numSent++
+			return nil, nil
+		}
+
+		scheduler, ticks := newManualScheduler(db, pk, send)
+		scheduler.Start()
+
+		// real queries are submitted in a burst, faster than the rate
+		var wg sync.WaitGroup
+		for i := 0; i < numReal; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				scheduler.Query(query)
+			}()
+		}
+
+		for i := 0; i < numTicks; i++ {
+			ticks <- time.Time{}
+		}
+
+		scheduler.Stop()
+		wg.Wait()
+
+		mu.Lock()
+		sent := numSent
+		mu.Unlock()
+
+		// the observed number of queries only depends on the number of ticks
+		if sent != numTicks {
+			t.Fatalf("Sent %v queries with %v real queries, expected %v\n", sent, numReal, numTicks)
+		}
+
+		real, cover := scheduler.NumSent()
+		if real+cover != sent || real > numReal {
+			t.Fatalf("Counted %v real and %v null queries for %v sent queries\n", real, cover, sent)
+		}
+	}
+}
+
+func TestCoverTrafficPause(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	scheduler, ticks := newManualScheduler(db, pk,
+		func(query *DoublyEncryptedQuery) (*DoublyEncryptedQueryResult, error) {
+			return nil, nil
+		})
+
+	if _, err := scheduler.Query(db.NewDoublyEncryptedNullQuery(pk, 1)); err != ErrSchedulerStopped {
+		t.Fatalf("Expected %v, got %v\n", ErrSchedulerStopped, err)
+	}
+
+	scheduler.Start()
+
+	ticks <- time.Time{}
+	scheduler.Pause()
+	ticks <- time.Time{} // the first tick is complete once the second is picked up
+	real, cover := scheduler.NumSent()
+
+	for i := 0; i < 5; i++ {
+		ticks <- time.Time{}
+	}
+
+	if r, c := scheduler.NumSent(); r != real || c != cover {
+		t.Fatalf("Queries were sent while paused\n")
+	}
+
+	scheduler.Resume()
+	ticks <- time.Time{}
+	scheduler.Stop()
+
+	if _, c := scheduler.NumSent(); c != cover+1 {
+		t.Fatalf("Sent %v null queries after resuming, expected %v\n", c-cover, 1)
+	}
+}
+
+func TestCoverTrafficShape(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	_, otherPk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	scheduler, ticks := newManualScheduler(db, pk,
+		func(query *DoublyEncryptedQuery) (*DoublyEncryptedQueryResult, error) {
+			return nil, nil
+		})
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	wide := db.NewDoublyEncryptedQuery(pk, 1, 0)
+	wide.Row.DBWidth *= 2
+
+	layoutDB := GenerateRandomDB(TestDBSize, SlotBytes)
+	layout, err := NewGroupLayout(
+		TestDBSize,
+		&GroupRegion{Start: 0, End: TestDBSize / 2, GroupSize: 1},
+		&GroupRegion{Start: TestDBSize / 2, End: TestDBSize, GroupSize: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layoutDB.Layout = layout
+
+	for name, query := range map[string]*DoublyEncryptedQuery{
+		"width":      wide,
+		"group size": db.NewDoublyEncryptedQuery(pk, 2, 0),
+		"key":        db.NewDoublyEncryptedQuery(otherPk, 1, 0),
+		"region":     layoutDB.NewDoublyEncryptedQuery(pk, 1, 0),
+		"mask":       db.NewDoublyEncryptedQueryWithColumnMask(pk, 1, 0, []bool{true}),
+	} {
+		if _, err := scheduler.Query(query); err != ErrCoverShapeMismatch {
+			t.Fatalf("Expected %v for a query with another %v, got %v\n", ErrCoverShapeMismatch, name, err)
+		}
+	}
+
+	// a query with the shape of the null queries is sent
+	done := make(chan error)
+	go func() {
+		_, err := scheduler.Query(db.NewDoublyEncryptedQuery(pk, 1, 0))
+		done <- err
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		case ticks <- time.Time{}:
+		}
+	}
+}
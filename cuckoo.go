@@ -0,0 +1,193 @@
+package pir
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// ErrCuckooPlacementFailed is returned when the keys could not be placed into the cuckoo table
+// within the configured number of kicks; the caller can retry with a lower load factor
+// (or simply retry, since each build uses fresh hash functions)
+var ErrCuckooPlacementFailed = errors.New("cuckoo placement failed; retry with a lower load factor")
+
+// CuckooConfig configures the cuckoo table of a keyword database (see NewCuckooDatabase).
+//
+// Each key is stored in one of NumHashes candidate slots determined by the key and the
+// client retrieves a key with NumHashes index queries (one per candidate slot) such that
+// the servers do not learn which candidate holds the key. The table has
+// ceil(numKeys / LoadFactor) slots. The trade-offs are:
+//   - more hash functions allow higher load factors (placement succeeds with high probability
+//     below roughly 0.5 for 2 hashes, 0.91 for 3, and 0.97 for 4) but cost one more query each;
+//   - a higher load factor saves storage and server time (the scan is over the table)
+//     but placement needs more kicks and fails as it approaches the threshold;
+//   - MaxKicks bounds the time spent moving keys between their candidate slots before
+//     giving up with ErrCuckooPlacementFailed
+type CuckooConfig struct {
+	NumHashes  int
+	LoadFactor float64
+	MaxKicks   int
+}
+
+// DefaultCuckooConfig places the keys with 3 hash functions at a load factor of 0.8
+var DefaultCuckooConfig = CuckooConfig{NumHashes: 3, LoadFactor: 0.8, MaxKicks: 1000}
+
+// cuckooHeaderBytes is the size of the header of each slot of the table:
+// one byte marking occupied slots followed by the keyword
+const cuckooHeaderBytes = 1 + 4
+
+// CuckooMetadata is the public description of a cuckoo table that clients need to query it
+type CuckooMetadata struct {
+	DBMetadata
+	Config     CuckooConfig
+	Seed       []byte // seed of the hash functions
+	ValueBytes int
+}
+
+// CuckooDatabase is a keyword database that stores (keyword, value) pairs in a cuckoo table
+// such that keywords are retrieved with index queries (see CuckooMetadata.NewCuckooQueryShares)
+type CuckooDatabase struct {
+	DB       *Database
+	Metadata *CuckooMetadata
+}
+
+// NewCuckooDatabase places the values (of at most valueBytes bytes) into a cuckoo table
+// under their keywords (which must be distinct and fit in KeywordBits bits).
+// Returns ErrCuckooPlacementFailed if the keys do not fit within config.MaxKicks kicks
+func NewCuckooDatabase(keywords []uint, values []*Slot, valueBytes int, config CuckooConfig) (*CuckooDatabase, error) {
+
+	if len(keywords) != len(values) {
+		return nil, errors.New("number of keywords does not match the number of values")
+	}
+
+	if config.NumHashes < 1 || config.LoadFactor <= 0 || config.LoadFactor > 1 || config.MaxKicks < 0 {
+		return nil, errors.New("invalid cuckoo configuration")
+	}
+
+	seen := make(map[uint]bool)
+	for i, keyword := range keywords {
+		if uint64(keyword) >= 1<<KeywordBits {
+			return nil, ErrKeywordOutOfDomain
+		}
+
+		if seen[keyword] {
+			return nil, errors.New("duplicate keyword")
+		}
+		seen[keyword] = true
+
+		if len(values[i].Data) > valueBytes {
+			return nil, errors.New("value does not fit into the slot")
+		}
+	}
+
+	tableSize := int(math.Ceil(float64(len(keywords)) / config.LoadFactor))
+	if tableSize == 0 {
+		tableSize = 1
+	}
+
+	md := &CuckooMetadata{
+		DBMetadata: DBMetadata{SlotBytes: cuckooHeaderBytes + valueBytes, DBSize: tableSize},
+		Config:     config,
+		Seed:       make([]byte, 16),
+		ValueBytes: valueBytes,
+	}
+	randomBytes(md.Seed)
+
+	// index of the key placed in each slot of the table (-1 if empty)
+	table := make([]int, tableSize)
+	for i := range table {
+		table[i] = -1
+	}
+
+	for key := range keywords {
+		if !md.place(table, keywords, key) {
+			return nil, ErrCuckooPlacementFailed
+		}
+	}
+
+	db := NewDatabase()
+	db.SlotBytes = md.SlotBytes
+	db.DBSize = tableSize
+	db.Slots = make([]*Slot, tableSize)
+
+	for i, key := range table {
+		db.Slots[i] = NewEmptySlot(md.SlotBytes)
+		if key >= 0 {
+			db.Slots[i].Data[0] = 1
+			binary.BigEndian.PutUint32(db.Slots[i].Data[1:cuckooHeaderBytes], uint32(keywords[key]))
+			copy(db.Slots[i].Data[cuckooHeaderBytes:], values[key].Data)
+		}
+	}
+
+	return &CuckooDatabase{DB: db, Metadata: md}, nil
+}
+
+// place inserts the key into the table by evicting keys to their other
+// candidate slots (random walk) and returns false after MaxKicks evictions
+func (md *CuckooMetadata) place(table []int, keywords []uint, key int) bool {
+
+	for kick := 0; kick <= md.Config.MaxKicks; kick++ {
+		positions := md.Positions(keywords[key])
+		for _, pos := range positions {
+			if table[pos] < 0 {
+				table[pos] = key
+				return true
+			}
+		}
+
+		// evict the key of a random candidate slot and place it next
+		pos := positions[rand.Intn(len(positions))]
+		table[pos], key = key, table[pos]
+	}
+
+	return false
+}
+
+// Positions returns the candidate slots of the keyword (one per hash function)
+func (md *CuckooMetadata) Positions(keyword uint) []int {
+
+	buf := make([]byte, len(md.Seed)+8+4)
+	copy(buf, md.Seed)
+	binary.BigEndian.PutUint64(buf[len(md.Seed):], uint64(keyword))
+
+	positions := make([]int, md.Config.NumHashes)
+	for i := range positions {
+		binary.BigEndian.PutUint32(buf[len(md.Seed)+8:], uint32(i))
+		digest := sha256.Sum256(buf)
+		positions[i] = int(binary.BigEndian.Uint64(digest[:8]) % uint64(md.DBSize))
+	}
+
+	return positions
+}
+
+// NewCuckooQueryShares generates one set of index query shares for each candidate slot
+// of the keyword; the servers answer every query with PrivateSecretSharedQuery
+func (md *CuckooMetadata) NewCuckooQueryShares(keyword uint, numShares uint) [][]*QueryShare {
+
+	positions := md.Positions(keyword)
+
+	queries := make([][]*QueryShare, len(positions))
+	for i, pos := range positions {
+		queries[i] = md.NewIndexQueryShares(pos, 1, numShares)
+	}
+
+	return queries
+}
+
+// RecoverCuckoo recovers the value of the keyword from the result shares of the queries
+// generated by NewCuckooQueryShares (results[i] holds the result shares of query i)
+// and returns false if the keyword is not in the table
+func (md *CuckooMetadata) RecoverCuckoo(keyword uint, results [][]*SecretSharedQueryResult) (*Slot, bool) {
+
+	for _, resShares := range results {
+		slot := Recover(resShares)[0]
+
+		if slot.Data[0] == 1 && uint(binary.BigEndian.Uint32(slot.Data[1:cuckooHeaderBytes])) == keyword {
+			return NewSlot(slot.Data[cuckooHeaderBytes:]), true
+		}
+	}
+
+	return nil, false
+}
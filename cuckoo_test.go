@@ -0,0 +1,76 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCuckooDatabase(t *testing.T) {
+	setup()
+
+	numKeys := 256
+	keywords := make([]uint, numKeys)
+	values := make([]*Slot, numKeys)
+	seen := make(map[uint]bool)
+	for i := range keywords {
+		for {
+			keywords[i] = uint(rand.Uint32())
+			if !seen[keywords[i]] {
+				break
+			}
+		}
+		seen[keywords[i]] = true
+		values[i] = NewRandomSlot(SlotBytes)
+	}
+
+	configs := []CuckooConfig{
+		DefaultCuckooConfig,
+		{NumHashes: 3, LoadFactor: 0.95, MaxKicks: 1000}, // close to the threshold
+		{NumHashes: 2, LoadFactor: 1, MaxKicks: 100},     // above the threshold (almost always fails)
+	}
+
+	for _, config := range configs {
+		cdb, err := NewCuckooDatabase(keywords, values, SlotBytes, config)
+		if err == ErrCuckooPlacementFailed {
+			t.Logf("Placement failed for %+v\n", config)
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		md := cdb.Metadata
+		for i, keyword := range keywords {
+			queries := md.NewCuckooQueryShares(keyword, 2)
+			if len(queries) != config.NumHashes {
+				t.Fatalf("Generated %v queries, expected %v\n", len(queries), config.NumHashes)
+			}
+
+			results := make([][]*SecretSharedQueryResult, len(queries))
+			for q, shares := range queries {
+				results[q] = make([]*SecretSharedQueryResult, len(shares))
+				for s, share := range shares {
+					results[q][s], err = cdb.DB.PrivateSecretSharedQuery(share, NumProcsForQuery)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			value, ok := md.RecoverCuckoo(keyword, results)
+			if !ok || !value.Equal(values[i]) {
+				t.Fatalf("Incorrect value for keyword %v\n", keyword)
+			}
+		}
+	}
+
+	// the default configuration always places the keys
+	if _, err := NewCuckooDatabase(keywords, values, SlotBytes, DefaultCuckooConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewCuckooDatabase([]uint{1, 1}, values[:2], SlotBytes, DefaultCuckooConfig); err == nil {
+		t.Fatalf("Placed duplicate keywords\n")
+	}
+}
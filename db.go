@@ -1,9 +1,13 @@
 package pir
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ncw/gmp"
 	"github.com/sachaservan/paillier"
@@ -15,20 +19,54 @@ import (
 type DBMetadata struct {
 	SlotBytes int
 	DBSize    int
+	Layout    *GroupLayout // per-region group sizes (optional)
+
+	// byte that absent records are filled with (see EmptySlot).
+	// With the default of zero, an absent record cannot be told apart
+	// from a real all-zero record; 0xFF is a good sentinel for text data
+	EmptyFill byte
+
+	// if set, encrypted queries use the dimensions that minimize the communication
+	// given the slot size (see GetSlotAwareDimentions) instead of the sqrt layout
+	SlotAwareDimentions bool
 }
 
 // Database is a set of slots arranged in a grid of size width x height
 // where each slot has size slotBytes
 type Database struct {
 	DBMetadata
-	Slots    []*Slot
-	Keywords []uint // set of keywords (optional)
+	Slots      []*Slot
+	Keywords   []uint   // set of keywords (optional)
+	ColKeys    []uint   // keywords of the column groups for 2D keyword queries (optional)
+	Generation uint64   // incremented every time the database is modified
+	Versions   []uint64 // generation at which each slot was last modified (public)
+	RealDBSize int      // number of slots before padding (0 if the database is not padded)
+	Occupancy  []byte   // public bitmap of non-empty slots skipped by the scans (optional, see OccupancyBitmap)
+
+	// counts the homomorphic operations performed when answering AHE queries (optional)
+	OpCounter *OpCounter
+
+	// columnar copy of the slots read by the AHE scan (optional, see BuildSlotStore)
+	Store *SlotStore
+
+	// receives a diagnostic event for every query (optional, see Logger)
+	Logger Logger
+
+	// if set, PrivateSecretSharedQuery XORs the selected rows while expanding the query
+	// instead of materializing the selection vector of the query (see EvalFullDomainStream).
+	// This uses constant memory in the height of the database but expands the query in a single thread
+	StreamQueryExpansion bool
+
+	closed int32 // set by Close
 }
 
 // SecretSharedQueryResult contains shares of the resulting slots
 type SecretSharedQueryResult struct {
 	SlotBytes int
 	Shares    []*Slot
+
+	// compression of the binary encoding of the result (see Compression)
+	Compression Compression
 }
 
 // EncryptedSlot is an array of ciphertext bytes
@@ -43,12 +81,18 @@ type DoublyEncryptedSlot struct {
 	Cts []*paillier.Ciphertext // note: level2 ciphertexts (see Paillier)
 }
 
-// EncryptedQueryResult is an array of encrypted slots
+// EncryptedQueryResult is an array of encrypted slots.
+// Slots[j] is the encryption of column j of the selected row, i.e.,
+// of db.Slots[row*DBWidth + j] for the row selected by the query
+// (regardless of the number of workers that processed the query)
 type EncryptedQueryResult struct {
 	Slots                 []*EncryptedSlot
 	Pk                    *paillier.PublicKey
 	SlotBytes             int
 	NumBytesPerCiphertext int
+
+	// compression of the binary encoding of the result (see Compression)
+	Compression Compression
 }
 
 // DoublyEncryptedQueryResult is an array of encrypted slots
@@ -57,6 +101,11 @@ type DoublyEncryptedQueryResult struct {
 	Pk                    *paillier.PublicKey
 	SlotBytes             int
 	NumBytesPerCiphertext int
+	GroupSize             int
+	ColumnMask            []bool // group members included in Slots (nil if all are included)
+
+	// compression of the binary encoding of the result (see Compression)
+	Compression Compression
 }
 
 // NewDatabase returns an empty database
@@ -67,16 +116,75 @@ func NewDatabase() *Database {
 // PrivateSecretSharedQuery uses the provided PIR query to retreive a slot row
 func (db *Database) PrivateSecretSharedQuery(query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
 
-	bits := db.ExpandSharedQuery(query, nprocs)
-	return db.PrivateSecretSharedQueryWithExpandedBits(query, bits, nprocs)
+	return db.PrivateSecretSharedQueryContext(context.Background(), query, nprocs)
+}
+
+// PrivateSecretSharedQueryContext is the same as PrivateSecretSharedQuery
+// but returns ctx.Err() if the context is cancelled before the query is answered
+func (db *Database) PrivateSecretSharedQueryContext(ctx context.Context, query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+
+	start := time.Now()
+	res, err := db.privateSecretSharedQuery(ctx, query, nprocs)
+	db.logSecretSharedQuery(query, nprocs, start, err)
+
+	return res, err
+}
+
+func (db *Database) privateSecretSharedQuery(ctx context.Context, query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+
+	// answer the query over the window of its region
+	if query.Region != nil {
+		regionDB, err := db.regionDatabase(query.Region, query.GroupSize)
+		if err != nil {
+			return nil, err
+		}
+
+		regionQuery := *query
+		regionQuery.Region = nil
+
+		return regionDB.privateSecretSharedQuery(ctx, &regionQuery, nprocs)
+	}
+
+	if db.StreamQueryExpansion {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return db.privateSecretSharedQueryStreamed(query)
+	}
+
+	bits, err := db.ExpandSharedQueryContext(ctx, query, nprocs)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.privateSecretSharedQueryWithExpandedBits(ctx, query, bits, nprocs)
 }
 
 // PrivateSecretSharedQueryWithExpandedBits returns the result without expanding the query DPF
 func (db *Database) PrivateSecretSharedQueryWithExpandedBits(query *QueryShare, bits []bool, nprocs int) (*SecretSharedQueryResult, error) {
+	return db.PrivateSecretSharedQueryWithExpandedBitsContext(context.Background(), query, bits, nprocs)
+}
+
+// PrivateSecretSharedQueryWithExpandedBitsContext returns the result without expanding the query DPF
+// and returns ctx.Err() if the context is cancelled before the scan completes
+func (db *Database) PrivateSecretSharedQueryWithExpandedBitsContext(ctx context.Context, query *QueryShare, bits []bool, nprocs int) (*SecretSharedQueryResult, error) {
+
+	start := time.Now()
+	res, err := db.privateSecretSharedQueryWithExpandedBits(ctx, query, bits, nprocs)
+	db.logSecretSharedQuery(query, nprocs, start, err)
+
+	return res, err
+}
+
+func (db *Database) privateSecretSharedQueryWithExpandedBits(ctx context.Context, query *QueryShare, bits []bool, nprocs int) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
 
 	// height of databse given query.GroupSize = dbWidth
 	dimWidth := query.GroupSize
-	dimHeight := int(math.Ceil(float64(db.DBSize / query.GroupSize)))
+	dimHeight := int(math.Ceil(float64(db.DBSize) / float64(query.GroupSize)))
 
 	// mapping of results; one for each process
 	results := make([]*Slot, dimWidth)
@@ -90,49 +198,149 @@ func (db *Database) PrivateSecretSharedQueryWithExpandedBits(query *QueryShare,
 
 	for row := 0; row < dimHeight; row++ {
 
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		if bits[row] {
 			for col := 0; col < dimWidth; col++ {
 				slotIndex := row*dimWidth + col
 				// xor if bit is set and within bounds
-				if slotIndex < len(db.Slots) {
-					XorSlots(results[col], db.Slots[slotIndex])
-				} else {
+				if slotIndex >= len(db.Slots) {
 					break
 				}
+
+				// empty slots do not change the result
+				if !db.isOccupied(slotIndex) {
+					continue
+				}
+
+				// read from the store (if any) as the AHE scan does
+				XorSlotView(results[col], db.SlotView(slotIndex))
 			}
 		}
 	}
 
-	return &SecretSharedQueryResult{db.SlotBytes, results}, nil
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: results}, nil
+}
+
+// PrivateSecretSharedQueryMulti answers the same query share over several databases
+// (e.g., a thumbnail database and a full-size image database keyed identically)
+// by expanding the DPF only once and scanning each database with the expanded bits.
+// The databases must have the same number of slots (and keywords for keyword queries)
+// but can have different slot sizes; result i contains slots of size dbs[i].SlotBytes
+func PrivateSecretSharedQueryMulti(dbs []*Database, query *QueryShare, nprocs int) ([]*SecretSharedQueryResult, error) {
+
+	if len(dbs) == 0 {
+		return nil, errors.New("no databases provided")
+	}
+
+	for _, db := range dbs[1:] {
+		if db.DBSize != dbs[0].DBSize {
+			return nil, errors.New("databases have different sizes")
+		}
+
+		if query.IsKeywordBased && !equalKeywords(db.Keywords, dbs[0].Keywords) {
+			return nil, errors.New("databases have different keywords")
+		}
+	}
+
+	// the expanded bits only depend on the number of slots (and keywords)
+	bits := dbs[0].ExpandSharedQuery(query, nprocs)
+
+	results := make([]*SecretSharedQueryResult, len(dbs))
+	for i, db := range dbs {
+		res, err := db.PrivateSecretSharedQueryWithExpandedBits(query, bits, nprocs)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = res
+	}
+
+	return results, nil
+}
+
+func equalKeywords(a, b []uint) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
 }
 
 // ExpandSharedQuery returns the expands the DPF and returns an array of bits
 func (db *Database) ExpandSharedQuery(query *QueryShare, nprocs int) []bool {
+	bits, _ := db.ExpandSharedQueryContext(context.Background(), query, nprocs)
+	return bits
+}
 
-	var wg sync.WaitGroup
+// queryDomainBits returns the size (in bits) of the domain of the query's DPF
+// over a database of dimHeight rows
+func queryDomainBits(query *QueryShare, dimHeight int) uint {
 
-	dimHeight := int(math.Ceil(float64(db.DBSize / query.GroupSize)))
+	if query.PrefixBits > 0 {
+		return query.PrefixBits
+	}
+
+	if query.IsKeywordBased {
+		return uint(KeywordBits)
+	}
 
 	// num bits to represent the index
-	numBits := uint(math.Log2(float64(dimHeight)) + 1)
+	return uint(math.Log2(float64(dimHeight)) + 1)
+}
+
+// queryKey returns the point that the query's DPF is evaluated at for the row:
+// the index of the row, its keyword, or the prefix of its keyword
+func (db *Database) queryKey(query *QueryShare, row int) uint {
+
+	if query.PrefixBits > 0 {
+		return db.Keywords[row] >> (KeywordBits - query.PrefixBits)
+	}
 
 	if query.IsKeywordBased {
-		numBits = uint(32)
+		return db.Keywords[row]
+	}
+
+	return uint(row)
+}
+
+// ExpandSharedQueryContext expands the DPF and returns an array of bits
+// or ctx.Err() if the context is cancelled before the expansion completes
+func (db *Database) ExpandSharedQueryContext(ctx context.Context, query *QueryShare, nprocs int) ([]bool, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
 	}
 
+	var wg sync.WaitGroup
+
+	dimHeight := int(math.Ceil(float64(db.DBSize) / float64(query.GroupSize)))
+
 	// init server DPF
-	pf := dpf.ServerInitialize(query.PrfKeys, numBits)
+	pf := dpf.ServerInitialize(query.PrfKeys, queryDomainBits(query, dimHeight))
 
 	bits := make([]bool, dimHeight)
 	// expand the DPF into the bits array
 	for i := 0; i < dimHeight; i++ {
+
+		if err := ctx.Err(); err != nil {
+			wg.Wait()
+			return nil, err
+		}
+
 		// key (index or uint) depending on whether
 		// the query is keyword based or index based
 		// when keyword based use FSS
-		key := uint(i)
-		if query.IsKeywordBased {
-			key = db.Keywords[i]
-		}
+		key := db.queryKey(query, i)
 
 		// don't spin up go routines in the single-thread case
 		if nprocs == 1 {
@@ -170,7 +378,7 @@ func (db *Database) ExpandSharedQuery(query *QueryShare, nprocs int) []bool {
 		}
 	}
 
-	return bits
+	return bits, nil
 }
 
 // PrivateEncryptedQuery uses the provided PIR query to retreive a slot row (encrypted)
@@ -178,22 +386,86 @@ func (db *Database) ExpandSharedQuery(query *QueryShare, nprocs int) []bool {
 // the encryption scheme might not have a message space large enough to accomodate
 // all the bytes in a slot, thus requiring the bytes to be split up into several ciphertexts
 func (db *Database) PrivateEncryptedQuery(query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, error) {
+	return db.PrivateEncryptedQueryContext(context.Background(), query, nprocs)
+}
+
+// PrivateEncryptedQueryContext is the same as PrivateEncryptedQuery
+// but returns ctx.Err() if the context is cancelled before the scan completes
+func (db *Database) PrivateEncryptedQueryContext(ctx context.Context, query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, error) {
+	return db.privateEncryptedQueryWithChunkSize(ctx, query, nprocs, 0)
+}
+
+// PrivateEncryptedQueryWithChunkSize is the same as PrivateEncryptedQuery
+// but splits the rows into chunks of chunkSize rows that the nprocs workers
+// pull from a shared queue until all rows are processed.
+// Small chunks balance the load when rows vary in processing cost
+// at the expense of more scheduling overhead.
+// If chunkSize <= 0, each worker processes one contiguous chunk of dimHeight/nprocs rows
+func (db *Database) PrivateEncryptedQueryWithChunkSize(query *EncryptedQuery, nprocs, chunkSize int) (*EncryptedQueryResult, error) {
+	return db.privateEncryptedQueryWithChunkSize(context.Background(), query, nprocs, chunkSize)
+}
+
+func (db *Database) privateEncryptedQueryWithChunkSize(ctx context.Context, query *EncryptedQuery, nprocs, chunkSize int) (*EncryptedQueryResult, error) {
+
+	start := time.Now()
+	res, err := db.privateEncryptedQuery(ctx, query, nprocs, chunkSize)
+
+	db.logQuery(&QueryEvent{
+		Variant:   EncryptedVariant,
+		DBWidth:   query.DBWidth,
+		DBHeight:  query.DBHeight,
+		GroupSize: query.GroupSize,
+		NumProcs:  nprocs,
+	}, start, err)
+
+	return res, err
+}
+
+func (db *Database) privateEncryptedQuery(ctx context.Context, query *EncryptedQuery, nprocs, chunkSize int) (*EncryptedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if err := checkQueryKey(query.Pk); err != nil {
+		return nil, err
+	}
+
+	// answer the query over the window of its region
+	if query.Region != nil {
+		regionDB, err := db.regionDatabase(query.Region, query.GroupSize)
+		if err != nil {
+			return nil, err
+		}
+
+		regionQuery := *query
+		regionQuery.Region = nil
+
+		return regionDB.privateEncryptedQuery(ctx, &regionQuery, nprocs, chunkSize)
+	}
 
 	// width of databse given query.height
 	dimWidth := query.DBWidth
 	dimHeight := query.DBHeight
 
-	// how many ciphertexts are needed to represent a slot
-	msgSpaceBytes := float64(len(query.Pk.N.Bytes()) - 2)
-	numCiphertextsPerSlot := int(math.Ceil(float64(db.SlotBytes) / msgSpaceBytes))
+	if dimWidth < 0 || len(query.EBits) != dimHeight {
+		return nil, fmt.Errorf("%w: number of encrypted bits does not match query height", ErrInvalidQuery)
+	}
 
-	numBytesPerCiphertext := 0
+	// how many ciphertexts are needed to represent a slot
+	// and the number of bytes that each ciphertext represents
+	// (computed upfront so that it is set even if no slot is processed)
+	numCiphertextsPerSlot, numBytesPerCiphertext := ciphertextPacking(query.Pk, db.SlotBytes)
 
 	// mapping of results; one for each process
 	slotRes := make([][]*EncryptedSlot, nprocs)
 
-	// how many rows each process gets
-	numRowsPerProc := int(float64(dimHeight) / float64(nprocs))
+	// chunks of rows processed by the workers
+	chunks := rowChunks(dimHeight, nprocs, chunkSize)
+	var nextChunk int64 = -1
+
+	// error of each worker
+	errs := make([]error, nprocs)
 
 	var wg sync.WaitGroup
 
@@ -204,14 +476,6 @@ func (db *Database) PrivateEncryptedQuery(query *EncryptedQuery, nprocs int) (*E
 		go func(i int) {
 			defer wg.Done()
 
-			start := i * numRowsPerProc
-			end := i*numRowsPerProc + numRowsPerProc
-
-			// handle the edge case
-			if i+1 == nprocs {
-				end = dimHeight
-			}
-
 			// initialize the slots
 			for col := 0; col < dimWidth; col++ {
 				slotRes[i][col] = &EncryptedSlot{
@@ -223,36 +487,61 @@ func (db *Database) PrivateEncryptedQuery(query *EncryptedQuery, nprocs int) (*E
 				}
 			}
 
-			for row := start; row < end; row++ {
-				for col := 0; col < dimWidth; col++ {
-					slotIndex := row*dimWidth + col
-					if slotIndex >= len(db.Slots) {
-						continue
-					}
+			// number of homomorphic operations performed by the worker
+			numOps := 0
 
-					// convert the slot into big.Int array
-					intArr, numBytesPerInt, err := db.Slots[slotIndex].ToGmpIntArray(numCiphertextsPerSlot)
-					if err != nil {
-						panic(err)
-					}
+			for {
+				c := int(atomic.AddInt64(&nextChunk, 1))
+				if c >= len(chunks) || ctx.Err() != nil {
+					break
+				}
 
-					// set the number of bytes that each ciphertest represents
-					if numBytesPerCiphertext == 0 {
-						numBytesPerCiphertext = numBytesPerInt
-					}
+				if db.Store != nil {
+					numOps += db.accumulateColumnar(query, chunks[c][0], chunks[c][1], numCiphertextsPerSlot, slotRes[i])
+					continue
+				}
 
-					for j, val := range intArr {
-						sel := query.Pk.ConstMult(query.EBits[row], val)
-						slotRes[i][col].Cts[j] = query.Pk.Add(slotRes[i][col].Cts[j], sel)
+				for row := chunks[c][0]; row < chunks[c][1] && ctx.Err() == nil; row++ {
+					for col := 0; col < dimWidth; col++ {
+						slotIndex := row*dimWidth + col
+						if slotIndex >= len(db.Slots) || !db.isOccupied(slotIndex) {
+							continue
+						}
+
+						// convert the slot into big.Int array
+						intArr, _, err := db.Slots[slotIndex].ToGmpIntArray(numCiphertextsPerSlot)
+						if err != nil {
+							errs[i] = err
+							return
+						}
+
+						for j, val := range intArr {
+							sel := query.Pk.ConstMult(query.EBits[row], val)
+							slotRes[i][col].Cts[j] = query.Pk.Add(slotRes[i][col].Cts[j], sel)
+						}
+
+						numOps += len(intArr)
 					}
 				}
 			}
 
+			db.OpCounter.count(numOps, numOps)
+
 		}(i)
 	}
 
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	slots := slotRes[0]
 	for i := 1; i < nprocs; i++ {
 		for j := 0; j < dimWidth; j++ {
@@ -260,6 +549,8 @@ func (db *Database) PrivateEncryptedQuery(query *EncryptedQuery, nprocs int) (*E
 		}
 	}
 
+	db.OpCounter.count((nprocs-1)*dimWidth*numCiphertextsPerSlot, 0)
+
 	queryResult := &EncryptedQueryResult{
 		Pk:                    query.Pk,
 		Slots:                 slots,
@@ -270,37 +561,139 @@ func (db *Database) PrivateEncryptedQuery(query *EncryptedQuery, nprocs int) (*E
 	return queryResult, nil
 }
 
+// rowChunks splits the rows [0, height) into chunks of chunkSize rows
+// or into nprocs contiguous chunks (with the last chunk getting the remainder) if chunkSize <= 0
+func rowChunks(height, nprocs, chunkSize int) [][2]int {
+
+	chunks := make([][2]int, 0)
+
+	if chunkSize <= 0 {
+		// how many rows each process gets
+		numRowsPerProc := int(float64(height) / float64(nprocs))
+
+		for i := 0; i < nprocs; i++ {
+			start := i * numRowsPerProc
+			end := i*numRowsPerProc + numRowsPerProc
+
+			// handle the edge case
+			if i+1 == nprocs {
+				end = height
+			}
+
+			chunks = append(chunks, [2]int{start, end})
+		}
+
+		return chunks
+	}
+
+	for start := 0; start < height; start += chunkSize {
+		end := int(math.Min(float64(start+chunkSize), float64(height)))
+		chunks = append(chunks, [2]int{start, end})
+	}
+
+	return chunks
+}
+
 // PrivateDoublyEncryptedQuery executes a row PIR query and col PIR query by recursively
 // applying PrivateEncryptedQuery
 func (db *Database) PrivateDoublyEncryptedQuery(query *DoublyEncryptedQuery, nprocs int) (*DoublyEncryptedQueryResult, error) {
+	return db.PrivateDoublyEncryptedQueryContext(context.Background(), query, nprocs)
+}
+
+// PrivateDoublyEncryptedQueryContext is the same as PrivateDoublyEncryptedQuery
+// but returns ctx.Err() if the context is cancelled before the row query is answered
+func (db *Database) PrivateDoublyEncryptedQueryContext(ctx context.Context, query *DoublyEncryptedQuery, nprocs int) (*DoublyEncryptedQueryResult, error) {
+
+	start := time.Now()
+	res, err := db.privateDoublyEncryptedQuery(ctx, query, nprocs)
+
+	db.logQuery(&QueryEvent{
+		Variant:   DoublyEncryptedVariant,
+		DBWidth:   query.Row.DBWidth,
+		DBHeight:  query.Row.DBHeight,
+		GroupSize: query.Col.GroupSize,
+		NumProcs:  nprocs,
+	}, start, err)
+
+	return res, err
+}
+
+func (db *Database) privateDoublyEncryptedQuery(ctx context.Context, query *DoublyEncryptedQuery, nprocs int) (*DoublyEncryptedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
 
 	if query.Row.GroupSize > db.DBSize || query.Row.GroupSize == 0 {
-		return nil, errors.New("invalid group size provided in query")
+		return nil, fmt.Errorf("%w: invalid group size provided in query", ErrInvalidQuery)
 	}
 
 	if query.Col.GroupSize > query.Row.DBWidth || query.Col.GroupSize == 0 {
-		return nil, errors.New("invalid group size provided in query")
+		return nil, fmt.Errorf("%w: invalid group size provided in query", ErrInvalidQuery)
+	}
+
+	if query.ColumnMask != nil && len(query.ColumnMask) != query.Col.GroupSize {
+		return nil, fmt.Errorf("%w: column mask does not match the group size", ErrInvalidQuery)
+	}
+
+	// answer the query over the window of its region
+	if query.Row.Region != nil || query.Col.Region != nil {
+		if query.Row.Region == nil || query.Col.Region == nil || *query.Row.Region != *query.Col.Region {
+			return nil, fmt.Errorf("%w: row and column queries are over different regions", ErrInvalidQuery)
+		}
+
+		regionDB, err := db.regionDatabase(query.Row.Region, query.Row.GroupSize)
+		if err != nil {
+			return nil, err
+		}
+
+		row, col := *query.Row, *query.Col
+		row.Region, col.Region = nil, nil
+
+		return regionDB.privateDoublyEncryptedQuery(ctx, &DoublyEncryptedQuery{Row: &row, Col: &col, ColumnMask: query.ColumnMask}, nprocs)
 	}
 
 	// get the row
-	rowQueryRes, err := db.PrivateEncryptedQuery(query.Row, nprocs)
+	rowQueryRes, err := db.privateEncryptedQuery(ctx, query.Row, nprocs, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	return db.PrivateEncryptedQueryOverEncryptedResult(query.Col, rowQueryRes, nprocs)
+	return db.privateEncryptedQueryOverEncryptedResult(query.Col, rowQueryRes, query.ColumnMask, nprocs)
 }
 
 // PrivateEncryptedQueryOverEncryptedResult executes the query over an encrypted query result
 func (db *Database) PrivateEncryptedQueryOverEncryptedResult(query *EncryptedQuery, result *EncryptedQueryResult, nprocs int) (*DoublyEncryptedQueryResult, error) {
+	return db.privateEncryptedQueryOverEncryptedResult(query, result, nil, nprocs)
+}
 
-	// number of ciphertexts needed to encrypt a slot
-	numCiphertextsPerSlot := len(result.Slots[0].Cts)
+// privateEncryptedQueryOverEncryptedResult executes the query over an encrypted query result
+// and only returns the group members for which mask is true (or all members if mask is nil)
+func (db *Database) privateEncryptedQueryOverEncryptedResult(query *EncryptedQuery, result *EncryptedQueryResult, mask []bool, nprocs int) (*DoublyEncryptedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if err := checkQueryKey(query.Pk); err != nil {
+		return nil, err
+	}
+
+	if query.GroupSize <= 0 || len(result.Slots) == 0 || len(result.Slots)%query.GroupSize != 0 {
+		return nil, fmt.Errorf("%w: row has a size that is not a multiple of the group size", ErrInvalidQuery)
+	}
+
+	if len(query.EBits) != len(result.Slots)/query.GroupSize {
+		return nil, fmt.Errorf("%w: number of encrypted bits does not match query width", ErrInvalidQuery)
+	}
 
-	if len(result.Slots)%query.GroupSize != 0 {
-		panic("row has a size that is not a multiple of the group size")
+	if mask != nil && len(mask) != query.GroupSize {
+		return nil, fmt.Errorf("%w: column mask does not match the group size", ErrInvalidQuery)
 	}
 
+	// number of ciphertexts needed to encrypt a slot
+	numCiphertextsPerSlot := len(result.Slots[0].Cts)
+
 	// need to encrypt each of the ciphertexts representing one slot
 	// res is a 2D array where each row is an encrypted slot composed of possibly multiple ciphertexts
 	res := make([][]*paillier.Ciphertext, query.GroupSize)
@@ -323,6 +716,12 @@ func (db *Database) PrivateEncryptedQueryOverEncryptedResult(query *EncryptedQue
 			member = 0
 		}
 
+		// skip the members that are masked out
+		if mask != nil && !mask[member] {
+			member++
+			continue
+		}
+
 		// "selection" bit
 		bitIndex := int(col / query.GroupSize)
 		bitCt := query.EBits[bitIndex]
@@ -335,15 +734,21 @@ func (db *Database) PrivateEncryptedQueryOverEncryptedResult(query *EncryptedQue
 			res[member][j] = query.Pk.Add(res[member][j], sel)
 		}
 
+		db.OpCounter.count(len(slotCiphertexts), len(slotCiphertexts))
+
 		member++
 	}
 
-	resSlots := make([]*DoublyEncryptedSlot, query.GroupSize)
+	resSlots := make([]*DoublyEncryptedSlot, 0, query.GroupSize)
 
 	for i, cts := range res {
-		resSlots[i] = &DoublyEncryptedSlot{
-			Cts: cts,
+		if mask != nil && !mask[i] {
+			continue
 		}
+
+		resSlots = append(resSlots, &DoublyEncryptedSlot{
+			Cts: cts,
+		})
 	}
 
 	queryResult := &DoublyEncryptedQueryResult{
@@ -351,6 +756,8 @@ func (db *Database) PrivateEncryptedQueryOverEncryptedResult(query *EncryptedQue
 		Slots:                 resSlots,
 		NumBytesPerCiphertext: result.NumBytesPerCiphertext,
 		SlotBytes:             db.SlotBytes,
+		GroupSize:             query.GroupSize,
+		ColumnMask:            mask,
 	}
 
 	return queryResult, nil
@@ -388,11 +795,190 @@ func (db *Database) BuildForDataWithSlotSize(data []string, slotSize int) {
 	}
 }
 
+// BuildOccupancyDatabase returns a database with the same size as db
+// where each slot is a single byte flag set to 1 if the corresponding slot
+// of db is non-empty and 0 otherwise.
+// Any query for db can be issued to the occupancy database (with 1 byte slots)
+// to privately check whether a row is populated without downloading the record
+func (db *Database) BuildOccupancyDatabase() *Database {
+
+	occupancyDB := NewDatabase()
+	occupancyDB.Slots = make([]*Slot, db.DBSize)
+	occupancyDB.SlotBytes = 1
+	occupancyDB.DBSize = db.DBSize
+	occupancyDB.Keywords = db.Keywords
+
+	for i := 0; i < db.DBSize; i++ {
+		occupancyDB.Slots[i] = NewEmptySlot(1)
+		if i < len(db.Slots) && !db.Slots[i].IsEmpty() {
+			occupancyDB.Slots[i].Data[0] = 1
+		}
+	}
+
+	return occupancyDB
+}
+
+// SlotView is a read-only view of a slot in the backing storage of a database:
+// the columns of the database's SlotStore (if any) or the data of the slot
+type SlotView struct {
+	data  []byte
+	store *SlotStore
+	index int
+}
+
+// SlotView returns a view of the slot at index that reads the slot from
+// db.Store (if any) or db.Slots without copying it (see XorSlotView).
+// The view aliases the database: it is invalidated by any modification of
+// the slot (e.g., SetSlot), by rebuilding the store, or by Close
+func (db *Database) SlotView(index int) SlotView {
+
+	if db.Store != nil {
+		return SlotView{store: db.Store, index: index}
+	}
+
+	return SlotView{data: db.Slots[index].Data}
+}
+
+// SubDatabase returns a database view over the slots in the window [start, end)
+// such that queries processed by the view treat the window as indices [0, end-start).
+// The slots are shared with db (not copied) and the global index of
+// a slot retrieved from the view is start + local index
+func (db *Database) SubDatabase(start, end int) (*Database, error) {
+
+	if start < 0 || end > db.DBSize || start >= end {
+		return nil, errors.New("invalid window for sub-database")
+	}
+
+	sub := NewDatabase()
+	sub.Slots = db.Slots[start:end:end] // appending to the view must not overwrite the parent
+	sub.SlotBytes = db.SlotBytes
+	sub.DBSize = end - start
+	sub.StreamQueryExpansion = db.StreamQueryExpansion
+	sub.Logger = db.Logger
+
+	if db.Keywords != nil {
+		sub.Keywords = db.Keywords[start:end:end]
+	}
+
+	if db.Store != nil {
+		sub.Store = db.Store.View(start, end)
+	}
+
+	return sub, nil
+}
+
+// PadToPowerOfTwo returns a copy of the database padded with empty slots
+// up to the next power of two (the slots themselves are shared).
+// The number of slots before padding is recorded in RealDBSize;
+// queries to padded positions return empty slots.
+// Padded slots get the keyword 0, which is harmless since empty slots
+// do not change the result of a keyword query
+func (db *Database) PadToPowerOfTwo() *Database {
+
+	size := 1
+	for size < db.DBSize {
+		size <<= 1
+	}
+
+	padded := NewDatabase()
+	padded.SlotBytes = db.SlotBytes
+	padded.DBSize = size
+	padded.Generation = db.Generation
+	padded.RealDBSize = db.DBSize
+	if db.RealDBSize > 0 {
+		padded.RealDBSize = db.RealDBSize
+	}
+
+	padded.Slots = make([]*Slot, size)
+	copy(padded.Slots, db.Slots)
+	for i := db.DBSize; i < size; i++ {
+		padded.Slots[i] = NewEmptySlot(db.SlotBytes)
+	}
+
+	if db.Keywords != nil {
+		padded.Keywords = make([]uint, size)
+		copy(padded.Keywords, db.Keywords)
+	}
+
+	if db.Versions != nil {
+		padded.Versions = make([]uint64, size)
+		copy(padded.Versions, db.Versions)
+	}
+
+	return padded
+}
+
+// Transpose returns a copy of the database viewed as a width x height grid
+// with the slot at (row, col) moved to (col, row), such that the result is a height x width grid
+// (e.g., to compare the cost of queries over both layouts).
+// If the database has fewer than width*height slots, it is padded with empty slots
+// (with keyword 0 and version 0) before transposing. The slots themselves are shared
+func (db *Database) Transpose(width, height int) (*Database, error) {
+
+	if width <= 0 || height <= 0 || db.DBSize > width*height {
+		return nil, errors.New("database does not fit the dimensions")
+	}
+
+	size := width * height
+
+	transposed := NewDatabase()
+	transposed.SlotBytes = db.SlotBytes
+	transposed.DBSize = size
+	transposed.Generation = db.Generation
+	transposed.Slots = make([]*Slot, size)
+
+	if db.Keywords != nil {
+		transposed.Keywords = make([]uint, size)
+	}
+
+	if db.Versions != nil {
+		transposed.Versions = make([]uint64, size)
+	}
+
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			from := row*width + col
+			to := col*height + row
+
+			if from >= db.DBSize {
+				transposed.Slots[to] = NewEmptySlot(db.SlotBytes)
+				continue
+			}
+
+			transposed.Slots[to] = db.Slots[from]
+
+			if db.Keywords != nil {
+				transposed.Keywords[to] = db.Keywords[from]
+			}
+
+			if db.Versions != nil {
+				transposed.Versions[to] = db.Versions[from]
+			}
+		}
+	}
+
+	return transposed, nil
+}
+
 // SetKeywords set the keywords (uints) associated with each row of the database
 func (db *Database) SetKeywords(keywords []uint) {
 	db.Keywords = keywords
 }
 
+// EmptySlot returns the slot that represents an absent record,
+// i.e., a slot filled with the EmptyFill sentinel.
+// The sentinel must be chosen such that no real record consists only of the sentinel byte.
+// Note that group members past the end of the database (and the padding added by
+// PadToPowerOfTwo, which must not change the result of keyword queries) recover as all-zero slots
+func (dbmd *DBMetadata) EmptySlot() *Slot {
+	return NewEmptySlotFill(dbmd.SlotBytes, dbmd.EmptyFill)
+}
+
+// IsEmptySlot returns true if the recovered slot is the EmptySlot sentinel
+func (dbmd *DBMetadata) IsEmptySlot(slot *Slot) bool {
+	return slot.Equal(dbmd.EmptySlot())
+}
+
 // IndexToCoordinates returns the 2D coodindates for an index
 // a PIR query should use the first value to recover the row
 // and the second value to recover the column in the response
@@ -405,7 +991,9 @@ func (dbmd *DBMetadata) IndexToCoordinates(index, width, height int) (int, int)
 // groupSize is the number of *adjacent* slots needed to constitute a "group" (default = 1)
 func (dbmd *DBMetadata) GetDimentionsForDatabase(height int, groupSize int) (int, int) {
 
-	dimWidth := int(math.Ceil(float64(dbmd.DBSize / (height * groupSize))))
+	checkGroupSize(groupSize)
+
+	dimWidth := int(math.Ceil(float64(dbmd.DBSize) / float64(height*groupSize)))
 
 	if dimWidth == 0 {
 		dimWidth = 1
@@ -414,11 +1002,53 @@ func (dbmd *DBMetadata) GetDimentionsForDatabase(height int, groupSize int) (int
 	dimHeight := height
 
 	// trim the height to fit the database without extra rows
-	dimHeight = int(math.Ceil(float64(dbmd.DBSize / (dimWidth * groupSize))))
+	dimHeight = int(math.Ceil(float64(dbmd.DBSize) / float64(dimWidth*groupSize)))
 
 	return dimWidth * groupSize, dimHeight
 }
 
+// GetSlotAwareDimentions returns the width and height (as GetDimentionsForDatabase)
+// that minimize the communication of an encrypted query under pk.
+// The query consists of one ciphertext per row and the result of one ciphertext
+// per ciphertext-sized chunk of each slot of the row (see ciphertextPacking), and both
+// are ciphertexts of the same level, so the layout minimizes height + width * numCiphertextsPerSlot.
+// The sqrt layout is only optimal when each slot fits into a single ciphertext;
+// wider slots shift the optimal layout towards fewer columns
+func (dbmd *DBMetadata) GetSlotAwareDimentions(pk *paillier.PublicKey, groupSize int) (int, int) {
+
+	checkGroupSize(groupSize)
+
+	numCiphertextsPerSlot, _ := ciphertextPacking(pk, dbmd.SlotBytes)
+	numGroups := int(math.Ceil(float64(dbmd.DBSize) / float64(groupSize)))
+
+	bestWidth, bestHeight := groupSize, numGroups
+	bestCost := math.MaxInt64
+	for g := 1; g <= numGroups; g++ {
+		width := g * groupSize
+		height := int(math.Ceil(float64(dbmd.DBSize) / float64(width)))
+
+		if cost := height + width*numCiphertextsPerSlot; cost < bestCost {
+			bestWidth, bestHeight, bestCost = width, height, cost
+		}
+	}
+
+	return bestWidth, bestHeight
+}
+
+// encryptedQueryDimentions returns the dimensions of the encrypted queries
+// generated by NewEncryptedQuery: the sqrt layout by default
+// or the slot-aware layout if dbmd.SlotAwareDimentions is set
+func (dbmd *DBMetadata) encryptedQueryDimentions(pk *paillier.PublicKey, groupSize int) (int, int) {
+
+	if dbmd.SlotAwareDimentions {
+		return dbmd.GetSlotAwareDimentions(pk, groupSize)
+	}
+
+	// compute sqrt dimentions
+	height := int(math.Ceil(math.Sqrt(float64(dbmd.DBSize))))
+	return dbmd.GetDimentionsForDatabase(height, groupSize)
+}
+
 // GetSqrtOfDBSize returns sqrt(DBSize) + 1
 func (dbmd *DBMetadata) GetSqrtOfDBSize() int {
 	return int(math.Sqrt(float64(dbmd.DBSize)) + 1)
@@ -445,6 +1075,32 @@ func GetOptimalWeightedDBDimentions(slotSize int, dbSize int, weight int) (int,
 	return newWidth, newHeight
 }
 
+// ciphertextPacking returns the number of ciphertexts needed to represent a slot
+// of slotBytes bytes and the number of bytes that each ciphertext represents
+func ciphertextPacking(pk *paillier.PublicKey, slotBytes int) (int, int) {
+
+	msgSpaceBytes := float64(len(pk.N.Bytes()) - 2)
+	numCiphertextsPerSlot := int(math.Ceil(float64(slotBytes) / msgSpaceBytes))
+	numBytesPerCiphertext := int(math.Max(1, math.Ceil(float64(slotBytes)/float64(numCiphertextsPerSlot))))
+
+	return numCiphertextsPerSlot, numBytesPerCiphertext
+}
+
+// checkQueryKey returns ErrInvalidQuery if the public key of a query
+// is missing or too small to encrypt a byte of a slot (see ciphertextPacking)
+func checkQueryKey(pk *paillier.PublicKey) error {
+
+	if pk == nil || pk.N == nil {
+		return fmt.Errorf("%w: query does not have a public key", ErrInvalidQuery)
+	}
+
+	if len(pk.N.Bytes()) <= 2 {
+		return fmt.Errorf("%w: public key is too small", ErrInvalidQuery)
+	}
+
+	return nil
+}
+
 func addEncryptedSlots(pk *paillier.PublicKey, a, b *EncryptedSlot) {
 
 	for j := 0; j < len(b.Cts); j++ {
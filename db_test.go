@@ -3,6 +3,8 @@ package pir
 import (
 	"math"
 	"math/rand"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,41 +24,15 @@ func TestSharedQuery(t *testing.T) {
 
 	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
 
-		dimWidth := groupSize
-		dimHeight := int(math.Ceil(float64(TestDBSize / dimWidth)))
+		dimHeight := int(math.Ceil(float64(TestDBSize / groupSize)))
 
 		for i := 0; i < NumQueries; i++ {
 			qIndex := rand.Intn(dimHeight)
-			shares := db.NewIndexQueryShares(qIndex, groupSize, 2)
 
-			resA, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
-			if err != nil {
-				t.Fatalf("%v", err)
-			}
-
-			resB, err := db.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
-			if err != nil {
-				t.Fatalf("%v", err)
-			}
-
-			resultShares := [...]*SecretSharedQueryResult{resA, resB}
-			res := Recover(resultShares[:])
-
-			for j := 0; j < dimWidth; j++ {
-
-				index := int(qIndex)*dimWidth + j
-				if index >= db.DBSize {
-					break
-				}
-
-				if !db.Slots[index].Equal(res[j]) {
-					t.Fatalf(
-						"Query result is incorrect. %v != %v\n",
-						db.Slots[index],
-						res[j],
-					)
-				}
+			res := sharedQueryGroup(t, db, groupSize, qIndex*groupSize)
+			checkGroup(t, db, groupSize, qIndex*groupSize, res)
 
+			for j := range res {
 				t.Logf("Slot %v, is %v\n", j, res[j])
 			}
 		}
@@ -74,39 +50,11 @@ func TestEncryptedQuery(t *testing.T) {
 
 		for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
 
-			dimWidth, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+			_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
 
 			for i := 0; i < NumQueries; i++ {
 				qIndex := rand.Intn(dimHeight)
-
-				query := db.NewEncryptedQuery(pk, groupSize, qIndex)
-
-				response, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
-				if err != nil {
-					t.Fatalf("%v", err)
-				}
-
-				res := RecoverEncrypted(response, sk)
-
-				if len(res)%groupSize != 0 {
-					t.Fatalf("Response size is not a multiple of DBGroupSize")
-				}
-
-				for j := 0; j < dimWidth; j++ {
-
-					index := qIndex*dimWidth + j
-					if index >= db.DBSize {
-						break
-					}
-
-					if !db.Slots[index].Equal(res[j]) {
-						t.Fatalf(
-							"Query result is incorrect. %v != %v\n",
-							db.Slots[index],
-							res[j],
-						)
-					}
-				}
+				encryptedQueryRow(t, db, sk, pk, groupSize, qIndex)
 			}
 		}
 	}
@@ -134,7 +82,10 @@ func TestEncryptedNullQuery(t *testing.T) {
 					t.Fatalf("%v", err)
 				}
 
-				res := RecoverEncrypted(response, sk)
+				res, err := RecoverEncrypted(response, sk)
+				if err != nil {
+					t.Fatalf("%v", err)
+				}
 
 				if len(res)%groupSize != 0 {
 					t.Fatalf("Response size is not a multiple of DBGroupSize")
@@ -175,7 +126,10 @@ func TestDoublyEncryptedNullQuery(t *testing.T) {
 					t.Fatalf("%v", err)
 				}
 
-				res := RecoverDoublyEncrypted(response, sk)
+				res, err := RecoverDoublyEncrypted(response, sk)
+				if err != nil {
+					t.Fatal(err)
+				}
 				emptySlot := NewEmptySlot(len(res[0].Data))
 
 				for col := 0; col < groupSize; col++ {
@@ -224,46 +178,253 @@ func TestDoublyEncryptedQuery(t *testing.T) {
 				// select a random group
 				qIndex := int(rand.Intn(dimWidth*dimHeight) / groupSize)
 
-				query := db.NewDoublyEncryptedQuery(pk, groupSize, qIndex)
+				res := doublyEncryptedQueryGroup(t, db, sk, pk, groupSize, qIndex)
+				checkGroup(t, db, groupSize, qIndex, res)
+			}
+		}
+	}
+}
 
-				if len(query.Col.EBits) > (dimWidth / groupSize) {
-					t.Fatalf(
-						"Query consists of %v encrypted bits for a db width of %v\n",
-						len(query.Col.EBits),
-						(query.Col.DBWidth / groupSize),
-					)
-				}
+func TestDoublyEncryptedQueryWideSlots(t *testing.T) {
+	setup()
 
-				response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
-				if err != nil {
-					t.Fatalf("%v", err)
-				}
+	sk, pk := paillier.KeyGen(128)
 
-				res := RecoverDoublyEncrypted(response, sk)
+	msgSpaceBytes := len(pk.N.Bytes()) - 2
+	for _, slotBytes := range []int{msgSpaceBytes + 1, 3 * msgSpaceBytes, 100} {
 
-				rowIndex, colIndex := db.IndexToCoordinates(qIndex, dimWidth, dimHeight)
-				colIndex = int(colIndex / groupSize)
+		// slots are packed into several ciphertexts that are each nested at level two
+		numCiphertextsPerSlot, _ := ciphertextPacking(pk, slotBytes)
+		if numCiphertextsPerSlot < 2 {
+			t.Fatalf("Slots of %v bytes fit in one ciphertext\n", slotBytes)
+		}
 
-				for j := 0; j < groupSize; j++ {
+		db := GenerateRandomDB(TestDBSize, slotBytes)
 
-					index := rowIndex*dimWidth + colIndex*groupSize + j
-					if index >= db.DBSize {
-						break
-					}
+		for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+			for i := 0; i < 5; i++ {
+				qIndex := rand.Intn(TestDBSize)
 
-					if !db.Slots[index].Equal(res[j]) {
-						t.Fatalf(
-							"Query result is incorrect. %v != %v\n",
-							db.Slots[index],
-							res[j],
-						)
-					}
-				}
+				res := doublyEncryptedQueryGroup(t, db, sk, pk, groupSize, qIndex)
+				checkGroup(t, db, groupSize, qIndex, res)
+			}
+		}
+	}
+}
+
+// run with 'go test -v -run TestAllVariants' to see log outputs.
+func TestAllVariants(t *testing.T) {
+	setup()
+
+	tests := []struct {
+		dbSize    int
+		slotBytes int
+		groupSize int
+	}{
+		{TestDBSize, SlotBytes, 1},
+		{TestDBSize, SlotBytes, 3},
+		{TestDBSize, 1, 2},
+		{TestDBSize, 40, 1},
+		{1000, SlotBytes, 1},
+		{1000, SlotBytes, 3},
+		{1001, 7, 4},
+		{17, SlotBytes, 2},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < NumTrials; i++ {
+			index := rand.Intn(test.dbSize)
+			if i == 0 {
+				index = test.dbSize - 1 // last slot is the edge case for non-divisible sizes
 			}
+
+			testAllVariants(t, test.dbSize, test.slotBytes, test.groupSize, index)
+		}
+	}
+}
+
+// testAllVariants runs the secret shared, encrypted, and doubly encrypted
+// variants on the same database and asserts that all three
+// recover the group containing the slot at index
+func testAllVariants(t *testing.T, dbSize, slotBytes, groupSize, index int) {
+	t.Helper()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(dbSize, slotBytes)
+
+	shared := sharedQueryGroup(t, db, groupSize, index)
+	checkGroup(t, db, groupSize, index, shared)
+
+	query := db.NewEncryptedQuery(pk, groupSize, 0)
+	rowIndex := index / query.DBWidth
+	offset := ((index % query.DBWidth) / groupSize) * groupSize
+	encrypted := encryptedQueryRow(t, db, sk, pk, groupSize, rowIndex)[offset : offset+groupSize]
+	checkGroup(t, db, groupSize, index, encrypted)
+
+	doublyEncrypted := doublyEncryptedQueryGroup(t, db, sk, pk, groupSize, index)
+	checkGroup(t, db, groupSize, index, doublyEncrypted)
+
+	for j := 0; j < groupSize; j++ {
+		if !shared[j].Equal(encrypted[j]) || !shared[j].Equal(doublyEncrypted[j]) {
+			t.Fatalf(
+				"Variants disagree (db size %v, slot bytes %v, group size %v, index %v): %v, %v, %v\n",
+				dbSize, slotBytes, groupSize, index,
+				shared[j], encrypted[j], doublyEncrypted[j],
+			)
+		}
+	}
+}
+
+// sharedQueryGroup retrieves the group containing the slot at index
+// using a two-server secret shared query
+func sharedQueryGroup(t *testing.T, db *Database, groupSize, index int) []*Slot {
+	t.Helper()
+
+	shares := db.NewIndexQueryShares(index/groupSize, groupSize, 2)
+
+	resA, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	resB, err := db.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return Recover([]*SecretSharedQueryResult{resA, resB})
+}
+
+// encryptedQueryRow retrieves the row at rowIndex using an encrypted query
+// (with the default dimensions) and checks it against the database
+func encryptedQueryRow(t *testing.T, db *Database, sk *paillier.SecretKey, pk *paillier.PublicKey, groupSize, rowIndex int) []*Slot {
+	t.Helper()
+
+	query := db.NewEncryptedQuery(pk, groupSize, rowIndex)
+	return encryptedQueryRowWithQuery(t, db, sk, query, rowIndex)
+}
+
+// encryptedQueryRowWithQuery processes the encrypted query and checks
+// that the recovered row matches the row at rowIndex in the database
+func encryptedQueryRowWithQuery(t *testing.T, db *Database, sk *paillier.SecretKey, query *EncryptedQuery, rowIndex int) []*Slot {
+	t.Helper()
+
+	response, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	res, err := RecoverEncrypted(response, sk)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(res)%query.GroupSize != 0 {
+		t.Fatalf("Response size is not a multiple of DBGroupSize")
+	}
+
+	for j := 0; j < query.DBWidth; j++ {
+
+		index := rowIndex*query.DBWidth + j
+		if index >= db.DBSize {
+			break
+		}
+
+		if !db.Slots[index].Equal(res[j]) {
+			t.Fatalf(
+				"Query result is incorrect. %v != %v\n",
+				db.Slots[index],
+				res[j],
+			)
+		}
+	}
+
+	return res
+}
+
+// doublyEncryptedQueryGroup retrieves the group containing the slot at index
+// using a doubly encrypted query (with the default dimensions)
+func doublyEncryptedQueryGroup(t *testing.T, db *Database, sk *paillier.SecretKey, pk *paillier.PublicKey, groupSize, index int) []*Slot {
+	t.Helper()
+
+	query := db.NewDoublyEncryptedQuery(pk, groupSize, index)
+
+	if len(query.Col.EBits) > (query.Row.DBWidth / groupSize) {
+		t.Fatalf(
+			"Query consists of %v encrypted bits for a db width of %v\n",
+			len(query.Col.EBits),
+			(query.Col.DBWidth / groupSize),
+		)
+	}
+
+	response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	res, err := RecoverDoublyEncrypted(response, sk)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return res
+}
+
+// checkGroup asserts that res is the group containing the slot at index
+// where positions past the end of the database are empty
+func checkGroup(t *testing.T, db *Database, groupSize, index int, res []*Slot) {
+	t.Helper()
+
+	if len(res) < groupSize {
+		t.Fatalf("Expected at least %v slots, got %v\n", groupSize, len(res))
+	}
+
+	start := (index / groupSize) * groupSize
+	for j := 0; j < groupSize; j++ {
+
+		expected := NewEmptySlot(db.SlotBytes)
+		if start+j < db.DBSize {
+			expected = db.Slots[start+j]
+		}
+
+		if !expected.Equal(res[j]) {
+			t.Fatalf(
+				"Query result is incorrect. %v != %v\n",
+				expected,
+				res[j],
+			)
 		}
 	}
 }
 
+func TestEmptyFill(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.EmptyFill = 0xFF
+
+	// a real all-zero record and an absent record
+	zeroIndex, emptyIndex := 0, 1+rand.Intn(TestDBSize-1)
+	if err := db.SetSlot(zeroIndex, NewEmptySlot(SlotBytes)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.ClearSlot(emptyIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	zero := sharedQueryGroup(t, db, 1, zeroIndex)[0]
+	empty := sharedQueryGroup(t, db, 1, emptyIndex)[0]
+
+	if db.IsEmptySlot(zero) || !zero.IsEmpty() {
+		t.Fatalf("All-zero record was recovered as absent\n")
+	}
+
+	if !db.IsEmptySlot(empty) {
+		t.Fatalf("Absent record was not recovered as empty: %v\n", empty)
+	}
+}
+
 func BenchmarkBuildDB(b *testing.B) {
 	setup()
 
@@ -324,6 +485,41 @@ func BenchmarkQuerySecretSharesSingle8Thread(b *testing.B) {
 	}
 }
 
+// benchmarkScanSlotStore XORs every slot of a store-backed database into a result,
+// reading each slot as a copy (Slot) or through a zero-copy view (SlotView)
+func benchmarkScanSlotStore(b *testing.B, view bool) {
+	setup()
+
+	// slots large enough that copies are not allocated on the stack
+	slotBytes := 256
+	db := GenerateRandomDB(BenchmarkDBSize/16, slotBytes)
+	db.BuildSlotStore()
+	res := NewEmptySlot(slotBytes)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for index := 0; index < db.DBSize; index++ {
+			if view {
+				XorSlotView(res, db.SlotView(index))
+			} else {
+				XorSlots(res, db.Store.Slot(index))
+			}
+		}
+	}
+}
+
+// baseline for BenchmarkScanSlotStoreView: one allocation per slot
+func BenchmarkScanSlotStoreCopy(b *testing.B) {
+	benchmarkScanSlotStore(b, false)
+}
+
+// no allocations per slot
+func BenchmarkScanSlotStoreView(b *testing.B) {
+	benchmarkScanSlotStore(b, true)
+}
+
 func BenchmarkGenEncryptedQuery(b *testing.B) {
 	setup()
 
@@ -475,3 +671,1213 @@ func fakeDoublyEncryptedQuery(pk *paillier.PublicKey, dbSize int) *DoublyEncrypt
 		Col: colQuery,
 	}
 }
+
+// run with 'go test -v -run TestSingleByteSlots' to see log outputs.
+func TestSingleByteSlots(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, 1)
+
+	// make sure the edge case byte values are in the database
+	db.Slots[0] = NewSlot([]byte{0})
+	db.Slots[1] = NewSlot([]byte{255})
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		dimWidth, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+
+		for i := 0; i < NumQueries; i++ {
+			index := rand.Intn(db.DBSize)
+			if i < 2 {
+				index = i
+			}
+
+			res := sharedQueryGroup(t, db, groupSize, index)
+			checkGroup(t, db, groupSize, index, res)
+
+			res = encryptedQueryRow(t, db, sk, pk, groupSize, rand.Intn(dimHeight))
+			for j := range res {
+				if len(res[j].Data) != 1 {
+					t.Fatalf("Encrypted query returned a slot of %v bytes\n", len(res[j].Data))
+				}
+			}
+
+			res = doublyEncryptedQueryGroup(t, db, sk, pk, groupSize, index%(dimWidth*dimHeight))
+			checkGroup(t, db, groupSize, index%(dimWidth*dimHeight), res)
+		}
+	}
+
+	// the number of bytes per ciphertext must be set even when a single byte is encrypted
+	query := db.NewEncryptedQuery(pk, 1, 0)
+	response, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.NumBytesPerCiphertext != 1 {
+		t.Fatalf("Expected 1 byte per ciphertext, got %v\n", response.NumBytesPerCiphertext)
+	}
+}
+
+func TestRecoverN(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for i := 0; i < NumQueries; i++ {
+		qIndex := rand.Intn(TestDBSize)
+		shares := db.NewIndexQueryShares(qIndex, 1, 2)
+
+		resA, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := db.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := RecoverN([]*SecretSharedQueryResult{resA, resB}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !db.Slots[qIndex].Equal(res[0]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[qIndex], res[0])
+		}
+
+		// recover with a missing share
+		if _, err := RecoverN([]*SecretSharedQueryResult{resB}, 2); err == nil {
+			t.Fatal("Recovered from a missing share without error")
+		}
+
+		// recover with a nil share
+		if _, err := RecoverN([]*SecretSharedQueryResult{resA, nil}, 2); err == nil {
+			t.Fatal("Recovered from a nil share without error")
+		}
+	}
+}
+
+func TestOccupancyQuery(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// empty out every other slot
+	for i := 0; i < db.DBSize; i += 2 {
+		db.Slots[i] = NewEmptySlot(SlotBytes)
+	}
+
+	occupancyDB := db.BuildOccupancyDatabase()
+	if occupancyDB.SlotBytes != 1 || occupancyDB.DBSize != db.DBSize {
+		t.Fatalf("Occupancy database has incorrect metadata %v\n", occupancyDB.DBMetadata)
+	}
+
+	for i := 0; i < NumQueries; i++ {
+		qIndex := rand.Intn(TestDBSize)
+		occupied := !db.Slots[qIndex].IsEmpty()
+
+		// secret shared variant
+		shares := occupancyDB.NewIndexQueryShares(qIndex, 1, 2)
+		resA, err := occupancyDB.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := occupancyDB.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Recover([]*SecretSharedQueryResult{resA, resB})
+		if (res[0].Data[0] == 1) != occupied {
+			t.Fatalf("Occupancy of index %v is incorrect, expected %v\n", qIndex, occupied)
+		}
+
+		// doubly encrypted variant
+		query := occupancyDB.NewDoublyEncryptedQuery(pk, 1, qIndex)
+		response, err := occupancyDB.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err = RecoverDoublyEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (res[0].Data[0] == 1) != occupied {
+			t.Fatalf("Occupancy of index %v is incorrect, expected %v\n", qIndex, occupied)
+		}
+	}
+}
+
+func TestDoublyEncryptedQueryWithColumnMask(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	groupSize := 4
+
+	dimWidth, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+
+	for i := 0; i < NumQueries; i++ {
+		qIndex := rand.Intn(db.DBSize)
+
+		// mask out the second half of the group
+		mask := []bool{true, true, false, false}
+		if i%2 == 1 {
+			mask = []bool{false, true, false, true}
+		}
+
+		query := db.NewDoublyEncryptedQueryWithColumnMask(pk, groupSize, qIndex, mask)
+		response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(response.Slots) != 2 {
+			t.Fatalf("Expected 2 slots in the response, got %v\n", len(response.Slots))
+		}
+
+		res, err := RecoverDoublyEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rowIndex, colIndex := db.IndexToCoordinates(qIndex, dimWidth, dimHeight)
+		start := rowIndex*dimWidth + (colIndex/groupSize)*groupSize
+
+		next := 0
+		for j := 0; j < groupSize; j++ {
+			if !mask[j] {
+				continue
+			}
+
+			if !db.Slots[start+j].Equal(res[next]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[start+j], res[next])
+			}
+			next++
+		}
+	}
+
+	query := db.NewDoublyEncryptedQueryWithColumnMask(pk, groupSize, 0, []bool{true})
+	if _, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery); err == nil {
+		t.Fatal("Accepted a column mask that does not match the group size")
+	}
+}
+
+func TestSubDatabase(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// split the database into two shards
+	split := rand.Intn(TestDBSize-2) + 1
+	windows := [][2]int{{0, split}, {split, TestDBSize}}
+
+	shards := make([]*Database, len(windows))
+	for i, w := range windows {
+		shard, err := db.SubDatabase(w[0], w[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if shard.DBSize != w[1]-w[0] {
+			t.Fatalf("Shard has size %v, expected %v\n", shard.DBSize, w[1]-w[0])
+		}
+
+		shards[i] = shard
+	}
+
+	for i := 0; i < NumQueries; i++ {
+		globalIndex := rand.Intn(TestDBSize)
+
+		shardIndex := 0
+		if globalIndex >= split {
+			shardIndex = 1
+		}
+
+		shard := shards[shardIndex]
+		localIndex := globalIndex - windows[shardIndex][0]
+
+		res := sharedQueryGroup(t, shard, 1, localIndex)
+		if !db.Slots[globalIndex].Equal(res[0]) {
+			t.Fatalf("Shared query result is incorrect. %v != %v\n", db.Slots[globalIndex], res[0])
+		}
+
+		res = doublyEncryptedQueryGroup(t, shard, sk, pk, 1, localIndex)
+		if !db.Slots[globalIndex].Equal(res[0]) {
+			t.Fatalf("Doubly encrypted query result is incorrect. %v != %v\n", db.Slots[globalIndex], res[0])
+		}
+	}
+
+	// appending to a shard does not overwrite the slots of the parent
+	next := db.Slots[split]
+	shards[0].Slots = append(shards[0].Slots, NewRandomSlot(SlotBytes))
+	if db.Slots[split] != next {
+		t.Fatalf("Append to the sub-database overwrote the parent's slots\n")
+	}
+
+	for _, w := range [][2]int{{-1, 10}, {10, 10}, {0, TestDBSize + 1}} {
+		if _, err := db.SubDatabase(w[0], w[1]); err == nil {
+			t.Fatalf("Accepted invalid window %v\n", w)
+		}
+	}
+}
+
+func TestRecoverDoublyEncryptedSlot(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		for i := 0; i < NumQueries; i++ {
+			qIndex := rand.Intn(db.DBSize)
+
+			query := db.NewDoublyEncryptedQuery(pk, groupSize, qIndex)
+			response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := RecoverDoublyEncrypted(response, sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			offset := rand.Intn(groupSize)
+			slot := RecoverDoublyEncryptedSlot(response, sk, offset)
+			if !res[offset].Equal(slot) {
+				t.Fatalf("Recovered slot is incorrect. %v != %v\n", res[offset], slot)
+			}
+		}
+	}
+
+	query := db.NewDoublyEncryptedQuery(pk, 2, 0)
+	response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if RecoverDoublyEncryptedSlot(response, sk, 2) != nil || RecoverDoublyEncryptedSlot(response, sk, -1) != nil {
+		t.Fatal("Recovered a slot outside of the group")
+	}
+}
+
+func TestRecoverDoublyEncryptedSlotLength(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	// slots that span several ciphertexts
+	db := GenerateRandomDB(TestDBSize, 40)
+
+	query := db.NewDoublyEncryptedQuery(pk, 1, rand.Intn(db.DBSize))
+	response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a ciphertext that decrypts to more bytes than it encodes
+	cts := response.Slots[0].Cts
+	last := cts[len(cts)-1]
+	inner := pk.Encrypt(new(gmp.Int).Lsh(gmp.NewInt(1), 8*13))
+	cts[len(cts)-1] = pk.EncryptWithRAtLevel(inner.C, randomNonZeroMod(pk.N), paillier.EncLevelTwo)
+
+	_, err = RecoverDoublyEncrypted(response, sk)
+	if err == nil || !strings.Contains(err.Error(), "decrypts to more than") {
+		t.Fatalf("Expected an error for an oversized ciphertext, got %v\n", err)
+	}
+
+	// a short slot (missing its last ciphertext)
+	cts[len(cts)-1] = last
+	response.Slots[0].Cts = cts[:len(cts)-1]
+
+	_, err = RecoverDoublyEncrypted(response, sk)
+	if err == nil || !strings.Contains(err.Error(), "ciphertexts, expected") {
+		t.Fatalf("Expected an error for a short slot, got %v\n", err)
+	}
+
+	if RecoverDoublyEncryptedSlot(response, sk, 0) != nil {
+		t.Fatal("Recovered a short slot")
+	}
+}
+
+func TestRecoverEncryptedChecksBytesPerCiphertext(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	for _, slotBytes := range []int{1, SlotBytes, 40} {
+		db := GenerateRandomDB(TestDBSize, slotBytes)
+
+		query := db.NewEncryptedQuery(pk, 1, 0)
+		response, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := RecoverEncrypted(response, sk); err != nil {
+			t.Fatal(err)
+		}
+
+		response.NumBytesPerCiphertext++
+		if _, err := RecoverEncrypted(response, sk); err == nil {
+			t.Fatalf("Did not detect an inconsistent number of bytes per ciphertext")
+		}
+	}
+}
+
+func TestRecoverEncryptedVariableCiphertexts(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	slotBytes := 40
+	numCiphertextsPerSlot, numBytesPerCiphertext := ciphertextPacking(pk, slotBytes)
+	db := GenerateRandomDB(TestDBSize, slotBytes)
+
+	query := db.NewEncryptedQuery(pk, 1, 0)
+	response, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slot j only carries the ciphertexts of the first numCts[j] chunks of the record
+	numCts := make([]int, len(response.Slots))
+	for j, eslot := range response.Slots {
+		numCts[j] = j%numCiphertextsPerSlot + 1
+		eslot.Cts = eslot.Cts[:numCts[j]]
+	}
+
+	slots, err := RecoverEncrypted(response, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j, slot := range slots {
+		length := numCts[j] * numBytesPerCiphertext
+		if length > slotBytes {
+			length = slotBytes
+		}
+
+		expected := NewEmptySlot(slotBytes)
+		copy(expected.Data, db.Slots[j].Data[:length])
+
+		if !expected.Equal(slot) {
+			t.Fatalf("Slot %v with %v ciphertexts recovered incorrectly. %v != %v\n", j, numCts[j], expected, slot)
+		}
+	}
+
+	// more ciphertexts than the slot can hold are rejected (rather than overflowing the slot)
+	response.Slots[0].Cts = append(response.Slots[0].Cts, make([]*paillier.Ciphertext, numCiphertextsPerSlot)...)
+	for i := range response.Slots[0].Cts {
+		response.Slots[0].Cts[i] = pk.EncryptZero()
+	}
+
+	if _, err := RecoverEncrypted(response, sk); err == nil {
+		t.Fatalf("Did not detect a slot with too many ciphertexts\n")
+	}
+}
+
+func TestDoublyEncryptedResultGroupSize(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		query := db.NewDoublyEncryptedQuery(pk, groupSize, rand.Intn(db.DBSize))
+		response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.GroupSize != groupSize {
+			t.Fatalf("Result has group size %v, expected %v\n", response.GroupSize, groupSize)
+		}
+
+		// recovery only relies on the result metadata
+		res, err := RecoverDoublyEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != response.GroupSize {
+			t.Fatalf("Recovered %v slots, expected %v\n", len(res), response.GroupSize)
+		}
+
+		// inconsistent group size
+		response.GroupSize++
+		if _, err := RecoverDoublyEncrypted(response, sk); err == nil {
+			t.Fatalf("Did not detect a result with an inconsistent group size")
+		}
+	}
+}
+
+func TestEncryptedQueryWithChunkSize(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for _, chunkSize := range []int{0, 1, 3, 7, TestDBSize} {
+		for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+			_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+			qIndex := rand.Intn(dimHeight)
+
+			query := db.NewEncryptedQuery(pk, groupSize, qIndex)
+			response, err := db.PrivateEncryptedQueryWithChunkSize(query, NumProcsForQuery, chunkSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := RecoverEncrypted(response, sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for j := 0; j < query.DBWidth; j++ {
+				index := qIndex*query.DBWidth + j
+				if index >= db.DBSize {
+					break
+				}
+
+				if !db.Slots[index].Equal(res[j]) {
+					t.Fatalf("Query result with chunk size %v is incorrect. %v != %v\n", chunkSize, db.Slots[index], res[j])
+				}
+			}
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	setup()
+
+	width := 4
+	height := 3
+
+	// the last row is ragged and gets padded
+	db := GenerateRandomDB(width*height-2, SlotBytes)
+
+	transposed, err := db.Transpose(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transposed.DBSize != width*height {
+		t.Fatalf("Expected %v slots, got %v\n", width*height, transposed.DBSize)
+	}
+
+	// row col of the transposed database is column col of the original
+	for col := 0; col < width; col++ {
+		shares := transposed.NewIndexQueryShares(col, height, 2)
+
+		resA, err := transposed.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := transposed.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Recover([]*SecretSharedQueryResult{resA, resB})
+		for row := 0; row < height; row++ {
+			index := row*width + col
+			if index >= db.DBSize {
+				if !res[row].IsEmpty() {
+					t.Fatalf("Padded slot (%v, %v) is not empty\n", row, col)
+				}
+				continue
+			}
+
+			if !db.Slots[index].Equal(res[row]) {
+				t.Fatalf("Transposed slot (%v, %v) is incorrect. %v != %v\n", col, row, db.Slots[index], res[row])
+			}
+		}
+	}
+
+	if _, err := db.Transpose(2, 2); err == nil {
+		t.Fatal("Transposed a database that does not fit the dimensions")
+	}
+}
+
+func TestRecoverEncryptedOrder(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for _, nprocs := range []int{1, 2, 3, 8} {
+		for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+			_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+			qIndex := rand.Intn(dimHeight)
+
+			query := db.NewEncryptedQuery(pk, groupSize, qIndex)
+			response, err := db.PrivateEncryptedQueryWithChunkSize(query, nprocs, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := RecoverEncrypted(response, sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != query.DBWidth {
+				t.Fatalf("Expected %v slots, got %v\n", query.DBWidth, len(res))
+			}
+
+			// recovered slot j is column j of the queried row
+			for j := range res {
+				index := qIndex*query.DBWidth + j
+				if index < db.DBSize && !db.Slots[index].Equal(res[j]) {
+					t.Fatalf("Slot %v recovered with %v workers is out of order\n", j, nprocs)
+				}
+			}
+		}
+	}
+}
+
+func TestRecoverInto(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	var dst []*Slot
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+		shares := db.NewIndexQueryShares(index/groupSize, groupSize, 2)
+
+		resShares := make([]*SecretSharedQueryResult, len(shares))
+		for i, share := range shares {
+			res, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resShares[i] = res
+		}
+
+		// dst is reused across group sizes
+		expected := Recover(resShares)
+		dst = RecoverInto(dst, resShares)
+		checkSameSlots(t, expected, dst)
+	}
+
+	var dstEnc, dstDoubly []*Slot
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+		response, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, groupSize, rand.Intn(dimHeight)), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := RecoverEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dstEnc, err = RecoverEncryptedInto(dstEnc, response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSameSlots(t, expected, dstEnc)
+
+		doubly, err := db.PrivateDoublyEncryptedQuery(db.NewDoublyEncryptedQuery(pk, groupSize, rand.Intn(TestDBSize)), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err = RecoverDoublyEncrypted(doubly, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dstDoubly, err = RecoverDoublyEncryptedInto(dstDoubly, doubly, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSameSlots(t, expected, dstDoubly)
+	}
+}
+
+func checkSameSlots(t *testing.T, expected, res []*Slot) {
+	t.Helper()
+
+	if len(res) != len(expected) {
+		t.Fatalf("Expected %v slots, got %v\n", len(expected), len(res))
+	}
+
+	for j := range expected {
+		if !expected[j].Equal(res[j]) {
+			t.Fatalf("Slot %v differs. %v != %v\n", j, expected[j], res[j])
+		}
+	}
+}
+
+func BenchmarkRecover(b *testing.B) {
+	benchmarkRecover(b, false)
+}
+
+func BenchmarkRecoverInto(b *testing.B) {
+	benchmarkRecover(b, true)
+}
+
+func benchmarkRecover(b *testing.B, into bool) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	shares := db.NewIndexQueryShares(0, MaxGroupSize, 2)
+
+	resShares := make([]*SecretSharedQueryResult, len(shares))
+	for i, share := range shares {
+		res, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+		if err != nil {
+			panic(err)
+		}
+		resShares[i] = res
+	}
+
+	var dst []*Slot
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if into {
+			dst = RecoverInto(dst, resShares)
+		} else {
+			Recover(resShares)
+		}
+	}
+}
+
+func BenchmarkEncryptedQueryAHESkewedCoarseChunks(b *testing.B) {
+	benchmarkEncryptedQueryAHESkewed(b, 0)
+}
+
+func BenchmarkEncryptedQueryAHESkewedFineChunks(b *testing.B) {
+	benchmarkEncryptedQueryAHESkewed(b, 1)
+}
+
+// skewed workload where only the first quarter of the database is non-empty
+// (the homomorphic scalar multiplication by zero is cheap)
+func benchmarkEncryptedQueryAHESkewed(b *testing.B, chunkSize int) {
+	setup()
+
+	_, pk := paillier.KeyGen(1024)
+	db := GenerateEmptyDB(BenchmarkDBSize, SlotBytes)
+	for i := 0; i < BenchmarkDBSize/4; i++ {
+		db.Slots[i] = NewRandomSlot(SlotBytes)
+	}
+
+	query := db.NewEncryptedQuery(pk, 1, 0)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := db.PrivateEncryptedQueryWithChunkSize(query, 8, chunkSize)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestEncryptedQuerySelfCheck(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	query := db.NewEncryptedQuery(pk, 1, rand.Intn(TestDBHeight))
+	if err := query.SelfCheck(sk); err != nil {
+		t.Fatalf("Well-formed query failed the self check: %v\n", err)
+	}
+
+	// index outside the query generates the null query
+	nullQuery := db.NewEncryptedQuery(pk, 1, -1)
+	if err := nullQuery.SelfCheck(sk); err != nil {
+		t.Fatalf("Null query failed the self check: %v\n", err)
+	}
+
+	// two bits set
+	malformed := db.NewEncryptedQuery(pk, 1, 0)
+	malformed.EBits[1] = pk.EncryptOne()
+	if err := malformed.SelfCheck(sk); err == nil {
+		t.Fatalf("Query with two bits set passed the self check\n")
+	}
+
+	// non-binary value
+	malformed = db.NewEncryptedQuery(pk, 1, 0)
+	malformed.EBits[1] = pk.Encrypt(gmp.NewInt(2))
+	if err := malformed.SelfCheck(sk); err == nil {
+		t.Fatalf("Query with a non-binary value passed the self check\n")
+	}
+
+	// missing bits
+	malformed = db.NewEncryptedQuery(pk, 1, 0)
+	malformed.EBits = malformed.EBits[1:]
+	if err := malformed.SelfCheck(sk); err == nil {
+		t.Fatalf("Query with missing bits passed the self check\n")
+	}
+}
+
+func TestSharedQueryMultiDifferentSlotSizes(t *testing.T) {
+	setup()
+
+	thumbnails := GenerateRandomDB(TestDBSize, SlotBytes)
+	images := GenerateRandomDB(TestDBSize, SlotBytes*SlotBytesStep)
+	dbs := []*Database{thumbnails, images}
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize / groupSize)
+		shares := thumbnails.NewIndexQueryShares(index, groupSize, 2)
+
+		resA, err := PrivateSecretSharedQueryMulti(dbs, shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := PrivateSecretSharedQueryMulti(dbs, shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, db := range dbs {
+			res := Recover([]*SecretSharedQueryResult{resA[i], resB[i]})
+			for j := 0; j < groupSize; j++ {
+				if len(res[j].Data) != db.SlotBytes {
+					t.Fatalf("Result has slot size %v, expected %v\n", len(res[j].Data), db.SlotBytes)
+				}
+
+				if !db.Slots[index*groupSize+j].Equal(res[j]) {
+					t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index*groupSize+j], res[j])
+				}
+			}
+		}
+	}
+
+	mismatched := []*Database{thumbnails, GenerateRandomDB(TestDBSize+1, SlotBytes)}
+	if _, err := PrivateSecretSharedQueryMulti(mismatched, thumbnails.NewIndexQueryShares(0, 1, 2)[0], NumProcsForQuery); err == nil {
+		t.Fatal("Answered a query over databases of different sizes")
+	}
+}
+
+func TestIsNullResult(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		// encrypted
+		nullResponse, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, groupSize, -1), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !nullResponse.IsNullResult(sk) {
+			t.Fatalf("Result of a null query is not null\n")
+		}
+
+		response, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, groupSize, 0), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.IsNullResult(sk) {
+			t.Fatalf("Result of a real query is null\n")
+		}
+
+		// doubly encrypted
+		doublyNullResponse, err := db.PrivateDoublyEncryptedQuery(db.NewDoublyEncryptedNullQuery(pk, groupSize), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !doublyNullResponse.IsNullResult(sk) {
+			t.Fatalf("Result of a doubly encrypted null query is not null\n")
+		}
+
+		doublyResponse, err := db.PrivateDoublyEncryptedQuery(db.NewDoublyEncryptedQuery(pk, groupSize, 0), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if doublyResponse.IsNullResult(sk) {
+			t.Fatalf("Result of a real doubly encrypted query is null\n")
+		}
+	}
+}
+
+func TestPadToPowerOfTwo(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(1000, SlotBytes)
+	padded := db.PadToPowerOfTwo()
+
+	if padded.DBSize != 1024 || padded.RealDBSize != 1000 {
+		t.Fatalf("Padded database has size %v (real size %v), expected 1024 (1000)\n", padded.DBSize, padded.RealDBSize)
+	}
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		for _, index := range []int{0, rand.Intn(1000), 999, 1000, rand.Intn(24) + 1000, 1023} {
+			groupIndex := index / groupSize
+
+			res := sharedQueryGroup(t, padded, groupSize, index)
+			for j := 0; j < groupSize; j++ {
+				slotIndex := groupIndex*groupSize + j
+				if slotIndex >= padded.DBSize {
+					break
+				}
+
+				expected := NewEmptySlot(SlotBytes)
+				if slotIndex < 1000 {
+					expected = db.Slots[slotIndex]
+				}
+
+				if !expected.Equal(res[j]) {
+					t.Fatalf("Query result for slot %v is incorrect. %v != %v\n", slotIndex, expected, res[j])
+				}
+			}
+
+			res = doublyEncryptedQueryGroup(t, padded, sk, pk, groupSize, index)
+			checkGroup(t, padded, groupSize, index, res)
+		}
+	}
+
+	// padding a database whose size is a power of two does nothing
+	if padded.PadToPowerOfTwo().DBSize != 1024 {
+		t.Fatal("Padding changed the size of a power of two database")
+	}
+}
+
+func TestSlotAwareDimentions(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	// large slots span many ciphertexts
+	slotBytes := 200
+	db := GenerateRandomDB(TestDBSize, slotBytes)
+
+	sqrtWidth, _ := db.encryptedQueryDimentions(pk, 1)
+	width, height := db.GetSlotAwareDimentions(pk, 1)
+	if width >= sqrtWidth || width*height < TestDBSize {
+		t.Fatalf("Slot-aware layout %v x %v does not have fewer columns than the sqrt layout (%v)\n", width, height, sqrtWidth)
+	}
+
+	// the default layout is unchanged
+	query := db.NewEncryptedQuery(pk, 1, 0)
+	if query.DBWidth != sqrtWidth {
+		t.Fatalf("Default query width %v, expected %v\n", query.DBWidth, sqrtWidth)
+	}
+
+	db.SlotAwareDimentions = true
+	row := rand.Intn(height)
+	query = db.NewEncryptedQuery(pk, 1, row)
+	if query.DBWidth != width || query.DBHeight != height {
+		t.Fatalf("Query dimensions %v x %v, expected %v x %v\n", query.DBWidth, query.DBHeight, width, height)
+	}
+
+	res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := RecoverEncrypted(res, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for col, slot := range slots {
+		slotIndex := row*width + col
+		if slotIndex < TestDBSize && !slot.Equal(db.Slots[slotIndex]) {
+			t.Fatalf("Recovered slot %v is incorrect\n", slotIndex)
+		}
+	}
+
+	// slots that fit into a single ciphertext keep the sqrt layout
+	small := GenerateRandomDB(TestDBSize, SlotBytes)
+	if width, _ := small.GetSlotAwareDimentions(pk, 1); width != sqrtWidth {
+		t.Fatalf("Slot-aware width %v for small slots, expected %v\n", width, sqrtWidth)
+	}
+}
+
+func TestSingleSlotDatabase(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(1, SlotBytes)
+
+	// secret shared (with the DPF evaluated per row and over the full domain)
+	for _, stream := range []bool{false, true} {
+		db.StreamQueryExpansion = stream
+
+		res := sharedQueryGroup(t, db, 1, 0)
+		if len(res) != 1 || !res[0].Equal(db.Slots[0]) {
+			t.Fatalf("Secret shared query result is incorrect. %v != %v\n", res, db.Slots[0])
+		}
+	}
+
+	width, height := db.GetSlotAwareDimentions(pk, 1)
+	if width != 1 || height != 1 {
+		t.Fatalf("Slot-aware dimensions %v x %v, expected 1 x 1\n", width, height)
+	}
+
+	// encrypted
+	query := db.NewEncryptedQuery(pk, 1, 0)
+	encRes, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := RecoverEncrypted(encRes, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(slots) != 1 || !slots[0].Equal(db.Slots[0]) {
+		t.Fatalf("Encrypted query result is incorrect. %v != %v\n", slots, db.Slots[0])
+	}
+
+	// doubly encrypted
+	dquery := db.NewDoublyEncryptedQuery(pk, 1, 0)
+	dres, err := db.PrivateDoublyEncryptedQuery(dquery, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err = RecoverDoublyEncrypted(dres, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(slots) != 1 || !slots[0].Equal(db.Slots[0]) {
+		t.Fatalf("Doubly encrypted query result is incorrect. %v != %v\n", slots, db.Slots[0])
+	}
+}
+
+func TestPrfKeyFingerprint(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	shares := db.NewIndexQueryShares(rand.Intn(TestDBHeight), 1, 2)
+	fpA, fpB := shares[0].PrfKeyFingerprint(), shares[1].PrfKeyFingerprint()
+	if err := CheckPrfKeyFingerprints(fpA, fpB); err != nil {
+		t.Fatalf("Fingerprints of the shares of a query differ\n")
+	}
+
+	// a buggy client sends the second share of a different query
+	other := db.NewIndexQueryShares(rand.Intn(TestDBHeight), 1, 2)
+	mismatched := *shares[1]
+	mismatched.PrfKeys = other[1].PrfKeys
+
+	if err := CheckPrfKeyFingerprints(fpA, mismatched.PrfKeyFingerprint()); err != ErrPrfKeyMismatch {
+		t.Fatalf("Expected %v, got %v\n", ErrPrfKeyMismatch, err)
+	}
+}
+
+func TestGroupSizeFullWidth(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	for groupSize := MinGroupSize; groupSize <= MaxGroupSize; groupSize++ {
+
+		// the group spans the entire width of the database
+		// so the column query selects among a single group
+		width := groupSize
+		height := int(math.Ceil(float64(TestDBSize) / float64(width)))
+		db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+		for i := 0; i < 5; i++ {
+			row := rand.Intn(height)
+			index := row * width
+
+			// secret shared
+			res := sharedQueryGroup(t, db, groupSize, index)
+			checkGroup(t, db, groupSize, index, res)
+
+			// encrypted
+			query := db.NewEncryptedQueryWithDimentions(pk, width, height, groupSize, row)
+			res = encryptedQueryRowWithQuery(t, db, sk, query, row)
+			checkGroup(t, db, groupSize, index, res)
+
+			// doubly encrypted
+			dquery := db.NewDoublyEncryptedQueryWithDimentions(pk, width, height, groupSize, index)
+			if len(dquery.Col.EBits) != 1 {
+				t.Fatalf("Column query has %v encrypted bits, expected 1\n", len(dquery.Col.EBits))
+			}
+
+			dres, err := db.PrivateDoublyEncryptedQuery(dquery, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err = RecoverDoublyEncrypted(dres, sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGroup(t, db, groupSize, index, res)
+		}
+	}
+}
+
+func TestInvalidGroupSize(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	_, pk := paillier.KeyGen(128)
+
+	groupSize := -1
+
+	constructors := map[string]func(){
+		"NewIndexQueryShares":         func() { db.NewIndexQueryShares(0, groupSize, 2) },
+		"NewEncryptedQuery":           func() { db.NewEncryptedQuery(pk, groupSize, 0) },
+		"NewEncryptedQueryWithProof":  func() { db.NewEncryptedQueryWithProof(pk, groupSize, 0) },
+		"NewDelegatedQuery":           func() { db.NewDelegatedQuery(pk, groupSize, 0) },
+		"NewDoublyEncryptedQuery":     func() { db.NewDoublyEncryptedQuery(pk, groupSize, 0) },
+		"NewDoublyEncryptedNullQuery": func() { db.NewDoublyEncryptedNullQuery(pk, groupSize) },
+		"NewEncryptedQueryWithDimentions": func() {
+			db.NewEncryptedQueryWithDimentions(pk, TestDBHeight, TestDBSize/TestDBHeight, groupSize, 0)
+		},
+		"NewDoublyEncryptedQueryWithDimentions": func() {
+			db.NewDoublyEncryptedQueryWithDimentions(pk, TestDBHeight, TestDBSize/TestDBHeight, groupSize, 0)
+		},
+		"NewDoublyEncryptedQueryWithColumnMask": func() {
+			db.NewDoublyEncryptedQueryWithColumnMask(pk, groupSize, 0, nil)
+		},
+		"NewEncryptedQueryWithRandomness":       func() { db.NewEncryptedQueryWithRandomness(pk, groupSize, 0) },
+		"NewDoublyEncryptedQueryWithRandomness": func() { db.NewDoublyEncryptedQueryWithRandomness(pk, groupSize, 0) },
+		"NewEncryptedQueryTemplate":             func() { db.NewEncryptedQueryTemplate(pk, groupSize) },
+		"NewColumnOrQueryShares":                func() { db.NewColumnOrQueryShares([]int{0}, groupSize, 1) },
+		"GetDimentionsForDatabase":              func() { db.GetDimentionsForDatabase(TestDBHeight, groupSize) },
+		"GetSlotAwareDimentions":                func() { db.GetSlotAwareDimentions(pk, groupSize) },
+		"BuildKeyDBFromDataWithGroupSize":       func() { BuildKeyDBFromDataWithGroupSize(db, 16, groupSize) },
+		"NewCoverTrafficScheduler":              func() { NewCoverTrafficScheduler(&db.DBMetadata, pk, groupSize, time.Second, nil) },
+		"AllowShape":                            func() { NewServer(db).AllowShape(TestDBHeight, TestDBHeight, groupSize) },
+	}
+
+	for name, constructor := range constructors {
+		if v := panicValue(constructor); v != ErrInvalidGroupSize {
+			t.Fatalf("%v did not panic with ErrInvalidGroupSize (got %v)\n", name, v)
+		}
+	}
+
+	if _, err := db.NewKeywordQueryShares(0, groupSize, 2); err != ErrInvalidGroupSize {
+		t.Fatalf("NewKeywordQueryShares returned %v, expected ErrInvalidGroupSize\n", err)
+	}
+
+	if _, err := SplitRecord([]byte{1}, SlotBytes, groupSize); err != ErrInvalidGroupSize {
+		t.Fatalf("SplitRecord returned %v, expected ErrInvalidGroupSize\n", err)
+	}
+
+	if _, err := NewServer(db).OpenSession(pk, TestDBHeight, TestDBHeight, groupSize); err != ErrInvalidGroupSize {
+		t.Fatalf("OpenSession returned %v, expected ErrInvalidGroupSize\n", err)
+	}
+}
+
+// panicValue returns the value f panics with (or nil if f returns)
+func panicValue(f func()) (v interface{}) {
+	defer func() { v = recover() }()
+	f()
+	return nil
+}
+
+func TestRecoverEncryptedStream(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	for _, slotBytes := range []int{SlotBytes, 40} {
+		db := GenerateRandomDB(TestDBSize, slotBytes)
+
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, 1)
+		row := rand.Intn(dimHeight)
+		query := db.NewEncryptedQuery(pk, 1, row)
+
+		res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := RecoverEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, nprocs := range []int{1, NumProcsForQuery} {
+
+			// slots may be streamed out of order when decrypting in parallel
+			// (the callback only collects them; the assertions run on the test goroutine)
+			var mu sync.Mutex
+			streamed := make(map[int]*Slot)
+			duplicates := 0
+			err := RecoverEncryptedStreamParallel(res, sk, nprocs, func(slotIndex int, slot *Slot) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if _, ok := streamed[slotIndex]; ok {
+					duplicates++
+				}
+				streamed[slotIndex] = slot
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if duplicates != 0 {
+				t.Fatalf("%v slots streamed more than once\n", duplicates)
+			}
+
+			if len(streamed) != len(expected) {
+				t.Fatalf("Streamed %v slots, expected %v\n", len(streamed), len(expected))
+			}
+
+			for i, slot := range expected {
+				if !slot.Equal(streamed[i]) {
+					t.Fatalf("Streamed slot %v is incorrect. %v != %v\n", i, streamed[i], slot)
+				}
+			}
+		}
+	}
+
+	// decryption errors are returned
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	res, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, 1, 0), NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSk, _ := paillier.KeyGen(128)
+	if err := RecoverEncryptedStream(res, otherSk, func(int, *Slot) {}); err == nil {
+		t.Fatalf("Recovered the result with the wrong secret key\n")
+	}
+}
@@ -0,0 +1,17 @@
+package pir
+
+import "github.com/sachaservan/paillier"
+
+// NewDelegatedQuery generates an encrypted PIR query (as NewEncryptedQuery) whose result
+// can only be recovered by the recipient holding the secret key for recipientPk.
+//
+// Delegated recovery: the querying client builds the query under the recipient's public key
+// and sends it to the server. The server processes the query as usual (the result is
+// encrypted under the key of the query) and the result is forwarded to the recipient,
+// who recovers it with RecoverEncrypted using its secret key.
+// The querying client knows the index but cannot decrypt the result; RecoverEncrypted
+// returns an error if called with a secret key other than the recipient's.
+// Note that the recipient does not learn the index from the query itself
+func (dbmd *DBMetadata) NewDelegatedQuery(recipientPk *paillier.PublicKey, groupSize, index int) *EncryptedQuery {
+	return dbmd.NewEncryptedQuery(recipientPk, groupSize, index)
+}
@@ -0,0 +1,48 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestDelegatedQuery(t *testing.T) {
+	setup()
+
+	skA, _ := paillier.KeyGen(128)
+	skB, pkB := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+		rowIndex := rand.Intn(dimHeight)
+
+		// client A queries on behalf of recipient B
+		query := db.NewDelegatedQuery(pkB, groupSize, rowIndex)
+
+		response, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// B recovers the result
+		res, err := RecoverEncrypted(response, skB)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j := range res {
+			index := rowIndex*query.DBWidth + j
+			if index < db.DBSize && !db.Slots[index].Equal(res[j]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[j])
+			}
+		}
+
+		// A cannot recover the result with its own key
+		if _, err := RecoverEncrypted(response, skA); err == nil {
+			t.Fatal("Querying client recovered a result encrypted for the recipient")
+		}
+	}
+}
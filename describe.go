@@ -0,0 +1,69 @@
+package pir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describe returns a human-readable summary of the parameters of the encrypted query
+// (dimensions, group size, and number of encrypted bits) for debugging.
+// Whether the query is real or null is hidden by the encryption
+// (see SelfCheck for a check with the secret key)
+func (query *EncryptedQuery) Describe() string {
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "encrypted query: %v x %v (width x height) slots, group size %v, %v encrypted bits",
+		query.DBWidth, query.DBHeight, query.GroupSize, len(query.EBits))
+
+	if len(query.EBits) != query.DBHeight {
+		fmt.Fprintf(&b, " (expected %v)", query.DBHeight)
+	}
+
+	if len(query.EBits) > 0 && query.EBits[0] != nil {
+		fmt.Fprintf(&b, " at level %v", query.EBits[0].Level)
+	}
+
+	if query.Pk != nil {
+		fmt.Fprintf(&b, ", %v-bit modulus", query.Pk.N.BitLen())
+	}
+
+	if query.Proof != nil {
+		b.WriteString(", with proof")
+	}
+
+	return b.String()
+}
+
+// Describe returns a human-readable summary of the parameters of the query share
+// (kind of query, share number, group size, and DPF domain) for debugging.
+// Whether the query is real or null is hidden by the secret sharing
+func (query *QueryShare) Describe() string {
+
+	kind := "index"
+	if query.PrefixBits > 0 {
+		kind = fmt.Sprintf("keyword prefix (%v bits)", query.PrefixBits)
+	} else if query.IsKeywordBased {
+		kind = "keyword"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v query share %v, group size %v", kind, query.ShareNumber, query.GroupSize)
+
+	if query.IsTwoParty {
+		if query.KeyTwoParty != nil {
+			fmt.Fprintf(&b, ", two-party DPF over %v-bit domain", len(query.KeyTwoParty.CW))
+		} else {
+			b.WriteString(", two-party DPF (missing key)")
+		}
+	} else if query.KeyMultiParty != nil {
+		fmt.Fprintf(&b, ", %v-party DPF", query.KeyMultiParty.NumParties)
+	} else {
+		b.WriteString(", multi-party DPF (missing key)")
+	}
+
+	if query.Region != nil {
+		fmt.Fprintf(&b, ", region [%v, %v)", query.Region.Start, query.Region.End)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package pir
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestDescribe(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	query := db.NewEncryptedQuery(pk, 2, 0)
+	desc := query.Describe()
+	for _, want := range []string{
+		fmt.Sprintf("%v x %v", query.DBWidth, query.DBHeight),
+		"group size 2",
+		fmt.Sprintf("%v encrypted bits", len(query.EBits)),
+		fmt.Sprintf("%v-bit modulus", pk.N.BitLen()),
+	} {
+		if !strings.Contains(desc, want) {
+			t.Fatalf("Description %q does not contain %q\n", desc, want)
+		}
+	}
+
+	query.EBits = query.EBits[1:]
+	if desc := query.Describe(); !strings.Contains(desc, fmt.Sprintf("(expected %v)", query.DBHeight)) {
+		t.Fatalf("Description %q does not flag the missing encrypted bits\n", desc)
+	}
+
+	// index queries over 1024/4 = 256 rows use a 9-bit domain
+	share := db.NewIndexQueryShares(0, 4, 2)[1]
+	desc = share.Describe()
+	for _, want := range []string{"index query share 1", "group size 4", "two-party DPF over 9-bit domain"} {
+		if !strings.Contains(desc, want) {
+			t.Fatalf("Description %q does not contain %q\n", desc, want)
+		}
+	}
+
+	prefixShare, err := NewKeywordPrefixQuery(3, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if desc := prefixShare[0].Describe(); !strings.Contains(desc, "keyword prefix (4 bits)") || !strings.Contains(desc, "4-bit domain") {
+		t.Fatalf("Description %q does not reflect the prefix query\n", desc)
+	}
+}
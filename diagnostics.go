@@ -0,0 +1,102 @@
+package pir
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Logger receives diagnostic events from the database (see Database.Logger).
+//
+// Privacy contract: an event only carries what the server learns from the query anyway
+// (the variant, the dimensions of the query, and the size of the database), the time
+// taken to answer the query, and the category of the error (if any). Events never
+// contain the queried index or keyword, the DPF keys or encrypted bits of the query,
+// the recovered data, or error messages; QueryEvent has no field that could hold them,
+// so no Logger implementation can leak them.
+// LogQuery may be called concurrently
+type Logger interface {
+	LogQuery(event *QueryEvent)
+}
+
+// NopLogger discards all events (the default when Database.Logger is nil)
+type NopLogger struct{}
+
+// LogQuery discards the event
+func (NopLogger) LogQuery(event *QueryEvent) {}
+
+// ErrorCategory is the coarse category of the error returned for a query
+// (error messages are not logged)
+type ErrorCategory string
+
+const (
+	// ErrCategoryNone is the category of queries answered without an error
+	ErrCategoryNone ErrorCategory = ""
+	// ErrCategoryClosed is the category of queries to a closed database
+	ErrCategoryClosed ErrorCategory = "closed"
+	// ErrCategoryCancelled is the category of queries whose context was cancelled
+	ErrCategoryCancelled ErrorCategory = "cancelled"
+	// ErrCategoryInvalidQuery is the category of all other errors (e.g., malformed queries)
+	ErrCategoryInvalidQuery ErrorCategory = "invalid query"
+)
+
+// QueryEvent is the diagnostic event logged for every query answered by the database.
+// For secret-shared queries, DBWidth is the group size and DBHeight the number of groups;
+// for doubly encrypted queries, the dimensions are those of the row query
+type QueryEvent struct {
+	Variant   QueryVariant
+	DBSize    int
+	SlotBytes int
+	DBWidth   int
+	DBHeight  int
+	GroupSize int
+	NumProcs  int
+	Duration  time.Duration
+	Error     ErrorCategory
+}
+
+// logQuery sends the event for a query that started at start
+// and returned err to the database's logger (if any)
+func (db *Database) logQuery(event *QueryEvent, start time.Time, err error) {
+
+	if db.Logger == nil {
+		return
+	}
+
+	event.DBSize = db.DBSize
+	event.SlotBytes = db.SlotBytes
+	event.Duration = time.Since(start)
+	event.Error = errorCategory(err)
+
+	db.Logger.LogQuery(event)
+}
+
+// logSecretSharedQuery logs the event of a secret-shared query (see logQuery)
+func (db *Database) logSecretSharedQuery(query *QueryShare, nprocs int, start time.Time, err error) {
+
+	dimHeight := 0
+	if query.GroupSize > 0 {
+		dimHeight = int(math.Ceil(float64(db.DBSize) / float64(query.GroupSize)))
+	}
+
+	db.logQuery(&QueryEvent{
+		Variant:   SecretSharedVariant,
+		DBWidth:   query.GroupSize,
+		DBHeight:  dimHeight,
+		GroupSize: query.GroupSize,
+		NumProcs:  nprocs,
+	}, start, err)
+}
+
+func errorCategory(err error) ErrorCategory {
+	switch err {
+	case nil:
+		return ErrCategoryNone
+	case ErrDatabaseClosed:
+		return ErrCategoryClosed
+	case context.Canceled, context.DeadlineExceeded:
+		return ErrCategoryCancelled
+	default:
+		return ErrCategoryInvalidQuery
+	}
+}
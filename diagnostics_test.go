@@ -0,0 +1,155 @@
+package pir
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+// capturingLogger records the events it receives
+type capturingLogger struct {
+	mu     sync.Mutex
+	events []*QueryEvent
+}
+
+func (logger *capturingLogger) LogQuery(event *QueryEvent) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.events = append(logger.events, event)
+}
+
+func TestLoggerEvents(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	groupSize := 2
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	logger := &capturingLogger{}
+	db.Logger = logger
+
+	// the same queries for two different indices
+	for _, index := range []int{0, TestDBSize - 1} {
+		share := db.NewIndexQueryShares(index/groupSize, groupSize, 2)[0]
+		if _, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+
+		width, _ := db.encryptedQueryDimentions(pk, groupSize)
+		query := db.NewEncryptedQuery(pk, groupSize, index/width)
+		if _, err := db.PrivateEncryptedQuery(query, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+
+		dquery := db.NewDoublyEncryptedQuery(pk, groupSize, index)
+		dres, err := db.PrivateDoublyEncryptedQuery(dquery, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := RecoverDoublyEncrypted(dres, sk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// one event per query (the doubly encrypted query does not log its row query)
+	if len(logger.events) != 6 {
+		t.Fatalf("Logged %v events, expected 6\n", len(logger.events))
+	}
+
+	variants := []QueryVariant{SecretSharedVariant, EncryptedVariant, DoublyEncryptedVariant}
+	for i, event := range logger.events[:3] {
+		if event.Variant != variants[i] {
+			t.Fatalf("Event %v has variant %v, expected %v\n", i, event.Variant, variants[i])
+		}
+
+		if event.DBSize != TestDBSize || event.SlotBytes != SlotBytes || event.GroupSize != groupSize {
+			t.Fatalf("Event %v has incorrect database metadata: %+v\n", i, event)
+		}
+
+		if event.DBWidth <= 0 || event.DBHeight <= 0 || event.Duration <= 0 || event.Error != ErrCategoryNone {
+			t.Fatalf("Event %v is missing dimensions or timings: %+v\n", i, event)
+		}
+
+		// the events do not depend on the queried index
+		other := *logger.events[i+3]
+		other.Duration = event.Duration
+		if other != *event {
+			t.Fatalf("Events differ for different indices: %+v != %+v\n", *event, other)
+		}
+	}
+
+	// errors are only logged by category
+	db.Close()
+	share := db.NewIndexQueryShares(0, groupSize, 2)[0]
+	if _, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery); err == nil {
+		t.Fatalf("Closed database answered a query\n")
+	}
+
+	if event := logger.events[len(logger.events)-1]; event.Error != ErrCategoryClosed {
+		t.Fatalf("Logged error category %q, expected %q\n", event.Error, ErrCategoryClosed)
+	}
+}
+
+func TestLoggerOtherQueries(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	keywords := make([]uint, TestDBSize)
+	for i, keyword := range rand.Perm(TestDBSize) {
+		keywords[i] = uint(keyword)
+	}
+	db.SetKeywords(keywords)
+
+	logger := &capturingLogger{}
+	db.Logger = logger
+
+	// each query logs exactly one event
+	checkEvent := func(name string, expected ErrorCategory) {
+		t.Helper()
+
+		if len(logger.events) != 1 {
+			t.Fatalf("%v logged %v events, expected 1\n", name, len(logger.events))
+		}
+
+		if event := logger.events[0]; event.Error != expected {
+			t.Fatalf("%v logged error category %q, expected %q\n", name, event.Error, expected)
+		}
+
+		logger.events = nil
+	}
+
+	share := db.NewIndexQueryShares(0, 1, 2)[0]
+	bits := db.ExpandSharedQuery(share, NumProcsForQuery)
+	if _, err := db.PrivateSecretSharedQueryWithExpandedBits(share, bits, NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+	checkEvent("Query with expanded bits", ErrCategoryNone)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.PrivateSecretSharedQueryWithExpandedBitsContext(ctx, share, bits, NumProcsForQuery); err != context.Canceled {
+		t.Fatalf("Expected %v, got %v\n", context.Canceled, err)
+	}
+	checkEvent("Cancelled query", ErrCategoryCancelled)
+
+	prefixShares, err := NewKeywordPrefixQuery(0, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.PrivateKeywordPrefixQuery(prefixShares[0], 2, NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+	checkEvent("Prefix query", ErrCategoryNone)
+
+	if _, _, err := db.PrivateVerifiableEncryptedQuery(db.NewEncryptedQuery(pk, 1, 0), NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+	checkEvent("Verifiable query", ErrCategoryNone)
+}
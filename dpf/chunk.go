@@ -0,0 +1,324 @@
+package dpf
+
+// This file contains the serialization of DPF keys and the chunking used to
+// resume a partially transmitted key.
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// KeyChunk is a piece of a serialized DPF key.
+// A chunk is identified by the KeyID of the key it belongs to
+// and its Index among the Total chunks of that key
+type KeyChunk struct {
+	KeyID uint64 // derived from the digest of the serialized key
+	Index uint32
+	Total uint32
+	Data  []byte
+}
+
+// KeyAssembler collects the chunks of a serialized DPF key
+// received (possibly out of order) and reports the missing ones
+type KeyAssembler struct {
+	KeyID    uint64
+	Total    uint32
+	chunks   [][]byte
+	received uint32
+}
+
+// Bytes serializes the two-party key
+func (k *Key2P) Bytes() []byte {
+	buf := make([]byte, 0)
+	buf = appendBytes(buf, k.SInit)
+	buf = append(buf, k.TInit)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(k.CW)))
+	for _, cw := range k.CW {
+		buf = appendBytes(buf, cw)
+	}
+	buf = binary.BigEndian.AppendUint64(buf, uint64(int64(k.FinalCW)))
+
+	return buf
+}
+
+// Key2PFromBytes deserializes a two-party key produced by Bytes
+func Key2PFromBytes(b []byte) (*Key2P, error) {
+	r := &keyReader{buf: b}
+	k := &Key2P{}
+	k.SInit = r.readBytes()
+	k.TInit = r.readByte()
+	numCW := r.readUint32()
+	if r.err == nil && uint64(numCW) > uint64(len(r.buf)) {
+		r.err = errors.New("malformed DPF key")
+	}
+	if r.err == nil {
+		k.CW = make([][]byte, numCW)
+		for i := range k.CW {
+			k.CW[i] = r.readBytes()
+		}
+	}
+	k.FinalCW = int(int64(r.readUint64()))
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Bytes serializes the multi-party key
+func (k *KeyMP) Bytes() []byte {
+	buf := make([]byte, 0)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(k.NumParties))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(k.CW)))
+	for _, cw := range k.CW {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(cw)))
+		for _, w := range cw {
+			buf = binary.BigEndian.AppendUint32(buf, w)
+		}
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(k.Sigma)))
+	for _, sigma := range k.Sigma {
+		buf = appendBytes(buf, sigma)
+	}
+
+	return buf
+}
+
+// KeyMPFromBytes deserializes a multi-party key produced by Bytes
+func KeyMPFromBytes(b []byte) (*KeyMP, error) {
+	r := &keyReader{buf: b}
+	k := &KeyMP{}
+	k.NumParties = uint(r.readUint32())
+	numCW := r.readUint32()
+	if r.err == nil && uint64(numCW) > uint64(len(r.buf)) {
+		r.err = errors.New("malformed DPF key")
+	}
+	if r.err == nil {
+		k.CW = make([][]uint32, numCW)
+		for i := range k.CW {
+			numWords := r.readUint32()
+			if r.err != nil || uint64(numWords)*4 > uint64(len(r.buf)) {
+				r.err = errors.New("malformed DPF key")
+				break
+			}
+			k.CW[i] = make([]uint32, numWords)
+			for j := range k.CW[i] {
+				k.CW[i][j] = r.readUint32()
+			}
+		}
+	}
+	numSigma := r.readUint32()
+	if r.err == nil && uint64(numSigma) > uint64(len(r.buf)) {
+		r.err = errors.New("malformed DPF key")
+	}
+	if r.err == nil {
+		k.Sigma = make([][]byte, numSigma)
+		for i := range k.Sigma {
+			k.Sigma[i] = r.readBytes()
+		}
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Chunks splits the serialized two-party key into chunks of at most chunkSize bytes
+func (k *Key2P) Chunks(chunkSize int) []*KeyChunk {
+	return SplitKeyBytes(k.Bytes(), chunkSize)
+}
+
+// Chunks splits the serialized multi-party key into chunks of at most chunkSize bytes
+func (k *KeyMP) Chunks(chunkSize int) []*KeyChunk {
+	return SplitKeyBytes(k.Bytes(), chunkSize)
+}
+
+// SplitKeyBytes splits a serialized key into chunks of at most chunkSize bytes.
+// All chunks carry the same KeyID such that the receiver can tell
+// chunks of different keys apart and request missing chunks by index
+func SplitKeyBytes(data []byte, chunkSize int) []*KeyChunk {
+
+	if chunkSize <= 0 {
+		panic("chunk size must be positive")
+	}
+
+	keyID := keyIDOf(data)
+	total := (len(data) + chunkSize - 1) / chunkSize
+	if total == 0 {
+		total = 1
+	}
+
+	chunks := make([]*KeyChunk, total)
+	for i := 0; i < total; i++ {
+		start := i * chunkSize
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunks[i] = &KeyChunk{
+			KeyID: keyID,
+			Index: uint32(i),
+			Total: uint32(total),
+			Data:  append([]byte{}, data[start:end]...),
+		}
+	}
+
+	return chunks
+}
+
+// SelectChunks returns the chunks with the requested indices
+// (used by the client to resend the chunks reported missing by the server)
+func SelectChunks(chunks []*KeyChunk, indices []uint32) []*KeyChunk {
+
+	selected := make([]*KeyChunk, 0, len(indices))
+	for _, index := range indices {
+		if int(index) < len(chunks) {
+			selected = append(selected, chunks[index])
+		}
+	}
+
+	return selected
+}
+
+// NewKeyAssembler returns an assembler that accepts the chunks
+// of the first key it receives a chunk for
+func NewKeyAssembler() *KeyAssembler {
+	return &KeyAssembler{}
+}
+
+// Add records the chunk. Duplicate chunks are ignored
+func (a *KeyAssembler) Add(chunk *KeyChunk) error {
+
+	if chunk.Total == 0 || chunk.Index >= chunk.Total {
+		return errors.New("invalid chunk index")
+	}
+
+	if a.chunks == nil {
+		a.KeyID = chunk.KeyID
+		a.Total = chunk.Total
+		a.chunks = make([][]byte, chunk.Total)
+	}
+
+	if chunk.KeyID != a.KeyID || chunk.Total != a.Total {
+		return errors.New("chunk belongs to a different key")
+	}
+
+	if a.chunks[chunk.Index] == nil {
+		a.chunks[chunk.Index] = append([]byte{}, chunk.Data...)
+		a.received++
+	}
+
+	return nil
+}
+
+// Complete returns true if all chunks of the key were received
+func (a *KeyAssembler) Complete() bool {
+	return a.chunks != nil && a.received == a.Total
+}
+
+// Missing returns the indices of the chunks that have not been received
+func (a *KeyAssembler) Missing() []uint32 {
+
+	missing := make([]uint32, 0)
+	for i, chunk := range a.chunks {
+		if chunk == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+
+	return missing
+}
+
+// Bytes reassembles the serialized key and checks it against the KeyID
+func (a *KeyAssembler) Bytes() ([]byte, error) {
+
+	if !a.Complete() {
+		return nil, errors.New("missing chunks of the DPF key")
+	}
+
+	data := make([]byte, 0)
+	for _, chunk := range a.chunks {
+		data = append(data, chunk...)
+	}
+
+	if keyIDOf(data) != a.KeyID {
+		return nil, errors.New("reassembled DPF key does not match the key ID")
+	}
+
+	return data, nil
+}
+
+func keyIDOf(data []byte) uint64 {
+	digest := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(digest[:8])
+}
+
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
+
+// keyReader reads the fields of a serialized key and
+// records the first error encountered
+type keyReader struct {
+	buf []byte
+	err error
+}
+
+func (r *keyReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errors.New("malformed DPF key")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *keyReader) readByte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *keyReader) readUint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *keyReader) readUint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *keyReader) readBytes() []byte {
+	n := r.readUint32()
+	if r.err != nil || uint64(n) > uint64(len(r.buf)) {
+		r.next(-1)
+		return nil
+	}
+	return append([]byte{}, r.next(int(n))...)
+}
+
+func (r *keyReader) done() error {
+	if r.err == nil && len(r.buf) != 0 {
+		r.err = errors.New("trailing bytes in DPF key")
+	}
+	return r.err
+}
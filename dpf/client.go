@@ -9,13 +9,23 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/binary"
+	"io"
 )
 
 // ClientInitialize client with this function
 // numBits represents the input domain for the function, i.e. the number
 // of bits to check
 func ClientInitialize(numBits uint) *Dpf {
+	return ClientInitializeWithRandomness(numBits, rand.Reader)
+}
+
+// ClientInitializeWithRandomness is the same as ClientInitialize but samples
+// the PRF keys and the key seeds from r (e.g., a seeded stream to generate
+// deterministic test vectors). r must be a cryptographically secure source
+// for the keys to hide the point
+func ClientInitializeWithRandomness(numBits uint, r io.Reader) *Dpf {
 	f := new(Dpf)
+	f.Rand = r
 	f.NumBits = numBits
 	f.PrfKeys = make([]*PrfKey, initPRFLen)
 	// Create fixed AES blocks
@@ -24,7 +34,7 @@ func ClientInitialize(numBits uint) *Dpf {
 		f.PrfKeys[i] = &PrfKey{}
 		f.PrfKeys[i].Bytes = make([]byte, aes.BlockSize)
 
-		rand.Read(f.PrfKeys[i].Bytes)
+		f.read(f.PrfKeys[i].Bytes)
 		//fmt.Println("client")
 		//fmt.Println(f.PrfKeys[i])
 		block, err := aes.NewCipher(f.PrfKeys[i].Bytes)
@@ -57,14 +67,14 @@ func (f *Dpf) GenerateTwoServer(a, b uint) []*Key2P {
 	fssKeys := make([]*Key2P, 2)
 	// Set up initial values
 	tempRand1 := make([]byte, aes.BlockSize+1)
-	rand.Read(tempRand1)
+	f.read(tempRand1)
 	fssKeys[0] = &Key2P{}
 	fssKeys[0].SInit = tempRand1[:aes.BlockSize]
 	fssKeys[0].TInit = tempRand1[aes.BlockSize] % 2
 
 	fssKeys[1] = &Key2P{}
 	fssKeys[1].SInit = make([]byte, aes.BlockSize)
-	rand.Read(fssKeys[1].SInit)
+	f.read(fssKeys[1].SInit)
 	fssKeys[1].TInit = fssKeys[0].TInit ^ 1
 
 	// Set current seed being used
@@ -149,6 +159,18 @@ func (f *Dpf) GenerateTwoServer(a, b uint) []*Key2P {
 	return fssKeys
 }
 
+// read fills b with bytes from the randomness source of the DPF
+func (f *Dpf) read(b []byte) {
+	r := f.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+
+	if _, err := io.ReadFull(r, b); err != nil {
+		panic(err.Error())
+	}
+}
+
 func (f *Dpf) GenerateMultiServer(a, b, num_p uint) []*KeyMP {
 
 	panic("not implemented")
@@ -5,6 +5,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/binary"
+	"io"
 )
 
 const initPRFLen uint = 4
@@ -24,6 +25,7 @@ type Dpf struct {
 	NumBits     uint   // number of bits in domain
 	Temp        []byte // temporary slices so that we only need to allocate memory at the beginning
 	Out         []byte
+	Rand        io.Reader // source of the key randomness (crypto/rand if nil)
 }
 
 // Key2P is a two-party DPF key
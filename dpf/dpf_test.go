@@ -3,6 +3,7 @@ package dpf
 import (
 	"math"
 	"math/rand"
+	"reflect"
 	"testing"
 )
 
@@ -70,6 +71,38 @@ func TestCorrectPointFunctionTwoServer(t *testing.T) {
 	}
 }
 
+func TestEvalFullDomainTwoServer(t *testing.T) {
+
+	for trial := 0; trial < 100; trial++ {
+		num := rand.Intn(1<<10) + 1
+
+		specialIndex := uint(rand.Intn(num))
+
+		fClient := ClientInitialize(uint(math.Log2(float64(num))) + 1)
+		fssKeys := fClient.GenerateTwoServer(specialIndex, 1)
+
+		fServer := ServerInitialize(fClient.PrfKeys, fClient.NumBits)
+
+		for server := uint(0); server < 2; server++ {
+			next := uint(0)
+			fServer.EvalFullDomain2P(server, fssKeys[server], uint(num), func(x uint, res int) {
+				if x != next {
+					t.Fatalf("Expected input %v Got: %v", next, x)
+				}
+				next++
+
+				if expected := fServer.Evaluate2P(server, fssKeys[server], x); res != expected {
+					t.Fatalf("Expected: %v Got: %v", expected, res)
+				}
+			})
+
+			if next != uint(num) {
+				t.Fatalf("Evaluated %v inputs, expected %v", next, num)
+			}
+		}
+	}
+}
+
 func TestCorrectTwoServerKeyword(t *testing.T) {
 
 	for trial := 0; trial < numTrials; trial++ {
@@ -142,3 +175,98 @@ func Benchmark2Party64BitKeywordEval(b *testing.B) {
 		fServer.Evaluate2P(0, fssKeys[0], uint(i))
 	}
 }
+
+func TestResumeChunkedTwoServerKey(t *testing.T) {
+
+	num := 1 << 10
+	specialIndex := uint(rand.Intn(num))
+
+	fClient := ClientInitialize(10)
+	fssKeys := fClient.GenerateTwoServer(specialIndex, 1)
+	chunks := fssKeys[0].Chunks(32)
+	if len(chunks) < 3 {
+		t.Fatalf("Expected at least 3 chunks Got: %v", len(chunks))
+	}
+
+	// drop a middle chunk during the transfer
+	dropped := uint32(len(chunks) / 2)
+	assembler := NewKeyAssembler()
+	for _, chunk := range chunks {
+		if chunk.Index == dropped {
+			continue
+		}
+		if err := assembler.Add(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := assembler.Bytes(); err == nil {
+		t.Fatalf("Expected an error when reassembling an incomplete key")
+	}
+
+	missing := assembler.Missing()
+	if len(missing) != 1 || missing[0] != dropped {
+		t.Fatalf("Expected missing chunk: %v Got: %v", dropped, missing)
+	}
+
+	// resend the missing chunk
+	for _, chunk := range SelectChunks(chunks, missing) {
+		if err := assembler.Add(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := assembler.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := Key2PFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fServer := ServerInitialize(fClient.PrfKeys, fClient.NumBits)
+	for i := 0; i < num; i++ {
+		ans0 := fServer.Evaluate2P(0, key, uint(i))
+		ans1 := fServer.Evaluate2P(1, fssKeys[1], uint(i))
+
+		if uint(i) == specialIndex && ans0+ans1 != 1 {
+			t.Fatalf("Expected: 1 Got: %v", ans0+ans1)
+		}
+
+		if uint(i) != specialIndex && ans0+ans1 != 0 {
+			t.Fatalf("Expected: 0 Got: %v", ans0+ans1)
+		}
+	}
+}
+
+func TestSerializeMultiServerKey(t *testing.T) {
+
+	key := &KeyMP{
+		NumParties: 3,
+		CW:         [][]uint32{{1, 2, 3}, {4, 5, 6}},
+		Sigma:      [][]byte{{7, 8}, {9}},
+	}
+
+	assembler := NewKeyAssembler()
+	for _, chunk := range key.Chunks(5) {
+		if err := assembler.Add(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := assembler.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := KeyMPFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(key, res) {
+		t.Fatalf("Expected: %v Got: %v", key, res)
+	}
+}
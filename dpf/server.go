@@ -142,3 +142,76 @@ func (f *Dpf) EvaluateMP(k *KeyMP, x uint) uint32 {
 	}
 	return y[delta]
 }
+
+// EvalFullDomain2P evaluates the 2-party key on every input in [0, domainSize)
+// in increasing order and calls emit with each result (as returned by Evaluate2P).
+// The evaluation tree is walked depth-first so only one seed per level is kept in memory
+// and each internal node is expanded once (rather than once per input below it)
+func (f *Dpf) EvalFullDomain2P(serverNum uint, k *Key2P, domainSize uint, emit func(x uint, res int)) {
+
+	numBits := f.NumBits
+	seeds := make([][]byte, numBits+1)
+	outs := make([][]byte, numBits+1)
+	ts := make([]byte, numBits+1)
+	for i := range seeds {
+		seeds[i] = make([]byte, aes.BlockSize)
+		outs[i] = make([]byte, aes.BlockSize*initPRFLen)
+	}
+	fTemp := make([]byte, aes.BlockSize)
+
+	copy(seeds[0], k.SInit)
+	ts[0] = k.TInit
+
+	var walk func(level, prefix uint)
+	walk = func(level, prefix uint) {
+
+		if level == numBits {
+			sFinal, _ := binary.Varint(seeds[level][:8])
+			res := int(sFinal) + int(ts[level])*k.FinalCW
+			if serverNum != 0 {
+				res = -res
+			}
+			emit(prefix, res)
+			return
+		}
+
+		// same seed expansion as in Evaluate2P
+		fOut := outs[level]
+		tCurr := ts[level]
+		prf(seeds[level], f.FixedBlocks, 3, fTemp, fOut)
+		count := 0
+		for j := 0; j < aes.BlockSize*2+2; j++ {
+			if j == aes.BlockSize+1 {
+				count = 0
+			} else if j == aes.BlockSize*2+1 {
+				count = aes.BlockSize + 1
+			}
+
+			fOut[j] = fOut[j] ^ (tCurr * k.CW[level][count])
+			count++
+		}
+
+		for xBit := uint(0); xBit < 2; xBit++ {
+			child := prefix<<1 | xBit
+
+			// skip subtrees that are entirely outside of the domain
+			if child<<(numBits-level-1) >= domainSize {
+				return
+			}
+
+			if xBit == 0 {
+				copy(seeds[level+1], fOut[:aes.BlockSize])
+				ts[level+1] = fOut[aes.BlockSize] % 2
+			} else {
+				copy(seeds[level+1], fOut[(aes.BlockSize+1):(aes.BlockSize*2+1)])
+				ts[level+1] = fOut[aes.BlockSize*2+1] % 2
+			}
+
+			walk(level+1, child)
+		}
+	}
+
+	if domainSize > 0 {
+		walk(0, 0)
+	}
+}
@@ -0,0 +1,405 @@
+package pir
+
+import (
+	"encoding/json"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir/dpf"
+)
+
+// This file contains the JSON encodings of queries and results for front-ends
+// that talk to the server over HTTP/JSON. Byte strings (ciphertexts, DPF and PRF
+// keys, and slots) are encoded as standard base64 strings.
+//
+// As with the binary encodings, the Paillier public key (and the query proof) is
+// not part of the encoding: UnmarshalJSON leaves Pk nil and the receiver must set
+// it to the key exchanged ahead of time before processing the query or result
+// (UnmarshalEncryptedQueryJSON and UnmarshalDoublyEncryptedQueryJSON set it when decoding)
+
+type jsonCiphertext struct {
+	Level int    `json:"level"`
+	C     []byte `json:"c"`
+}
+
+type jsonGroupRegion struct {
+	Start     int `json:"start"`
+	End       int `json:"end"`
+	GroupSize int `json:"group_size"`
+}
+
+type jsonQueryShare struct {
+	IsTwoParty     bool             `json:"is_two_party"`
+	IsKeywordBased bool             `json:"is_keyword_based"`
+	ShareNumber    uint             `json:"share_number"`
+	GroupSize      int              `json:"group_size"`
+	PrfKeys        [][]byte         `json:"prf_keys"`
+	DPFKey         []byte           `json:"dpf_key"`
+	Region         *jsonGroupRegion `json:"region,omitempty"`
+	PrefixBits     uint             `json:"prefix_bits,omitempty"`
+}
+
+type jsonSecretSharedQueryResult struct {
+	SlotBytes int      `json:"slot_bytes"`
+	Shares    [][]byte `json:"shares"`
+}
+
+type jsonEncryptedQuery struct {
+	GroupSize int               `json:"group_size"`
+	DBWidth   int               `json:"db_width"`
+	DBHeight  int               `json:"db_height"`
+	EBits     []*jsonCiphertext `json:"ebits"`
+	Region    *jsonGroupRegion  `json:"region,omitempty"`
+}
+
+type jsonDoublyEncryptedQuery struct {
+	Row        *EncryptedQuery `json:"row"`
+	Col        *EncryptedQuery `json:"col"`
+	ColumnMask []bool          `json:"column_mask"`
+}
+
+type jsonEncryptedQueryResult struct {
+	SlotBytes             int                 `json:"slot_bytes"`
+	NumBytesPerCiphertext int                 `json:"num_bytes_per_ciphertext"`
+	Slots                 [][]*jsonCiphertext `json:"slots"`
+}
+
+type jsonDoublyEncryptedQueryResult struct {
+	SlotBytes             int                 `json:"slot_bytes"`
+	NumBytesPerCiphertext int                 `json:"num_bytes_per_ciphertext"`
+	GroupSize             int                 `json:"group_size"`
+	ColumnMask            []bool              `json:"column_mask"`
+	Slots                 [][]*jsonCiphertext `json:"slots"`
+}
+
+// MarshalJSON encodes the query share
+func (query *QueryShare) MarshalJSON() ([]byte, error) {
+
+	enc := &jsonQueryShare{
+		IsTwoParty:     query.IsTwoParty,
+		IsKeywordBased: query.IsKeywordBased,
+		ShareNumber:    query.ShareNumber,
+		GroupSize:      query.GroupSize,
+		PrfKeys:        make([][]byte, len(query.PrfKeys)),
+		PrefixBits:     query.PrefixBits,
+	}
+
+	for i, key := range query.PrfKeys {
+		enc.PrfKeys[i] = key.Bytes
+	}
+
+	var err error
+	if enc.DPFKey, err = query.dpfKeyBytes(); err != nil {
+		return nil, err
+	}
+
+	enc.Region = toJSONRegion(query.Region)
+
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a query share encoded with MarshalJSON
+func (query *QueryShare) UnmarshalJSON(data []byte) error {
+
+	enc := &jsonQueryShare{}
+	if err := json.Unmarshal(data, enc); err != nil {
+		return err
+	}
+
+	res := &QueryShare{
+		IsTwoParty:     enc.IsTwoParty,
+		IsKeywordBased: enc.IsKeywordBased,
+		ShareNumber:    enc.ShareNumber,
+		GroupSize:      enc.GroupSize,
+		PrfKeys:        make([]*dpf.PrfKey, len(enc.PrfKeys)),
+		PrefixBits:     enc.PrefixBits,
+	}
+
+	for i, key := range enc.PrfKeys {
+		res.PrfKeys[i] = &dpf.PrfKey{Bytes: key}
+	}
+
+	if err := res.setDPFKey(enc.DPFKey); err != nil {
+		return err
+	}
+
+	res.Region = fromJSONRegion(enc.Region)
+
+	*query = *res
+	return nil
+}
+
+// MarshalJSON encodes the result share
+func (res *SecretSharedQueryResult) MarshalJSON() ([]byte, error) {
+
+	enc := &jsonSecretSharedQueryResult{
+		SlotBytes: res.SlotBytes,
+		Shares:    make([][]byte, len(res.Shares)),
+	}
+
+	for i, share := range res.Shares {
+		enc.Shares[i] = share.Data
+	}
+
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a result share encoded with MarshalJSON
+func (res *SecretSharedQueryResult) UnmarshalJSON(data []byte) error {
+
+	enc := &jsonSecretSharedQueryResult{}
+	if err := json.Unmarshal(data, enc); err != nil {
+		return err
+	}
+
+	shares := make([]*Slot, len(enc.Shares))
+	for i, share := range enc.Shares {
+		shares[i] = NewSlot(share)
+	}
+
+	res.SlotBytes = enc.SlotBytes
+	res.Shares = shares
+	return nil
+}
+
+// MarshalJSON encodes the encrypted query (without the public key and the query proof)
+func (query *EncryptedQuery) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(&jsonEncryptedQuery{
+		GroupSize: query.GroupSize,
+		DBWidth:   query.DBWidth,
+		DBHeight:  query.DBHeight,
+		EBits:     toJSONCiphertexts(query.EBits),
+		Region:    toJSONRegion(query.Region),
+	})
+}
+
+// UnmarshalJSON decodes an encrypted query encoded with MarshalJSON (Pk is left nil)
+func (query *EncryptedQuery) UnmarshalJSON(data []byte) error {
+
+	enc := &jsonEncryptedQuery{}
+	if err := json.Unmarshal(data, enc); err != nil {
+		return err
+	}
+
+	ebits, err := fromJSONCiphertexts(enc.EBits)
+	if err != nil {
+		return err
+	}
+
+	*query = EncryptedQuery{
+		GroupSize: enc.GroupSize,
+		DBWidth:   enc.DBWidth,
+		DBHeight:  enc.DBHeight,
+		EBits:     ebits,
+		Region:    fromJSONRegion(enc.Region),
+	}
+
+	return nil
+}
+
+// UnmarshalEncryptedQueryJSON decodes an encrypted query encoded with MarshalJSON
+// that is encrypted under pk
+func UnmarshalEncryptedQueryJSON(data []byte, pk *paillier.PublicKey) (*EncryptedQuery, error) {
+
+	query := &EncryptedQuery{}
+	if err := query.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	query.Pk = pk
+
+	return query, nil
+}
+
+// MarshalJSON encodes the doubly encrypted query (without the public key)
+func (query *DoublyEncryptedQuery) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(&jsonDoublyEncryptedQuery{
+		Row:        query.Row,
+		Col:        query.Col,
+		ColumnMask: query.ColumnMask,
+	})
+}
+
+// UnmarshalJSON decodes a doubly encrypted query encoded with MarshalJSON
+// (Pk of the row and column queries is left nil)
+func (query *DoublyEncryptedQuery) UnmarshalJSON(data []byte) error {
+
+	enc := &jsonDoublyEncryptedQuery{}
+	if err := json.Unmarshal(data, enc); err != nil {
+		return err
+	}
+
+	if enc.Row == nil || enc.Col == nil {
+		return errMalformedEncoding
+	}
+
+	*query = DoublyEncryptedQuery{
+		Row:        enc.Row,
+		Col:        enc.Col,
+		ColumnMask: enc.ColumnMask,
+	}
+
+	return nil
+}
+
+// UnmarshalDoublyEncryptedQueryJSON decodes a doubly encrypted query encoded with MarshalJSON
+// whose row and column queries are encrypted under pk
+func UnmarshalDoublyEncryptedQueryJSON(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQuery, error) {
+
+	query := &DoublyEncryptedQuery{}
+	if err := query.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	query.SetPublicKey(pk)
+
+	return query, nil
+}
+
+// SetPublicKey sets the public key of the row and column queries
+// (e.g., after decoding the query with UnmarshalJSON)
+func (query *DoublyEncryptedQuery) SetPublicKey(pk *paillier.PublicKey) {
+	query.Row.Pk = pk
+	query.Col.Pk = pk
+}
+
+// MarshalJSON encodes the encrypted result (without the public key)
+func (res *EncryptedQueryResult) MarshalJSON() ([]byte, error) {
+
+	enc := &jsonEncryptedQueryResult{
+		SlotBytes:             res.SlotBytes,
+		NumBytesPerCiphertext: res.NumBytesPerCiphertext,
+		Slots:                 make([][]*jsonCiphertext, len(res.Slots)),
+	}
+
+	for i, eslot := range res.Slots {
+		enc.Slots[i] = toJSONCiphertexts(eslot.Cts)
+	}
+
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes an encrypted result encoded with MarshalJSON (Pk is left nil)
+func (res *EncryptedQueryResult) UnmarshalJSON(data []byte) error {
+
+	enc := &jsonEncryptedQueryResult{}
+	if err := json.Unmarshal(data, enc); err != nil {
+		return err
+	}
+
+	slots := make([]*EncryptedSlot, len(enc.Slots))
+	for i, cts := range enc.Slots {
+		ciphertexts, err := fromJSONCiphertexts(cts)
+		if err != nil {
+			return err
+		}
+		slots[i] = &EncryptedSlot{Cts: ciphertexts}
+	}
+
+	*res = EncryptedQueryResult{
+		Slots:                 slots,
+		SlotBytes:             enc.SlotBytes,
+		NumBytesPerCiphertext: enc.NumBytesPerCiphertext,
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the doubly encrypted result (without the public key)
+func (res *DoublyEncryptedQueryResult) MarshalJSON() ([]byte, error) {
+
+	enc := &jsonDoublyEncryptedQueryResult{
+		SlotBytes:             res.SlotBytes,
+		NumBytesPerCiphertext: res.NumBytesPerCiphertext,
+		GroupSize:             res.GroupSize,
+		ColumnMask:            res.ColumnMask,
+		Slots:                 make([][]*jsonCiphertext, len(res.Slots)),
+	}
+
+	for i, slot := range res.Slots {
+		enc.Slots[i] = toJSONCiphertexts(slot.Cts)
+	}
+
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a doubly encrypted result encoded with MarshalJSON (Pk is left nil)
+func (res *DoublyEncryptedQueryResult) UnmarshalJSON(data []byte) error {
+
+	enc := &jsonDoublyEncryptedQueryResult{}
+	if err := json.Unmarshal(data, enc); err != nil {
+		return err
+	}
+
+	slots := make([]*DoublyEncryptedSlot, len(enc.Slots))
+	for i, cts := range enc.Slots {
+		ciphertexts, err := fromJSONCiphertexts(cts)
+		if err != nil {
+			return err
+		}
+		slots[i] = &DoublyEncryptedSlot{Cts: ciphertexts}
+	}
+
+	*res = DoublyEncryptedQueryResult{
+		Slots:                 slots,
+		SlotBytes:             enc.SlotBytes,
+		NumBytesPerCiphertext: enc.NumBytesPerCiphertext,
+		GroupSize:             enc.GroupSize,
+		ColumnMask:            enc.ColumnMask,
+	}
+
+	return nil
+}
+
+func toJSONCiphertexts(cts []*paillier.Ciphertext) []*jsonCiphertext {
+
+	enc := make([]*jsonCiphertext, len(cts))
+	for i, ct := range cts {
+		enc[i] = &jsonCiphertext{Level: int(ct.Level), C: ct.C.Bytes()}
+	}
+
+	return enc
+}
+
+func fromJSONCiphertexts(enc []*jsonCiphertext) ([]*paillier.Ciphertext, error) {
+
+	cts := make([]*paillier.Ciphertext, len(enc))
+	for i, ct := range enc {
+		if ct == nil {
+			return nil, errMalformedEncoding
+		}
+
+		cts[i] = &paillier.Ciphertext{
+			C:     new(gmp.Int).SetBytes(ct.C),
+			Level: paillier.EncryptionLevel(ct.Level),
+		}
+	}
+
+	return cts, nil
+}
+
+func toJSONRegion(region *GroupRegion) *jsonGroupRegion {
+
+	if region == nil {
+		return nil
+	}
+
+	return &jsonGroupRegion{
+		Start:     region.Start,
+		End:       region.End,
+		GroupSize: region.GroupSize,
+	}
+}
+
+func fromJSONRegion(enc *jsonGroupRegion) *GroupRegion {
+
+	if enc == nil {
+		return nil
+	}
+
+	return &GroupRegion{
+		Start:     enc.Start,
+		End:       enc.End,
+		GroupSize: enc.GroupSize,
+	}
+}
@@ -0,0 +1,191 @@
+package pir
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestSecretSharedQueryJSON(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// the server answers query shares posted as JSON
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := &QueryShare{}
+		if err := json.NewDecoder(r.Body).Decode(query); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res, err := db.PrivateSecretSharedQuery(query, NumProcsForQuery)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+
+		resShares := make([]*SecretSharedQueryResult, 2)
+		for j, share := range db.NewIndexQueryShares(index, 1, 2) {
+			body, err := json.Marshal(share)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resShares[j] = &SecretSharedQueryResult{}
+			err = json.NewDecoder(resp.Body).Decode(resShares[j])
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res := Recover(resShares)
+		if !db.Slots[index].Equal(res[0]) {
+			t.Fatalf("Incorrect result over JSON: expected %v, got %v\n", db.Slots[index], res[0])
+		}
+	}
+}
+
+func TestEncryptedQueryJSON(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	width, height := db.encryptedQueryDimentions(pk, 1)
+	row := rand.Intn(height)
+
+	data, err := json.Marshal(db.NewEncryptedQuery(pk, 1, row))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ciphertexts are base64 strings under explicit field names
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"group_size", "db_width", "db_height", "ebits"} {
+		if _, ok := fields[name]; !ok {
+			t.Fatalf("Missing field %v in %s\n", name, data)
+		}
+	}
+
+	// a query decoded without its public key is rejected rather than answered
+	query := &EncryptedQuery{}
+	if err := json.Unmarshal(data, query); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.PrivateEncryptedQuery(query, NumProcsForQuery); err == nil {
+		t.Fatalf("Answered a query without a public key\n")
+	}
+
+	query, err = UnmarshalEncryptedQueryJSON(data, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res = &EncryptedQueryResult{}
+	if err := json.Unmarshal(data, res); err != nil {
+		t.Fatal(err)
+	}
+	res.Pk = pk
+
+	slots, err := RecoverEncrypted(res, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j := 0; j < width && row*width+j < db.DBSize; j++ {
+		if !db.Slots[row*width+j].Equal(slots[j]) {
+			t.Fatalf("Incorrect result over JSON: expected %v, got %v\n", db.Slots[row*width+j], slots[j])
+		}
+	}
+}
+
+func TestDoublyEncryptedQueryJSON(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+
+		data, err := json.Marshal(db.NewDoublyEncryptedQuery(pk, groupSize, index))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, err := UnmarshalDoublyEncryptedQueryJSON(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err = json.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res = &DoublyEncryptedQueryResult{}
+		if err := json.Unmarshal(data, res); err != nil {
+			t.Fatal(err)
+		}
+		res.Pk = pk
+
+		slots, err := RecoverDoublyEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := (index / groupSize) * groupSize
+		for j := 0; j < groupSize; j++ {
+			expected := NewEmptySlot(SlotBytes)
+			if start+j < db.DBSize {
+				expected = db.Slots[start+j]
+			}
+
+			if !expected.Equal(slots[j]) {
+				t.Fatalf("Incorrect result over JSON: expected %v, got %v\n", expected, slots[j])
+			}
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"row":null,"col":null}`), &DoublyEncryptedQuery{}); err == nil {
+		t.Fatalf("Decoded a doubly encrypted query without row and column queries\n")
+	}
+}
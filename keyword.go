@@ -91,8 +91,8 @@ func (sqst *PrivateSqrtST) PrivateEncryptedQuery(
 // GetSecondLayerMetadata returns the metadata for PIR database of the second layer
 func (sqst *PrivateSqrtST) GetSecondLayerMetadata() *DBMetadata {
 	return &DBMetadata{
-		sqst.SecondLayer.SlotBytes,
-		sqst.SecondLayer.DBSize,
+		SlotBytes: sqst.SecondLayer.SlotBytes,
+		DBSize:    sqst.SecondLayer.DBSize,
 	}
 }
 
@@ -129,3 +129,23 @@ func PadToSqrt(data []string) []string {
 
 	return newdata
 }
+
+// KeywordFailureProbability estimates the probability that keyword PIR fails
+// when numEntries keywords (e.g., hashes of the actual keys) are mapped into a
+// domain of keywordBits bits: if two entries share a keyword, a query for either
+// retrieves the XOR of both. This is the birthday bound 1 - exp(-n(n-1)/2^(keywordBits+1)).
+// (A query for an absent key additionally matches an entry with probability n/2^keywordBits,
+// which is smaller for n > 2.)
+// If numEntries <= 0, the size of the database is used
+func (dbmd *DBMetadata) KeywordFailureProbability(keywordBits uint, numEntries int) float64 {
+
+	n := float64(numEntries)
+	if numEntries <= 0 {
+		n = float64(dbmd.DBSize)
+	}
+
+	pairs := n * (n - 1) / 2
+	domain := math.Pow(2, float64(keywordBits))
+
+	return -math.Expm1(-pairs / domain)
+}
@@ -0,0 +1,160 @@
+package pir
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/sachaservan/pir/dpf"
+)
+
+/*
+ Two dimensional keyword queries (e.g., by (userID, date)).
+
+ The database is viewed as a grid where each row is labeled with a keyword
+ (Keywords) and each column group is labeled with a keyword (ColKeys).
+ Like the doubly encrypted queries, a query selects a row by keyword1 and
+ a column group by keyword2. However, the product of two secret shared
+ selection vectors cannot be computed locally by the servers, so the query
+ is a single DPF over the 64-bit domain (keyword1, keyword2) which the servers
+ evaluate at the label of each cell.
+
+ Collisions: keywords are 32-bit values in each dimension. If two rows
+ (or two column groups) share a keyword, a query for that keyword returns
+ the XOR of the colliding cells. Keywords should therefore be unique within
+ each dimension (e.g., user IDs and day numbers rather than hashes).
+ When keywords are hashes, collisions are likely after about 2^16 distinct
+ values per dimension (birthday bound) and must be checked when building the database.
+*/
+
+// SetDoublyKeywords sets the row and column group keywords of the database
+// used by 2D keyword queries. The database is viewed as a grid of
+// len(rowKeywords) rows and len(colKeywords) column groups
+// where each group has DBSize / (len(rowKeywords) * len(colKeywords)) slots
+func (db *Database) SetDoublyKeywords(rowKeywords, colKeywords []uint) error {
+
+	if len(rowKeywords) == 0 || len(colKeywords) == 0 {
+		return errors.New("no keywords provided")
+	}
+
+	numCells := len(rowKeywords) * len(colKeywords)
+	if db.DBSize < numCells || db.DBSize%numCells != 0 {
+		return errors.New("database size is not a multiple of the number of cells")
+	}
+
+	for _, keywords := range [][]uint{rowKeywords, colKeywords} {
+		for _, keyword := range keywords {
+			if keyword>>32 != 0 {
+				return errors.New("keywords must fit in 32 bits")
+			}
+		}
+	}
+
+	db.Keywords = rowKeywords
+	db.ColKeys = colKeywords
+
+	return nil
+}
+
+// DoublyKeywordGroupSize returns the number of slots in each cell of the grid
+// (0 if the 2D keywords are not set)
+func (db *Database) DoublyKeywordGroupSize() int {
+
+	if len(db.Keywords) == 0 || len(db.ColKeys) == 0 {
+		return 0
+	}
+
+	return db.DBSize / (len(db.Keywords) * len(db.ColKeys))
+}
+
+// NewDoublyKeywordQuery generates PIR query shares that select
+// the row labeled keyword1 and the column group labeled keyword2
+// (see SetDoublyKeywords). Both keywords must fit in 32 bits
+func (dbmd *DBMetadata) NewDoublyKeywordQuery(keyword1, keyword2 int, numShares uint) ([]*QueryShare, error) {
+
+	for _, keyword := range []int{keyword1, keyword2} {
+		if keyword < 0 || uint64(keyword)>>32 != 0 {
+			return nil, errors.New("keywords must fit in 32 bits")
+		}
+	}
+
+	shares := newDPFQueryShares(doublyKeyword(uint(keyword1), uint(keyword2)), 64, 0, numShares)
+	for i := range shares {
+		shares[i].IsKeywordBased = true
+	}
+
+	return shares, nil
+}
+
+// PrivateSecretSharedDoublyKeywordQuery uses the provided 2D keyword query
+// to retrieve the slots of the cell (group) labeled with the queried keywords
+func (db *Database) PrivateSecretSharedDoublyKeywordQuery(query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	groupSize := db.DoublyKeywordGroupSize()
+	if groupSize == 0 {
+		return nil, errors.New("database does not have 2D keywords")
+	}
+
+	if !query.IsKeywordBased {
+		return nil, errors.New("query is not keyword based")
+	}
+
+	numRows := len(db.Keywords)
+	numCols := len(db.ColKeys)
+
+	// expand the DPF at the label of each cell
+	bits := make([]bool, numRows*numCols)
+
+	var wg sync.WaitGroup
+	for _, chunk := range rowChunks(numRows, nprocs, 0) {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+
+			pf := dpf.ServerInitialize(query.PrfKeys, 64)
+
+			for row := start; row < end; row++ {
+				for col := 0; col < numCols; col++ {
+					key := doublyKeyword(db.Keywords[row], db.ColKeys[col])
+
+					if query.IsTwoParty {
+						res := pf.Evaluate2P(query.ShareNumber, query.KeyTwoParty, key)
+						// IMPORTANT: take mod 2 of uint *before* casting to float64, otherwise there is an overflow edge case!
+						bits[row*numCols+col] = (int(math.Abs(float64(res%2))) == 0)
+					} else {
+						res := pf.EvaluateMP(query.KeyMultiParty, key)
+						bits[row*numCols+col] = (int(math.Abs(float64(res%2))) == 0)
+					}
+				}
+			}
+		}(chunk[0], chunk[1])
+	}
+
+	wg.Wait()
+
+	results := make([]*Slot, groupSize)
+	for j := range results {
+		results[j] = NewEmptySlot(db.SlotBytes)
+	}
+
+	for cell, bit := range bits {
+		if !bit {
+			continue
+		}
+
+		for j := 0; j < groupSize; j++ {
+			XorSlots(results[j], db.Slots[cell*groupSize+j])
+		}
+	}
+
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: results}, nil
+}
+
+// doublyKeyword maps a pair of 32-bit keywords to a 64-bit keyword
+func doublyKeyword(keyword1, keyword2 uint) uint {
+	return uint(uint64(keyword1)<<32 | uint64(keyword2))
+}
@@ -0,0 +1,104 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDoublyKeywordQuery(t *testing.T) {
+	setup()
+
+	numRows := 20
+	numCols := 7
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		db := GenerateRandomDB(numRows*numCols*groupSize, SlotBytes)
+
+		// e.g., user IDs and day numbers
+		rowKeywords := make([]uint, numRows)
+		for i, keyword := range rand.Perm(1 << 16)[:numRows] {
+			rowKeywords[i] = uint(keyword)
+		}
+
+		colKeywords := make([]uint, numCols)
+		for i, keyword := range rand.Perm(1 << 16)[:numCols] {
+			colKeywords[i] = uint(keyword) + 1<<31
+		}
+
+		if err := db.SetDoublyKeywords(rowKeywords, colKeywords); err != nil {
+			t.Fatal(err)
+		}
+
+		// place a value at the (k1, k2) cell
+		row := rand.Intn(numRows)
+		col := rand.Intn(numCols)
+		cell := (row*numCols + col) * groupSize
+		db.Slots[cell] = NewSlotFromString("val", SlotBytes)
+
+		query := func(keyword1, keyword2 uint) []*Slot {
+			shares, err := db.NewDoublyKeywordQuery(int(keyword1), int(keyword2), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resA, err := db.PrivateSecretSharedDoublyKeywordQuery(shares[0], NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resB, err := db.PrivateSecretSharedDoublyKeywordQuery(shares[1], NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return Recover([]*SecretSharedQueryResult{resA, resB})
+		}
+
+		res := query(rowKeywords[row], colKeywords[col])
+		if len(res) != groupSize {
+			t.Fatalf("Result has %v slots, expected %v\n", len(res), groupSize)
+		}
+
+		for j := 0; j < groupSize; j++ {
+			if !db.Slots[cell+j].Equal(res[j]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[cell+j], res[j])
+			}
+		}
+
+		if res[0].ToString() != "val" {
+			t.Fatalf("Expected val, got %v\n", res[0].ToString())
+		}
+
+		// swapping the dimensions does not match any cell
+		res = query(colKeywords[col], rowKeywords[row])
+		if !res[0].IsEmpty() {
+			t.Fatalf("Query with swapped keywords returned %v\n", res[0])
+		}
+
+		// keywords wider than 32 bits are rejected rather than truncated
+		if _, err := db.NewDoublyKeywordQuery(int(rowKeywords[row])+1<<32, int(colKeywords[col]), 2); err == nil {
+			t.Fatalf("Query accepted a keyword wider than 32 bits\n")
+		}
+	}
+}
+
+func TestSetDoublyKeywords(t *testing.T) {
+
+	db := GenerateRandomDB(30, SlotBytes)
+
+	if err := db.SetDoublyKeywords([]uint{1, 2, 3}, []uint{1, 2, 3, 4}); err == nil {
+		t.Fatal("Set keywords that do not divide the database")
+	}
+
+	if err := db.SetDoublyKeywords([]uint{1, 2, 3}, []uint{1 << 32, 2}); err == nil {
+		t.Fatal("Set keywords that do not fit in 32 bits")
+	}
+
+	if err := db.SetDoublyKeywords([]uint{1, 2, 3}, []uint{1, 2, 3, 4, 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	if db.DoublyKeywordGroupSize() != 2 {
+		t.Fatalf("Group size is %v, expected 2\n", db.DoublyKeywordGroupSize())
+	}
+}
@@ -100,3 +100,202 @@ func TestKeywordQuerySqrtST(t *testing.T) {
 		}
 	}
 }
+
+func TestKeywordQueryDomain(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	keywords := make([]uint, TestDBSize)
+	for i := range keywords {
+		keywords[i] = uint(i) + 1<<31
+	}
+	db.SetKeywords(keywords)
+
+	// a 64-bit hash is not silently truncated to its low 32 bits
+	if _, err := db.NewKeywordQueryShares(1<<32+int(keywords[0]), 1, 2); err != ErrKeywordOutOfDomain {
+		t.Fatalf("Expected %v, got %v\n", ErrKeywordOutOfDomain, err)
+	}
+
+	if _, err := db.NewKeywordQueryShares(-1, 1, 2); err != ErrKeywordOutOfDomain {
+		t.Fatalf("Expected %v, got %v\n", ErrKeywordOutOfDomain, err)
+	}
+
+	// keywords in the domain (but larger than the database) are retrieved
+	index := rand.Intn(TestDBSize)
+	shares, err := db.NewKeywordQueryShares(int(keywords[index]), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resA, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resB, err := db.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Recover([]*SecretSharedQueryResult{resA, resB})
+	if !db.Slots[index].Equal(res[0]) {
+		t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[0])
+	}
+}
+
+func TestKeywordFailureProbability(t *testing.T) {
+
+	dbmd := &DBMetadata{SlotBytes: SlotBytes, DBSize: TestDBSize}
+
+	prev := 1.0
+	for keywordBits := uint(8); keywordBits <= 64; keywordBits += 8 {
+		p := dbmd.KeywordFailureProbability(keywordBits, 1<<16)
+		if p < 0 || p > 1 {
+			t.Fatalf("Probability %v is out of range\n", p)
+		}
+
+		if p >= prev && prev != 1.0 {
+			t.Fatalf("Probability did not decrease with %v keyword bits: %v >= %v\n", keywordBits, p, prev)
+		}
+
+		prev = p
+	}
+
+	// 2^16 entries in a 32-bit domain collide with probability ~ 1 - 1/e^(1/2)
+	if p := dbmd.KeywordFailureProbability(32, 1<<16); math.Abs(p-(1-math.Exp(-0.5))) > 0.01 {
+		t.Fatalf("Unexpected failure probability %v\n", p)
+	}
+
+	if dbmd.KeywordFailureProbability(32, 0) != dbmd.KeywordFailureProbability(32, TestDBSize) {
+		t.Fatal("Failure probability does not default to the database size")
+	}
+}
+
+func TestPrivateKeywordCountQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// each keyword appears a known number of times
+	keywords := make([]uint, TestDBSize)
+	counts := make(map[uint]int)
+	for i := range keywords {
+		keywords[i] = uint(rand.Intn(TestDBSize / 8))
+		counts[keywords[i]]++
+	}
+	db.SetKeywords(keywords)
+
+	for trial := 0; trial < 10; trial++ {
+		keyword := keywords[rand.Intn(TestDBSize)]
+		if trial == 0 {
+			keyword = TestDBSize // not in the database
+		}
+
+		shares, err := db.NewKeywordQueryShares(int(keyword), 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resShares := make([]*CountQueryResult, len(shares))
+		for i, share := range shares {
+			resShares[i], err = db.PrivateKeywordCountQuery(share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if count := RecoverCount(resShares); count != counts[keyword] {
+			t.Fatalf("Recovered count %v for keyword %v, expected %v\n", count, keyword, counts[keyword])
+		}
+	}
+}
+
+func TestKeywordPrefixQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// records with keywords under a common prefix are placed
+	// at random positions among records with other prefixes
+	prefixBits := uint(8)
+	prefix := uint32(0xab)
+	numMatches := 5
+
+	keywords := make([]uint, TestDBSize)
+	for i := range keywords {
+		keywords[i] = uint(rand.Intn(1 << 31))
+		if keywords[i]>>(KeywordBits-prefixBits) == uint(prefix) {
+			keywords[i] ^= 1 << 30 // not under the prefix
+		}
+	}
+
+	matches := rand.Perm(TestDBSize)[:numMatches]
+	sort.Ints(matches)
+	for _, index := range matches {
+		keywords[index] = uint(prefix)<<(KeywordBits-prefixBits) | uint(rand.Intn(1<<(KeywordBits-prefixBits)))
+	}
+	db.SetKeywords(keywords)
+
+	shares, err := NewKeywordPrefixQuery(prefix, prefixBits, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each matching record is retrieved separately
+	maxMatches := numMatches + 2
+	resShares := make([]*SecretSharedQueryResult, len(shares))
+	for i, share := range shares {
+		resShares[i], err = db.PrivateKeywordPrefixQuery(share, maxMatches, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res := Recover(resShares)
+	for i := 0; i < maxMatches; i++ {
+		expected := NewEmptySlot(SlotBytes)
+		if i < numMatches {
+			expected = db.Slots[matches[i]]
+		}
+
+		if !expected.Equal(res[i]) {
+			t.Fatalf("Match %v is incorrect. %v != %v\n", i, expected, res[i])
+		}
+	}
+
+	// the count query returns the number of matches
+	countShares := make([]*CountQueryResult, len(shares))
+	for i, share := range shares {
+		countShares[i], err = db.PrivateKeywordCountQuery(share, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := RecoverCount(countShares); count != numMatches {
+		t.Fatalf("Recovered count %v, expected %v\n", count, numMatches)
+	}
+
+	// the secret-shared query returns the XOR of the matches
+	xorShares := make([]*SecretSharedQueryResult, len(shares))
+	for i, share := range shares {
+		xorShares[i], err = db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := NewEmptySlot(SlotBytes)
+	for _, index := range matches {
+		XorSlots(expected, db.Slots[index])
+	}
+
+	if xor := Recover(xorShares)[0]; !expected.Equal(xor) {
+		t.Fatalf("XOR of the matches is incorrect. %v != %v\n", expected, xor)
+	}
+
+	if _, err := NewKeywordPrefixQuery(1<<prefixBits, prefixBits, 2); err != ErrKeywordOutOfDomain {
+		t.Fatalf("Expected %v, got %v\n", ErrKeywordOutOfDomain, err)
+	}
+}
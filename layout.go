@@ -0,0 +1,152 @@
+package pir
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GroupRegion is a window [Start, End) of the database whose
+// slots are retrieved in groups of GroupSize slots
+type GroupRegion struct {
+	Start, End int
+	GroupSize  int
+}
+
+// GroupLayout splits the database into consecutive regions with different
+// group sizes (e.g., frequently accessed records can be grouped larger).
+// A query is answered over the window of a single region, so the server learns
+// which region the query is for, but not which group within the region
+type GroupLayout struct {
+	Regions []*GroupRegion
+}
+
+// NewGroupLayout returns a layout over a database of dbSize slots and returns an error
+// unless the regions are non-empty, consecutive, and cover the entire database
+func NewGroupLayout(dbSize int, regions ...*GroupRegion) (*GroupLayout, error) {
+
+	next := 0
+	for _, region := range regions {
+		if region.Start != next || region.End <= region.Start {
+			return nil, errors.New("regions must be non-empty and consecutive")
+		}
+
+		if region.GroupSize <= 0 {
+			return nil, errors.New("invalid group size for region")
+		}
+
+		next = region.End
+	}
+
+	if next != dbSize {
+		return nil, errors.New("regions do not cover the database")
+	}
+
+	return &GroupLayout{Regions: regions}, nil
+}
+
+// RegionOf returns the region containing the slot at index
+func (layout *GroupLayout) RegionOf(index int) (*GroupRegion, error) {
+
+	for _, region := range layout.Regions {
+		if index >= region.Start && index < region.End {
+			return region, nil
+		}
+	}
+
+	return nil, errors.New("index outside of the layout")
+}
+
+// GroupOffset returns the position of the slot at index
+// within the group of slots recovered by a layout query for index
+func (layout *GroupLayout) GroupOffset(index int) (int, error) {
+
+	region, err := layout.RegionOf(index)
+	if err != nil {
+		return 0, err
+	}
+
+	return (index - region.Start) % region.GroupSize, nil
+}
+
+// LayoutRowOffset returns the position of the slot at index within the row
+// recovered by an encrypted query for index over a database with a layout
+// (see DBMetadata.NewEncryptedQuery)
+func (query *EncryptedQuery) LayoutRowOffset(index int) int {
+
+	if query.Region == nil {
+		return index % query.DBWidth
+	}
+
+	return (index - query.Region.Start) % query.DBWidth
+}
+
+// layoutRegion returns the region of the layout containing the slot at index
+// along with the metadata of the window of the region (in which the slot has
+// index index - region.Start). It returns a nil region if the database does
+// not have a layout or if index is outside of it (e.g., -1 for null queries),
+// in which case the query is over the entire database
+func (dbmd *DBMetadata) layoutRegion(index int) (*GroupRegion, *DBMetadata) {
+
+	if dbmd.Layout == nil {
+		return nil, nil
+	}
+
+	region, err := dbmd.Layout.RegionOf(index)
+	if err != nil {
+		return nil, nil
+	}
+
+	regionMD := *dbmd
+	regionMD.DBSize = region.End - region.Start
+	regionMD.Layout = nil
+
+	return region, &regionMD
+}
+
+// NewLayoutIndexQueryShares generates PIR query shares that retrieve the group
+// containing the slot at index, using the group size of the index's region in the layout.
+// Recovering the result returns GroupSize slots of that region
+// (see GroupOffset for the position of the slot within the group)
+func (dbmd *DBMetadata) NewLayoutIndexQueryShares(index int, numShares uint) ([]*QueryShare, error) {
+
+	if dbmd.Layout == nil {
+		return nil, fmt.Errorf("%w: database does not have a group layout", ErrInvalidQuery)
+	}
+
+	region, regionMD := dbmd.layoutRegion(index)
+	if region == nil {
+		return nil, errors.New("index outside of the layout")
+	}
+
+	row := (index - region.Start) / region.GroupSize
+
+	shares := regionMD.NewIndexQueryShares(row, region.GroupSize, numShares)
+	for _, share := range shares {
+		share.Region = region
+	}
+
+	return shares, nil
+}
+
+// regionDatabase returns the window of the database for the region
+// and returns an error if the region is not part of the database's layout
+func (db *Database) regionDatabase(region *GroupRegion, groupSize int) (*Database, error) {
+
+	if db.Layout == nil {
+		return nil, fmt.Errorf("%w: database does not have a group layout", ErrInvalidQuery)
+	}
+
+	for _, r := range db.Layout.Regions {
+		if *r != *region {
+			continue
+		}
+
+		if groupSize != r.GroupSize {
+			return nil, fmt.Errorf("%w: group size does not match the region", ErrInvalidQuery)
+		}
+
+		return db.SubDatabase(r.Start, r.End)
+	}
+
+	return nil, fmt.Errorf("%w: region is not part of the database layout", ErrInvalidQuery)
+}
@@ -0,0 +1,269 @@
+package pir
+
+import (
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestGroupLayoutQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// frequently accessed records in the first region are grouped larger
+	layout, err := NewGroupLayout(
+		TestDBSize,
+		&GroupRegion{Start: 0, End: TestDBSize / 4, GroupSize: 4},
+		&GroupRegion{Start: TestDBSize / 4, End: TestDBSize, GroupSize: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Layout = layout
+
+	for _, index := range []int{5, TestDBSize/4 - 1, TestDBSize / 4, TestDBSize - 1} {
+		shares, err := db.NewLayoutIndexQueryShares(index, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resA, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := db.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Recover([]*SecretSharedQueryResult{resA, resB})
+
+		region, _ := layout.RegionOf(index)
+		if len(res) != region.GroupSize {
+			t.Fatalf("Expected %v slots, got %v\n", region.GroupSize, len(res))
+		}
+
+		offset, err := layout.GroupOffset(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !db.Slots[index].Equal(res[offset]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[offset])
+		}
+	}
+
+	// a region that is not part of the layout is rejected
+	shares, _ := db.NewLayoutIndexQueryShares(0, 2)
+	shares[0].Region = &GroupRegion{Start: 0, End: TestDBSize / 2, GroupSize: 4}
+	if _, err := db.PrivateSecretSharedQuery(shares[0], NumProcsForQuery); err == nil {
+		t.Fatal("Server accepted a query for a region that is not part of the layout")
+	}
+}
+
+func TestGroupLayoutQueryConstructors(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	layout, err := NewGroupLayout(
+		TestDBSize,
+		&GroupRegion{Start: 0, End: TestDBSize / 4, GroupSize: 4},
+		&GroupRegion{Start: TestDBSize / 4, End: TestDBSize, GroupSize: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Layout = layout
+
+	server := NewServer(db)
+	server.AllowDefaultShapes(1)
+
+	// the group size argument is superseded by the group size of the region
+	for _, index := range []int{5, TestDBSize/4 - 1, TestDBSize / 4, TestDBSize - 1} {
+		region, _ := layout.RegionOf(index)
+		offset, _ := layout.GroupOffset(index)
+
+		// secret shared
+		shares, err := db.NewLayoutIndexQueryShares(index, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resShares := make([]*SecretSharedQueryResult, len(shares))
+		for i, share := range shares {
+			if resShares[i], err = server.PrivateSecretSharedQuery(share, NumProcsForQuery); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res := Recover(resShares)
+		if len(res) != region.GroupSize || !db.Slots[index].Equal(res[offset]) {
+			t.Fatalf("Secret shared query for slot %v is incorrect\n", index)
+		}
+
+		// encrypted (through the binary encoding, which carries the region)
+		query := db.NewEncryptedQuery(pk, 1, index)
+		data, err := query.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if query, err = UnmarshalEncryptedQuery(data, pk); err != nil {
+			t.Fatal(err)
+		}
+
+		encRes, err := server.PrivateEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		row, err := RecoverEncrypted(encRes, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !db.Slots[index].Equal(row[query.LayoutRowOffset(index)]) {
+			t.Fatalf("Encrypted query for slot %v is incorrect\n", index)
+		}
+
+		// doubly encrypted
+		doublyRes, err := server.PrivateDoublyEncryptedQuery(db.NewDoublyEncryptedQuery(pk, 1, index), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		group, err := RecoverDoublyEncrypted(doublyRes, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(group) != region.GroupSize || !db.Slots[index].Equal(group[offset]) {
+			t.Fatalf("Doubly encrypted query for slot %v is incorrect\n", index)
+		}
+	}
+
+	// null queries are over the entire database
+	null := db.NewDoublyEncryptedNullQuery(pk, 1)
+	if null.Row.Region != nil {
+		t.Fatalf("Null query is over a region\n")
+	}
+
+	// index queries are for groups of the given size over the entire database
+	shares := db.NewIndexQueryShares(3, 2, 2)
+	if shares[0].Region != nil || shares[0].GroupSize != 2 {
+		t.Fatalf("Index query depends on the layout\n")
+	}
+
+	// slots outside of the layout are an error
+	if _, err := db.NewLayoutIndexQueryShares(TestDBSize, 2); err == nil {
+		t.Fatalf("Layout query for a slot outside of the layout did not fail\n")
+	}
+}
+
+func TestGroupLayoutClient(t *testing.T) {
+	setup()
+
+	sk, _ := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	layout, err := NewGroupLayout(
+		TestDBSize,
+		&GroupRegion{Start: 0, End: 10, GroupSize: 3},
+		&GroupRegion{Start: 10, End: TestDBSize, GroupSize: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Layout = layout
+
+	server := NewServer(db)
+	server.AllowDefaultShapes(1)
+
+	for _, numServers := range []int{1, 2} {
+		endpoints := make([]*ServerEndpoint, numServers)
+		for i := range endpoints {
+			endpoints[i] = &ServerEndpoint{Server: server, NumProcs: NumProcsForQuery}
+		}
+
+		client := &Client{
+			DBMetadata:         db.DBMetadata,
+			GroupSize:          1,
+			NumServers:         numServers,
+			PreferSecretShared: true,
+			Shared:             NewSharedQueryFunc(endpoints),
+			Sk:                 sk,
+			Encrypted: func(query *EncryptedQuery) (*EncryptedQueryResult, error) {
+				return server.PrivateEncryptedQuery(query, NumProcsForQuery)
+			},
+		}
+
+		for _, index := range []int{0, 9, 10, 11, TestDBSize - 1} {
+			group, err := client.Query(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			region, _ := layout.RegionOf(index)
+			offset, _ := layout.GroupOffset(index)
+			if len(group) != region.GroupSize || !db.Slots[index].Equal(group[offset]) {
+				t.Fatalf("Client with %v servers retrieved the wrong group for slot %v\n", numServers, index)
+			}
+		}
+	}
+
+	// downloading a tiny database returns the same groups
+	tiny := GenerateRandomDB(8, SlotBytes)
+	if tiny.Layout, err = NewGroupLayout(
+		8,
+		&GroupRegion{Start: 0, End: 3, GroupSize: 2},
+		&GroupRegion{Start: 3, End: 8, GroupSize: 3},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{
+		DBMetadata:           tiny.DBMetadata,
+		NumServers:           1,
+		Sk:                   sk,
+		AllowTrivialDownload: true,
+		Download: func() ([]*Slot, error) {
+			return tiny.Slots, nil
+		},
+	}
+
+	if !client.UsesTrivialDownload() {
+		t.Fatalf("Client does not download a tiny database\n")
+	}
+
+	for index := 0; index < 8; index++ {
+		group, err := client.Query(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		region, _ := tiny.Layout.RegionOf(index)
+		offset, _ := tiny.Layout.GroupOffset(index)
+		if len(group) != region.GroupSize || !tiny.Slots[index].Equal(group[offset]) {
+			t.Fatalf("Downloaded the wrong group for slot %v\n", index)
+		}
+	}
+}
+
+func TestGroupLayoutValidation(t *testing.T) {
+
+	if _, err := NewGroupLayout(10, &GroupRegion{0, 4, 2}, &GroupRegion{5, 10, 1}); err == nil {
+		t.Fatal("Accepted a layout with a gap between regions")
+	}
+
+	if _, err := NewGroupLayout(10, &GroupRegion{0, 4, 2}); err == nil {
+		t.Fatal("Accepted a layout that does not cover the database")
+	}
+
+	if _, err := NewGroupLayout(10, &GroupRegion{0, 10, 0}); err == nil {
+		t.Fatal("Accepted a region with group size zero")
+	}
+}
@@ -0,0 +1,112 @@
+package pir
+
+import (
+	"errors"
+	"sync"
+)
+
+// LogDatabase is an append-only database (e.g., a feed or inbox)
+// where clients privately retrieve entries by their position from the end of the log.
+// Appends can happen concurrently with queries, so a client pins the head
+// of the log (see Head) and resolves positions against it;
+// the server then answers the query over the log as it was at the pinned head.
+// The underlying database is only accessed through the methods of the log,
+// which hold the lock of the log
+type LogDatabase struct {
+	db *Database
+	mu sync.RWMutex
+}
+
+// LogHead is the public state of the log at a given generation
+// (DBSize is the number of entries in the log at that generation)
+type LogHead struct {
+	DBMetadata
+	Generation uint64
+}
+
+// NewLogDatabase returns an empty log of slots of size slotBytes
+func NewLogDatabase(slotBytes int) *LogDatabase {
+	db := NewDatabase()
+	db.SlotBytes = slotBytes
+
+	return &LogDatabase{db: db}
+}
+
+// Append adds an entry to the end of the log
+func (ldb *LogDatabase) Append(slot *Slot) error {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	return ldb.db.Append(slot)
+}
+
+// LatestIndex returns the physical index of the most recent entry
+// (-1 if the log is empty)
+func (ldb *LogDatabase) LatestIndex() int {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	return ldb.db.DBSize - 1
+}
+
+// Head pins the current state of the log
+func (ldb *LogDatabase) Head() *LogHead {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	return &LogHead{
+		DBMetadata: ldb.db.DBMetadata,
+		Generation: ldb.db.Generation,
+	}
+}
+
+// NewQueryFromEnd generates PIR query shares for the entry that is
+// offset positions before the last entry of the log at the pinned head
+// (offset 0 is the latest entry)
+func (head *LogHead) NewQueryFromEnd(offset int, numShares uint) ([]*QueryShare, error) {
+
+	if offset < 0 || offset >= head.DBSize {
+		return nil, errors.New("offset outside of the log")
+	}
+
+	return head.NewIndexQueryShares(head.DBSize-1-offset, 1, numShares), nil
+}
+
+// PrivateSecretSharedQueryAtHead answers the query over the log as it was at the pinned head.
+// Entries appended after the head are not part of the answer such that
+// positions resolved by the client against the head remain valid
+func (ldb *LogDatabase) PrivateSecretSharedQueryAtHead(head *LogHead, query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+
+	db, err := ldb.atHead(head)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.PrivateSecretSharedQuery(query, nprocs)
+}
+
+// atHead returns a view of the log at the pinned head
+func (ldb *LogDatabase) atHead(head *LogHead) (*Database, error) {
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	if head.Generation > ldb.db.Generation || head.DBSize > ldb.db.DBSize {
+		return nil, errors.New("head is ahead of the log")
+	}
+
+	if head.SlotBytes != ldb.db.SlotBytes {
+		return nil, errors.New("head slot size does not match the log")
+	}
+
+	// entries are never modified once appended; check anyway
+	// in case the versions of the database disagree with the head
+	for i := 0; i < head.DBSize && i < len(ldb.db.Versions); i++ {
+		if ldb.db.Versions[i] > head.Generation {
+			return nil, errors.New("log entry modified after the pinned head")
+		}
+	}
+
+	// appends never modify the slots of the prefix so the view
+	// can be used after releasing the lock
+	return ldb.db.SubDatabase(0, head.DBSize)
+}
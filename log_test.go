@@ -0,0 +1,116 @@
+package pir
+
+import (
+	"testing"
+)
+
+func TestLogQueryFromEnd(t *testing.T) {
+	setup()
+
+	ldb := NewLogDatabase(SlotBytes)
+
+	entries := make([]*Slot, 0)
+	appendEntries := func(n int) {
+		for i := 0; i < n; i++ {
+			slot := NewRandomSlot(SlotBytes)
+			if err := ldb.Append(slot); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, slot)
+		}
+	}
+
+	queryFromEnd := func(head *LogHead, offset int) *Slot {
+		shares, err := head.NewQueryFromEnd(offset, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resShares := make([]*SecretSharedQueryResult, len(shares))
+		for i, share := range shares {
+			resShares[i], err = ldb.PrivateSecretSharedQueryAtHead(head, share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return Recover(resShares)[0]
+	}
+
+	appendEntries(10)
+
+	head := ldb.Head()
+	if ldb.LatestIndex() != 9 {
+		t.Fatalf("Latest index is %v, expected 9\n", ldb.LatestIndex())
+	}
+
+	res := queryFromEnd(head, 1)
+	if !res.Equal(entries[8]) {
+		t.Fatalf("Incorrect 2nd-from-last entry. %v != %v\n", res, entries[8])
+	}
+
+	// the pinned head resolves against the old log even after appending
+	appendEntries(TestDBSize)
+
+	res = queryFromEnd(head, 1)
+	if !res.Equal(entries[8]) {
+		t.Fatalf("Incorrect 2nd-from-last entry at pinned head. %v != %v\n", res, entries[8])
+	}
+
+	head = ldb.Head()
+	res = queryFromEnd(head, 1)
+	if !res.Equal(entries[len(entries)-2]) {
+		t.Fatalf("Incorrect 2nd-from-last entry. %v != %v\n", res, entries[len(entries)-2])
+	}
+
+	if _, err := head.NewQueryFromEnd(head.DBSize, 2); err == nil {
+		t.Fatal("Generated a query outside of the log")
+	}
+
+	// overwriting an entry (which the log does not expose) invalidates the heads pinned before the change
+	if err := ldb.db.SetSlot(0, NewRandomSlot(SlotBytes)); err != nil {
+		t.Fatal(err)
+	}
+
+	shares, _ := head.NewQueryFromEnd(0, 2)
+	if _, err := ldb.PrivateSecretSharedQueryAtHead(head, shares[0], NumProcsForQuery); err == nil {
+		t.Fatal("Answered a query at a head that is no longer valid")
+	}
+}
+
+func TestLogConcurrentAppends(t *testing.T) {
+	setup()
+
+	ldb := NewLogDatabase(SlotBytes)
+	for i := 0; i < 10; i++ {
+		if err := ldb.Append(NewRandomSlot(SlotBytes)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < TestDBSize; i++ {
+			if err := ldb.Append(NewRandomSlot(SlotBytes)); err != nil {
+				panic(err)
+			}
+		}
+		done <- true
+	}()
+
+	for i := 0; i < NumQueries; i++ {
+		head := ldb.Head()
+		shares, err := head.NewQueryFromEnd(0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, share := range shares {
+			if _, err := ldb.PrivateSecretSharedQueryAtHead(head, share, NumProcsForQuery); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	<-done
+}
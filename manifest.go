@@ -0,0 +1,94 @@
+package pir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Manifest describes the expected contents of a database (e.g., published alongside
+// the database files) such that a server can check the database it loaded
+// before serving queries (see VerifyAgainstManifest).
+// Either (or both) of the per-slot hashes and the Merkle root can be provided:
+// the hashes identify the corrupted slots while the root is constant-size
+type Manifest struct {
+	SlotBytes int
+	DBSize    int
+
+	// SHA-256 digest of each slot (optional)
+	SlotHashes [][]byte
+
+	// Merkle root of the database viewed as a Width x Height grid (optional, see Digest)
+	Root          []byte
+	Width, Height int
+}
+
+// NewManifest returns the manifest of the database with the per-slot hashes
+// and the Merkle root of the database viewed as a width x height grid
+func NewManifest(db *Database, width, height int) *Manifest {
+
+	hashes := make([][]byte, db.DBSize)
+	for i := range hashes {
+		hashes[i] = slotHash(db.manifestSlot(i))
+	}
+
+	return &Manifest{
+		SlotBytes:  db.SlotBytes,
+		DBSize:     db.DBSize,
+		SlotHashes: hashes,
+		Root:       db.Digest(width, height),
+		Width:      width,
+		Height:     height,
+	}
+}
+
+// VerifyAgainstManifest recomputes the slot hashes and the Merkle root of the database
+// (whichever the manifest provides) and returns an error if they do not match the manifest
+func VerifyAgainstManifest(db *Database, manifest Manifest) error {
+
+	if db.SlotBytes != manifest.SlotBytes || db.DBSize != manifest.DBSize {
+		return errors.New("database size does not match the manifest")
+	}
+
+	if manifest.SlotHashes == nil && manifest.Root == nil {
+		return errors.New("manifest has neither slot hashes nor a root")
+	}
+
+	if manifest.SlotHashes != nil {
+		if len(manifest.SlotHashes) != db.DBSize {
+			return errors.New("manifest does not have a hash for every slot")
+		}
+
+		for i, hash := range manifest.SlotHashes {
+			if !bytes.Equal(slotHash(db.manifestSlot(i)), hash) {
+				return fmt.Errorf("slot %v does not match the manifest", i)
+			}
+		}
+	}
+
+	if manifest.Root != nil {
+		if manifest.Width <= 0 || manifest.Height <= 0 || manifest.Width*manifest.Height < db.DBSize {
+			return errors.New("manifest dimensions do not cover the database")
+		}
+
+		if !bytes.Equal(db.Digest(manifest.Width, manifest.Height), manifest.Root) {
+			return errors.New("database does not match the manifest root")
+		}
+	}
+
+	return nil
+}
+
+// manifestSlot returns the slot at index (an empty slot past the stored slots)
+func (db *Database) manifestSlot(index int) *Slot {
+	if index < len(db.Slots) {
+		return db.Slots[index]
+	}
+	return NewEmptySlot(db.SlotBytes)
+}
+
+func slotHash(slot *Slot) []byte {
+	hash := sha256.Sum256(slot.Data)
+	return hash[:]
+}
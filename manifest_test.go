@@ -0,0 +1,42 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestVerifyAgainstManifest(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	width, height := db.GetDimentionsForDatabase(TestDBHeight, 1)
+	manifest := NewManifest(db, width, height)
+
+	if err := VerifyAgainstManifest(db, *manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	// each part of the manifest detects the corrupted slot on its own
+	hashesOnly := *manifest
+	hashesOnly.Root = nil
+
+	rootOnly := *manifest
+	rootOnly.SlotHashes = nil
+
+	index := rand.Intn(TestDBSize)
+	db.Slots[index].Data[0] ^= 1
+
+	for _, m := range []Manifest{*manifest, hashesOnly, rootOnly} {
+		if err := VerifyAgainstManifest(db, m); err == nil {
+			t.Fatalf("Corrupted slot %v passed manifest verification\n", index)
+		}
+	}
+
+	db.Slots[index].Data[0] ^= 1
+
+	// a database of another size fails
+	db.DBSize--
+	if err := VerifyAgainstManifest(db, *manifest); err == nil {
+		t.Fatalf("Database of a different size passed manifest verification\n")
+	}
+}
@@ -0,0 +1,191 @@
+package pir
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// ErrInvalidManifestSignature is returned when the signature of a metadata manifest
+// does not verify under the server's public key
+var ErrInvalidManifestSignature = errors.New("invalid metadata manifest signature")
+
+// manifestSignaturePrefix separates manifest signatures from other uses of the signing key
+const manifestSignaturePrefix = "pir metadata manifest\n"
+
+// MetadataManifest is the public description of a database that a client needs to build
+// queries that the server accepts: the metadata of the database (see DBMetadata),
+// the query shapes allowed by the server (the group sizes and dimensions it expects),
+// and the epoch (generation) of the database that the description is for
+type MetadataManifest struct {
+	Metadata *DBMetadata
+	Shapes   []QueryShape // empty if the server accepts all shapes
+	Epoch    uint64
+}
+
+type jsonQueryShape struct {
+	Width     int `json:"width"`
+	Height    int `json:"height"`
+	GroupSize int `json:"group_size"`
+}
+
+type jsonMetadataManifest struct {
+	SlotBytes           int                `json:"slot_bytes"`
+	DBSize              int                `json:"db_size"`
+	EmptyFill           byte               `json:"empty_fill"`
+	SlotAwareDimentions bool               `json:"slot_aware_dimentions"`
+	Layout              []*jsonGroupRegion `json:"layout,omitempty"`
+	Shapes              []*jsonQueryShape  `json:"shapes,omitempty"`
+	Epoch               uint64             `json:"epoch"`
+}
+
+type jsonSignedManifest struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Signature []byte          `json:"signature"`
+}
+
+// MetadataManifest returns the manifest of the server's database
+// at its current generation with the shapes allowed by the server
+func (s *Server) MetadataManifest() *MetadataManifest {
+
+	shapes := make([]QueryShape, 0, len(s.AllowedShapes))
+	for shape, ok := range s.AllowedShapes {
+		if ok {
+			shapes = append(shapes, shape)
+		}
+	}
+
+	// deterministic order such that the same state gives the same file
+	sort.Slice(shapes, func(i, j int) bool {
+		a, b := shapes[i], shapes[j]
+		if a.GroupSize != b.GroupSize {
+			return a.GroupSize < b.GroupSize
+		}
+		if a.Width != b.Width {
+			return a.Width < b.Width
+		}
+		return a.Height < b.Height
+	})
+
+	metadata := s.DB.DBMetadata
+
+	return &MetadataManifest{
+		Metadata: &metadata,
+		Shapes:   shapes,
+		Epoch:    s.DB.Generation,
+	}
+}
+
+// ExportMetadataManifest writes the manifest signed with the server's key to w
+// (as a JSON document holding the manifest and its Ed25519 signature)
+func ExportMetadataManifest(w io.Writer, manifest *MetadataManifest, key ed25519.PrivateKey) error {
+
+	if len(key) != ed25519.PrivateKeySize {
+		return errors.New("invalid manifest signing key size")
+	}
+
+	enc := &jsonMetadataManifest{
+		SlotBytes:           manifest.Metadata.SlotBytes,
+		DBSize:              manifest.Metadata.DBSize,
+		EmptyFill:           manifest.Metadata.EmptyFill,
+		SlotAwareDimentions: manifest.Metadata.SlotAwareDimentions,
+		Epoch:               manifest.Epoch,
+	}
+
+	if manifest.Metadata.Layout != nil {
+		for _, region := range manifest.Metadata.Layout.Regions {
+			enc.Layout = append(enc.Layout, &jsonGroupRegion{
+				Start:     region.Start,
+				End:       region.End,
+				GroupSize: region.GroupSize,
+			})
+		}
+	}
+
+	for _, shape := range manifest.Shapes {
+		enc.Shapes = append(enc.Shapes, &jsonQueryShape{
+			Width:     shape.Width,
+			Height:    shape.Height,
+			GroupSize: shape.GroupSize,
+		})
+	}
+
+	data, err := json.Marshal(enc)
+	if err != nil {
+		return err
+	}
+
+	signed := &jsonSignedManifest{
+		Manifest:  data,
+		Signature: ed25519.Sign(key, append([]byte(manifestSignaturePrefix), data...)),
+	}
+
+	return json.NewEncoder(w).Encode(signed)
+}
+
+// ImportMetadataManifest reads a manifest written by ExportMetadataManifest from r and
+// returns ErrInvalidManifestSignature unless it is signed with the server's key pub.
+// The metadata of the manifest can be used to build queries (e.g., with
+// NewIndexQueryShares) and the shapes can be checked against the epoch of the server
+func ImportMetadataManifest(r io.Reader, pub ed25519.PublicKey) (*MetadataManifest, error) {
+
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid manifest verification key size")
+	}
+
+	signed := &jsonSignedManifest{}
+	if err := json.NewDecoder(r).Decode(signed); err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(pub, append([]byte(manifestSignaturePrefix), signed.Manifest...), signed.Signature) {
+		return nil, ErrInvalidManifestSignature
+	}
+
+	enc := &jsonMetadataManifest{}
+	if err := json.Unmarshal(signed.Manifest, enc); err != nil {
+		return nil, err
+	}
+
+	if enc.SlotBytes < 0 || enc.DBSize < 0 {
+		return nil, errors.New("invalid database size in manifest")
+	}
+
+	manifest := &MetadataManifest{
+		Metadata: &DBMetadata{
+			SlotBytes:           enc.SlotBytes,
+			DBSize:              enc.DBSize,
+			EmptyFill:           enc.EmptyFill,
+			SlotAwareDimentions: enc.SlotAwareDimentions,
+		},
+		Shapes: make([]QueryShape, 0, len(enc.Shapes)),
+		Epoch:  enc.Epoch,
+	}
+
+	if enc.Layout != nil {
+		regions := make([]*GroupRegion, len(enc.Layout))
+		for i, region := range enc.Layout {
+			if region == nil {
+				return nil, errors.New("invalid region in manifest")
+			}
+			regions[i] = &GroupRegion{Start: region.Start, End: region.End, GroupSize: region.GroupSize}
+		}
+
+		layout, err := NewGroupLayout(enc.DBSize, regions...)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Metadata.Layout = layout
+	}
+
+	for _, shape := range enc.Shapes {
+		if shape == nil || shape.GroupSize < 1 {
+			return nil, errors.New("invalid query shape in manifest")
+		}
+		manifest.Shapes = append(manifest.Shapes, QueryShape{shape.Width, shape.Height, shape.GroupSize})
+	}
+
+	return manifest, nil
+}
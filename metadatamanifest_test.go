@@ -0,0 +1,128 @@
+package pir
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"math/rand"
+	"testing"
+)
+
+func TestMetadataManifest(t *testing.T) {
+	setup()
+
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.EmptyFill = 0xFF
+	db.Layout, err = NewGroupLayout(TestDBSize,
+		&GroupRegion{Start: 0, End: TestDBSize / 2, GroupSize: 1},
+		&GroupRegion{Start: TestDBSize / 2, End: TestDBSize, GroupSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetSlot(0, NewRandomSlot(SlotBytes)); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(db)
+	server.AllowDefaultShapes(MinGroupSize, MaxGroupSize)
+
+	var file bytes.Buffer
+	if err := ExportMetadataManifest(&file, server.MetadataManifest(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := ImportMetadataManifest(bytes.NewReader(file.Bytes()), pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if manifest.Epoch != db.Generation {
+		t.Fatalf("Manifest epoch %v does not match the generation %v\n", manifest.Epoch, db.Generation)
+	}
+
+	md := manifest.Metadata
+	if md.SlotBytes != db.SlotBytes || md.DBSize != db.DBSize || md.EmptyFill != db.EmptyFill {
+		t.Fatalf("Manifest metadata %v does not match the database\n", md)
+	}
+
+	if md.Layout == nil || len(md.Layout.Regions) != 2 || md.Layout.Regions[1].GroupSize != 4 {
+		t.Fatalf("Manifest layout does not match the database\n")
+	}
+
+	if len(manifest.Shapes) != len(server.AllowedShapes) {
+		t.Fatalf("Manifest has %v shapes, expected %v\n", len(manifest.Shapes), len(server.AllowedShapes))
+	}
+
+	for _, shape := range manifest.Shapes {
+		if server.CheckShape(shape) != nil {
+			t.Fatalf("Manifest shape %v is not allowed by the server\n", shape)
+		}
+	}
+
+	// queries built from the manifest alone are accepted and answered correctly
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize / 2)
+		shares := md.NewIndexQueryShares(index, 1, 2)
+
+		resShares := make([]*SecretSharedQueryResult, 2)
+		for j, share := range shares {
+			resShares[j], err = server.PrivateSecretSharedQuery(share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res := Recover(resShares)
+		if !db.Slots[index].Equal(res[0]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[0])
+		}
+	}
+}
+
+func TestMetadataManifestSignature(t *testing.T) {
+	setup()
+
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(GenerateRandomDB(TestDBSize, SlotBytes))
+
+	var file bytes.Buffer
+	if err := ExportMetadataManifest(&file, server.MetadataManifest(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ImportMetadataManifest(bytes.NewReader(file.Bytes()), otherPub); err != ErrInvalidManifestSignature {
+		t.Fatalf("Expected ErrInvalidManifestSignature for another key, got %v\n", err)
+	}
+
+	tampered := bytes.Replace(file.Bytes(), []byte(`"slot_bytes":3`), []byte(`"slot_bytes":4`), 1)
+	if bytes.Equal(tampered, file.Bytes()) {
+		t.Fatalf("Failed to tamper with the manifest\n")
+	}
+
+	if _, err := ImportMetadataManifest(bytes.NewReader(tampered), pub); err != ErrInvalidManifestSignature {
+		t.Fatalf("Expected ErrInvalidManifestSignature for a tampered manifest, got %v\n", err)
+	}
+
+	// keys of the wrong size are rejected instead of panicking
+	if err := ExportMetadataManifest(&file, server.MetadataManifest(), key[:10]); err == nil {
+		t.Fatalf("Exported a manifest with a truncated key\n")
+	}
+
+	if _, err := ImportMetadataManifest(bytes.NewReader(file.Bytes()), pub[:10]); err == nil {
+		t.Fatalf("Imported a manifest with a truncated key\n")
+	}
+}
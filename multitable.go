@@ -0,0 +1,125 @@
+package pir
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoMatchingTable is returned when the key of a multi-table query is in none of the tables
+var ErrNoMatchingTable = errors.New("key not found in any table")
+
+// MultiTableQueryShare is the share of a multi-table query sent to one server.
+// It contains one keyword query share for each table (in the order of the tables)
+type MultiTableQueryShare struct {
+	Tables []*QueryShare
+}
+
+// MultiTableQuery generates query shares that look up key in every table of a
+// horizontally-partitioned dataset (tables are keyword databases and the key is
+// expected to be in at most one of them). Server i receives shares[i].
+//
+// Every table is queried with an independent keyword query for the same key,
+// so a server (or any coalition of fewer than numShares servers) learns neither
+// the key nor which table holds it; it only learns the number and sizes of the tables.
+// The client learns the per-table results, and therefore the number of tables
+// matching the key (the match count). RecoverMultiTable rejects results with more
+// than one match, so a client whose subsequent behavior depends on the outcome
+// (e.g., retrying on ErrNoMatchingTable) reveals whether the key matched
+func MultiTableQuery(tables []*Database, key int, numShares uint) ([]*MultiTableQueryShare, error) {
+
+	if len(tables) == 0 {
+		return nil, errors.New("no tables provided")
+	}
+
+	shares := make([]*MultiTableQueryShare, numShares)
+	for i := range shares {
+		shares[i] = &MultiTableQueryShare{Tables: make([]*QueryShare, len(tables))}
+	}
+
+	for t, table := range tables {
+		tableShares, err := table.NewKeywordQueryShares(key, 1, numShares)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range shares {
+			shares[i].Tables[t] = tableShares[i]
+		}
+	}
+
+	return shares, nil
+}
+
+// PrivateMultiTableQuery answers the share of a multi-table query
+// and returns one result share per table
+func PrivateMultiTableQuery(tables []*Database, query *MultiTableQueryShare, nprocs int) ([]*SecretSharedQueryResult, error) {
+
+	if len(query.Tables) != len(tables) {
+		return nil, fmt.Errorf("query has %v table shares, expected %v", len(query.Tables), len(tables))
+	}
+
+	results := make([]*SecretSharedQueryResult, len(tables))
+	for t, table := range tables {
+		if !query.Tables[t].IsKeywordBased {
+			return nil, errors.New("multi-table queries must be keyword based")
+		}
+
+		res, err := table.PrivateSecretSharedQuery(query.Tables[t], nprocs)
+		if err != nil {
+			return nil, err
+		}
+
+		results[t] = res
+	}
+
+	return results, nil
+}
+
+// RecoverMultiTable combines the result shares of a multi-table query,
+// where resShares[i] is the result of server i, and returns the record
+// along with the index of the table that holds it.
+// Returns ErrNoMatchingTable if no table holds the key
+// and an error if more than one table does
+func RecoverMultiTable(resShares [][]*SecretSharedQueryResult) (*Slot, int, error) {
+
+	if len(resShares) == 0 {
+		return nil, 0, errors.New("no result shares provided")
+	}
+
+	numTables := len(resShares[0])
+
+	var record *Slot
+	table := -1
+	for t := 0; t < numTables; t++ {
+
+		tableShares := make([]*SecretSharedQueryResult, len(resShares))
+		for i, serverShares := range resShares {
+			if len(serverShares) != numTables {
+				return nil, 0, errors.New("result shares have inconsistent number of tables")
+			}
+			tableShares[i] = serverShares[t]
+		}
+
+		slots, err := RecoverN(tableShares, len(resShares))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if slots[0].IsEmpty() {
+			continue
+		}
+
+		if record != nil {
+			return nil, 0, fmt.Errorf("key found in tables %v and %v", table, t)
+		}
+
+		record = slots[0]
+		table = t
+	}
+
+	if record == nil {
+		return nil, 0, ErrNoMatchingTable
+	}
+
+	return record, table, nil
+}
@@ -0,0 +1,62 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMultiTableQuery(t *testing.T) {
+	setup()
+
+	// three tables with disjoint keywords
+	perm := rand.Perm(3 * TestDBSize)
+	tables := make([]*Database, 3)
+	for i := range tables {
+		tables[i] = GenerateEmptyDB(TestDBSize, SlotBytes)
+
+		keywords := make([]uint, TestDBSize)
+		for j := range keywords {
+			keywords[j] = uint(perm[i*TestDBSize+j])
+		}
+		tables[i].SetKeywords(keywords)
+	}
+
+	// place the record in one of the tables
+	table := rand.Intn(len(tables))
+	index := rand.Intn(TestDBSize)
+	tables[table].Slots[index] = NewRandomSlot(SlotBytes)
+	key := int(tables[table].Keywords[index])
+
+	record, found, err := RecoverMultiTable(multiTableQuery(t, tables, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found != table || !record.Equal(tables[table].Slots[index]) {
+		t.Fatalf("Retrieved record from table %v, expected table %v\n", found, table)
+	}
+
+	// keys that are in none of the tables are reported
+	if _, _, err := RecoverMultiTable(multiTableQuery(t, tables, 3*TestDBSize)); err != ErrNoMatchingTable {
+		t.Fatalf("Expected %v, got %v\n", ErrNoMatchingTable, err)
+	}
+}
+
+func multiTableQuery(t *testing.T, tables []*Database, key int) [][]*SecretSharedQueryResult {
+	t.Helper()
+
+	shares, err := MultiTableQuery(tables, key, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resShares := make([][]*SecretSharedQueryResult, len(shares))
+	for i, share := range shares {
+		resShares[i], err = PrivateMultiTableQuery(tables, share, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return resShares
+}
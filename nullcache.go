@@ -0,0 +1,102 @@
+package pir
+
+import (
+	"context"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+// nullResponseKey identifies the null responses that are interchangeable:
+// the same public key and query shape over the same database generation
+type nullResponseKey struct {
+	N             string
+	Width, Height int
+	GroupSize     int
+}
+
+// PrivateEncryptedNullQuery answers a null query (see NewEncryptedQuery with index -1)
+// without scanning the database. The response to a null query only depends on the
+// public key, the query shape, and the database, so the server computes it once per
+// database generation and returns a freshly re-randomized copy for each null query.
+// Re-randomized copies are indistinguishable from a freshly computed null response.
+//
+// Note: the server only knows that a query is null because the client uses this method,
+// which reveals to the server that the query is a cover query. This saves server work for
+// cover traffic that hides access frequency from parties other than the server
+// (e.g., network observers); cover queries meant to hide frequency from the server itself
+// must be sent to PrivateEncryptedQuery like any other query
+func (s *Server) PrivateEncryptedNullQuery(query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, error) {
+
+	defer s.padResponseTime(time.Now())
+
+	if err := s.checkQuery(context.Background(), QueryShape{query.DBWidth, query.DBHeight, query.GroupSize}); err != nil {
+		return nil, err
+	}
+
+	key := nullResponseKey{
+		N:         query.Pk.N.String(),
+		Width:     query.DBWidth,
+		Height:    query.DBHeight,
+		GroupSize: query.GroupSize,
+	}
+
+	s.nullMu.Lock()
+	if s.nullResponses == nil || s.nullGeneration != s.DB.Generation {
+		s.nullResponses = make(map[nullResponseKey]*EncryptedQueryResult)
+		s.nullGeneration = s.DB.Generation
+	}
+	cached := s.nullResponses[key]
+	s.nullMu.Unlock()
+
+	if cached == nil {
+		// scan with a null query built by the server such that a client
+		// cannot plant a non-null response in the cache
+		nullQuery := &EncryptedQuery{
+			Pk:        query.Pk,
+			EBits:     make([]*paillier.Ciphertext, query.DBHeight),
+			GroupSize: query.GroupSize,
+			DBWidth:   query.DBWidth,
+			DBHeight:  query.DBHeight,
+		}
+
+		for i := range nullQuery.EBits {
+			nullQuery.EBits[i] = nullCiphertext(query.Pk, paillier.EncLevelOne)
+		}
+
+		res, err := s.DB.PrivateEncryptedQuery(nullQuery, nprocs)
+		if err != nil {
+			return nil, err
+		}
+
+		s.nullMu.Lock()
+		s.nullResponses[key] = res
+		s.nullMu.Unlock()
+
+		cached = res
+	}
+
+	return rerandomizeResult(cached), nil
+}
+
+// rerandomizeResult returns a copy of the result where each ciphertext
+// is re-randomized by adding a fresh encryption of zero
+func rerandomizeResult(res *EncryptedQueryResult) *EncryptedQueryResult {
+
+	slots := make([]*EncryptedSlot, len(res.Slots))
+	for i, eslot := range res.Slots {
+		cts := make([]*paillier.Ciphertext, len(eslot.Cts))
+		for j, ct := range eslot.Cts {
+			cts[j] = res.Pk.Add(ct, res.Pk.EncryptZero())
+		}
+
+		slots[i] = &EncryptedSlot{Cts: cts}
+	}
+
+	return &EncryptedQueryResult{
+		Slots:                 slots,
+		Pk:                    res.Pk,
+		SlotBytes:             res.SlotBytes,
+		NumBytesPerCiphertext: res.NumBytesPerCiphertext,
+	}
+}
@@ -0,0 +1,40 @@
+package pir
+
+import "sync/atomic"
+
+// OpCounter counts the homomorphic additions and scalar multiplications
+// performed by the server when answering (doubly) encrypted queries.
+// Unlike timings, the counts only depend on the database and the query
+// dimensions, which makes them useful for comparing HE schemes.
+// Set Database.OpCounter to enable counting
+type OpCounter struct {
+	adds       uint64
+	constMults uint64
+}
+
+// NewOpCounter returns a counter set to zero
+func NewOpCounter() *OpCounter {
+	return &OpCounter{}
+}
+
+// Totals returns the number of homomorphic additions and scalar multiplications counted so far
+func (c *OpCounter) Totals() (adds, constMults uint64) {
+	return atomic.LoadUint64(&c.adds), atomic.LoadUint64(&c.constMults)
+}
+
+// Reset sets the counts to zero
+func (c *OpCounter) Reset() {
+	atomic.StoreUint64(&c.adds, 0)
+	atomic.StoreUint64(&c.constMults, 0)
+}
+
+// count is a no-op if the counter is nil (i.e., counting is disabled)
+func (c *OpCounter) count(adds, constMults int) {
+
+	if c == nil {
+		return
+	}
+
+	atomic.AddUint64(&c.adds, uint64(adds))
+	atomic.AddUint64(&c.constMults, uint64(constMults))
+}
@@ -0,0 +1,58 @@
+package pir
+
+import (
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestOpCounter(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+
+	// single ciphertext per slot
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.OpCounter = NewOpCounter()
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		// encrypted: one scalar multiplication and addition per slot
+		// plus the additions to combine the results of the workers
+		query := db.NewEncryptedQuery(pk, groupSize, 0)
+		if _, err := db.PrivateEncryptedQuery(query, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+
+		adds, constMults := db.OpCounter.Totals()
+		expectedAdds := uint64(TestDBSize + (NumProcsForQuery-1)*query.DBWidth)
+		if adds != expectedAdds || constMults != TestDBSize {
+			t.Fatalf("Counted (%v, %v) operations, expected (%v, %v)\n", adds, constMults, expectedAdds, TestDBSize)
+		}
+
+		db.OpCounter.Reset()
+
+		// doubly encrypted: the row query plus one scalar multiplication
+		// and addition for each slot of the row
+		doublyQuery := db.NewDoublyEncryptedQuery(pk, groupSize, 0)
+		if _, err := db.PrivateDoublyEncryptedQuery(doublyQuery, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+
+		width := uint64(doublyQuery.Row.DBWidth)
+		adds, constMults = db.OpCounter.Totals()
+		expectedAdds = uint64(TestDBSize) + uint64(NumProcsForQuery-1)*width + width
+		expectedConstMults := uint64(TestDBSize) + width
+		if adds != expectedAdds || constMults != expectedConstMults {
+			t.Fatalf("Counted (%v, %v) operations, expected (%v, %v)\n", adds, constMults, expectedAdds, expectedConstMults)
+		}
+
+		db.OpCounter.Reset()
+	}
+
+	// counting is disabled by default
+	db.OpCounter = nil
+	if _, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, 1, 0), NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,39 @@
+package pir
+
+import (
+	"errors"
+)
+
+// PlaintextIndexQuery is a NON-PRIVATE query that sends the index of the slot
+// to the server in the clear. It provides no privacy whatsoever and is only meant
+// as a baseline for benchmarks and as a reference result in tests
+// (or when the index is protected by other means than PIR)
+type PlaintextIndexQuery struct {
+	Index int
+}
+
+// NewPlaintextIndexQuery generates a NON-PRIVATE query for the slot at index
+// (see PlaintextIndexQuery)
+func NewPlaintextIndexQuery(index int) *PlaintextIndexQuery {
+	return &PlaintextIndexQuery{Index: index}
+}
+
+// PlaintextIndexQuery answers the NON-PRIVATE query by a direct lookup of the slot.
+// The result has a single (plaintext) share such that Recover returns the slot
+func (db *Database) PlaintextIndexQuery(query *PlaintextIndexQuery) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if query.Index < 0 || query.Index >= db.DBSize {
+		return nil, errors.New("requesting index outside of domain")
+	}
+
+	slot := NewEmptySlot(db.SlotBytes)
+	if query.Index < len(db.Slots) {
+		copy(slot.Data, db.Slots[query.Index].Data)
+	}
+
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: []*Slot{slot}}, nil
+}
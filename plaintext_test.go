@@ -0,0 +1,33 @@
+package pir
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestPlaintextIndexQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+
+		res, err := db.PlaintextIndexQuery(NewPlaintextIndexQuery(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots := Recover([]*SecretSharedQueryResult{res})
+		private := sharedQueryGroup(t, db, 1, index)
+
+		if len(slots) != 1 || !bytes.Equal(slots[0].Data, private[0].Data) {
+			t.Fatalf("Plaintext query result does not match the private query result at index %v\n", index)
+		}
+	}
+
+	if _, err := db.PlaintextIndexQuery(NewPlaintextIndexQuery(TestDBSize)); err == nil {
+		t.Fatalf("Query outside of the domain did not fail\n")
+	}
+}
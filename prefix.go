@@ -0,0 +1,70 @@
+package pir
+
+import (
+	"errors"
+	"time"
+)
+
+// PrivateKeywordPrefixQuery returns shares of (up to) maxMatches slots whose keywords
+// match the prefix of the query (generated with NewKeywordPrefixQuery).
+//
+// Each row is assigned a public rank: the number of rows before it with the same prefix.
+// Result slot r is the XOR of the selected rows of rank r; since the DPF only selects rows
+// under the queried prefix, at most one row of each rank is selected, and recovering
+// the result yields the matching slots in database order followed by empty slots.
+// Matches of rank maxMatches or more are not returned.
+//
+// Leakage: the servers learn maxMatches (which the client should fix ahead of time)
+// but not the prefix. The client learns the number of matches (up to maxMatches),
+// which it can also obtain with PrivateKeywordCountQuery
+func (db *Database) PrivateKeywordPrefixQuery(query *QueryShare, maxMatches int, nprocs int) (*SecretSharedQueryResult, error) {
+
+	start := time.Now()
+	res, err := db.privateKeywordPrefixQuery(query, maxMatches, nprocs)
+	db.logSecretSharedQuery(query, nprocs, start, err)
+
+	return res, err
+}
+
+func (db *Database) privateKeywordPrefixQuery(query *QueryShare, maxMatches int, nprocs int) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if query.PrefixBits == 0 || query.PrefixBits > KeywordBits {
+		return nil, errors.New("query is not a keyword prefix query")
+	}
+
+	if query.GroupSize != 1 {
+		return nil, errors.New("invalid group size provided in query")
+	}
+
+	if maxMatches <= 0 {
+		return nil, errors.New("maximum number of matches must be positive")
+	}
+
+	if len(db.Keywords) < db.DBSize {
+		return nil, errors.New("database does not have keywords")
+	}
+
+	bits := db.ExpandSharedQuery(query, nprocs)
+
+	results := make([]*Slot, maxMatches)
+	for i := range results {
+		results[i] = NewEmptySlot(db.SlotBytes)
+	}
+
+	ranks := make(map[uint]int)
+	for row := 0; row < db.DBSize && row < len(db.Slots); row++ {
+		prefix := db.queryKey(query, row)
+		rank := ranks[prefix]
+		ranks[prefix]++
+
+		if bits[row] && rank < maxMatches {
+			XorSlots(results[rank], db.Slots[row])
+		}
+	}
+
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: results}, nil
+}
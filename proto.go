@@ -0,0 +1,951 @@
+package pir
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir/dpf"
+)
+
+// This file contains the protobuf encodings of the PIR messages such that non-Go
+// clients can interoperate with a Go server. The schema is proto/pir.proto and the
+// encoders below emit (and the decoders accept) the standard protobuf wire format
+// for it, so any protobuf library can generate the other end from the schema.
+//
+// As with the binary encodings, the Paillier public key is not part
+// of the messages and is passed to the decoders
+
+var errMalformedProto = errors.New("malformed protobuf encoding")
+
+// marshalDDLEQProof encodes a DDLEQ proof of the paillier library, which does not
+// define a wire format for its proofs, with encoding/gob. The encoding is therefore
+// specific to Go (and to the version of the paillier library)
+func marshalDDLEQProof(proof *paillier.DDLEQProof) ([]byte, error) {
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(proof); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// unmarshalDDLEQProof decodes a DDLEQ proof encoded by marshalDDLEQProof
+func unmarshalDDLEQProof(data []byte) (*paillier.DDLEQProof, error) {
+
+	proof := &paillier.DDLEQProof{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(proof); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// protobuf wire types
+const (
+	protoVarint = 0
+	protoBytes  = 2
+)
+
+// MarshalProto encodes the query share as a QueryShare message
+func (query *QueryShare) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	if err := writeQueryShare(&protoFieldWriter{w: w}, query); err != nil {
+		return nil, err
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalProto decodes a QueryShare message
+func (query *QueryShare) UnmarshalProto(data []byte) error {
+
+	res := &QueryShare{}
+	var keyBytes []byte
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			res.IsTwoParty = r.readBool()
+		case 2:
+			res.IsKeywordBased = r.readBool()
+		case 3:
+			res.ShareNumber = uint(r.readUint())
+		case 4:
+			res.GroupSize = int(r.readUint())
+		case 5:
+			res.PrfKeys = append(res.PrfKeys, &dpf.PrfKey{Bytes: r.readBytes()})
+		case 6:
+			keyBytes = r.readBytes()
+		case 7:
+			res.Region = r.readRegion()
+		case 8:
+			res.PrefixBits = uint(r.readUint())
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	if err := res.setDPFKey(keyBytes); err != nil {
+		return err
+	}
+
+	*query = *res
+	return nil
+}
+
+// MarshalProto encodes the result share as a SecretSharedQueryResult message
+func (res *SecretSharedQueryResult) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeUint(1, uint64(res.SlotBytes))
+	for _, share := range res.Shares {
+		w.writeRepeatedBytes(2, share.Data)
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalProto decodes a SecretSharedQueryResult message
+func (res *SecretSharedQueryResult) UnmarshalProto(data []byte) error {
+
+	dec := &SecretSharedQueryResult{Shares: []*Slot{}}
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			dec.SlotBytes = int(r.readUint())
+		case 2:
+			dec.Shares = append(dec.Shares, NewSlot(r.readBytes()))
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	*res = *dec
+	return nil
+}
+
+// MarshalProto encodes the encrypted query (without the public key and the query proof)
+// as an EncryptedQuery message
+func (query *EncryptedQuery) MarshalProto() ([]byte, error) {
+	return protoEncryptedQuery(query).buf, nil
+}
+
+// UnmarshalEncryptedQueryProto decodes an EncryptedQuery message encrypted under pk
+func UnmarshalEncryptedQueryProto(data []byte, pk *paillier.PublicKey) (*EncryptedQuery, error) {
+
+	r := &protoReader{buf: data}
+	query := r.readEncryptedQuery(pk)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return query, nil
+}
+
+// MarshalProto encodes the encrypted result (without the public key)
+// as an EncryptedQueryResult message
+func (res *EncryptedQueryResult) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeUint(1, uint64(res.SlotBytes))
+	w.writeUint(2, uint64(res.NumBytesPerCiphertext))
+	for _, eslot := range res.Slots {
+		w.writeMessage(3, protoEncryptedSlot(eslot.Cts))
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalEncryptedQueryResultProto decodes an EncryptedQueryResult message encrypted under pk
+func UnmarshalEncryptedQueryResultProto(data []byte, pk *paillier.PublicKey) (*EncryptedQueryResult, error) {
+
+	res := &EncryptedQueryResult{Pk: pk, Slots: []*EncryptedSlot{}}
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			res.SlotBytes = int(r.readUint())
+		case 2:
+			res.NumBytesPerCiphertext = int(r.readUint())
+		case 3:
+			res.Slots = append(res.Slots, &EncryptedSlot{Cts: r.readEncryptedSlot()})
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return res, nil
+}
+
+// MarshalProto encodes the doubly encrypted query (without the public key)
+// as a DoublyEncryptedQuery message
+func (query *DoublyEncryptedQuery) MarshalProto() ([]byte, error) {
+	return protoDoublyEncryptedQuery(query).buf, nil
+}
+
+// UnmarshalDoublyEncryptedQueryProto decodes a DoublyEncryptedQuery message encrypted under pk
+func UnmarshalDoublyEncryptedQueryProto(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQuery, error) {
+
+	r := &protoReader{buf: data}
+	query := r.readDoublyEncryptedQuery(pk)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return query, nil
+}
+
+// MarshalProto encodes the doubly encrypted result (without the public key)
+// as a DoublyEncryptedQueryResult message
+func (res *DoublyEncryptedQueryResult) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeUint(1, uint64(res.SlotBytes))
+	w.writeUint(2, uint64(res.NumBytesPerCiphertext))
+	w.writeUint(3, uint64(res.GroupSize))
+	w.writeColumnMask(4, 5, res.ColumnMask)
+	for _, slot := range res.Slots {
+		w.writeMessage(6, protoEncryptedSlot(slot.Cts))
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalDoublyEncryptedQueryResultProto decodes a DoublyEncryptedQueryResult
+// message encrypted under pk
+func UnmarshalDoublyEncryptedQueryResultProto(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQueryResult, error) {
+
+	res := &DoublyEncryptedQueryResult{Pk: pk, Slots: []*DoublyEncryptedSlot{}}
+	hasMask := false
+	var mask []bool
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			res.SlotBytes = int(r.readUint())
+		case 2:
+			res.NumBytesPerCiphertext = int(r.readUint())
+		case 3:
+			res.GroupSize = int(r.readUint())
+		case 4:
+			hasMask = r.readBool()
+		case 5:
+			mask = r.readBools(mask)
+		case 6:
+			res.Slots = append(res.Slots, &DoublyEncryptedSlot{Cts: r.readEncryptedSlot()})
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	res.ColumnMask = columnMask(hasMask, mask)
+
+	return res, nil
+}
+
+// MarshalProto encodes the authenticated query (without the public key)
+// as an AuthenticatedEncryptedQuery message
+func (query *AuthenticatedEncryptedQuery) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeMessage(1, protoDoublyEncryptedQuery(query.Query0))
+	w.writeMessage(2, protoDoublyEncryptedQuery(query.Query1))
+	w.writeMessage(3, protoCommitment(query.AuthTokenComm0))
+	w.writeMessage(4, protoCommitment(query.AuthTokenComm1))
+
+	return w.buf, nil
+}
+
+// UnmarshalAuthenticatedEncryptedQueryProto decodes an AuthenticatedEncryptedQuery
+// message encrypted under pk
+func UnmarshalAuthenticatedEncryptedQueryProto(data []byte, pk *paillier.PublicKey) (*AuthenticatedEncryptedQuery, error) {
+
+	query := &AuthenticatedEncryptedQuery{}
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			query.Query0 = r.readDoublyEncryptedQueryMessage(pk)
+		case 2:
+			query.Query1 = r.readDoublyEncryptedQueryMessage(pk)
+		case 3:
+			query.AuthTokenComm0 = r.readCommitment()
+		case 4:
+			query.AuthTokenComm1 = r.readCommitment()
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err == nil && (query.Query0 == nil || query.Query1 == nil ||
+		query.AuthTokenComm0 == nil || query.AuthTokenComm1 == nil) {
+		r.err = errMalformedProto
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return query, nil
+}
+
+// MarshalProto encodes the challenge as a ChalToken message
+func (chal *ChalToken) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeMessage(1, protoCiphertext(chal.Token0))
+	w.writeMessage(2, protoCiphertext(chal.Token1))
+	w.writeUint(3, uint64(chal.SecParam))
+
+	return w.buf, nil
+}
+
+// UnmarshalProto decodes a ChalToken message
+func (chal *ChalToken) UnmarshalProto(data []byte) error {
+
+	res := &ChalToken{}
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			res.Token0 = r.readCiphertext()
+		case 2:
+			res.Token1 = r.readCiphertext()
+		case 3:
+			res.SecParam = int(r.readUint())
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err == nil && (res.Token0 == nil || res.Token1 == nil) {
+		r.err = errMalformedProto
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	*chal = *res
+	return nil
+}
+
+// MarshalProto encodes the proof as a ProofToken message
+func (proof *ProofToken) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeMessage(1, protoCiphertext(proof.AuthToken))
+	w.writeMessage(2, protoCiphertext(proof.T))
+	if proof.P != nil {
+		data, err := marshalDDLEQProof(proof.P)
+		if err != nil {
+			return nil, err
+		}
+		w.writeBytes(3, data)
+	}
+	w.writeUint(4, uint64(proof.QBit))
+	w.writeBytes(5, proof.R.Bytes())
+	w.writeBytes(6, proof.S.Bytes())
+
+	return w.buf, nil
+}
+
+// UnmarshalProto decodes a ProofToken message
+func (proof *ProofToken) UnmarshalProto(data []byte) error {
+
+	res := &ProofToken{R: new(gmp.Int), S: new(gmp.Int)}
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			res.AuthToken = r.readCiphertext()
+		case 2:
+			res.T = r.readCiphertext()
+		case 3:
+			p, err := unmarshalDDLEQProof(r.readBytes())
+			if err != nil && r.err == nil {
+				r.err = errMalformedProto
+			}
+			res.P = p
+		case 4:
+			res.QBit = int(r.readUint())
+		case 5:
+			res.R.SetBytes(r.readBytes())
+		case 6:
+			res.S.SetBytes(r.readBytes())
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err == nil && (res.AuthToken == nil || res.T == nil || res.P == nil) {
+		r.err = errMalformedProto
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// MarshalProto encodes the authenticated query share as an AuthenticatedQueryShare message
+func (query *AuthenticatedQueryShare) MarshalProto() ([]byte, error) {
+
+	share, err := query.QueryShare.MarshalProto()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &protoWriter{}
+	w.writeMessage(1, &protoWriter{buf: share})
+	w.writeRepeatedBytes(2, query.AuthToken.T.Data)
+
+	return w.buf, nil
+}
+
+// UnmarshalProto decodes an AuthenticatedQueryShare message
+func (query *AuthenticatedQueryShare) UnmarshalProto(data []byte) error {
+
+	res := &AuthenticatedQueryShare{}
+	var share []byte
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			share = r.readBytes()
+		case 2:
+			res.AuthToken = &AuthTokenShare{T: NewSlot(r.readBytes())}
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err == nil && (share == nil || res.AuthToken == nil) {
+		r.err = errMalformedProto
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	res.QueryShare = &QueryShare{}
+	if err := res.QueryShare.UnmarshalProto(share); err != nil {
+		return err
+	}
+
+	*query = *res
+	return nil
+}
+
+// MarshalProto encodes the audit token share as an AuditTokenShare message
+func (audit *AuditTokenShare) MarshalProto() ([]byte, error) {
+
+	w := &protoWriter{}
+	w.writeRepeatedBytes(1, audit.T.Data)
+	w.writeBytes(2, audit.DigestCommitment)
+
+	return w.buf, nil
+}
+
+// UnmarshalProto decodes an AuditTokenShare message
+func (audit *AuditTokenShare) UnmarshalProto(data []byte) error {
+
+	res := &AuditTokenShare{T: NewSlot([]byte{})}
+
+	r := &protoReader{buf: data}
+	for r.next() {
+		switch r.field {
+		case 1:
+			res.T = NewSlot(r.readBytes())
+		case 2:
+			res.DigestCommitment = r.readBytes()
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	*audit = *res
+	return nil
+}
+
+// message encoders shared by several messages
+
+func protoCiphertext(ct *paillier.Ciphertext) *protoWriter {
+	w := &protoWriter{}
+	w.writeUint(1, uint64(ct.Level))
+	w.writeBytes(2, ct.C.Bytes())
+	return w
+}
+
+func protoEncryptedSlot(cts []*paillier.Ciphertext) *protoWriter {
+	w := &protoWriter{}
+	for _, ct := range cts {
+		w.writeMessage(1, protoCiphertext(ct))
+	}
+	return w
+}
+
+func protoEncryptedQuery(query *EncryptedQuery) *protoWriter {
+	w := &protoWriter{}
+	w.writeUint(1, uint64(query.GroupSize))
+	w.writeUint(2, uint64(query.DBWidth))
+	w.writeUint(3, uint64(query.DBHeight))
+	for _, ct := range query.EBits {
+		w.writeMessage(4, protoCiphertext(ct))
+	}
+	if query.Region != nil {
+		w.writeMessage(5, protoRegion(query.Region))
+	}
+	return w
+}
+
+func protoRegion(region *GroupRegion) *protoWriter {
+	w := &protoWriter{}
+	w.writeUint(1, uint64(region.Start))
+	w.writeUint(2, uint64(region.End))
+	w.writeUint(3, uint64(region.GroupSize))
+	return w
+}
+
+func protoDoublyEncryptedQuery(query *DoublyEncryptedQuery) *protoWriter {
+	w := &protoWriter{}
+	w.writeMessage(1, protoEncryptedQuery(query.Row))
+	w.writeMessage(2, protoEncryptedQuery(query.Col))
+	w.writeColumnMask(3, 4, query.ColumnMask)
+	return w
+}
+
+func protoCommitment(comm *ROCommitment) *protoWriter {
+	w := &protoWriter{}
+	w.writeBytes(1, comm.HashBytes)
+	w.writeBytes(2, comm.R.Bytes())
+	return w
+}
+
+// columnMask returns the decoded mask (nil unless the message has one)
+func columnMask(hasMask bool, mask []bool) []bool {
+	if !hasMask {
+		return nil
+	}
+	if mask == nil {
+		return []bool{}
+	}
+	return mask
+}
+
+// protoWriter appends protobuf fields to a buffer.
+// As in proto3, singular fields with the default value are omitted
+type protoWriter struct {
+	buf []byte
+}
+
+func (w *protoWriter) writeTag(field int, wireType int) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (w *protoWriter) writeUint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	w.writeTag(field, protoVarint)
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *protoWriter) writeBool(field int, b bool) {
+	if b {
+		w.writeUint(field, 1)
+	}
+}
+
+func (w *protoWriter) writeBytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	w.writeRepeatedBytes(field, b)
+}
+
+// writeRepeatedBytes writes the field even if b is empty (elements of repeated fields)
+func (w *protoWriter) writeRepeatedBytes(field int, b []byte) {
+	w.writeTag(field, protoBytes)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *protoWriter) writeMessage(field int, m *protoWriter) {
+	w.writeRepeatedBytes(field, m.buf)
+}
+
+// protoFieldWriter writes the fields of a message to w in order
+// and numbers them consecutively from 1 (see writeQueryShare)
+type protoFieldWriter struct {
+	w     *protoWriter
+	field int
+}
+
+func (f *protoFieldWriter) nextField() int {
+	f.field++
+	return f.field
+}
+
+func (f *protoFieldWriter) writeBool(b bool) {
+	f.w.writeBool(f.nextField(), b)
+}
+
+func (f *protoFieldWriter) writeUint(v uint64) {
+	f.w.writeUint(f.nextField(), v)
+}
+
+func (f *protoFieldWriter) writeBytes(b []byte) {
+	f.w.writeBytes(f.nextField(), b)
+}
+
+func (f *protoFieldWriter) writeBytesList(bs [][]byte) {
+	field := f.nextField()
+	for _, b := range bs {
+		f.w.writeRepeatedBytes(field, b)
+	}
+}
+
+func (f *protoFieldWriter) writeRegion(region *GroupRegion) {
+	field := f.nextField()
+	if region != nil {
+		f.w.writeMessage(field, protoRegion(region))
+	}
+}
+
+// writeColumnMask writes the (optional) column mask as a presence flag and packed bools
+func (w *protoWriter) writeColumnMask(hasField, maskField int, mask []bool) {
+
+	w.writeBool(hasField, mask != nil)
+	if len(mask) == 0 {
+		return
+	}
+
+	packed := make([]byte, len(mask))
+	for i, b := range mask {
+		if b {
+			packed[i] = 1
+		}
+	}
+	w.writeRepeatedBytes(maskField, packed)
+}
+
+// protoReader iterates over the fields of a protobuf message
+// and records the first error encountered.
+// Unknown fields are skipped such that the schema can be extended
+type protoReader struct {
+	buf      []byte
+	err      error
+	field    int
+	wireType int
+}
+
+// next reads the tag of the next field and returns false at the end of the message
+func (r *protoReader) next() bool {
+
+	if r.err != nil || len(r.buf) == 0 {
+		return false
+	}
+
+	tag := r.varint()
+	if r.err != nil {
+		return false
+	}
+
+	r.field = int(tag >> 3)
+	r.wireType = int(tag & 7)
+
+	if r.field == 0 {
+		r.err = errMalformedProto
+		return false
+	}
+
+	return true
+}
+
+func (r *protoReader) varint() uint64 {
+
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errMalformedProto
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *protoReader) readUint() uint64 {
+
+	if r.wireType != protoVarint {
+		r.err = errMalformedProto
+		return 0
+	}
+
+	return r.varint()
+}
+
+func (r *protoReader) readBool() bool {
+	return r.readUint() != 0
+}
+
+func (r *protoReader) readBytes() []byte {
+
+	if r.wireType != protoBytes {
+		r.err = errMalformedProto
+		return nil
+	}
+
+	n := r.varint()
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = errMalformedProto
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	b := append([]byte{}, r.buf[:n]...)
+	r.buf = r.buf[n:]
+	return b
+}
+
+// readMessage returns a reader over the embedded message of the current field
+// (errors of the embedded reader are propagated with merge)
+func (r *protoReader) readMessage() *protoReader {
+	return &protoReader{buf: r.readBytes(), err: r.err}
+}
+
+func (r *protoReader) merge(sub *protoReader) {
+	if r.err == nil {
+		r.err = sub.err
+	}
+}
+
+// readBools appends the elements of a repeated bool field
+// (in packed or unpacked encoding) to bools
+func (r *protoReader) readBools(bools []bool) []bool {
+
+	if r.wireType == protoVarint {
+		return append(bools, r.readBool())
+	}
+
+	packed := &protoReader{buf: r.readBytes(), err: r.err}
+	for packed.err == nil && len(packed.buf) > 0 {
+		bools = append(bools, packed.varint() != 0)
+	}
+	r.merge(packed)
+
+	return bools
+}
+
+// skip skips the value of the current (unknown) field
+func (r *protoReader) skip() {
+
+	switch r.wireType {
+	case protoVarint:
+		r.varint()
+	case protoBytes:
+		r.readBytes()
+	case 1, 5: // fixed64 and fixed32
+		size := 8
+		if r.wireType == 5 {
+			size = 4
+		}
+		if len(r.buf) < size {
+			r.err = errMalformedProto
+			return
+		}
+		r.buf = r.buf[size:]
+	default:
+		r.err = errMalformedProto
+	}
+}
+
+func (r *protoReader) readCiphertext() *paillier.Ciphertext {
+
+	ct := &paillier.Ciphertext{C: new(gmp.Int)}
+
+	m := r.readMessage()
+	for m.next() {
+		switch m.field {
+		case 1:
+			ct.Level = paillier.EncryptionLevel(m.readUint())
+		case 2:
+			ct.C.SetBytes(m.readBytes())
+		default:
+			m.skip()
+		}
+	}
+	r.merge(m)
+
+	return ct
+}
+
+func (r *protoReader) readEncryptedSlot() []*paillier.Ciphertext {
+
+	cts := []*paillier.Ciphertext{}
+
+	m := r.readMessage()
+	for m.next() {
+		if m.field == 1 {
+			cts = append(cts, m.readCiphertext())
+		} else {
+			m.skip()
+		}
+	}
+	r.merge(m)
+
+	return cts
+}
+
+// readEncryptedQuery reads the fields of an EncryptedQuery message from r
+func (r *protoReader) readEncryptedQuery(pk *paillier.PublicKey) *EncryptedQuery {
+
+	query := &EncryptedQuery{Pk: pk, EBits: []*paillier.Ciphertext{}}
+	for r.next() {
+		switch r.field {
+		case 1:
+			query.GroupSize = int(r.readUint())
+		case 2:
+			query.DBWidth = int(r.readUint())
+		case 3:
+			query.DBHeight = int(r.readUint())
+		case 4:
+			query.EBits = append(query.EBits, r.readCiphertext())
+		case 5:
+			query.Region = r.readRegion()
+		default:
+			r.skip()
+		}
+	}
+
+	return query
+}
+
+// readRegion reads a GroupRegion message
+func (r *protoReader) readRegion() *GroupRegion {
+
+	region := &GroupRegion{}
+	rr := r.readMessage()
+	for rr.next() {
+		switch rr.field {
+		case 1:
+			region.Start = int(rr.readUint())
+		case 2:
+			region.End = int(rr.readUint())
+		case 3:
+			region.GroupSize = int(rr.readUint())
+		default:
+			rr.skip()
+		}
+	}
+	r.merge(rr)
+
+	return region
+}
+
+// readDoublyEncryptedQuery reads the fields of a DoublyEncryptedQuery message from r
+func (r *protoReader) readDoublyEncryptedQuery(pk *paillier.PublicKey) *DoublyEncryptedQuery {
+
+	query := &DoublyEncryptedQuery{}
+	hasMask := false
+	var mask []bool
+
+	for r.next() {
+		switch r.field {
+		case 1:
+			m := r.readMessage()
+			query.Row = m.readEncryptedQuery(pk)
+			r.merge(m)
+		case 2:
+			m := r.readMessage()
+			query.Col = m.readEncryptedQuery(pk)
+			r.merge(m)
+		case 3:
+			hasMask = r.readBool()
+		case 4:
+			mask = r.readBools(mask)
+		default:
+			r.skip()
+		}
+	}
+
+	if r.err == nil && (query.Row == nil || query.Col == nil) {
+		r.err = errMalformedProto
+	}
+
+	query.ColumnMask = columnMask(hasMask, mask)
+
+	return query
+}
+
+// readDoublyEncryptedQueryMessage reads an embedded DoublyEncryptedQuery message
+func (r *protoReader) readDoublyEncryptedQueryMessage(pk *paillier.PublicKey) *DoublyEncryptedQuery {
+	m := r.readMessage()
+	query := m.readDoublyEncryptedQuery(pk)
+	r.merge(m)
+	return query
+}
+
+func (r *protoReader) readCommitment() *ROCommitment {
+
+	comm := &ROCommitment{R: new(gmp.Int)}
+
+	m := r.readMessage()
+	for m.next() {
+		switch m.field {
+		case 1:
+			comm.HashBytes = m.readBytes()
+		case 2:
+			comm.R.SetBytes(m.readBytes())
+		default:
+			m.skip()
+		}
+	}
+	r.merge(m)
+
+	return comm
+}
@@ -0,0 +1,5 @@
+// Package pirpb contains the Go types generated from pir.proto, the schema of the
+// protobuf encodings of the pir package (see proto.go in the pir package)
+package pirpb
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative proto/pir.proto
@@ -0,0 +1,1168 @@
+// Wire format of the PIR messages (see proto.go for the Go encoders).
+//
+// pir.pb.go is generated from this file with protoc-gen-go (see gen.go); the
+// tests of the pir package check the encoders of proto.go against it.
+//
+// Big integers (ciphertexts, commitment randomness) are encoded as unsigned
+// big-endian bytes. DPF keys are encoded with the dpf package's key encoding
+// (dpf.Key2P.Bytes and dpf.KeyMP.Bytes). The Paillier public key is not part
+// of the messages; it is exchanged once ahead of time.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: proto/pir.proto
+
+package pirpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Ciphertext struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         uint32                 `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	C             []byte                 `protobuf:"bytes,2,opt,name=c,proto3" json:"c,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ciphertext) Reset() {
+	*x = Ciphertext{}
+	mi := &file_proto_pir_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ciphertext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ciphertext) ProtoMessage() {}
+
+func (x *Ciphertext) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ciphertext.ProtoReflect.Descriptor instead.
+func (*Ciphertext) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Ciphertext) GetLevel() uint32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *Ciphertext) GetC() []byte {
+	if x != nil {
+		return x.C
+	}
+	return nil
+}
+
+type GroupRegion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         uint64                 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           uint64                 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	GroupSize     uint64                 `protobuf:"varint,3,opt,name=group_size,json=groupSize,proto3" json:"group_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupRegion) Reset() {
+	*x = GroupRegion{}
+	mi := &file_proto_pir_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupRegion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupRegion) ProtoMessage() {}
+
+func (x *GroupRegion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupRegion.ProtoReflect.Descriptor instead.
+func (*GroupRegion) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GroupRegion) GetStart() uint64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *GroupRegion) GetEnd() uint64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *GroupRegion) GetGroupSize() uint64 {
+	if x != nil {
+		return x.GroupSize
+	}
+	return 0
+}
+
+type QueryShare struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IsTwoParty     bool                   `protobuf:"varint,1,opt,name=is_two_party,json=isTwoParty,proto3" json:"is_two_party,omitempty"`
+	IsKeywordBased bool                   `protobuf:"varint,2,opt,name=is_keyword_based,json=isKeywordBased,proto3" json:"is_keyword_based,omitempty"`
+	ShareNumber    uint64                 `protobuf:"varint,3,opt,name=share_number,json=shareNumber,proto3" json:"share_number,omitempty"`
+	GroupSize      uint64                 `protobuf:"varint,4,opt,name=group_size,json=groupSize,proto3" json:"group_size,omitempty"`
+	PrfKeys        [][]byte               `protobuf:"bytes,5,rep,name=prf_keys,json=prfKeys,proto3" json:"prf_keys,omitempty"`
+	DpfKey         []byte                 `protobuf:"bytes,6,opt,name=dpf_key,json=dpfKey,proto3" json:"dpf_key,omitempty"`
+	Region         *GroupRegion           `protobuf:"bytes,7,opt,name=region,proto3" json:"region,omitempty"`
+	PrefixBits     uint32                 `protobuf:"varint,8,opt,name=prefix_bits,json=prefixBits,proto3" json:"prefix_bits,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *QueryShare) Reset() {
+	*x = QueryShare{}
+	mi := &file_proto_pir_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryShare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryShare) ProtoMessage() {}
+
+func (x *QueryShare) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryShare.ProtoReflect.Descriptor instead.
+func (*QueryShare) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryShare) GetIsTwoParty() bool {
+	if x != nil {
+		return x.IsTwoParty
+	}
+	return false
+}
+
+func (x *QueryShare) GetIsKeywordBased() bool {
+	if x != nil {
+		return x.IsKeywordBased
+	}
+	return false
+}
+
+func (x *QueryShare) GetShareNumber() uint64 {
+	if x != nil {
+		return x.ShareNumber
+	}
+	return 0
+}
+
+func (x *QueryShare) GetGroupSize() uint64 {
+	if x != nil {
+		return x.GroupSize
+	}
+	return 0
+}
+
+func (x *QueryShare) GetPrfKeys() [][]byte {
+	if x != nil {
+		return x.PrfKeys
+	}
+	return nil
+}
+
+func (x *QueryShare) GetDpfKey() []byte {
+	if x != nil {
+		return x.DpfKey
+	}
+	return nil
+}
+
+func (x *QueryShare) GetRegion() *GroupRegion {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+func (x *QueryShare) GetPrefixBits() uint32 {
+	if x != nil {
+		return x.PrefixBits
+	}
+	return 0
+}
+
+type SecretSharedQueryResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SlotBytes     uint64                 `protobuf:"varint,1,opt,name=slot_bytes,json=slotBytes,proto3" json:"slot_bytes,omitempty"`
+	Shares        [][]byte               `protobuf:"bytes,2,rep,name=shares,proto3" json:"shares,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretSharedQueryResult) Reset() {
+	*x = SecretSharedQueryResult{}
+	mi := &file_proto_pir_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretSharedQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretSharedQueryResult) ProtoMessage() {}
+
+func (x *SecretSharedQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretSharedQueryResult.ProtoReflect.Descriptor instead.
+func (*SecretSharedQueryResult) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SecretSharedQueryResult) GetSlotBytes() uint64 {
+	if x != nil {
+		return x.SlotBytes
+	}
+	return 0
+}
+
+func (x *SecretSharedQueryResult) GetShares() [][]byte {
+	if x != nil {
+		return x.Shares
+	}
+	return nil
+}
+
+type EncryptedQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupSize     uint64                 `protobuf:"varint,1,opt,name=group_size,json=groupSize,proto3" json:"group_size,omitempty"`
+	DbWidth       uint64                 `protobuf:"varint,2,opt,name=db_width,json=dbWidth,proto3" json:"db_width,omitempty"`
+	DbHeight      uint64                 `protobuf:"varint,3,opt,name=db_height,json=dbHeight,proto3" json:"db_height,omitempty"`
+	Ebits         []*Ciphertext          `protobuf:"bytes,4,rep,name=ebits,proto3" json:"ebits,omitempty"`
+	Region        *GroupRegion           `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncryptedQuery) Reset() {
+	*x = EncryptedQuery{}
+	mi := &file_proto_pir_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncryptedQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedQuery) ProtoMessage() {}
+
+func (x *EncryptedQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedQuery.ProtoReflect.Descriptor instead.
+func (*EncryptedQuery) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EncryptedQuery) GetGroupSize() uint64 {
+	if x != nil {
+		return x.GroupSize
+	}
+	return 0
+}
+
+func (x *EncryptedQuery) GetDbWidth() uint64 {
+	if x != nil {
+		return x.DbWidth
+	}
+	return 0
+}
+
+func (x *EncryptedQuery) GetDbHeight() uint64 {
+	if x != nil {
+		return x.DbHeight
+	}
+	return 0
+}
+
+func (x *EncryptedQuery) GetEbits() []*Ciphertext {
+	if x != nil {
+		return x.Ebits
+	}
+	return nil
+}
+
+func (x *EncryptedQuery) GetRegion() *GroupRegion {
+	if x != nil {
+		return x.Region
+	}
+	return nil
+}
+
+type EncryptedSlot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cts           []*Ciphertext          `protobuf:"bytes,1,rep,name=cts,proto3" json:"cts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncryptedSlot) Reset() {
+	*x = EncryptedSlot{}
+	mi := &file_proto_pir_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncryptedSlot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedSlot) ProtoMessage() {}
+
+func (x *EncryptedSlot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedSlot.ProtoReflect.Descriptor instead.
+func (*EncryptedSlot) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EncryptedSlot) GetCts() []*Ciphertext {
+	if x != nil {
+		return x.Cts
+	}
+	return nil
+}
+
+type EncryptedQueryResult struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	SlotBytes             uint64                 `protobuf:"varint,1,opt,name=slot_bytes,json=slotBytes,proto3" json:"slot_bytes,omitempty"`
+	NumBytesPerCiphertext uint64                 `protobuf:"varint,2,opt,name=num_bytes_per_ciphertext,json=numBytesPerCiphertext,proto3" json:"num_bytes_per_ciphertext,omitempty"`
+	Slots                 []*EncryptedSlot       `protobuf:"bytes,3,rep,name=slots,proto3" json:"slots,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *EncryptedQueryResult) Reset() {
+	*x = EncryptedQueryResult{}
+	mi := &file_proto_pir_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncryptedQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedQueryResult) ProtoMessage() {}
+
+func (x *EncryptedQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedQueryResult.ProtoReflect.Descriptor instead.
+func (*EncryptedQueryResult) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EncryptedQueryResult) GetSlotBytes() uint64 {
+	if x != nil {
+		return x.SlotBytes
+	}
+	return 0
+}
+
+func (x *EncryptedQueryResult) GetNumBytesPerCiphertext() uint64 {
+	if x != nil {
+		return x.NumBytesPerCiphertext
+	}
+	return 0
+}
+
+func (x *EncryptedQueryResult) GetSlots() []*EncryptedSlot {
+	if x != nil {
+		return x.Slots
+	}
+	return nil
+}
+
+type DoublyEncryptedQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Row           *EncryptedQuery        `protobuf:"bytes,1,opt,name=row,proto3" json:"row,omitempty"`
+	Col           *EncryptedQuery        `protobuf:"bytes,2,opt,name=col,proto3" json:"col,omitempty"`
+	HasColumnMask bool                   `protobuf:"varint,3,opt,name=has_column_mask,json=hasColumnMask,proto3" json:"has_column_mask,omitempty"`
+	ColumnMask    []bool                 `protobuf:"varint,4,rep,packed,name=column_mask,json=columnMask,proto3" json:"column_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DoublyEncryptedQuery) Reset() {
+	*x = DoublyEncryptedQuery{}
+	mi := &file_proto_pir_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DoublyEncryptedQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DoublyEncryptedQuery) ProtoMessage() {}
+
+func (x *DoublyEncryptedQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DoublyEncryptedQuery.ProtoReflect.Descriptor instead.
+func (*DoublyEncryptedQuery) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DoublyEncryptedQuery) GetRow() *EncryptedQuery {
+	if x != nil {
+		return x.Row
+	}
+	return nil
+}
+
+func (x *DoublyEncryptedQuery) GetCol() *EncryptedQuery {
+	if x != nil {
+		return x.Col
+	}
+	return nil
+}
+
+func (x *DoublyEncryptedQuery) GetHasColumnMask() bool {
+	if x != nil {
+		return x.HasColumnMask
+	}
+	return false
+}
+
+func (x *DoublyEncryptedQuery) GetColumnMask() []bool {
+	if x != nil {
+		return x.ColumnMask
+	}
+	return nil
+}
+
+type DoublyEncryptedQueryResult struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	SlotBytes             uint64                 `protobuf:"varint,1,opt,name=slot_bytes,json=slotBytes,proto3" json:"slot_bytes,omitempty"`
+	NumBytesPerCiphertext uint64                 `protobuf:"varint,2,opt,name=num_bytes_per_ciphertext,json=numBytesPerCiphertext,proto3" json:"num_bytes_per_ciphertext,omitempty"`
+	GroupSize             uint64                 `protobuf:"varint,3,opt,name=group_size,json=groupSize,proto3" json:"group_size,omitempty"`
+	HasColumnMask         bool                   `protobuf:"varint,4,opt,name=has_column_mask,json=hasColumnMask,proto3" json:"has_column_mask,omitempty"`
+	ColumnMask            []bool                 `protobuf:"varint,5,rep,packed,name=column_mask,json=columnMask,proto3" json:"column_mask,omitempty"`
+	Slots                 []*EncryptedSlot       `protobuf:"bytes,6,rep,name=slots,proto3" json:"slots,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *DoublyEncryptedQueryResult) Reset() {
+	*x = DoublyEncryptedQueryResult{}
+	mi := &file_proto_pir_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DoublyEncryptedQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DoublyEncryptedQueryResult) ProtoMessage() {}
+
+func (x *DoublyEncryptedQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DoublyEncryptedQueryResult.ProtoReflect.Descriptor instead.
+func (*DoublyEncryptedQueryResult) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DoublyEncryptedQueryResult) GetSlotBytes() uint64 {
+	if x != nil {
+		return x.SlotBytes
+	}
+	return 0
+}
+
+func (x *DoublyEncryptedQueryResult) GetNumBytesPerCiphertext() uint64 {
+	if x != nil {
+		return x.NumBytesPerCiphertext
+	}
+	return 0
+}
+
+func (x *DoublyEncryptedQueryResult) GetGroupSize() uint64 {
+	if x != nil {
+		return x.GroupSize
+	}
+	return 0
+}
+
+func (x *DoublyEncryptedQueryResult) GetHasColumnMask() bool {
+	if x != nil {
+		return x.HasColumnMask
+	}
+	return false
+}
+
+func (x *DoublyEncryptedQueryResult) GetColumnMask() []bool {
+	if x != nil {
+		return x.ColumnMask
+	}
+	return nil
+}
+
+func (x *DoublyEncryptedQueryResult) GetSlots() []*EncryptedSlot {
+	if x != nil {
+		return x.Slots
+	}
+	return nil
+}
+
+type Commitment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	R             []byte                 `protobuf:"bytes,2,opt,name=r,proto3" json:"r,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Commitment) Reset() {
+	*x = Commitment{}
+	mi := &file_proto_pir_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Commitment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Commitment) ProtoMessage() {}
+
+func (x *Commitment) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Commitment.ProtoReflect.Descriptor instead.
+func (*Commitment) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Commitment) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *Commitment) GetR() []byte {
+	if x != nil {
+		return x.R
+	}
+	return nil
+}
+
+type AuthenticatedEncryptedQuery struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Query0         *DoublyEncryptedQuery  `protobuf:"bytes,1,opt,name=query0,proto3" json:"query0,omitempty"`
+	Query1         *DoublyEncryptedQuery  `protobuf:"bytes,2,opt,name=query1,proto3" json:"query1,omitempty"`
+	AuthTokenComm0 *Commitment            `protobuf:"bytes,3,opt,name=auth_token_comm0,json=authTokenComm0,proto3" json:"auth_token_comm0,omitempty"`
+	AuthTokenComm1 *Commitment            `protobuf:"bytes,4,opt,name=auth_token_comm1,json=authTokenComm1,proto3" json:"auth_token_comm1,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AuthenticatedEncryptedQuery) Reset() {
+	*x = AuthenticatedEncryptedQuery{}
+	mi := &file_proto_pir_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthenticatedEncryptedQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthenticatedEncryptedQuery) ProtoMessage() {}
+
+func (x *AuthenticatedEncryptedQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthenticatedEncryptedQuery.ProtoReflect.Descriptor instead.
+func (*AuthenticatedEncryptedQuery) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AuthenticatedEncryptedQuery) GetQuery0() *DoublyEncryptedQuery {
+	if x != nil {
+		return x.Query0
+	}
+	return nil
+}
+
+func (x *AuthenticatedEncryptedQuery) GetQuery1() *DoublyEncryptedQuery {
+	if x != nil {
+		return x.Query1
+	}
+	return nil
+}
+
+func (x *AuthenticatedEncryptedQuery) GetAuthTokenComm0() *Commitment {
+	if x != nil {
+		return x.AuthTokenComm0
+	}
+	return nil
+}
+
+func (x *AuthenticatedEncryptedQuery) GetAuthTokenComm1() *Commitment {
+	if x != nil {
+		return x.AuthTokenComm1
+	}
+	return nil
+}
+
+type ChalToken struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token0        *Ciphertext            `protobuf:"bytes,1,opt,name=token0,proto3" json:"token0,omitempty"`
+	Token1        *Ciphertext            `protobuf:"bytes,2,opt,name=token1,proto3" json:"token1,omitempty"`
+	SecParam      uint64                 `protobuf:"varint,3,opt,name=sec_param,json=secParam,proto3" json:"sec_param,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChalToken) Reset() {
+	*x = ChalToken{}
+	mi := &file_proto_pir_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChalToken) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChalToken) ProtoMessage() {}
+
+func (x *ChalToken) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChalToken.ProtoReflect.Descriptor instead.
+func (*ChalToken) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ChalToken) GetToken0() *Ciphertext {
+	if x != nil {
+		return x.Token0
+	}
+	return nil
+}
+
+func (x *ChalToken) GetToken1() *Ciphertext {
+	if x != nil {
+		return x.Token1
+	}
+	return nil
+}
+
+func (x *ChalToken) GetSecParam() uint64 {
+	if x != nil {
+		return x.SecParam
+	}
+	return 0
+}
+
+type ProofToken struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	AuthToken *Ciphertext            `protobuf:"bytes,1,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	T         *Ciphertext            `protobuf:"bytes,2,opt,name=t,proto3" json:"t,omitempty"`
+	// DDLEQ proof of the paillier library in the encoding/gob encoding
+	// (the library does not define a wire format for its proofs)
+	Proof         []byte `protobuf:"bytes,3,opt,name=proof,proto3" json:"proof,omitempty"`
+	QueryBit      uint32 `protobuf:"varint,4,opt,name=query_bit,json=queryBit,proto3" json:"query_bit,omitempty"`
+	R             []byte `protobuf:"bytes,5,opt,name=r,proto3" json:"r,omitempty"`
+	S             []byte `protobuf:"bytes,6,opt,name=s,proto3" json:"s,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProofToken) Reset() {
+	*x = ProofToken{}
+	mi := &file_proto_pir_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProofToken) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProofToken) ProtoMessage() {}
+
+func (x *ProofToken) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProofToken.ProtoReflect.Descriptor instead.
+func (*ProofToken) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ProofToken) GetAuthToken() *Ciphertext {
+	if x != nil {
+		return x.AuthToken
+	}
+	return nil
+}
+
+func (x *ProofToken) GetT() *Ciphertext {
+	if x != nil {
+		return x.T
+	}
+	return nil
+}
+
+func (x *ProofToken) GetProof() []byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *ProofToken) GetQueryBit() uint32 {
+	if x != nil {
+		return x.QueryBit
+	}
+	return 0
+}
+
+func (x *ProofToken) GetR() []byte {
+	if x != nil {
+		return x.R
+	}
+	return nil
+}
+
+func (x *ProofToken) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+type AuthenticatedQueryShare struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         *QueryShare            `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	AuthToken     []byte                 `protobuf:"bytes,2,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthenticatedQueryShare) Reset() {
+	*x = AuthenticatedQueryShare{}
+	mi := &file_proto_pir_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthenticatedQueryShare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthenticatedQueryShare) ProtoMessage() {}
+
+func (x *AuthenticatedQueryShare) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthenticatedQueryShare.ProtoReflect.Descriptor instead.
+func (*AuthenticatedQueryShare) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AuthenticatedQueryShare) GetQuery() *QueryShare {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *AuthenticatedQueryShare) GetAuthToken() []byte {
+	if x != nil {
+		return x.AuthToken
+	}
+	return nil
+}
+
+type AuditTokenShare struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	T                []byte                 `protobuf:"bytes,1,opt,name=t,proto3" json:"t,omitempty"`
+	DigestCommitment []byte                 `protobuf:"bytes,2,opt,name=digest_commitment,json=digestCommitment,proto3" json:"digest_commitment,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *AuditTokenShare) Reset() {
+	*x = AuditTokenShare{}
+	mi := &file_proto_pir_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditTokenShare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditTokenShare) ProtoMessage() {}
+
+func (x *AuditTokenShare) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_pir_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditTokenShare.ProtoReflect.Descriptor instead.
+func (*AuditTokenShare) Descriptor() ([]byte, []int) {
+	return file_proto_pir_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AuditTokenShare) GetT() []byte {
+	if x != nil {
+		return x.T
+	}
+	return nil
+}
+
+func (x *AuditTokenShare) GetDigestCommitment() []byte {
+	if x != nil {
+		return x.DigestCommitment
+	}
+	return nil
+}
+
+var File_proto_pir_proto protoreflect.FileDescriptor
+
+const file_proto_pir_proto_rawDesc = "" +
+	"\n" +
+	"\x0fproto/pir.proto\x12\x03pir\"0\n" +
+	"\n" +
+	"Ciphertext\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\rR\x05level\x12\f\n" +
+	"\x01c\x18\x02 \x01(\fR\x01c\"T\n" +
+	"\vGroupRegion\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x04R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x04R\x03end\x12\x1d\n" +
+	"\n" +
+	"group_size\x18\x03 \x01(\x04R\tgroupSize\"\x99\x02\n" +
+	"\n" +
+	"QueryShare\x12 \n" +
+	"\fis_two_party\x18\x01 \x01(\bR\n" +
+	"isTwoParty\x12(\n" +
+	"\x10is_keyword_based\x18\x02 \x01(\bR\x0eisKeywordBased\x12!\n" +
+	"\fshare_number\x18\x03 \x01(\x04R\vshareNumber\x12\x1d\n" +
+	"\n" +
+	"group_size\x18\x04 \x01(\x04R\tgroupSize\x12\x19\n" +
+	"\bprf_keys\x18\x05 \x03(\fR\aprfKeys\x12\x17\n" +
+	"\adpf_key\x18\x06 \x01(\fR\x06dpfKey\x12(\n" +
+	"\x06region\x18\a \x01(\v2\x10.pir.GroupRegionR\x06region\x12\x1f\n" +
+	"\vprefix_bits\x18\b \x01(\rR\n" +
+	"prefixBits\"P\n" +
+	"\x17SecretSharedQueryResult\x12\x1d\n" +
+	"\n" +
+	"slot_bytes\x18\x01 \x01(\x04R\tslotBytes\x12\x16\n" +
+	"\x06shares\x18\x02 \x03(\fR\x06shares\"\xb8\x01\n" +
+	"\x0eEncryptedQuery\x12\x1d\n" +
+	"\n" +
+	"group_size\x18\x01 \x01(\x04R\tgroupSize\x12\x19\n" +
+	"\bdb_width\x18\x02 \x01(\x04R\adbWidth\x12\x1b\n" +
+	"\tdb_height\x18\x03 \x01(\x04R\bdbHeight\x12%\n" +
+	"\x05ebits\x18\x04 \x03(\v2\x0f.pir.CiphertextR\x05ebits\x12(\n" +
+	"\x06region\x18\x05 \x01(\v2\x10.pir.GroupRegionR\x06region\"2\n" +
+	"\rEncryptedSlot\x12!\n" +
+	"\x03cts\x18\x01 \x03(\v2\x0f.pir.CiphertextR\x03cts\"\x98\x01\n" +
+	"\x14EncryptedQueryResult\x12\x1d\n" +
+	"\n" +
+	"slot_bytes\x18\x01 \x01(\x04R\tslotBytes\x127\n" +
+	"\x18num_bytes_per_ciphertext\x18\x02 \x01(\x04R\x15numBytesPerCiphertext\x12(\n" +
+	"\x05slots\x18\x03 \x03(\v2\x12.pir.EncryptedSlotR\x05slots\"\xad\x01\n" +
+	"\x14DoublyEncryptedQuery\x12%\n" +
+	"\x03row\x18\x01 \x01(\v2\x13.pir.EncryptedQueryR\x03row\x12%\n" +
+	"\x03col\x18\x02 \x01(\v2\x13.pir.EncryptedQueryR\x03col\x12&\n" +
+	"\x0fhas_column_mask\x18\x03 \x01(\bR\rhasColumnMask\x12\x1f\n" +
+	"\vcolumn_mask\x18\x04 \x03(\bR\n" +
+	"columnMask\"\x86\x02\n" +
+	"\x1aDoublyEncryptedQueryResult\x12\x1d\n" +
+	"\n" +
+	"slot_bytes\x18\x01 \x01(\x04R\tslotBytes\x127\n" +
+	"\x18num_bytes_per_ciphertext\x18\x02 \x01(\x04R\x15numBytesPerCiphertext\x12\x1d\n" +
+	"\n" +
+	"group_size\x18\x03 \x01(\x04R\tgroupSize\x12&\n" +
+	"\x0fhas_column_mask\x18\x04 \x01(\bR\rhasColumnMask\x12\x1f\n" +
+	"\vcolumn_mask\x18\x05 \x03(\bR\n" +
+	"columnMask\x12(\n" +
+	"\x05slots\x18\x06 \x03(\v2\x12.pir.EncryptedSlotR\x05slots\".\n" +
+	"\n" +
+	"Commitment\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\fR\x04hash\x12\f\n" +
+	"\x01r\x18\x02 \x01(\fR\x01r\"\xf9\x01\n" +
+	"\x1bAuthenticatedEncryptedQuery\x121\n" +
+	"\x06query0\x18\x01 \x01(\v2\x19.pir.DoublyEncryptedQueryR\x06query0\x121\n" +
+	"\x06query1\x18\x02 \x01(\v2\x19.pir.DoublyEncryptedQueryR\x06query1\x129\n" +
+	"\x10auth_token_comm0\x18\x03 \x01(\v2\x0f.pir.CommitmentR\x0eauthTokenComm0\x129\n" +
+	"\x10auth_token_comm1\x18\x04 \x01(\v2\x0f.pir.CommitmentR\x0eauthTokenComm1\"z\n" +
+	"\tChalToken\x12'\n" +
+	"\x06token0\x18\x01 \x01(\v2\x0f.pir.CiphertextR\x06token0\x12'\n" +
+	"\x06token1\x18\x02 \x01(\v2\x0f.pir.CiphertextR\x06token1\x12\x1b\n" +
+	"\tsec_param\x18\x03 \x01(\x04R\bsecParam\"\xaa\x01\n" +
+	"\n" +
+	"ProofToken\x12.\n" +
+	"\n" +
+	"auth_token\x18\x01 \x01(\v2\x0f.pir.CiphertextR\tauthToken\x12\x1d\n" +
+	"\x01t\x18\x02 \x01(\v2\x0f.pir.CiphertextR\x01t\x12\x14\n" +
+	"\x05proof\x18\x03 \x01(\fR\x05proof\x12\x1b\n" +
+	"\tquery_bit\x18\x04 \x01(\rR\bqueryBit\x12\f\n" +
+	"\x01r\x18\x05 \x01(\fR\x01r\x12\f\n" +
+	"\x01s\x18\x06 \x01(\fR\x01s\"_\n" +
+	"\x17AuthenticatedQueryShare\x12%\n" +
+	"\x05query\x18\x01 \x01(\v2\x0f.pir.QueryShareR\x05query\x12\x1d\n" +
+	"\n" +
+	"auth_token\x18\x02 \x01(\fR\tauthToken\"L\n" +
+	"\x0fAuditTokenShare\x12\f\n" +
+	"\x01t\x18\x01 \x01(\fR\x01t\x12+\n" +
+	"\x11digest_commitment\x18\x02 \x01(\fR\x10digestCommitmentB(Z&github.com/sachaservan/pir/proto;pirpbb\x06proto3"
+
+var (
+	file_proto_pir_proto_rawDescOnce sync.Once
+	file_proto_pir_proto_rawDescData []byte
+)
+
+func file_proto_pir_proto_rawDescGZIP() []byte {
+	file_proto_pir_proto_rawDescOnce.Do(func() {
+		file_proto_pir_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_pir_proto_rawDesc), len(file_proto_pir_proto_rawDesc)))
+	})
+	return file_proto_pir_proto_rawDescData
+}
+
+var file_proto_pir_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_proto_pir_proto_goTypes = []any{
+	(*Ciphertext)(nil),                  // 0: pir.Ciphertext
+	(*GroupRegion)(nil),                 // 1: pir.GroupRegion
+	(*QueryShare)(nil),                  // 2: pir.QueryShare
+	(*SecretSharedQueryResult)(nil),     // 3: pir.SecretSharedQueryResult
+	(*EncryptedQuery)(nil),              // 4: pir.EncryptedQuery
+	(*EncryptedSlot)(nil),               // 5: pir.EncryptedSlot
+	(*EncryptedQueryResult)(nil),        // 6: pir.EncryptedQueryResult
+	(*DoublyEncryptedQuery)(nil),        // 7: pir.DoublyEncryptedQuery
+	(*DoublyEncryptedQueryResult)(nil),  // 8: pir.DoublyEncryptedQueryResult
+	(*Commitment)(nil),                  // 9: pir.Commitment
+	(*AuthenticatedEncryptedQuery)(nil), // 10: pir.AuthenticatedEncryptedQuery
+	(*ChalToken)(nil),                   // 11: pir.ChalToken
+	(*ProofToken)(nil),                  // 12: pir.ProofToken
+	(*AuthenticatedQueryShare)(nil),     // 13: pir.AuthenticatedQueryShare
+	(*AuditTokenShare)(nil),             // 14: pir.AuditTokenShare
+}
+var file_proto_pir_proto_depIdxs = []int32{
+	1,  // 0: pir.QueryShare.region:type_name -> pir.GroupRegion
+	0,  // 1: pir.EncryptedQuery.ebits:type_name -> pir.Ciphertext
+	1,  // 2: pir.EncryptedQuery.region:type_name -> pir.GroupRegion
+	0,  // 3: pir.EncryptedSlot.cts:type_name -> pir.Ciphertext
+	5,  // 4: pir.EncryptedQueryResult.slots:type_name -> pir.EncryptedSlot
+	4,  // 5: pir.DoublyEncryptedQuery.row:type_name -> pir.EncryptedQuery
+	4,  // 6: pir.DoublyEncryptedQuery.col:type_name -> pir.EncryptedQuery
+	5,  // 7: pir.DoublyEncryptedQueryResult.slots:type_name -> pir.EncryptedSlot
+	7,  // 8: pir.AuthenticatedEncryptedQuery.query0:type_name -> pir.DoublyEncryptedQuery
+	7,  // 9: pir.AuthenticatedEncryptedQuery.query1:type_name -> pir.DoublyEncryptedQuery
+	9,  // 10: pir.AuthenticatedEncryptedQuery.auth_token_comm0:type_name -> pir.Commitment
+	9,  // 11: pir.AuthenticatedEncryptedQuery.auth_token_comm1:type_name -> pir.Commitment
+	0,  // 12: pir.ChalToken.token0:type_name -> pir.Ciphertext
+	0,  // 13: pir.ChalToken.token1:type_name -> pir.Ciphertext
+	0,  // 14: pir.ProofToken.auth_token:type_name -> pir.Ciphertext
+	0,  // 15: pir.ProofToken.t:type_name -> pir.Ciphertext
+	2,  // 16: pir.AuthenticatedQueryShare.query:type_name -> pir.QueryShare
+	17, // [17:17] is the sub-list for method output_type
+	17, // [17:17] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_proto_pir_proto_init() }
+func file_proto_pir_proto_init() {
+	if File_proto_pir_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_pir_proto_rawDesc), len(file_proto_pir_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_pir_proto_goTypes,
+		DependencyIndexes: file_proto_pir_proto_depIdxs,
+		MessageInfos:      file_proto_pir_proto_msgTypes,
+	}.Build()
+	File_proto_pir_proto = out.File
+	file_proto_pir_proto_goTypes = nil
+	file_proto_pir_proto_depIdxs = nil
+}
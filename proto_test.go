@@ -0,0 +1,540 @@
+package pir
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+	pirpb "github.com/sachaservan/pir/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSecretSharedQueryProto(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+		shares := db.NewIndexQueryShares(index, 1, 2)
+
+		resShares := make([]*SecretSharedQueryResult, 2)
+		for j, share := range shares {
+			data, err := share.MarshalProto()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded := &QueryShare{}
+			if err := decoded.UnmarshalProto(data); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := db.PrivateSecretSharedQuery(decoded, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err = res.MarshalProto()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resShares[j] = &SecretSharedQueryResult{}
+			if err := resShares[j].UnmarshalProto(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res := Recover(resShares)
+		if !db.Slots[index].Equal(res[0]) {
+			t.Fatalf("Incorrect result after protobuf round trip: expected %v, got %v\n", db.Slots[index], res[0])
+		}
+	}
+}
+
+func TestEncryptedQueryProto(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		width, height := db.encryptedQueryDimentions(pk, groupSize)
+		row := rand.Intn(height)
+		query := db.NewEncryptedQuery(pk, groupSize, row)
+
+		data, err := query.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := UnmarshalEncryptedQueryProto(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := db.PrivateEncryptedQuery(decoded, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err = res.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err = UnmarshalEncryptedQueryResultProto(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots, err := RecoverEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j := 0; j < width && row*width+j < db.DBSize; j++ {
+			if !db.Slots[row*width+j].Equal(slots[j]) {
+				t.Fatalf("Incorrect result after protobuf round trip: expected %v, got %v\n", db.Slots[row*width+j], slots[j])
+			}
+		}
+	}
+}
+
+func TestDoublyEncryptedQueryProto(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+		query := db.NewDoublyEncryptedQuery(pk, groupSize, index)
+
+		data, err := query.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := UnmarshalDoublyEncryptedQueryProto(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := db.PrivateDoublyEncryptedQuery(decoded, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err = res.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err = UnmarshalDoublyEncryptedQueryResultProto(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots, err := RecoverDoublyEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := (index / groupSize) * groupSize
+		for j := 0; j < groupSize; j++ {
+			expected := NewEmptySlot(SlotBytes)
+			if start+j < db.DBSize {
+				expected = db.Slots[start+j]
+			}
+
+			if !expected.Equal(slots[j]) {
+				t.Fatalf("Incorrect result after protobuf round trip: expected %v, got %v\n", expected, slots[j])
+			}
+		}
+	}
+}
+
+func TestColumnMaskProto(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for _, mask := range [][]bool{nil, {}, {true, false, true}} {
+		query := db.NewDoublyEncryptedQuery(pk, 3, 0)
+		query.ColumnMask = mask
+
+		data, err := query.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := UnmarshalDoublyEncryptedQueryProto(data, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if (mask == nil) != (decoded.ColumnMask == nil) || len(mask) != len(decoded.ColumnMask) {
+			t.Fatalf("Column mask %v decoded as %v\n", mask, decoded.ColumnMask)
+		}
+
+		for i := range mask {
+			if mask[i] != decoded.ColumnMask[i] {
+				t.Fatalf("Column mask %v decoded as %v\n", mask, decoded.ColumnMask)
+			}
+		}
+	}
+}
+
+func TestASPIRProto(t *testing.T) {
+	setup()
+
+	secbytes := StatisticalSecurityBytes
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, secbytes)
+	keydb := GenerateRandomDB(TestDBSize, secbytes)
+	qIndex := rand.Intn(keydb.DBSize)
+
+	authQuery, state := db.NewAuthenticatedQuery(sk, 1, qIndex, keydb.Slots[qIndex])
+
+	data, err := authQuery.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authQuery, err = UnmarshalAuthenticatedEncryptedQueryProto(data, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chalToken, err := GenerateAuthChalForQuery(secbytes, keydb, authQuery, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = chalToken.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chalToken = &ChalToken{}
+	if err := chalToken.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+
+	proofToken, err := AuthProve(state, chalToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = proofToken.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &ProofToken{}
+	if err := decoded.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(decoded.CanonicalBytes(), proofToken.CanonicalBytes()) {
+		t.Fatalf("Proof token changed in protobuf round trip\n")
+	}
+
+	if !AuthCheck(pk, authQuery, chalToken, decoded) {
+		t.Fatalf("ASPIR proof failed after protobuf round trip\n")
+	}
+
+	// the DDLEQ proof must decode
+	w := &protoWriter{}
+	w.writeMessage(1, protoCiphertext(proofToken.AuthToken))
+	w.writeMessage(2, protoCiphertext(proofToken.T))
+	w.writeBytes(3, []byte{0x01, 0x02})
+	if err := decoded.UnmarshalProto(w.buf); err == nil {
+		t.Fatalf("Decoded a malformed DDLEQ proof\n")
+	}
+}
+
+func TestSharedASPIRProto(t *testing.T) {
+	setup()
+
+	keydb := GenerateRandomDB(TestDBSize, StatisticalSecurityBytes)
+	index := rand.Intn(TestDBSize)
+	queryShares := keydb.NewAuthenticatedIndexQueryShares(index, keydb.Slots[index], 1, 2)
+
+	audits := make([]*AuditTokenShare, 2)
+	for i, share := range queryShares {
+		data, err := share.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &AuthenticatedQueryShare{}
+		if err := decoded.UnmarshalProto(data); err != nil {
+			t.Fatal(err)
+		}
+
+		audit, err := GenerateAuditForSharedQuery(keydb, decoded, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audit.AttachDigest(keydb)
+
+		data, err = audit.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		audits[i] = &AuditTokenShare{}
+		if err := audits[i].UnmarshalProto(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !CheckAudit(audits...) {
+		t.Fatalf("Secret shared ASPIR proof failed after protobuf round trip\n")
+	}
+}
+
+func TestProtoWireFormat(t *testing.T) {
+
+	// SecretSharedQueryResult{slot_bytes: 3, shares: ["\x01\x02\x03"]} as encoded by protoc
+	expected := []byte{0x08, 0x03, 0x12, 0x03, 0x01, 0x02, 0x03}
+
+	res := &SecretSharedQueryResult{SlotBytes: 3, Shares: []*Slot{NewSlot([]byte{1, 2, 3})}}
+	data, err := res.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("Unexpected wire encoding: expected %x, got %x\n", expected, data)
+	}
+
+	// unknown fields (varint 9, bytes 10, fixed32 11, fixed64 12) are skipped
+	unknown := append([]byte{0x48, 0x01, 0x52, 0x01, 0xff, 0x5d, 0, 0, 0, 0, 0x61, 0, 0, 0, 0, 0, 0, 0, 0}, expected...)
+	decoded := &SecretSharedQueryResult{}
+	if err := decoded.UnmarshalProto(unknown); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.SlotBytes != 3 || len(decoded.Shares) != 1 || !decoded.Shares[0].Equal(res.Shares[0]) {
+		t.Fatalf("Incorrect decoding with unknown fields: %v\n", decoded)
+	}
+
+	if err := decoded.UnmarshalProto(expected[:len(expected)-1]); err == nil {
+		t.Fatalf("Decoded a truncated message\n")
+	}
+}
+
+// protoSchemaCase is a message encoded by proto.go, the type generated from
+// proto/pir.proto for it, and a function that decodes and re-encodes it with proto.go
+type protoSchemaCase struct {
+	name     string
+	data     []byte
+	message  proto.Message
+	reencode func(data []byte) ([]byte, error)
+}
+
+func TestProtoSchema(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, StatisticalSecurityBytes)
+	index := rand.Intn(TestDBSize)
+
+	var cases []protoSchemaCase
+	add := func(name string, message proto.Message, data []byte, err error, reencode func([]byte) ([]byte, error)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, protoSchemaCase{name, data, message, reencode})
+	}
+
+	for _, share := range db.NewIndexQueryShares(index, 1, 2) {
+		data, err := share.MarshalProto()
+		add("QueryShare", &pirpb.QueryShare{}, data, err, func(data []byte) ([]byte, error) {
+			decoded := &QueryShare{}
+			if err := decoded.UnmarshalProto(data); err != nil {
+				return nil, err
+			}
+			return decoded.MarshalProto()
+		})
+
+		res, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = res.MarshalProto()
+		add("SecretSharedQueryResult", &pirpb.SecretSharedQueryResult{}, data, err, func(data []byte) ([]byte, error) {
+			decoded := &SecretSharedQueryResult{}
+			if err := decoded.UnmarshalProto(data); err != nil {
+				return nil, err
+			}
+			return decoded.MarshalProto()
+		})
+	}
+
+	query := db.NewEncryptedQuery(pk, 1, index)
+	data, err := query.MarshalProto()
+	add("EncryptedQuery", &pirpb.EncryptedQuery{}, data, err, func(data []byte) ([]byte, error) {
+		decoded, err := UnmarshalEncryptedQueryProto(data, pk)
+		if err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = res.MarshalProto()
+	add("EncryptedQueryResult", &pirpb.EncryptedQueryResult{}, data, err, func(data []byte) ([]byte, error) {
+		decoded, err := UnmarshalEncryptedQueryResultProto(data, pk)
+		if err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	doubly := db.NewDoublyEncryptedQueryWithColumnMask(pk, 2, index, []bool{true, false})
+	data, err = doubly.MarshalProto()
+	add("DoublyEncryptedQuery", &pirpb.DoublyEncryptedQuery{}, data, err, func(data []byte) ([]byte, error) {
+		decoded, err := UnmarshalDoublyEncryptedQueryProto(data, pk)
+		if err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	doublyRes, err := db.PrivateDoublyEncryptedQuery(doubly, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = doublyRes.MarshalProto()
+	add("DoublyEncryptedQueryResult", &pirpb.DoublyEncryptedQueryResult{}, data, err, func(data []byte) ([]byte, error) {
+		decoded, err := UnmarshalDoublyEncryptedQueryResultProto(data, pk)
+		if err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	authQuery, state := db.NewAuthenticatedQuery(sk, 1, index, db.Slots[index])
+	data, err = authQuery.MarshalProto()
+	add("AuthenticatedEncryptedQuery", &pirpb.AuthenticatedEncryptedQuery{}, data, err, func(data []byte) ([]byte, error) {
+		decoded, err := UnmarshalAuthenticatedEncryptedQueryProto(data, pk)
+		if err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	chalToken, err := GenerateAuthChalForQuery(StatisticalSecurityBytes, db, authQuery, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = chalToken.MarshalProto()
+	add("ChalToken", &pirpb.ChalToken{}, data, err, func(data []byte) ([]byte, error) {
+		decoded := &ChalToken{}
+		if err := decoded.UnmarshalProto(data); err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	proofToken, err := AuthProve(state, chalToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = proofToken.MarshalProto()
+	add("ProofToken", &pirpb.ProofToken{}, data, err, func(data []byte) ([]byte, error) {
+		decoded := &ProofToken{}
+		if err := decoded.UnmarshalProto(data); err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	authShare := db.NewAuthenticatedIndexQueryShares(index, db.Slots[index], 1, 2)[0]
+	data, err = authShare.MarshalProto()
+	add("AuthenticatedQueryShare", &pirpb.AuthenticatedQueryShare{}, data, err, func(data []byte) ([]byte, error) {
+		decoded := &AuthenticatedQueryShare{}
+		if err := decoded.UnmarshalProto(data); err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	audit, err := GenerateAuditForSharedQuery(db, authShare, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit.AttachDigest(db)
+	data, err = audit.MarshalProto()
+	add("AuditTokenShare", &pirpb.AuditTokenShare{}, data, err, func(data []byte) ([]byte, error) {
+		decoded := &AuditTokenShare{}
+		if err := decoded.UnmarshalProto(data); err != nil {
+			return nil, err
+		}
+		return decoded.MarshalProto()
+	})
+
+	for _, c := range cases {
+
+		// the encoding of proto.go is a valid encoding of the message of the schema
+		// without fields that the schema does not define
+		if err := proto.Unmarshal(c.data, c.message); err != nil {
+			t.Fatalf("%v does not decode with the schema: %v\n", c.name, err)
+		}
+
+		if hasUnknownFields(c.message.ProtoReflect()) {
+			t.Fatalf("%v has fields that are not part of the schema\n", c.name)
+		}
+
+		// and proto.go decodes the encoding of the message of the schema
+		data, err := proto.Marshal(c.message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reencoded, err := c.reencode(data)
+		if err != nil {
+			t.Fatalf("%v encoded from the schema does not decode: %v\n", c.name, err)
+		}
+
+		if !bytes.Equal(reencoded, c.data) {
+			t.Fatalf("%v changed in a round trip through the schema\n", c.name)
+		}
+	}
+}
+
+// hasUnknownFields returns true if the message or one of its sub-messages has unknown fields
+func hasUnknownFields(m protoreflect.Message) bool {
+
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+
+	unknown := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len() && !unknown; i++ {
+				unknown = hasUnknownFields(v.List().Get(i).Message())
+			}
+		case fd.Message() != nil && !fd.IsMap():
+			unknown = hasUnknownFields(v.Message())
+		}
+		return !unknown
+	})
+
+	return unknown
+}
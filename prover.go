@@ -0,0 +1,35 @@
+package pir
+
+import (
+	"errors"
+
+	"github.com/sachaservan/paillier"
+)
+
+// ProverContext binds ASPIR proof generation (see AuthProve) to a client's secret key.
+//
+// The paillier library re-randomizes the challenge (NestedRandomize) and generates the
+// DDLEQ proof (ProveDDLEQ) from randomness that it draws internally for each challenge
+// and does not expose challenge-independent parameters, so the context cannot precompute
+// any part of a proof ahead of the challenge: every proof costs the same as AuthProve.
+// Precomputing the commitments of the DDLEQ proof requires a DDLEQ proof implemented
+// in this package, which is out of the scope of the context
+type ProverContext struct {
+	Sk *paillier.SecretKey
+}
+
+// NewProverContext returns a prover context bound to the client's secret key
+func NewProverContext(sk *paillier.SecretKey) *ProverContext {
+	return &ProverContext{Sk: sk}
+}
+
+// AuthProveWithContext is the same as AuthProve but checks that the state
+// was generated with the secret key of the context
+func AuthProveWithContext(ctx *ProverContext, state *AuthQueryPrivateState, chalToken *ChalToken) (*ProofToken, error) {
+
+	if state.Sk != ctx.Sk {
+		return nil, errors.New("query state was generated with a different secret key")
+	}
+
+	return AuthProve(state, chalToken)
+}
@@ -1,8 +1,14 @@
 package pir
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math"
-	"math/rand"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ncw/gmp"
 	"github.com/sachaservan/paillier"
@@ -19,65 +25,211 @@ type QueryShare struct {
 	IsTwoParty     bool
 	ShareNumber    uint
 	GroupSize      int // height of the database
+
+	// region of the database's GroupLayout that the query is over (nil for the entire database)
+	Region *GroupRegion
+
+	// if non-zero, the keyword query matches every keyword whose
+	// top PrefixBits bits equal the queried prefix (see NewKeywordPrefixQuery)
+	PrefixBits uint
+
+	// compression of the binary encoding of the share (see Compression)
+	Compression Compression
+}
+
+// ErrPrfKeyMismatch is returned when the shares of a query were generated with different PRF keys
+var ErrPrfKeyMismatch = errors.New("query shares have different PRF keys")
+
+// PrfKeyFingerprint returns a digest of the PRF keys of the DPF.
+// All the shares of a query carry the same (public) PRF keys, which are
+// independent of the queried index, so the servers can exchange the fingerprints
+// before processing the shares to detect a client that sent mismatched keys
+// (the expansions would diverge and the recovered result would be garbage)
+func (query *QueryShare) PrfKeyFingerprint() []byte {
+
+	h := sha256.New()
+	lenBytes := make([]byte, 8)
+	for _, key := range query.PrfKeys {
+		binary.BigEndian.PutUint64(lenBytes, uint64(len(key.Bytes)))
+		h.Write(lenBytes)
+		h.Write(key.Bytes)
+	}
+
+	return h.Sum(nil)
+}
+
+// CheckPrfKeyFingerprints returns ErrPrfKeyMismatch unless all the fingerprints
+// exchanged by the servers (see PrfKeyFingerprint) are equal
+func CheckPrfKeyFingerprints(fingerprints ...[]byte) error {
+
+	for _, fp := range fingerprints {
+		if !bytes.Equal(fp, fingerprints[0]) {
+			return ErrPrfKeyMismatch
+		}
+	}
+
+	return nil
 }
 
 // EncryptedQuery is an encryption of a point function
 // that evaluates to 1 at the desired row in the database
 // bits = (0, 0,.., 1, ...0, 0)
+//
+// The query has one ciphertext per row. Unlike lattice-based schemes (e.g., SealPIR),
+// Paillier is only additively homomorphic, so the server cannot obliviously expand
+// a compact encoding of the row (e.g., the encrypted bits of its index) into the
+// encrypted selection bits: each bit is a non-linear function of the index.
+// To reduce the upload, use a DoublyEncryptedQuery instead, which recurses over the
+// nested encryption levels and uploads O(sqrt(DBSize)) ciphertexts for the entire database.
+// A recursion over d dimensions (O(d * DBSize^(1/d)) ciphertexts) would need one nested
+// encryption level per dimension, and the paillier library only has two (EncLevelOne and EncLevelTwo)
 type EncryptedQuery struct {
 	Pk                *paillier.PublicKey
 	EBits             []*paillier.Ciphertext
 	GroupSize         int
 	DBWidth, DBHeight int // if a specific will force these dimentiojs
+
+	// region of the database's GroupLayout that the query is over (nil for the entire database);
+	// the query views the window of the region as a DBWidth x DBHeight grid
+	Region *GroupRegion
+
+	// optional proof that the query is well-formed (see QueryProof)
+	Proof *QueryProof
 }
 
 // DoublyEncryptedQuery consists of two encrypted point functions
 // that evaluates to 1 at the desired row and column in the database
 type DoublyEncryptedQuery struct {
-	Row *EncryptedQuery
-	Col *EncryptedQuery
+	Row        *EncryptedQuery
+	Col        *EncryptedQuery
+	ColumnMask []bool // public mask of the group members to return (nil returns all)
+}
+
+// ErrInvalidGroupSize is returned (or panicked with, by the query constructors
+// that do not return an error) when a group size is smaller than one
+var ErrInvalidGroupSize = errors.New("group size must be at least one")
+
+// checkGroupSize panics with ErrInvalidGroupSize if groupSize < 1 such that a
+// misconfigured group size fails when generating a query rather than deep inside a scan
+func checkGroupSize(groupSize int) {
+	if groupSize < 1 {
+		panic(ErrInvalidGroupSize)
+	}
 }
 
-// NewIndexQueryShares generates PIR query shares for the index
+// NewIndexQueryShares generates PIR query shares for the index of a group of groupSize
+// slots (i.e., the group of slots index*groupSize to (index+1)*groupSize-1) over the entire
+// database, regardless of its GroupLayout; use NewLayoutIndexQueryShares to retrieve
+// the group of a slot in its region of the layout
 func (dbmd *DBMetadata) NewIndexQueryShares(index int, groupSize int, numShares uint) []*QueryShare {
 	return dbmd.newQueryShares(index, groupSize, numShares, true)
 }
 
+// KeywordBits is the size (in bits) of the domain of keyword queries
+const KeywordBits = 32
+
+// ErrKeywordOutOfDomain is returned when a keyword does not fit in KeywordBits bits
+// (e.g., a 64-bit hash) and would otherwise be silently truncated
+var ErrKeywordOutOfDomain = fmt.Errorf("keyword does not fit in %v bits", KeywordBits)
+
 // NewKeywordQueryShares generates keyword-based PIR query shares for keyword
-func (dbmd *DBMetadata) NewKeywordQueryShares(keyword int, groupSize int, numShares uint) []*QueryShare {
-	return dbmd.newQueryShares(keyword, groupSize, numShares, false)
+// and returns ErrKeywordOutOfDomain if the keyword is outside the keyword domain
+func (dbmd *DBMetadata) NewKeywordQueryShares(keyword int, groupSize int, numShares uint) ([]*QueryShare, error) {
+
+	if keyword < 0 || uint64(keyword) >= 1<<KeywordBits {
+		return nil, ErrKeywordOutOfDomain
+	}
+
+	if groupSize < 1 {
+		return nil, ErrInvalidGroupSize
+	}
+
+	return dbmd.newQueryShares(keyword, groupSize, numShares, false), nil
+}
+
+// NewKeywordPrefixQuery generates keyword query shares that match every keyword whose
+// top prefixBits bits (out of KeywordBits) equal prefix, i.e., the keywords in the
+// subtree of prefix. The DPF is a point function over the domain of prefixes
+// evaluated at the prefix of each keyword.
+//
+// With PrivateSecretSharedQuery (and group size 1), the recovered slot is the XOR of all
+// the matching slots; with PrivateKeywordCountQuery, the recovered count is the number of
+// matches; and with PrivateKeywordPrefixQuery, each matching slot is recovered separately.
+// The servers learn neither the prefix nor the number of matches,
+// but the client learns the number of matches (up to the number of results requested)
+func NewKeywordPrefixQuery(prefix uint32, prefixBits uint, numShares uint) ([]*QueryShare, error) {
+
+	if prefixBits == 0 || prefixBits > KeywordBits {
+		return nil, fmt.Errorf("prefix must have between 1 and %v bits", KeywordBits)
+	}
+
+	if uint64(prefix) >= 1<<prefixBits {
+		return nil, ErrKeywordOutOfDomain
+	}
+
+	shares := newDPFQueryShares(uint(prefix), prefixBits, 1, numShares)
+	for _, share := range shares {
+		share.IsKeywordBased = true
+		share.PrefixBits = prefixBits
+	}
+
+	return shares, nil
 }
 
 // NewQueryShares generates random PIR query shares for the index
 func (dbmd *DBMetadata) newQueryShares(key int, groupSize int, numShares uint, isIndexQuery bool) []*QueryShare {
 
-	dimHeight := int(math.Ceil(float64(dbmd.DBSize / groupSize))) // need groupSize elements back
+	checkGroupSize(groupSize)
+
+	dimHeight, numBits := dbmd.indexQueryDomain(groupSize)
+
+	// otherwise assume keyword based (KeywordBits bit keys)
+	if !isIndexQuery {
+		numBits = uint(KeywordBits)
+	}
+
+	if isIndexQuery && key >= dimHeight {
+		panic("requesting key outside of domain")
+	}
+
+	shares := newDPFQueryShares(uint(key), numBits, groupSize, numShares)
+	for i := range shares {
+		shares[i].IsKeywordBased = !isIndexQuery
+	}
+
+	return shares
+}
+
+// indexQueryDomain returns the height of the database viewed with the group size
+// and the number of bits of the DPF domain of index queries over its rows
+func (dbmd *DBMetadata) indexQueryDomain(groupSize int) (int, uint) {
+
+	dimHeight := int(math.Ceil(float64(dbmd.DBSize) / float64(groupSize))) // need groupSize elements back
 
 	if dimHeight == 0 {
 		panic("database height is set to zero; something is wrong")
 	}
 
 	// num bits to represent the index
-	numBits := uint(math.Log2(float64(dimHeight)) + 1)
+	return dimHeight, uint(math.Log2(float64(dimHeight)) + 1)
+}
 
-	// otherwise assume keyword based (32 bit keys)
-	if !isIndexQuery {
-		numBits = uint(32)
-	}
+// newDPFQueryShares generates query shares of a point function
+// over a domain of numBits bits that evaluates to 1 at key
+func newDPFQueryShares(key uint, numBits uint, groupSize int, numShares uint) []*QueryShare {
+	return newDPFQuerySharesFrom(dpf.ClientInitialize(numBits), key, groupSize, numShares)
+}
 
-	pf := dpf.ClientInitialize(numBits)
+// newDPFQuerySharesFrom generates the query shares with the initialized DPF
+func newDPFQuerySharesFrom(pf *dpf.Dpf, key uint, groupSize int, numShares uint) []*QueryShare {
 
 	var dpfKeysTwoParty []*dpf.Key2P
 	var dpfKeysMultiParty []*dpf.KeyMP
 
 	if numShares == 2 {
-		dpfKeysTwoParty = pf.GenerateTwoServer(uint(key), 1)
+		dpfKeysTwoParty = pf.GenerateTwoServer(key, 1)
 	} else {
-		dpfKeysMultiParty = pf.GenerateMultiServer(uint(key), 1, numShares)
-	}
-
-	if key >= dimHeight {
-		panic("requesting key outside of domain")
+		dpfKeysMultiParty = pf.GenerateMultiServer(key, 1, numShares)
 	}
 
 	shares := make([]*QueryShare, numShares)
@@ -85,7 +237,6 @@ func (dbmd *DBMetadata) newQueryShares(key int, groupSize int, numShares uint, i
 		shares[i] = &QueryShare{}
 		shares[i].ShareNumber = uint(i)
 		shares[i].PrfKeys = pf.PrfKeys
-		shares[i].IsKeywordBased = !isIndexQuery
 		shares[i].GroupSize = groupSize
 
 		if numShares == 2 {
@@ -116,13 +267,23 @@ func (dbmd *DBMetadata) NewAuthenticatedIndexQueryShares(
 }
 
 // NewEncryptedQuery generates a new encrypted point function that acts as a PIR query
-// defaults to sqrt sized grid database layout
+// defaults to sqrt sized grid database layout (see DBMetadata.SlotAwareDimentions)
+// where index is the row of the layout (of query.DBWidth slots).
+// If the database has a GroupLayout, index is the index of a slot and the query
+// retrieves the row of the slot in the window of its region (see LayoutRowOffset)
 func (dbmd *DBMetadata) NewEncryptedQuery(pk *paillier.PublicKey, groupSize, index int) *EncryptedQuery {
 
-	// compute sqrt dimentions
-	height := int(math.Ceil(math.Sqrt(float64(dbmd.DBSize))))
-	var width int
-	width, height = dbmd.GetDimentionsForDatabase(height, groupSize)
+	if region, regionMD := dbmd.layoutRegion(index); region != nil {
+		width, height := regionMD.encryptedQueryDimentions(pk, region.GroupSize)
+		row := (index - region.Start) / width
+
+		query := regionMD.NewEncryptedQueryWithDimentions(pk, width, height, region.GroupSize, row)
+		query.Region = region
+
+		return query
+	}
+
+	width, height := dbmd.encryptedQueryDimentions(pk, groupSize)
 
 	return dbmd.NewEncryptedQueryWithDimentions(pk, width, height, groupSize, index)
 }
@@ -131,6 +292,8 @@ func (dbmd *DBMetadata) NewEncryptedQuery(pk *paillier.PublicKey, groupSize, ind
 // where the database is viewed as a width x height grid
 func (dbmd *DBMetadata) NewEncryptedQueryWithDimentions(pk *paillier.PublicKey, width, height, groupSize, index int) *EncryptedQuery {
 
+	checkGroupSize(groupSize)
+
 	res := make([]*paillier.Ciphertext, height)
 	for i := 0; i < height; i++ {
 		if i == index {
@@ -149,15 +312,60 @@ func (dbmd *DBMetadata) NewEncryptedQueryWithDimentions(pk *paillier.PublicKey,
 	}
 }
 
+// SelfCheck decrypts the encrypted bits of the query using the client's secret key
+// and returns an error unless exactly one bit is 1 and all others are 0
+// (or all bits are 0 for a null query).
+// This is a debugging aid for clients to catch malformed queries before sending them
+func (query *EncryptedQuery) SelfCheck(sk *paillier.SecretKey) error {
+
+	if len(query.EBits) != query.DBHeight {
+		return fmt.Errorf("query has %v encrypted bits, expected %v", len(query.EBits), query.DBHeight)
+	}
+
+	zero := gmp.NewInt(0)
+	one := gmp.NewInt(1)
+
+	numOnes := 0
+	for i, ct := range query.EBits {
+		if ct == nil {
+			return fmt.Errorf("encrypted bit %v is missing", i)
+		}
+
+		bit := sk.Decrypt(ct)
+		switch {
+		case bit.Cmp(one) == 0:
+			numOnes++
+		case bit.Cmp(zero) != 0:
+			return fmt.Errorf("encrypted bit %v is not a bit", i)
+		}
+	}
+
+	if numOnes > 1 {
+		return fmt.Errorf("query has %v bits set, expected at most one", numOnes)
+	}
+
+	return nil
+}
+
 // NewDoublyEncryptedNullQuery generates a PIR query that does not retrieve any value
 func (dbmd *DBMetadata) NewDoublyEncryptedNullQuery(pk *paillier.PublicKey, groupSize int) *DoublyEncryptedQuery {
 	return dbmd.NewDoublyEncryptedQuery(pk, groupSize, -1) // index -1 generates the all-zero query
 }
 
 // NewDoublyEncryptedQuery generates two encrypted point function that acts as a PIR query
-// to select the row and column in the database
+// to select the row and column in the database.
+// If the database has a GroupLayout, the query retrieves the group of the slot
+// in its region using the group size of the region (see GroupLayout.GroupOffset)
 func (dbmd *DBMetadata) NewDoublyEncryptedQuery(pk *paillier.PublicKey, groupSize, index int) *DoublyEncryptedQuery {
 
+	if region, regionMD := dbmd.layoutRegion(index); region != nil {
+		query := regionMD.NewDoublyEncryptedQuery(pk, region.GroupSize, index-region.Start)
+		query.Row.Region = region
+		query.Col.Region = region
+
+		return query
+	}
+
 	// compute sqrt dimentions
 	height := int(math.Ceil(math.Sqrt(float64(dbmd.DBSize))))
 	var width int
@@ -170,6 +378,8 @@ func (dbmd *DBMetadata) NewDoublyEncryptedQuery(pk *paillier.PublicKey, groupSiz
 // to select the row and column in the database that is viewed as a width x height grid
 func (dbmd *DBMetadata) NewDoublyEncryptedQueryWithDimentions(pk *paillier.PublicKey, width, height, groupSize, index int) *DoublyEncryptedQuery {
 
+	checkGroupSize(groupSize)
+
 	rowIndex, colIndex := dbmd.IndexToCoordinates(index, width, height)
 	colIndex = int(colIndex / groupSize)
 
@@ -220,6 +430,18 @@ func (dbmd *DBMetadata) NewDoublyEncryptedQueryWithDimentions(pk *paillier.Publi
 	}
 }
 
+// NewDoublyEncryptedQueryWithColumnMask generates a doubly encrypted PIR query
+// where the server only returns the group members for which mask is true.
+// The mask is public: it only reveals which members *within* a group
+// the client wants, not which group is retrieved
+func (dbmd *DBMetadata) NewDoublyEncryptedQueryWithColumnMask(pk *paillier.PublicKey, groupSize, index int, mask []bool) *DoublyEncryptedQuery {
+
+	query := dbmd.NewDoublyEncryptedQuery(pk, groupSize, index)
+	query.ColumnMask = mask
+
+	return query
+}
+
 // NewAuthenticatedQuery generates an authenticated PIR query that can be verified by the server
 func (dbmd *DBMetadata) NewAuthenticatedQuery(
 	sk *paillier.SecretKey,
@@ -241,7 +463,7 @@ func (dbmd *DBMetadata) NewAuthenticatedQuery(
 	var token0 *paillier.Ciphertext
 	var token1 *paillier.Ciphertext
 
-	bit := rand.Intn(2)
+	bit := randomBit()
 	if bit == 0 {
 		query0 = queryReal
 		token0 = realToken
@@ -276,16 +498,21 @@ func (dbmd *DBMetadata) NewAuthenticatedQuery(
 
 // Recover combines shares of slots to recover the data
 func Recover(resShares []*SecretSharedQueryResult) []*Slot {
+	return RecoverInto(nil, resShares)
+}
+
+// RecoverInto is the same as Recover but recovers into the slots of dst
+// to avoid allocating new slots when recovering many results.
+// The returned slice reuses the backing array of dst (if large enough) and
+// every slot of dst that has the size of the recovered slots (other slots are reallocated).
+// The previous contents of dst are overwritten, so the caller must not hold on to
+// the slots of a previous recovery (copy them if needed) when passing them as dst
+func RecoverInto(dst []*Slot, resShares []*SecretSharedQueryResult) []*Slot {
 
 	numSlots := len(resShares[0].Shares)
-	res := make([]*Slot, numSlots)
 
 	// init the slots with the correct size
-	for i := 0; i < numSlots; i++ {
-		res[i] = &Slot{
-			Data: make([]byte, resShares[0].SlotBytes),
-		}
-	}
+	res := reuseSlots(dst, numSlots, resShares[0].SlotBytes)
 
 	for i := 0; i < len(resShares); i++ {
 		for j := 0; j < numSlots; j++ {
@@ -296,39 +523,326 @@ func Recover(resShares []*SecretSharedQueryResult) []*Slot {
 	return res
 }
 
+// RecoverN combines shares of slots to recover the data and returns an error
+// if the number of provided shares does not match expectedShares
+// (recovering from a subset of the shares silently returns random data)
+func RecoverN(resShares []*SecretSharedQueryResult, expectedShares int) ([]*Slot, error) {
+
+	if len(resShares) != expectedShares {
+		return nil, fmt.Errorf("expected %v result shares, got %v", expectedShares, len(resShares))
+	}
+
+	for _, share := range resShares {
+		if share == nil {
+			return nil, errors.New("missing result share")
+		}
+
+		if len(share.Shares) != len(resShares[0].Shares) || share.SlotBytes != resShares[0].SlotBytes {
+			return nil, errors.New("result shares have inconsistent sizes")
+		}
+	}
+
+	return Recover(resShares), nil
+}
+
 // RecoverEncrypted decryptes the encrypted slot and returns slot
-func RecoverEncrypted(res *EncryptedQueryResult, sk *paillier.SecretKey) []*Slot {
+// and returns an error if the NumBytesPerCiphertext set by the server
+// is inconsistent with the modulus of the secret key
+// or if the result is not encrypted under the public key of sk (see NewDelegatedQuery).
+// The slots are returned in column order: slot j is db.Slots[row*DBWidth + j]
+// for the row selected by the query
+func RecoverEncrypted(res *EncryptedQueryResult, sk *paillier.SecretKey) ([]*Slot, error) {
+	return RecoverEncryptedInto(nil, res, sk)
+}
+
+// RecoverEncryptedInto is the same as RecoverEncrypted but recovers into the slots of dst
+// (with the same reuse contract as RecoverInto)
+func RecoverEncryptedInto(dst []*Slot, res *EncryptedQueryResult, sk *paillier.SecretKey) ([]*Slot, error) {
+
+	numCiphertextsPerSlot, err := checkEncryptedResult(res, sk)
+	if err != nil {
+		return nil, err
+	}
 
-	slots := make([]*Slot, len(res.Slots))
+	slots := reuseSlots(dst, len(res.Slots), res.SlotBytes)
 
 	// iterate over all the encrypted slots
-	for i, eslot := range res.Slots {
-		arr := make([]*gmp.Int, len(eslot.Cts))
-		for j, ct := range eslot.Cts {
-			arr[j] = sk.Decrypt(ct)
+	for i := range res.Slots {
+		slots[i], err = recoverEncryptedSlot(slots[i], res, sk, i, numCiphertextsPerSlot)
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	return slots, nil
+}
+
+// RecoverEncryptedStream is the same as RecoverEncrypted but invokes fn with each
+// slot (and its index in the result) as soon as the slot is decrypted
+// such that the client can process (e.g., display) the slots progressively.
+// Decryption stops at the first error, which is returned
+func RecoverEncryptedStream(res *EncryptedQueryResult, sk *paillier.SecretKey, fn func(slotIndex int, slot *Slot)) error {
+	return RecoverEncryptedStreamParallel(res, sk, 1, fn)
+}
+
+// RecoverEncryptedStreamParallel is the same as RecoverEncryptedStream but decrypts
+// the slots with nprocs workers. The slots are therefore not necessarily passed to fn
+// in slot order (use the slot index to place them); fn is never invoked concurrently
+func RecoverEncryptedStreamParallel(res *EncryptedQueryResult, sk *paillier.SecretKey, nprocs int, fn func(slotIndex int, slot *Slot)) error {
+
+	numCiphertextsPerSlot, err := checkEncryptedResult(res, sk)
+	if err != nil {
+		return err
+	}
+
+	if nprocs <= 0 {
+		nprocs = 1
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+
+	var next int64 = -1
+	for p := 0; p < nprocs; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(res.Slots) {
+					return
+				}
+
+				slot, err := recoverEncryptedSlot(nil, res, sk, i, numCiphertextsPerSlot)
+
+				mu.Lock()
+				if firstErr != nil {
+					mu.Unlock()
+					return
+				}
+
+				if err != nil {
+					firstErr = err
+					mu.Unlock()
+					return
+				}
+
+				fn(i, slot)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// checkEncryptedResult returns an error if the result cannot be decrypted with sk
+// and returns the number of ciphertexts per slot otherwise
+func checkEncryptedResult(res *EncryptedQueryResult, sk *paillier.SecretKey) (int, error) {
+
+	if res.Pk != nil && res.Pk.N.Cmp(sk.N) != 0 {
+		return 0, errors.New("result is not encrypted under the public key of the secret key")
+	}
 
-		slots[i] = NewSlotFromGmpIntArray(arr, res.SlotBytes, res.NumBytesPerCiphertext)
+	numCiphertextsPerSlot, numBytesPerCiphertext := ciphertextPacking(&sk.PublicKey, res.SlotBytes)
+	if res.NumBytesPerCiphertext != numBytesPerCiphertext {
+		return 0, fmt.Errorf(
+			"result has %v bytes per ciphertext, expected %v",
+			res.NumBytesPerCiphertext,
+			numBytesPerCiphertext,
+		)
 	}
 
-	return slots
+	return numCiphertextsPerSlot, nil
 }
 
-// RecoverDoublyEncrypted decryptes the encrypted slot and returns slot
-func RecoverDoublyEncrypted(res *DoublyEncryptedQueryResult, sk *paillier.SecretKey) []*Slot {
+// recoverEncryptedSlot decrypts slot i of the result into dst (allocated if nil).
+// A slot may carry fewer ciphertexts than numCiphertextsPerSlot (e.g., a record
+// shorter than the slot width), in which case the bytes past its last ciphertext are zero
+func recoverEncryptedSlot(dst *Slot, res *EncryptedQueryResult, sk *paillier.SecretKey, i int, numCiphertextsPerSlot int) (*Slot, error) {
+
+	eslot := res.Slots[i]
+	if len(eslot.Cts) > numCiphertextsPerSlot {
+		return nil, fmt.Errorf(
+			"slot %v has %v ciphertexts, expected at most %v for %v byte slots",
+			i,
+			len(eslot.Cts),
+			numCiphertextsPerSlot,
+			res.SlotBytes,
+		)
+	}
 
-	slots := make([]*Slot, len(res.Slots))
+	arr := make([]*gmp.Int, len(eslot.Cts))
+	for j, ct := range eslot.Cts {
+		arr[j] = sk.Decrypt(ct)
 
-	for i, slot := range res.Slots {
-		arr := make([]*gmp.Int, len(slot.Cts))
-		for j, c := range slot.Cts {
-			arr[j] = sk.NestedDecrypt(c)
+		// bytes of the slot encoded by ciphertext j
+		numBytes := res.SlotBytes - j*res.NumBytesPerCiphertext
+		if numBytes > res.NumBytesPerCiphertext {
+			numBytes = res.NumBytesPerCiphertext
 		}
 
-		slot := NewSlotFromGmpIntArray(arr, res.SlotBytes, res.NumBytesPerCiphertext)
+		if len(arr[j].Bytes()) > numBytes {
+			return nil, fmt.Errorf("ciphertext %v of slot %v decrypts to more than %v bytes", j, i, numBytes)
+		}
+	}
+
+	return setSlotFromGmpIntArray(dst, arr, res.SlotBytes, res.NumBytesPerCiphertext), nil
+}
+
+// IsNullResult returns true if all slots of the result are empty
+// (i.e., the result of a null query) and stops decrypting at the first non-zero ciphertext
+func (res *EncryptedQueryResult) IsNullResult(sk *paillier.SecretKey) bool {
+
+	zero := gmp.NewInt(0)
+	for _, eslot := range res.Slots {
+		for _, ct := range eslot.Cts {
+			if sk.Decrypt(ct).Cmp(zero) != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// RecoverDoublyEncrypted decryptes the encrypted slot and returns slot
+// (if the query had a column mask, only the unmasked group members are returned, in order).
+// Returns an error if the number of slots is inconsistent with the group size of the result
+// or if a slot does not decrypt to exactly SlotBytes bytes
+func RecoverDoublyEncrypted(res *DoublyEncryptedQueryResult, sk *paillier.SecretKey) ([]*Slot, error) {
+	return RecoverDoublyEncryptedInto(nil, res, sk)
+}
+
+// RecoverDoublyEncryptedInto is the same as RecoverDoublyEncrypted but recovers into the slots of dst
+// (with the same reuse contract as RecoverInto)
+func RecoverDoublyEncryptedInto(dst []*Slot, res *DoublyEncryptedQueryResult, sk *paillier.SecretKey) ([]*Slot, error) {
+
+	if err := res.checkNumSlots(); err != nil {
+		return nil, err
+	}
+
+	slots := reuseSlots(dst, len(res.Slots), res.SlotBytes)
+
+	for i := range res.Slots {
+		slot, err := recoverDoublyEncryptedSlot(slots[i], res, sk, i)
+		if err != nil {
+			return nil, err
+		}
 
 		slots[i] = slot
 	}
 
-	return slots
+	return slots, nil
+}
+
+// IsNullResult returns true if all slots of the result are empty
+// (i.e., the result of a null query) and stops decrypting at the first non-zero ciphertext
+func (res *DoublyEncryptedQueryResult) IsNullResult(sk *paillier.SecretKey) bool {
+
+	zero := gmp.NewInt(0)
+	for _, eslot := range res.Slots {
+		for _, ct := range eslot.Cts {
+			if sk.NestedDecrypt(ct).Cmp(zero) != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// RecoverDoublyEncryptedSlot decrypts only the group member at groupOffset
+// and returns nil if the offset is out of range or the slot is malformed.
+// This saves the client from decrypting the entire group when it only
+// needs one slot and is safe because the selection within the group
+// is done locally by the client (the server returns the same response regardless)
+func RecoverDoublyEncryptedSlot(res *DoublyEncryptedQueryResult, sk *paillier.SecretKey, groupOffset int) *Slot {
+
+	if groupOffset < 0 || groupOffset >= len(res.Slots) {
+		return nil
+	}
+
+	slot, err := recoverDoublyEncryptedSlot(nil, res, sk, groupOffset)
+	if err != nil {
+		return nil
+	}
+
+	return slot
+}
+
+// recoverDoublyEncryptedSlot decrypts the slot at i (into dst if possible) and returns an error
+// if the slot does not decrypt to exactly SlotBytes bytes
+func recoverDoublyEncryptedSlot(dst *Slot, res *DoublyEncryptedQueryResult, sk *paillier.SecretKey, i int) (*Slot, error) {
+
+	numCiphertextsPerSlot, _ := ciphertextPacking(&sk.PublicKey, res.SlotBytes)
+	if len(res.Slots[i].Cts) != numCiphertextsPerSlot {
+		return nil, fmt.Errorf(
+			"slot %v has %v ciphertexts, expected %v for %v byte slots",
+			i,
+			len(res.Slots[i].Cts),
+			numCiphertextsPerSlot,
+			res.SlotBytes,
+		)
+	}
+
+	arr := make([]*gmp.Int, len(res.Slots[i].Cts))
+	for j, c := range res.Slots[i].Cts {
+		arr[j] = sk.NestedDecrypt(c)
+
+		// bytes of the slot encoded by ciphertext j
+		numBytes := res.SlotBytes - j*res.NumBytesPerCiphertext
+		if numBytes > res.NumBytesPerCiphertext {
+			numBytes = res.NumBytesPerCiphertext
+		}
+
+		if len(arr[j].Bytes()) > numBytes {
+			return nil, fmt.Errorf("ciphertext %v of slot %v decrypts to more than %v bytes", j, i, numBytes)
+		}
+	}
+
+	slot := setSlotFromGmpIntArray(dst, arr, res.SlotBytes, res.NumBytesPerCiphertext)
+	if len(slot.Data) != res.SlotBytes {
+		return nil, fmt.Errorf("slot %v has %v bytes, expected %v", i, len(slot.Data), res.SlotBytes)
+	}
+
+	return slot, nil
+}
+
+// checkNumSlots returns an error if the number of slots in the result
+// does not match the group size (and column mask) of the result
+func (res *DoublyEncryptedQueryResult) checkNumSlots() error {
+
+	if res.GroupSize <= 0 {
+		return errors.New("result has an invalid group size")
+	}
+
+	if res.ColumnMask == nil {
+		if len(res.Slots) != res.GroupSize {
+			return fmt.Errorf("result has %v slots for a group size of %v", len(res.Slots), res.GroupSize)
+		}
+
+		return nil
+	}
+
+	if len(res.ColumnMask) != res.GroupSize {
+		return errors.New("column mask does not match the group size")
+	}
+
+	numUnmasked := 0
+	for _, include := range res.ColumnMask {
+		if include {
+			numUnmasked++
+		}
+	}
+
+	if len(res.Slots) != numUnmasked {
+		return fmt.Errorf("result has %v slots for a column mask with %v members", len(res.Slots), numUnmasked)
+	}
+
+	return nil
 }
@@ -0,0 +1,255 @@
+package pir
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+// QueryProofChallengeBits is the size of the (Fiat-Shamir) challenge
+// used in query proofs (must be smaller than the factors of the modulus)
+const QueryProofChallengeBits = 128
+
+// QueryProof is a non-interactive zero-knowledge proof that each
+// encrypted bit of an EncryptedQuery encrypts 0 or 1 and that
+// the encrypted bits sum to 1 (i.e., the query is a point function).
+// This prevents a malicious client from retrieving, e.g., the sum of all rows.
+//
+// Each bit is proven using an OR-composition of two proofs of
+// knowledge of an N-th root (encryption of 0 or encryption of 1) and
+// the sum is proven using a single proof of knowledge of an N-th root.
+//
+// Cost: the proof consists of 3 ciphertexts and 5 values mod N per encrypted bit.
+// Proving and verifying each require O(height) exponentiations mod N^2,
+// which is small compared to the O(width * height) exponentiations of the server's scan
+type QueryProof struct {
+	BitCommitments [][2]*paillier.Ciphertext
+	BitChallenges  [][2]*gmp.Int
+	BitResponses   [][2]*gmp.Int
+	SumCommitment  *paillier.Ciphertext
+	SumResponse    *gmp.Int
+}
+
+// NewEncryptedQueryWithProof generates a new encrypted point function (as NewEncryptedQuery)
+// along with a proof that the query is well-formed (see QueryProof)
+func (dbmd *DBMetadata) NewEncryptedQueryWithProof(pk *paillier.PublicKey, groupSize, index int) *EncryptedQuery {
+
+	query, qr := dbmd.NewEncryptedQueryWithRandomness(pk, groupSize, index)
+	query.Proof = newQueryProof(pk, query.EBits, qr.Bits, qr.Rands)
+
+	return query
+}
+
+// VerifyQueryProof returns an error if the query does not have a proof
+// or if the proof does not show that the query is a point function
+func (query *EncryptedQuery) VerifyQueryProof() error {
+
+	pk := query.Pk
+	proof := query.Proof
+
+	if proof == nil {
+		return errors.New("query does not have a proof")
+	}
+
+	n := len(query.EBits)
+	if len(proof.BitCommitments) != n || len(proof.BitChallenges) != n || len(proof.BitResponses) != n {
+		return errors.New("proof has an incorrect number of bit proofs")
+	}
+
+	if proof.SumCommitment == nil || proof.SumResponse == nil {
+		return errors.New("proof is missing the sum proof")
+	}
+
+	chal := queryProofChallenge(pk, query.EBits, proof)
+	mod := new(gmp.Int).Lsh(gmp.NewInt(1), QueryProofChallengeBits)
+
+	for i, ct := range query.EBits {
+		e0, e1 := proof.BitChallenges[i][0], proof.BitChallenges[i][1]
+		if e0 == nil || e1 == nil {
+			return fmt.Errorf("proof of bit %v is incomplete", i)
+		}
+
+		sum := new(gmp.Int).Add(e0, e1)
+		if sum.Mod(sum, mod).Cmp(chal) != 0 {
+			return fmt.Errorf("proof of bit %v has an invalid challenge", i)
+		}
+
+		for m := 0; m < 2; m++ {
+			ok := verifyNthRoot(
+				pk,
+				shiftCiphertext(pk, ct, m),
+				proof.BitCommitments[i][m],
+				proof.BitChallenges[i][m],
+				proof.BitResponses[i][m],
+			)
+
+			if !ok {
+				return fmt.Errorf("encrypted bit %v is not a bit", i)
+			}
+		}
+	}
+
+	sumCt := shiftCiphertext(pk, pk.Add(query.EBits...), 1)
+	if !verifyNthRoot(pk, sumCt, proof.SumCommitment, chal, proof.SumResponse) {
+		return errors.New("encrypted bits do not sum to 1")
+	}
+
+	return nil
+}
+
+// newQueryProof proves that each ebits[i] = Enc(bits[i]; rands[i]) encrypts a bit
+// and that the bits sum to 1 (the proof only verifies if the statements are true)
+func newQueryProof(pk *paillier.PublicKey, ebits []*paillier.Ciphertext, bits []int, rands []*gmp.Int) *QueryProof {
+
+	n := len(ebits)
+	proof := &QueryProof{
+		BitCommitments: make([][2]*paillier.Ciphertext, n),
+		BitChallenges:  make([][2]*gmp.Int, n),
+		BitResponses:   make([][2]*gmp.Int, n),
+	}
+
+	// randomness of the real (non-simulated) commitments
+	secrets := make([]*gmp.Int, n)
+
+	for i, ct := range ebits {
+		b := bits[i]
+		f := 1 - b
+
+		// real branch: commitment to a random N-th power
+		secrets[i] = randomNonZeroMod(pk.N)
+		proof.BitCommitments[i][b] = pk.EncryptWithR(gmp.NewInt(0), secrets[i])
+
+		// simulated branch: pick the challenge and response upfront
+		// and compute the commitment that makes the proof verify
+		proof.BitChallenges[i][f] = randomChallenge()
+		proof.BitCommitments[i][f], proof.BitResponses[i][f] = simulateNthRoot(
+			pk,
+			shiftCiphertext(pk, ct, f),
+			proof.BitChallenges[i][f],
+		)
+	}
+
+	sumSecret := randomNonZeroMod(pk.N)
+	proof.SumCommitment = pk.EncryptWithR(gmp.NewInt(0), sumSecret)
+
+	chal := queryProofChallenge(pk, ebits, proof)
+	mod := new(gmp.Int).Lsh(gmp.NewInt(1), QueryProofChallengeBits)
+
+	for i := range ebits {
+		b := bits[i]
+		f := 1 - b
+
+		eb := new(gmp.Int).Sub(chal, proof.BitChallenges[i][f])
+		proof.BitChallenges[i][b] = eb.Mod(eb, mod)
+		proof.BitResponses[i][b] = nthRootResponse(pk, secrets[i], rands[i], proof.BitChallenges[i][b])
+	}
+
+	// randomness of the sum of the encrypted bits
+	sumRand := gmp.NewInt(1)
+	for _, r := range rands {
+		sumRand.Mul(sumRand, r)
+		sumRand.Mod(sumRand, pk.N)
+	}
+
+	proof.SumResponse = nthRootResponse(pk, sumSecret, sumRand, chal)
+
+	return proof
+}
+
+// shiftCiphertext returns ct * Enc(-m; 1) which is an encryption of 0
+// (an N-th power) if and only if ct encrypts m
+func shiftCiphertext(pk *paillier.PublicKey, ct *paillier.Ciphertext, m int) *paillier.Ciphertext {
+
+	negM := new(gmp.Int).Sub(pk.N, gmp.NewInt(int64(m)))
+	negM.Mod(negM, pk.N)
+
+	return pk.Add(ct, pk.EncryptWithR(negM, gmp.NewInt(1)))
+}
+
+// verifyNthRoot checks that Enc(0; z) = a * u^e
+func verifyNthRoot(pk *paillier.PublicKey, u, a *paillier.Ciphertext, e, z *gmp.Int) bool {
+
+	if u == nil || a == nil || e == nil || z == nil {
+		return false
+	}
+
+	lhs := pk.EncryptWithR(gmp.NewInt(0), z)
+	rhs := pk.Add(a, pk.ConstMult(u, e))
+
+	return lhs.C.Cmp(rhs.C) == 0
+}
+
+// nthRootResponse returns z = s * r^e mod N for the commitment Enc(0; s)
+// and u = Enc(0; r)
+func nthRootResponse(pk *paillier.PublicKey, s, r, e *gmp.Int) *gmp.Int {
+
+	z := new(gmp.Int).Exp(r, e, pk.N)
+	z.Mul(z, s)
+
+	return z.Mod(z, pk.N)
+}
+
+// simulateNthRoot returns a commitment and response that verify
+// for u and the challenge e without knowing an N-th root of u.
+// Since u^(e(N-1)) = Enc(0; u^e mod N) * u^(-e), the commitment
+// a = Enc(0; z') * u^(e(N-1)) verifies with the response z = z' * u^e mod N
+func simulateNthRoot(pk *paillier.PublicKey, u *paillier.Ciphertext, e *gmp.Int) (*paillier.Ciphertext, *gmp.Int) {
+
+	zPrime := randomNonZeroMod(pk.N)
+
+	exp := new(gmp.Int).Sub(pk.N, gmp.NewInt(1))
+	exp.Mul(exp, e)
+
+	a := pk.Add(pk.EncryptWithR(gmp.NewInt(0), zPrime), pk.ConstMult(u, exp))
+
+	uModN := new(gmp.Int).Mod(u.C, pk.N)
+	z := new(gmp.Int).Exp(uModN, e, pk.N)
+	z.Mul(z, zPrime)
+
+	return a, z.Mod(z, pk.N)
+}
+
+// queryProofChallenge computes the Fiat-Shamir challenge
+// by hashing the public key, the query, and the commitments
+func queryProofChallenge(pk *paillier.PublicKey, ebits []*paillier.Ciphertext, proof *QueryProof) *gmp.Int {
+
+	h := sha256.New()
+	write := func(v *gmp.Int) {
+		b := v.Bytes()
+		h.Write([]byte{byte(len(b) >> 24), byte(len(b) >> 16), byte(len(b) >> 8), byte(len(b))})
+		h.Write(b)
+	}
+
+	write(pk.N)
+	for _, ct := range ebits {
+		write(ct.C)
+	}
+
+	for _, comms := range proof.BitCommitments {
+		for _, comm := range comms {
+			if comm != nil {
+				write(comm.C)
+			}
+		}
+	}
+
+	if proof.SumCommitment != nil {
+		write(proof.SumCommitment.C)
+	}
+
+	digest := h.Sum(nil)
+
+	return new(gmp.Int).SetBytes(digest[:QueryProofChallengeBits/8])
+}
+
+// randomChallenge returns a uniformly random challenge
+func randomChallenge() *gmp.Int {
+
+	b := make([]byte, QueryProofChallengeBits/8)
+	randomBytes(b)
+
+	return new(gmp.Int).SetBytes(b)
+}
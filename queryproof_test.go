@@ -0,0 +1,78 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+func TestQueryProof(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(512)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+		query := db.NewEncryptedQueryWithProof(pk, groupSize, rand.Intn(dimHeight))
+
+		if err := query.VerifyQueryProof(); err != nil {
+			t.Fatalf("Well-formed query failed to verify: %v\n", err)
+		}
+
+		if err := query.SelfCheck(sk); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueryProofAllOnes(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(512)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	query := db.NewEncryptedQuery(pk, 1, 0)
+
+	// an honestly generated proof for an all-ones query
+	bits := make([]int, query.DBHeight)
+	rands := make([]*gmp.Int, query.DBHeight)
+	for i := range bits {
+		bits[i] = 1
+		rands[i] = randomNonZeroMod(pk.N)
+		query.EBits[i] = pk.EncryptWithR(gmp.NewInt(1), rands[i])
+	}
+
+	query.Proof = newQueryProof(pk, query.EBits, bits, rands)
+	if err := query.VerifyQueryProof(); err == nil {
+		t.Fatal("All-ones query verified")
+	}
+
+	// a valid proof for another query
+	valid := db.NewEncryptedQueryWithProof(pk, 1, 0)
+	query.Proof = valid.Proof
+	if err := query.VerifyQueryProof(); err == nil {
+		t.Fatal("All-ones query verified with the proof of another query")
+	}
+
+	// no proof
+	query.Proof = nil
+	if err := query.VerifyQueryProof(); err == nil {
+		t.Fatal("Query without a proof verified")
+	}
+
+	// the server rejects queries without a valid proof
+	server := NewServer(db)
+	server.RequireQueryProofs = true
+	if _, err := server.PrivateEncryptedQuery(query, NumProcsForQuery); err == nil {
+		t.Fatal("Server answered a query without a valid proof")
+	}
+
+	if _, err := server.PrivateEncryptedQuery(valid, NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+}
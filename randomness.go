@@ -0,0 +1,177 @@
+package pir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+// randomSource is the source of all security-sensitive randomness sampled by the package
+// (e.g., the ASPIR query bit, auth tokens, commitments, and Paillier randomness).
+// It must be a cryptographically secure source and is only replaced in tests.
+// Note that the DPF keys are sampled by the dpf package and the randomness of
+// pk.Encrypt by the paillier library, both of which use crypto/rand
+var randomSource io.Reader = rand.Reader
+
+// randomBytes fills b with bytes read from randomSource
+func randomBytes(b []byte) {
+	if _, err := io.ReadFull(randomSource, b); err != nil {
+		panic(fmt.Sprintf("Generating random bytes failed with %v\n", err))
+	}
+}
+
+// randomBit returns a uniformly random bit read from randomSource
+func randomBit() int {
+	b := make([]byte, 1)
+	randomBytes(b)
+	return int(b[0] & 1)
+}
+
+// QueryRandomness records the plaintext bit and the Paillier randomness
+// used to generate each ciphertext of an encrypted query.
+// It allows the client to later prove (e.g., by opening a commitment to it)
+// that the query was generated honestly. The randomness must stay client-side:
+// anyone holding it can decrypt the query
+type QueryRandomness struct {
+	Bits  []int
+	Rands []*gmp.Int
+	Level paillier.EncryptionLevel
+}
+
+// DoublyEncryptedQueryRandomness records the randomness of the row and column queries
+type DoublyEncryptedQueryRandomness struct {
+	Row *QueryRandomness
+	Col *QueryRandomness
+}
+
+// NewEncryptedQueryWithRandomness generates a new encrypted query (as NewEncryptedQuery)
+// and returns the randomness used to generate it
+func (dbmd *DBMetadata) NewEncryptedQueryWithRandomness(pk *paillier.PublicKey, groupSize, index int) (*EncryptedQuery, *QueryRandomness) {
+
+	width, height := dbmd.encryptedQueryDimentions(pk, groupSize)
+
+	qr := newQueryRandomness(pk, height, index, paillier.EncLevelOne)
+
+	query := &EncryptedQuery{
+		Pk:        pk,
+		EBits:     qr.Encrypt(pk),
+		GroupSize: groupSize,
+		DBWidth:   width,
+		DBHeight:  height,
+	}
+
+	return query, qr
+}
+
+// NewDoublyEncryptedQueryWithRandomness generates a new doubly encrypted query
+// (as NewDoublyEncryptedQuery) and returns the randomness used to generate it
+func (dbmd *DBMetadata) NewDoublyEncryptedQueryWithRandomness(pk *paillier.PublicKey, groupSize, index int) (*DoublyEncryptedQuery, *DoublyEncryptedQueryRandomness) {
+
+	// compute sqrt dimentions
+	height := int(math.Ceil(math.Sqrt(float64(dbmd.DBSize))))
+	var width int
+	width, height = dbmd.GetDimentionsForDatabase(height, groupSize)
+
+	rowIndex, colIndex := dbmd.IndexToCoordinates(index, width, height)
+	colIndex = int(colIndex / groupSize)
+
+	if index == -1 {
+		rowIndex = -1
+		colIndex = -1
+	}
+
+	qr := &DoublyEncryptedQueryRandomness{
+		Row: newQueryRandomness(pk, height, rowIndex, paillier.EncLevelOne),
+		Col: newQueryRandomness(pk, width/groupSize, colIndex, paillier.EncLevelTwo),
+	}
+
+	query := &DoublyEncryptedQuery{
+		Row: &EncryptedQuery{
+			Pk:        pk,
+			EBits:     qr.Row.Encrypt(pk),
+			GroupSize: groupSize,
+			DBWidth:   width,
+			DBHeight:  height,
+		},
+		Col: &EncryptedQuery{
+			Pk:        pk,
+			EBits:     qr.Col.Encrypt(pk),
+			GroupSize: groupSize,
+			DBWidth:   width,
+			DBHeight:  1,
+		},
+	}
+
+	return query, qr
+}
+
+// Encrypt regenerates the ciphertexts from the recorded bits and randomness
+func (qr *QueryRandomness) Encrypt(pk *paillier.PublicKey) []*paillier.Ciphertext {
+
+	cts := make([]*paillier.Ciphertext, len(qr.Bits))
+	for i, bit := range qr.Bits {
+		cts[i] = pk.EncryptWithRAtLevel(gmp.NewInt(int64(bit)), qr.Rands[i], qr.Level)
+	}
+
+	return cts
+}
+
+// VerifyRandomness returns an error if the query was not generated from the randomness
+func (query *EncryptedQuery) VerifyRandomness(qr *QueryRandomness) error {
+	return verifyRandomness(query.Pk, query.EBits, qr)
+}
+
+// VerifyRandomness returns an error if the query was not generated from the randomness
+func (query *DoublyEncryptedQuery) VerifyRandomness(qr *DoublyEncryptedQueryRandomness) error {
+
+	if err := verifyRandomness(query.Row.Pk, query.Row.EBits, qr.Row); err != nil {
+		return fmt.Errorf("row query: %v", err)
+	}
+
+	if err := verifyRandomness(query.Col.Pk, query.Col.EBits, qr.Col); err != nil {
+		return fmt.Errorf("column query: %v", err)
+	}
+
+	return nil
+}
+
+func verifyRandomness(pk *paillier.PublicKey, ebits []*paillier.Ciphertext, qr *QueryRandomness) error {
+
+	if len(qr.Bits) != len(ebits) || len(qr.Rands) != len(ebits) {
+		return errors.New("randomness does not match the query size")
+	}
+
+	for i, ct := range qr.Encrypt(pk) {
+		if ct.C.Cmp(ebits[i].C) != 0 {
+			return fmt.Errorf("ciphertext %v does not match the randomness", i)
+		}
+	}
+
+	return nil
+}
+
+// newQueryRandomness samples the randomness for n encrypted bits
+// where only the bit at index is set
+func newQueryRandomness(pk *paillier.PublicKey, n, index int, level paillier.EncryptionLevel) *QueryRandomness {
+
+	qr := &QueryRandomness{
+		Bits:  make([]int, n),
+		Rands: make([]*gmp.Int, n),
+		Level: level,
+	}
+
+	for i := 0; i < n; i++ {
+		if i == index {
+			qr.Bits[i] = 1
+		}
+
+		qr.Rands[i] = randomNonZeroMod(pk.N)
+	}
+
+	return qr
+}
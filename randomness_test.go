@@ -0,0 +1,116 @@
+package pir
+
+import (
+	crand "crypto/rand"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestQueryRandomness(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+		rowIndex := rand.Intn(dimHeight)
+
+		query, qr := db.NewEncryptedQueryWithRandomness(pk, groupSize, rowIndex)
+		if err := query.VerifyRandomness(qr); err != nil {
+			t.Fatal(err)
+		}
+
+		// reconstructing the query from the randomness gives the same ciphertexts
+		for i, ct := range qr.Encrypt(pk) {
+			if ct.C.Cmp(query.EBits[i].C) != 0 {
+				t.Fatalf("Reconstructed ciphertext %v does not match the query\n", i)
+			}
+		}
+
+		// the query is still a valid query
+		encryptedQueryRowWithQuery(t, db, sk, query, rowIndex)
+
+		// randomness of another query does not verify
+		_, other := db.NewEncryptedQueryWithRandomness(pk, groupSize, (rowIndex+1)%dimHeight)
+		if err := query.VerifyRandomness(other); err == nil {
+			t.Fatal("Query verified against randomness of another query")
+		}
+	}
+}
+
+func TestDoublyEncryptedQueryRandomness(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+
+		query, qr := db.NewDoublyEncryptedQueryWithRandomness(pk, groupSize, index)
+		if err := query.VerifyRandomness(qr); err != nil {
+			t.Fatal(err)
+		}
+
+		response, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := RecoverDoublyEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		checkGroup(t, db, groupSize, index, res)
+
+		// flipping the retrieved column breaks the verification
+		for i := range qr.Col.Bits {
+			qr.Col.Bits[i] = 1 - qr.Col.Bits[i]
+		}
+
+		if err := query.VerifyRandomness(qr); err == nil {
+			t.Fatal("Query verified against modified randomness")
+		}
+	}
+}
+
+// constantReader returns the same byte forever
+type constantReader byte
+
+func (r constantReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = byte(r)
+	}
+	return len(b), nil
+}
+
+func TestAuthenticatedQueryBitSource(t *testing.T) {
+	setup()
+
+	sk, _ := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	authKey := NewRandomSlot(SlotBytes)
+
+	if randomSource != crand.Reader {
+		t.Fatalf("Security-sensitive randomness is not drawn from crypto/rand\n")
+	}
+
+	defer func(source io.Reader) { randomSource = source }(randomSource)
+
+	// the bit hiding which query is real is drawn from the package's random source
+	for _, bit := range []int{0, 1} {
+		randomSource = constantReader(bit)
+
+		_, state := db.NewAuthenticatedQuery(sk, 1, 0, authKey)
+		if state.Bit != bit {
+			t.Fatalf("Bit %v was not drawn from the random source (expected %v)\n", state.Bit, bit)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package pir
+
+import (
+	"errors"
+	"math"
+)
+
+// PrivateEncryptedQueryInRange answers an encrypted query over the rows [startRow, endRow)
+// of the database viewed as a grid of query.DBWidth slots per row, for clients that
+// know that the queried slot is in the range (e.g., from public metadata).
+// The query has one encrypted bit per row of the range (i.e., query.DBHeight = endRow - startRow)
+// and can be generated with NewEncryptedQueryWithDimentions using the row relative to startRow.
+//
+// Only the slots of the range are read: when the database has a SlotStore,
+// the scan reads a view of the store's columns restricted to the range, so a
+// disk-backed (e.g., mmap'ed) store only faults in the pages of the range.
+// The server learns the range but not the row within the range
+func (db *Database) PrivateEncryptedQueryInRange(query *EncryptedQuery, startRow, endRow int, nprocs int) (*EncryptedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if startRow < 0 || startRow >= endRow {
+		return nil, errors.New("invalid row range")
+	}
+
+	if query.DBHeight != endRow-startRow || len(query.EBits) != query.DBHeight {
+		return nil, errors.New("query height does not match the row range")
+	}
+
+	start := startRow * query.DBWidth
+	end := int(math.Min(float64(endRow*query.DBWidth), float64(db.DBSize)))
+	if start >= end {
+		return nil, errors.New("row range outside of the database")
+	}
+
+	sub, err := db.SubDatabase(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.OpCounter = db.OpCounter
+
+	return sub.PrivateEncryptedQuery(query, nprocs)
+}
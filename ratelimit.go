@@ -0,0 +1,131 @@
+package pir
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a query exceeds the rate allowed by the server's RateLimiter
+var ErrRateLimited = errors.New("query rate limit exceeded")
+
+// RateLimitKey is the metadata that queries are rate limited on.
+//
+// IMPORTANT: the key must only contain public, coarse metadata that the server
+// learns anyway (the declared dimensions of the query and the network source).
+// It must never depend on the content of the query (e.g., the encrypted bits or
+// DPF keys, or anything derived from the queried index or keyword): buckets keyed
+// on such data would link queries for the same item and break query privacy
+type RateLimitKey struct {
+	Source string // network source of the query (e.g., IP address) if known
+	Shape  QueryShape
+}
+
+// querySourceKey is the context key of the network source of a query
+type querySourceKey struct{}
+
+// WithQuerySource returns a copy of ctx that carries the network source of a query
+// (e.g., the IP address of the client), which the Context methods of Server
+// rate limit on (see RateLimitKey)
+func WithQuerySource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, querySourceKey{}, source)
+}
+
+// QuerySource returns the network source of a query set by WithQuerySource
+// (empty if the source is unknown)
+func QuerySource(ctx context.Context) string {
+	source, _ := ctx.Value(querySourceKey{}).(string)
+	return source
+}
+
+// RateLimiter is a token bucket rate limiter with one bucket per RateLimitKey.
+// Each bucket holds up to Burst tokens and refills at Rate tokens per second;
+// each query consumes one token.
+// A bucket that has refilled to Burst is the same as a new bucket, so full buckets
+// are evicted (at most once per refill period) and the limiter only holds the keys
+// that queried within the last refill period
+type RateLimiter struct {
+	Rate  float64
+	Burst int
+
+	mu        sync.Mutex
+	buckets   map[RateLimitKey]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time // time.Now unless set by tests
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a rate limiter allowing rate queries per second
+// with bursts of up to burst queries per key
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		Rate:    rate,
+		Burst:   burst,
+		buckets: make(map[RateLimitKey]*tokenBucket),
+	}
+}
+
+// Allow consumes a token from the bucket of the key
+// and returns ErrRateLimited if the bucket is empty
+func (rl *RateLimiter) Allow(key RateLimitKey) error {
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if rl.now != nil {
+		now = rl.now()
+	}
+
+	if rl.buckets == nil {
+		rl.buckets = make(map[RateLimitKey]*tokenBucket)
+	}
+
+	rl.evictFull(now)
+
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(rl.Burst), last: now}
+		rl.buckets[key] = bucket
+	}
+
+	bucket.refill(now, rl.Rate, rl.Burst)
+
+	if bucket.tokens < 1 {
+		return ErrRateLimited
+	}
+
+	bucket.tokens--
+	return nil
+}
+
+// evictFull removes the buckets that have refilled to Burst
+// if a refill period has passed since the last sweep
+func (rl *RateLimiter) evictFull(now time.Time) {
+
+	if rl.Rate <= 0 || now.Sub(rl.lastSweep).Seconds()*rl.Rate < float64(rl.Burst) {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, bucket := range rl.buckets {
+		if bucket.refill(now, rl.Rate, rl.Burst); bucket.tokens >= float64(rl.Burst) {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// refill adds the tokens accumulated since the last refill (up to burst)
+func (bucket *tokenBucket) refill(now time.Time, rate float64, burst int) {
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * rate
+	if bucket.tokens > float64(burst) {
+		bucket.tokens = float64(burst)
+	}
+	bucket.last = now
+}
@@ -0,0 +1,108 @@
+package pir
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ResultChunk is a piece of an EncryptedQueryResult that fits in a network frame.
+// Part contains consecutive slots of the result (with the same key and packing
+// parameters as the result) such that it can be encoded with MarshalBinary;
+// Seq is the position of the chunk and Total the number of chunks of the result
+type ResultChunk struct {
+	Seq, Total int
+	Part       *EncryptedQueryResult
+}
+
+// Chunks splits the result into chunks whose slots take at most maxBytes bytes
+// when encoded (see MarshalBinary). Slots are never split across chunks,
+// so a slot larger than maxBytes is sent in a chunk of its own
+func (res *EncryptedQueryResult) Chunks(maxBytes int) []*ResultChunk {
+
+	parts := make([][]*EncryptedSlot, 0)
+	current := make([]*EncryptedSlot, 0)
+	currentBytes := 0
+
+	for _, eslot := range res.Slots {
+		w := &binaryWriter{}
+		w.writeCiphertexts(eslot.Cts)
+		slotBytes := len(w.buf)
+
+		if len(current) > 0 && currentBytes+slotBytes > maxBytes {
+			parts = append(parts, current)
+			current = make([]*EncryptedSlot, 0)
+			currentBytes = 0
+		}
+
+		current = append(current, eslot)
+		currentBytes += slotBytes
+	}
+
+	// an empty result is sent as a single empty chunk
+	if len(current) > 0 || len(parts) == 0 {
+		parts = append(parts, current)
+	}
+
+	chunks := make([]*ResultChunk, len(parts))
+	for i, slots := range parts {
+		chunks[i] = &ResultChunk{
+			Seq:   i,
+			Total: len(parts),
+			Part: &EncryptedQueryResult{
+				Slots:                 slots,
+				Pk:                    res.Pk,
+				SlotBytes:             res.SlotBytes,
+				NumBytesPerCiphertext: res.NumBytesPerCiphertext,
+			},
+		}
+	}
+
+	return chunks
+}
+
+// ReassembleEncryptedResult combines the chunks of a result (in any order)
+// and returns an error if a chunk is missing or duplicated
+// or if the chunks do not belong to the same result
+func ReassembleEncryptedResult(chunks []*ResultChunk) (*EncryptedQueryResult, error) {
+
+	if len(chunks) == 0 {
+		return nil, errors.New("no chunks provided")
+	}
+
+	sorted := make([]*ResultChunk, len(chunks))
+	copy(sorted, chunks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Seq < sorted[j].Seq })
+
+	first := sorted[0].Part
+	total := sorted[0].Total
+	if len(sorted) != total {
+		return nil, fmt.Errorf("expected %v chunks, got %v", total, len(sorted))
+	}
+
+	res := &EncryptedQueryResult{
+		Slots:                 make([]*EncryptedSlot, 0),
+		Pk:                    first.Pk,
+		SlotBytes:             first.SlotBytes,
+		NumBytesPerCiphertext: first.NumBytesPerCiphertext,
+	}
+
+	for i, chunk := range sorted {
+		if chunk.Seq != i || chunk.Total != total {
+			return nil, fmt.Errorf("missing or duplicate chunk %v", i)
+		}
+
+		part := chunk.Part
+		if part.SlotBytes != first.SlotBytes || part.NumBytesPerCiphertext != first.NumBytesPerCiphertext {
+			return nil, errors.New("chunks belong to different results")
+		}
+
+		if part.Pk != first.Pk && (part.Pk == nil || first.Pk == nil || part.Pk.N.Cmp(first.Pk.N) != 0) {
+			return nil, errors.New("chunks are encrypted under different keys")
+		}
+
+		res.Slots = append(res.Slots, part.Slots...)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,72 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestEncryptedResultChunks(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	// wide rows of large slots such that the result spans many chunks
+	db := GenerateRandomDB(TestDBSize, 64)
+	width, height := 64, TestDBSize/64
+	row := rand.Intn(height)
+	query := db.NewEncryptedQueryWithDimentions(pk, width, height, 1, row)
+
+	res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maxBytes := 512
+	chunks := res.Chunks(maxBytes)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the result to be split into several chunks, got %v\n", len(chunks))
+	}
+
+	for _, chunk := range chunks {
+		encoded, err := chunk.Part.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the encoding adds a few bytes of header to the slots
+		if len(chunk.Part.Slots) > 1 && len(encoded) > maxBytes+16 {
+			t.Fatalf("Chunk %v has %v bytes, expected at most %v\n", chunk.Seq, len(encoded), maxBytes)
+		}
+	}
+
+	rand.Shuffle(len(chunks), func(i, j int) { chunks[i], chunks[j] = chunks[j], chunks[i] })
+
+	reassembled, err := ReassembleEncryptedResult(chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := RecoverEncrypted(reassembled, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j, slot := range slots {
+		if !slot.Equal(db.Slots[row*width+j]) {
+			t.Fatalf("Recovered slot %v is incorrect\n", j)
+		}
+	}
+
+	// missing and duplicate chunks are rejected
+	if _, err := ReassembleEncryptedResult(chunks[1:]); err == nil {
+		t.Fatalf("Reassembled a result with a missing chunk\n")
+	}
+
+	duplicate := append([]*ResultChunk{}, chunks...)
+	duplicate[len(duplicate)-1] = duplicate[0]
+	if _, err := ReassembleEncryptedResult(duplicate); err == nil {
+		t.Fatalf("Reassembled a result with a duplicate chunk\n")
+	}
+}
@@ -0,0 +1,406 @@
+package pir
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+// This file contains streaming encoders and decoders for encrypted results.
+// The stream is the binary encoding of the result (see MarshalBinary), written
+// and read one slot at a time such that large results are never materialized
+// as a single buffer: the encoders write each slot to the writer as soon as it
+// is encoded and the decoders hand out one slot at a time (see Next).
+// The decoders buffer the reader (unless it is a *bufio.Reader) and may read past
+// the end of the result; pass a *bufio.Reader to keep reading the stream afterwards
+
+// maxStreamBytes bounds the length of a single byte string (e.g., a ciphertext)
+// read from a stream such that a malformed stream cannot trigger a huge allocation
+const maxStreamBytes = 1 << 24
+
+// WriteEncryptedQueryResult writes the binary encoding of the result to w
+// (the same bytes as MarshalBinary) one slot at a time
+func WriteEncryptedQueryResult(w io.Writer, res *EncryptedQueryResult) error {
+
+	body, err := writeStreamHeader(w, res.Compression)
+	if err != nil {
+		return err
+	}
+
+	enc := &binaryWriter{version: CurrentWireVersion}
+	enc.writeUint(uint64(res.SlotBytes))
+	enc.writeUint(uint64(res.NumBytesPerCiphertext))
+	enc.writeUint(uint64(len(res.Slots)))
+
+	for _, eslot := range res.Slots {
+		enc.writeCiphertexts(eslot.Cts)
+		if err := enc.flush(body); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.flush(body); err != nil {
+		return err
+	}
+
+	return body.Close()
+}
+
+// WriteDoublyEncryptedQueryResult writes the binary encoding of the result to w
+// (the same bytes as MarshalBinary) one slot at a time
+func WriteDoublyEncryptedQueryResult(w io.Writer, res *DoublyEncryptedQueryResult) error {
+
+	body, err := writeStreamHeader(w, res.Compression)
+	if err != nil {
+		return err
+	}
+
+	enc := &binaryWriter{version: CurrentWireVersion}
+	enc.writeUint(uint64(res.SlotBytes))
+	enc.writeUint(uint64(res.NumBytesPerCiphertext))
+	enc.writeUint(uint64(res.GroupSize))
+	enc.writeColumnMask(res.ColumnMask)
+	enc.writeUint(uint64(len(res.Slots)))
+
+	for _, slot := range res.Slots {
+		enc.writeCiphertexts(slot.Cts)
+		if err := enc.flush(body); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.flush(body); err != nil {
+		return err
+	}
+
+	return body.Close()
+}
+
+// writeStreamHeader writes the wire format version and the compression to w
+// and returns the writer of the (compressed) rest of the encoding
+func writeStreamHeader(w io.Writer, c Compression) (io.WriteCloser, error) {
+
+	header := &binaryWriter{version: CurrentWireVersion}
+	header.writeUint(uint64(CurrentWireVersion))
+	header.writeUint(uint64(c))
+
+	body, err := compressedWriter(c, w)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := header.flush(w); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// flush writes the buffered bytes to w and empties the buffer
+func (w *binaryWriter) flush(out io.Writer) error {
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := out.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// EncryptedResultReader decodes an encrypted result written by WriteEncryptedQueryResult
+// (or encoded with MarshalBinary) one slot at a time.
+// The fields of the result other than the slots are decoded by NewEncryptedResultReader
+type EncryptedResultReader struct {
+	Pk                    *paillier.PublicKey
+	SlotBytes             int
+	NumBytesPerCiphertext int
+	NumSlots              int
+	Compression           Compression
+
+	r         *streamReader
+	remaining int
+}
+
+// NewEncryptedResultReader reads the header of an encrypted result encrypted under pk from r
+func NewEncryptedResultReader(r io.Reader, pk *paillier.PublicKey) (*EncryptedResultReader, error) {
+
+	sr := newStreamReader(r)
+
+	er := &EncryptedResultReader{Pk: pk, r: sr, Compression: sr.compression}
+	er.SlotBytes = int(sr.readUint())
+	er.NumBytesPerCiphertext = int(sr.readUint())
+	er.NumSlots = int(sr.readUint())
+	er.remaining = er.NumSlots
+
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	return er, nil
+}
+
+// Next returns the next slot of the result and io.EOF after the last slot
+func (er *EncryptedResultReader) Next() (*EncryptedSlot, error) {
+
+	if er.remaining == 0 {
+		return nil, io.EOF
+	}
+
+	cts := er.r.readCiphertexts()
+	if er.r.err != nil {
+		return nil, er.r.err
+	}
+
+	er.remaining--
+	return &EncryptedSlot{Cts: cts}, nil
+}
+
+// ReadEncryptedQueryResult decodes an encrypted result encrypted under pk from r
+func ReadEncryptedQueryResult(r io.Reader, pk *paillier.PublicKey) (*EncryptedQueryResult, error) {
+
+	er, err := NewEncryptedResultReader(r, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &EncryptedQueryResult{
+		Pk:                    pk,
+		SlotBytes:             er.SlotBytes,
+		NumBytesPerCiphertext: er.NumBytesPerCiphertext,
+		Slots:                 make([]*EncryptedSlot, 0),
+		Compression:           er.Compression,
+	}
+
+	for {
+		eslot, err := er.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res.Slots = append(res.Slots, eslot)
+	}
+}
+
+// DoublyEncryptedResultReader decodes a doubly encrypted result written by
+// WriteDoublyEncryptedQueryResult (or encoded with MarshalBinary) one slot at a time.
+// The fields of the result other than the slots are decoded by NewDoublyEncryptedResultReader
+type DoublyEncryptedResultReader struct {
+	Pk                    *paillier.PublicKey
+	SlotBytes             int
+	NumBytesPerCiphertext int
+	GroupSize             int
+	ColumnMask            []bool
+	NumSlots              int
+	Compression           Compression
+
+	r         *streamReader
+	remaining int
+}
+
+// NewDoublyEncryptedResultReader reads the header of a doubly encrypted result
+// encrypted under pk from r
+func NewDoublyEncryptedResultReader(r io.Reader, pk *paillier.PublicKey) (*DoublyEncryptedResultReader, error) {
+
+	sr := newStreamReader(r)
+
+	dr := &DoublyEncryptedResultReader{Pk: pk, r: sr, Compression: sr.compression}
+	dr.SlotBytes = int(sr.readUint())
+	dr.NumBytesPerCiphertext = int(sr.readUint())
+	dr.GroupSize = int(sr.readUint())
+	dr.ColumnMask = sr.readColumnMask()
+	dr.NumSlots = int(sr.readUint())
+	dr.remaining = dr.NumSlots
+
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	return dr, nil
+}
+
+// Next returns the next slot of the result and io.EOF after the last slot
+func (dr *DoublyEncryptedResultReader) Next() (*DoublyEncryptedSlot, error) {
+
+	if dr.remaining == 0 {
+		return nil, io.EOF
+	}
+
+	cts := dr.r.readCiphertexts()
+	if dr.r.err != nil {
+		return nil, dr.r.err
+	}
+
+	dr.remaining--
+	return &DoublyEncryptedSlot{Cts: cts}, nil
+}
+
+// ReadDoublyEncryptedQueryResult decodes a doubly encrypted result encrypted under pk from r
+func ReadDoublyEncryptedQueryResult(r io.Reader, pk *paillier.PublicKey) (*DoublyEncryptedQueryResult, error) {
+
+	dr, err := NewDoublyEncryptedResultReader(r, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DoublyEncryptedQueryResult{
+		Pk:                    pk,
+		SlotBytes:             dr.SlotBytes,
+		NumBytesPerCiphertext: dr.NumBytesPerCiphertext,
+		GroupSize:             dr.GroupSize,
+		ColumnMask:            dr.ColumnMask,
+		Slots:                 make([]*DoublyEncryptedSlot, 0),
+		Compression:           dr.Compression,
+	}
+
+	for {
+		slot, err := dr.Next()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res.Slots = append(res.Slots, slot)
+	}
+}
+
+// streamReader reads the fields written by binaryWriter from a stream
+// and records the first error encountered (as binaryReader)
+type streamReader struct {
+	r           *bufio.Reader
+	err         error
+	version     uint
+	compression Compression
+}
+
+// newStreamReader returns a reader over the stream after reading
+// its wire format version (ErrUnsupportedWireVersion if it is not supported)
+// and its compression (the rest of the stream is decompressed as it is read)
+func newStreamReader(r io.Reader) *streamReader {
+
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	sr := &streamReader{r: br}
+	sr.version = uint(sr.readUint())
+	if sr.err == nil && !IsSupportedWireVersion(sr.version) {
+		sr.err = ErrUnsupportedWireVersion
+	}
+
+	if sr.err == nil && sr.version >= WireVersion2 {
+		sr.compression = Compression(sr.readUint())
+	}
+
+	if sr.err != nil {
+		return sr
+	}
+
+	switch sr.compression {
+	case CompressionNone:
+	case CompressionGzip:
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			sr.fail(err)
+			return sr
+		}
+
+		// stop at the end of the compressed result
+		z.Multistream(false)
+		sr.r = bufio.NewReader(z)
+	default:
+		sr.err = ErrUnsupportedCompression
+	}
+
+	return sr
+}
+
+func (sr *streamReader) fail(err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	sr.err = err
+}
+
+func (sr *streamReader) readUint() uint64 {
+
+	if sr.err != nil {
+		return 0
+	}
+
+	v, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		sr.fail(err)
+		return 0
+	}
+
+	return v
+}
+
+func (sr *streamReader) readBool() bool {
+	return sr.readUint() == 1
+}
+
+func (sr *streamReader) readBytes() []byte {
+
+	n := sr.readUint()
+	if sr.err != nil {
+		return nil
+	}
+
+	if n > maxStreamBytes {
+		sr.err = errors.New("byte string in stream exceeds the maximum length")
+		return nil
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(sr.r, b); err != nil {
+		sr.fail(err)
+		return nil
+	}
+
+	return b
+}
+
+// readCiphertexts reads the ciphertexts of a slot (the number of ciphertexts
+// is not trusted to allocate the slice upfront)
+func (sr *streamReader) readCiphertexts() []*paillier.Ciphertext {
+
+	n := sr.readUint()
+
+	cts := make([]*paillier.Ciphertext, 0)
+	for i := uint64(0); i < n && sr.err == nil; i++ {
+		level := paillier.EncryptionLevel(sr.readUint())
+		c := new(gmp.Int).SetBytes(sr.readBytes())
+		cts = append(cts, &paillier.Ciphertext{C: c, Level: level})
+	}
+
+	return cts
+}
+
+func (sr *streamReader) readColumnMask() []bool {
+
+	if !sr.readBool() {
+		return nil
+	}
+
+	n := sr.readUint()
+
+	mask := make([]bool, 0)
+	for i := uint64(0); i < n && sr.err == nil; i++ {
+		mask = append(mask, sr.readBool())
+	}
+
+	return mask
+}
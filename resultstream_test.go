@@ -0,0 +1,169 @@
+package pir
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+// countingWriter counts the calls to Write
+type countingWriter struct {
+	bytes.Buffer
+	numWrites int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.numWrites++
+	return w.Buffer.Write(p)
+}
+
+func TestEncryptedResultStream(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	width, height := db.encryptedQueryDimentions(pk, 1)
+	row := rand.Intn(height)
+
+	res, err := db.PrivateEncryptedQuery(db.NewEncryptedQuery(pk, 1, row), NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &countingWriter{}
+	if err := WriteEncryptedQueryResult(w, res); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stream is the binary encoding, written slot by slot
+	expected, err := res.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(w.Bytes(), expected) {
+		t.Fatalf("Streamed encoding does not match MarshalBinary\n")
+	}
+
+	if w.numWrites != len(res.Slots)+1 {
+		t.Fatalf("Expected %v writes (header and one per slot), got %v\n", len(res.Slots)+1, w.numWrites)
+	}
+
+	// decode over a pipe such that the slots are decoded as they arrive
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(WriteEncryptedQueryResult(pw, res))
+	}()
+
+	decoded, err := ReadEncryptedQueryResult(pr, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := RecoverEncrypted(decoded, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j := 0; j < width && row*width+j < db.DBSize; j++ {
+		if !db.Slots[row*width+j].Equal(slots[j]) {
+			t.Fatalf("Incorrect streamed result: expected %v, got %v\n", db.Slots[row*width+j], slots[j])
+		}
+	}
+
+	// the decoder stops at the end of the result
+	br := bufio.NewReader(io.MultiReader(bytes.NewReader(expected), bytes.NewReader([]byte{42})))
+	if _, err := ReadEncryptedQueryResult(br, pk); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err := br.ReadByte(); err != nil || b != 42 {
+		t.Fatalf("Decoder read past the end of the result\n")
+	}
+
+	// truncated streams fail
+	if _, err := ReadEncryptedQueryResult(bytes.NewReader(expected[:len(expected)-1]), pk); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Expected io.ErrUnexpectedEOF for a truncated stream, got %v\n", err)
+	}
+}
+
+func TestDoublyEncryptedResultStream(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+
+		res, err := db.PrivateDoublyEncryptedQuery(db.NewDoublyEncryptedQuery(pk, groupSize, index), NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteDoublyEncryptedQueryResult(&buf, res); err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := res.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Fatalf("Streamed encoding does not match MarshalBinary\n")
+		}
+
+		// decode slot by slot
+		dr, err := NewDoublyEncryptedResultReader(&buf, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &DoublyEncryptedQueryResult{
+			Pk:                    pk,
+			SlotBytes:             dr.SlotBytes,
+			NumBytesPerCiphertext: dr.NumBytesPerCiphertext,
+			GroupSize:             dr.GroupSize,
+			ColumnMask:            dr.ColumnMask,
+		}
+
+		for {
+			slot, err := dr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded.Slots = append(decoded.Slots, slot)
+		}
+
+		if len(decoded.Slots) != dr.NumSlots {
+			t.Fatalf("Decoded %v slots, expected %v\n", len(decoded.Slots), dr.NumSlots)
+		}
+
+		slots, err := RecoverDoublyEncrypted(decoded, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := (index / groupSize) * groupSize
+		for j := 0; j < groupSize; j++ {
+			expected := NewEmptySlot(SlotBytes)
+			if start+j < db.DBSize {
+				expected = db.Slots[start+j]
+			}
+
+			if !expected.Equal(slots[j]) {
+				t.Fatalf("Incorrect streamed result: expected %v, got %v\n", expected, slots[j])
+			}
+		}
+	}
+}
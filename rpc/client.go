@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir"
+	pirpb "github.com/sachaservan/pir/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrAuthFailed is returned by Client.Authenticate when the server rejects the proof
+var ErrAuthFailed = errors.New("server rejected the ASPIR proof")
+
+// Client is a typed client of the PIR service
+// that converts the messages of the pir package to the generated messages of the service
+type Client struct {
+	pir PIRClient
+}
+
+// Challenge is an ASPIR challenge issued by the server for an authenticated query
+type Challenge struct {
+	ID    uint64
+	Token *pir.ChalToken
+}
+
+// NewClient returns a client of the PIR service over the connection
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{pir: NewPIRClient(conn)}
+}
+
+// toMessage decodes the protobuf encoding of a message of the pir package
+// (e.g., pir.EncryptedQuery.MarshalProto) into the generated message m
+func toMessage[M proto.Message](m M, marshal func() ([]byte, error)) (M, error) {
+
+	var zero M
+
+	data, err := marshal()
+	if err != nil {
+		return zero, err
+	}
+
+	if err := proto.Unmarshal(data, m); err != nil {
+		return zero, err
+	}
+
+	return m, nil
+}
+
+// RegisterPublicKey registers the public key with the server such that it accepts
+// queries encrypted under the key. The server keeps a bounded number of keys,
+// so a client whose queries are rejected with codes.FailedPrecondition registers its key again
+func (c *Client) RegisterPublicKey(ctx context.Context, pk *paillier.PublicKey, opts ...grpc.CallOption) error {
+
+	res, err := c.pir.RegisterPublicKey(ctx, &RegisterPublicKeyRequest{Modulus: pk.N.Bytes()}, opts...)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(res.KeyFingerprint, pir.PublicKeyFingerprint(pk)) {
+		return errors.New("server registered a different public key")
+	}
+
+	return nil
+}
+
+// PrivateSecretSharedQuery sends the query share to the server and returns the server's share of the result
+func (c *Client) PrivateSecretSharedQuery(ctx context.Context, query *pir.QueryShare, opts ...grpc.CallOption) (*pir.SecretSharedQueryResult, error) {
+
+	share, err := toMessage(&pirpb.QueryShare{}, query.MarshalProto)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.pir.PrivateSecretSharedQuery(ctx, &SecretSharedQueryRequest{Share: share}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pir.SecretSharedQueryResult{}
+	if err := result.UnmarshalProto(data); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PrivateEncryptedQuery sends the encrypted query to the server and returns the encrypted result.
+// The public key of the query must be registered with the server
+func (c *Client) PrivateEncryptedQuery(ctx context.Context, query *pir.EncryptedQuery, opts ...grpc.CallOption) (*pir.EncryptedQueryResult, error) {
+
+	msg, err := toMessage(&pirpb.EncryptedQuery{}, query.MarshalProto)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &EncryptedQueryRequest{KeyFingerprint: pir.PublicKeyFingerprint(query.Pk), Query: msg}
+	res, err := c.pir.PrivateEncryptedQuery(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return pir.UnmarshalEncryptedQueryResultProto(data, query.Pk)
+}
+
+// PrivateDoublyEncryptedQuery sends the doubly encrypted query to the server and returns the encrypted result.
+// The public key of the query must be registered with the server
+func (c *Client) PrivateDoublyEncryptedQuery(ctx context.Context, query *pir.DoublyEncryptedQuery, opts ...grpc.CallOption) (*pir.DoublyEncryptedQueryResult, error) {
+
+	msg, err := toMessage(&pirpb.DoublyEncryptedQuery{}, query.MarshalProto)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := query.Row.Pk
+	req := &DoublyEncryptedQueryRequest{KeyFingerprint: pir.PublicKeyFingerprint(pk), Query: msg}
+	res, err := c.pir.PrivateDoublyEncryptedQuery(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return pir.UnmarshalDoublyEncryptedQueryResultProto(data, pk)
+}
+
+// AuthChallenge sends the authenticated query to the server and returns the server's challenge
+func (c *Client) AuthChallenge(ctx context.Context, query *pir.AuthenticatedEncryptedQuery, opts ...grpc.CallOption) (*Challenge, error) {
+
+	msg, err := toMessage(&pirpb.AuthenticatedEncryptedQuery{}, query.MarshalProto)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &AuthChallengeRequest{KeyFingerprint: pir.PublicKeyFingerprint(query.Query0.Row.Pk), Query: msg}
+	res, err := c.pir.AuthChallenge(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := proto.Marshal(res.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	chal := &pir.ChalToken{}
+	if err := chal.UnmarshalProto(data); err != nil {
+		return nil, err
+	}
+
+	return &Challenge{ID: res.ChallengeId, Token: chal}, nil
+}
+
+// AuthVerify sends the proof for the challenge to the server
+// and returns true if and only if the server accepted the proof
+func (c *Client) AuthVerify(ctx context.Context, challengeID uint64, proofToken *pir.ProofToken, opts ...grpc.CallOption) (bool, error) {
+
+	proof, err := toMessage(&pirpb.ProofToken{}, proofToken.MarshalProto)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.pir.AuthVerify(ctx, &AuthVerifyRequest{ChallengeId: challengeID, Proof: proof}, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	return res.Ok, nil
+}
+
+// Authenticate runs the ASPIR flow for the authenticated query: it requests a challenge,
+// proves knowledge of the key (see pir.AuthProve), and returns ErrAuthFailed
+// if the server rejects the proof
+func (c *Client) Authenticate(ctx context.Context, query *pir.AuthenticatedEncryptedQuery, state *pir.AuthQueryPrivateState, opts ...grpc.CallOption) error {
+
+	chal, err := c.AuthChallenge(ctx, query, opts...)
+	if err != nil {
+		return err
+	}
+
+	proofToken, err := pir.AuthProve(state, chal.Token)
+	if err != nil {
+		return err
+	}
+
+	ok, err := c.AuthVerify(ctx, chal.ID, proofToken, opts...)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrAuthFailed
+	}
+
+	return nil
+}
@@ -0,0 +1,589 @@
+// gRPC service of the rpc package.
+//
+// pir.pb.go and pir_grpc.pb.go are generated from this file with protoc-gen-go and
+// protoc-gen-go-grpc (see gen.go). The queries and results are the messages of
+// ../proto/pir.proto. Encrypted queries reference the Paillier public key by its
+// fingerprint (the SHA-256 of the modulus, see pir.PublicKeyFingerprint); the
+// client registers the key with RegisterPublicKey first. The server keeps a bounded number of keys and rejects
+// queries under unregistered (or dropped) keys with FAILED_PRECONDITION.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: rpc/pir.proto
+
+package rpc
+
+import (
+	proto "github.com/sachaservan/pir/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// modulus is the Paillier modulus N (unsigned big-endian bytes)
+type RegisterPublicKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Modulus       []byte                 `protobuf:"bytes,1,opt,name=modulus,proto3" json:"modulus,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterPublicKeyRequest) Reset() {
+	*x = RegisterPublicKeyRequest{}
+	mi := &file_rpc_pir_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterPublicKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPublicKeyRequest) ProtoMessage() {}
+
+func (x *RegisterPublicKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPublicKeyRequest.ProtoReflect.Descriptor instead.
+func (*RegisterPublicKeyRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RegisterPublicKeyRequest) GetModulus() []byte {
+	if x != nil {
+		return x.Modulus
+	}
+	return nil
+}
+
+type RegisterPublicKeyResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	KeyFingerprint []byte                 `protobuf:"bytes,1,opt,name=key_fingerprint,json=keyFingerprint,proto3" json:"key_fingerprint,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RegisterPublicKeyResponse) Reset() {
+	*x = RegisterPublicKeyResponse{}
+	mi := &file_rpc_pir_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterPublicKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterPublicKeyResponse) ProtoMessage() {}
+
+func (x *RegisterPublicKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterPublicKeyResponse.ProtoReflect.Descriptor instead.
+func (*RegisterPublicKeyResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RegisterPublicKeyResponse) GetKeyFingerprint() []byte {
+	if x != nil {
+		return x.KeyFingerprint
+	}
+	return nil
+}
+
+type SecretSharedQueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Share         *proto.QueryShare      `protobuf:"bytes,1,opt,name=share,proto3" json:"share,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecretSharedQueryRequest) Reset() {
+	*x = SecretSharedQueryRequest{}
+	mi := &file_rpc_pir_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecretSharedQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecretSharedQueryRequest) ProtoMessage() {}
+
+func (x *SecretSharedQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecretSharedQueryRequest.ProtoReflect.Descriptor instead.
+func (*SecretSharedQueryRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SecretSharedQueryRequest) GetShare() *proto.QueryShare {
+	if x != nil {
+		return x.Share
+	}
+	return nil
+}
+
+type EncryptedQueryRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	KeyFingerprint []byte                 `protobuf:"bytes,1,opt,name=key_fingerprint,json=keyFingerprint,proto3" json:"key_fingerprint,omitempty"`
+	Query          *proto.EncryptedQuery  `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *EncryptedQueryRequest) Reset() {
+	*x = EncryptedQueryRequest{}
+	mi := &file_rpc_pir_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncryptedQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptedQueryRequest) ProtoMessage() {}
+
+func (x *EncryptedQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptedQueryRequest.ProtoReflect.Descriptor instead.
+func (*EncryptedQueryRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EncryptedQueryRequest) GetKeyFingerprint() []byte {
+	if x != nil {
+		return x.KeyFingerprint
+	}
+	return nil
+}
+
+func (x *EncryptedQueryRequest) GetQuery() *proto.EncryptedQuery {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+type DoublyEncryptedQueryRequest struct {
+	state          protoimpl.MessageState      `protogen:"open.v1"`
+	KeyFingerprint []byte                      `protobuf:"bytes,1,opt,name=key_fingerprint,json=keyFingerprint,proto3" json:"key_fingerprint,omitempty"`
+	Query          *proto.DoublyEncryptedQuery `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DoublyEncryptedQueryRequest) Reset() {
+	*x = DoublyEncryptedQueryRequest{}
+	mi := &file_rpc_pir_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DoublyEncryptedQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DoublyEncryptedQueryRequest) ProtoMessage() {}
+
+func (x *DoublyEncryptedQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DoublyEncryptedQueryRequest.ProtoReflect.Descriptor instead.
+func (*DoublyEncryptedQueryRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DoublyEncryptedQueryRequest) GetKeyFingerprint() []byte {
+	if x != nil {
+		return x.KeyFingerprint
+	}
+	return nil
+}
+
+func (x *DoublyEncryptedQueryRequest) GetQuery() *proto.DoublyEncryptedQuery {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+type AuthChallengeRequest struct {
+	state          protoimpl.MessageState             `protogen:"open.v1"`
+	KeyFingerprint []byte                             `protobuf:"bytes,1,opt,name=key_fingerprint,json=keyFingerprint,proto3" json:"key_fingerprint,omitempty"`
+	Query          *proto.AuthenticatedEncryptedQuery `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AuthChallengeRequest) Reset() {
+	*x = AuthChallengeRequest{}
+	mi := &file_rpc_pir_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthChallengeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthChallengeRequest) ProtoMessage() {}
+
+func (x *AuthChallengeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthChallengeRequest.ProtoReflect.Descriptor instead.
+func (*AuthChallengeRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AuthChallengeRequest) GetKeyFingerprint() []byte {
+	if x != nil {
+		return x.KeyFingerprint
+	}
+	return nil
+}
+
+func (x *AuthChallengeRequest) GetQuery() *proto.AuthenticatedEncryptedQuery {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+type AuthChallengeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChallengeId   uint64                 `protobuf:"varint,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+	Challenge     *proto.ChalToken       `protobuf:"bytes,2,opt,name=challenge,proto3" json:"challenge,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthChallengeResponse) Reset() {
+	*x = AuthChallengeResponse{}
+	mi := &file_rpc_pir_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthChallengeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthChallengeResponse) ProtoMessage() {}
+
+func (x *AuthChallengeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthChallengeResponse.ProtoReflect.Descriptor instead.
+func (*AuthChallengeResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AuthChallengeResponse) GetChallengeId() uint64 {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return 0
+}
+
+func (x *AuthChallengeResponse) GetChallenge() *proto.ChalToken {
+	if x != nil {
+		return x.Challenge
+	}
+	return nil
+}
+
+type AuthVerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChallengeId   uint64                 `protobuf:"varint,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+	Proof         *proto.ProofToken      `protobuf:"bytes,2,opt,name=proof,proto3" json:"proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthVerifyRequest) Reset() {
+	*x = AuthVerifyRequest{}
+	mi := &file_rpc_pir_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthVerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthVerifyRequest) ProtoMessage() {}
+
+func (x *AuthVerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthVerifyRequest.ProtoReflect.Descriptor instead.
+func (*AuthVerifyRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AuthVerifyRequest) GetChallengeId() uint64 {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return 0
+}
+
+func (x *AuthVerifyRequest) GetProof() *proto.ProofToken {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type AuthVerifyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthVerifyResponse) Reset() {
+	*x = AuthVerifyResponse{}
+	mi := &file_rpc_pir_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthVerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthVerifyResponse) ProtoMessage() {}
+
+func (x *AuthVerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_pir_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthVerifyResponse.ProtoReflect.Descriptor instead.
+func (*AuthVerifyResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_pir_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AuthVerifyResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+var File_rpc_pir_proto protoreflect.FileDescriptor
+
+const file_rpc_pir_proto_rawDesc = "" +
+	"\n" +
+	"\rrpc/pir.proto\x12\apir.rpc\x1a\x0fproto/pir.proto\"4\n" +
+	"\x18RegisterPublicKeyRequest\x12\x18\n" +
+	"\amodulus\x18\x01 \x01(\fR\amodulus\"D\n" +
+	"\x19RegisterPublicKeyResponse\x12'\n" +
+	"\x0fkey_fingerprint\x18\x01 \x01(\fR\x0ekeyFingerprint\"A\n" +
+	"\x18SecretSharedQueryRequest\x12%\n" +
+	"\x05share\x18\x01 \x01(\v2\x0f.pir.QueryShareR\x05share\"k\n" +
+	"\x15EncryptedQueryRequest\x12'\n" +
+	"\x0fkey_fingerprint\x18\x01 \x01(\fR\x0ekeyFingerprint\x12)\n" +
+	"\x05query\x18\x02 \x01(\v2\x13.pir.EncryptedQueryR\x05query\"w\n" +
+	"\x1bDoublyEncryptedQueryRequest\x12'\n" +
+	"\x0fkey_fingerprint\x18\x01 \x01(\fR\x0ekeyFingerprint\x12/\n" +
+	"\x05query\x18\x02 \x01(\v2\x19.pir.DoublyEncryptedQueryR\x05query\"w\n" +
+	"\x14AuthChallengeRequest\x12'\n" +
+	"\x0fkey_fingerprint\x18\x01 \x01(\fR\x0ekeyFingerprint\x126\n" +
+	"\x05query\x18\x02 \x01(\v2 .pir.AuthenticatedEncryptedQueryR\x05query\"h\n" +
+	"\x15AuthChallengeResponse\x12!\n" +
+	"\fchallenge_id\x18\x01 \x01(\x04R\vchallengeId\x12,\n" +
+	"\tchallenge\x18\x02 \x01(\v2\x0e.pir.ChalTokenR\tchallenge\"]\n" +
+	"\x11AuthVerifyRequest\x12!\n" +
+	"\fchallenge_id\x18\x01 \x01(\x04R\vchallengeId\x12%\n" +
+	"\x05proof\x18\x02 \x01(\v2\x0f.pir.ProofTokenR\x05proof\"$\n" +
+	"\x12AuthVerifyResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok2\x8f\x04\n" +
+	"\x03PIR\x12Z\n" +
+	"\x11RegisterPublicKey\x12!.pir.rpc.RegisterPublicKeyRequest\x1a\".pir.rpc.RegisterPublicKeyResponse\x12[\n" +
+	"\x18PrivateSecretSharedQuery\x12!.pir.rpc.SecretSharedQueryRequest\x1a\x1c.pir.SecretSharedQueryResult\x12R\n" +
+	"\x15PrivateEncryptedQuery\x12\x1e.pir.rpc.EncryptedQueryRequest\x1a\x19.pir.EncryptedQueryResult\x12d\n" +
+	"\x1bPrivateDoublyEncryptedQuery\x12$.pir.rpc.DoublyEncryptedQueryRequest\x1a\x1f.pir.DoublyEncryptedQueryResult\x12N\n" +
+	"\rAuthChallenge\x12\x1d.pir.rpc.AuthChallengeRequest\x1a\x1e.pir.rpc.AuthChallengeResponse\x12E\n" +
+	"\n" +
+	"AuthVerify\x12\x1a.pir.rpc.AuthVerifyRequest\x1a\x1b.pir.rpc.AuthVerifyResponseB Z\x1egithub.com/sachaservan/pir/rpcb\x06proto3"
+
+var (
+	file_rpc_pir_proto_rawDescOnce sync.Once
+	file_rpc_pir_proto_rawDescData []byte
+)
+
+func file_rpc_pir_proto_rawDescGZIP() []byte {
+	file_rpc_pir_proto_rawDescOnce.Do(func() {
+		file_rpc_pir_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rpc_pir_proto_rawDesc), len(file_rpc_pir_proto_rawDesc)))
+	})
+	return file_rpc_pir_proto_rawDescData
+}
+
+var file_rpc_pir_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_rpc_pir_proto_goTypes = []any{
+	(*RegisterPublicKeyRequest)(nil),          // 0: pir.rpc.RegisterPublicKeyRequest
+	(*RegisterPublicKeyResponse)(nil),         // 1: pir.rpc.RegisterPublicKeyResponse
+	(*SecretSharedQueryRequest)(nil),          // 2: pir.rpc.SecretSharedQueryRequest
+	(*EncryptedQueryRequest)(nil),             // 3: pir.rpc.EncryptedQueryRequest
+	(*DoublyEncryptedQueryRequest)(nil),       // 4: pir.rpc.DoublyEncryptedQueryRequest
+	(*AuthChallengeRequest)(nil),              // 5: pir.rpc.AuthChallengeRequest
+	(*AuthChallengeResponse)(nil),             // 6: pir.rpc.AuthChallengeResponse
+	(*AuthVerifyRequest)(nil),                 // 7: pir.rpc.AuthVerifyRequest
+	(*AuthVerifyResponse)(nil),                // 8: pir.rpc.AuthVerifyResponse
+	(*proto.QueryShare)(nil),                  // 9: pir.QueryShare
+	(*proto.EncryptedQuery)(nil),              // 10: pir.EncryptedQuery
+	(*proto.DoublyEncryptedQuery)(nil),        // 11: pir.DoublyEncryptedQuery
+	(*proto.AuthenticatedEncryptedQuery)(nil), // 12: pir.AuthenticatedEncryptedQuery
+	(*proto.ChalToken)(nil),                   // 13: pir.ChalToken
+	(*proto.ProofToken)(nil),                  // 14: pir.ProofToken
+	(*proto.SecretSharedQueryResult)(nil),     // 15: pir.SecretSharedQueryResult
+	(*proto.EncryptedQueryResult)(nil),        // 16: pir.EncryptedQueryResult
+	(*proto.DoublyEncryptedQueryResult)(nil),  // 17: pir.DoublyEncryptedQueryResult
+}
+var file_rpc_pir_proto_depIdxs = []int32{
+	9,  // 0: pir.rpc.SecretSharedQueryRequest.share:type_name -> pir.QueryShare
+	10, // 1: pir.rpc.EncryptedQueryRequest.query:type_name -> pir.EncryptedQuery
+	11, // 2: pir.rpc.DoublyEncryptedQueryRequest.query:type_name -> pir.DoublyEncryptedQuery
+	12, // 3: pir.rpc.AuthChallengeRequest.query:type_name -> pir.AuthenticatedEncryptedQuery
+	13, // 4: pir.rpc.AuthChallengeResponse.challenge:type_name -> pir.ChalToken
+	14, // 5: pir.rpc.AuthVerifyRequest.proof:type_name -> pir.ProofToken
+	0,  // 6: pir.rpc.PIR.RegisterPublicKey:input_type -> pir.rpc.RegisterPublicKeyRequest
+	2,  // 7: pir.rpc.PIR.PrivateSecretSharedQuery:input_type -> pir.rpc.SecretSharedQueryRequest
+	3,  // 8: pir.rpc.PIR.PrivateEncryptedQuery:input_type -> pir.rpc.EncryptedQueryRequest
+	4,  // 9: pir.rpc.PIR.PrivateDoublyEncryptedQuery:input_type -> pir.rpc.DoublyEncryptedQueryRequest
+	5,  // 10: pir.rpc.PIR.AuthChallenge:input_type -> pir.rpc.AuthChallengeRequest
+	7,  // 11: pir.rpc.PIR.AuthVerify:input_type -> pir.rpc.AuthVerifyRequest
+	1,  // 12: pir.rpc.PIR.RegisterPublicKey:output_type -> pir.rpc.RegisterPublicKeyResponse
+	15, // 13: pir.rpc.PIR.PrivateSecretSharedQuery:output_type -> pir.SecretSharedQueryResult
+	16, // 14: pir.rpc.PIR.PrivateEncryptedQuery:output_type -> pir.EncryptedQueryResult
+	17, // 15: pir.rpc.PIR.PrivateDoublyEncryptedQuery:output_type -> pir.DoublyEncryptedQueryResult
+	6,  // 16: pir.rpc.PIR.AuthChallenge:output_type -> pir.rpc.AuthChallengeResponse
+	8,  // 17: pir.rpc.PIR.AuthVerify:output_type -> pir.rpc.AuthVerifyResponse
+	12, // [12:18] is the sub-list for method output_type
+	6,  // [6:12] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_rpc_pir_proto_init() }
+func file_rpc_pir_proto_init() {
+	if File_rpc_pir_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rpc_pir_proto_rawDesc), len(file_rpc_pir_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rpc_pir_proto_goTypes,
+		DependencyIndexes: file_rpc_pir_proto_depIdxs,
+		MessageInfos:      file_rpc_pir_proto_msgTypes,
+	}.Build()
+	File_rpc_pir_proto = out.File
+	file_rpc_pir_proto_goTypes = nil
+	file_rpc_pir_proto_depIdxs = nil
+}
@@ -0,0 +1,327 @@
+// gRPC service of the rpc package.
+//
+// pir.pb.go and pir_grpc.pb.go are generated from this file with protoc-gen-go and
+// protoc-gen-go-grpc (see gen.go). The queries and results are the messages of
+// ../proto/pir.proto. Encrypted queries reference the Paillier public key by its
+// fingerprint (the SHA-256 of the modulus, see pir.PublicKeyFingerprint); the
+// client registers the key with RegisterPublicKey first. The server keeps a bounded number of keys and rejects
+// queries under unregistered (or dropped) keys with FAILED_PRECONDITION.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: rpc/pir.proto
+
+package rpc
+
+import (
+	context "context"
+	proto "github.com/sachaservan/pir/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PIR_RegisterPublicKey_FullMethodName           = "/pir.rpc.PIR/RegisterPublicKey"
+	PIR_PrivateSecretSharedQuery_FullMethodName    = "/pir.rpc.PIR/PrivateSecretSharedQuery"
+	PIR_PrivateEncryptedQuery_FullMethodName       = "/pir.rpc.PIR/PrivateEncryptedQuery"
+	PIR_PrivateDoublyEncryptedQuery_FullMethodName = "/pir.rpc.PIR/PrivateDoublyEncryptedQuery"
+	PIR_AuthChallenge_FullMethodName               = "/pir.rpc.PIR/AuthChallenge"
+	PIR_AuthVerify_FullMethodName                  = "/pir.rpc.PIR/AuthVerify"
+)
+
+// PIRClient is the client API for PIR service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PIRClient interface {
+	RegisterPublicKey(ctx context.Context, in *RegisterPublicKeyRequest, opts ...grpc.CallOption) (*RegisterPublicKeyResponse, error)
+	PrivateSecretSharedQuery(ctx context.Context, in *SecretSharedQueryRequest, opts ...grpc.CallOption) (*proto.SecretSharedQueryResult, error)
+	PrivateEncryptedQuery(ctx context.Context, in *EncryptedQueryRequest, opts ...grpc.CallOption) (*proto.EncryptedQueryResult, error)
+	PrivateDoublyEncryptedQuery(ctx context.Context, in *DoublyEncryptedQueryRequest, opts ...grpc.CallOption) (*proto.DoublyEncryptedQueryResult, error)
+	// ASPIR: the server issues a challenge for the authenticated query,
+	// the client proves knowledge of the key (pir.AuthProve),
+	// and the server verifies the proof (pir.AuthCheck)
+	AuthChallenge(ctx context.Context, in *AuthChallengeRequest, opts ...grpc.CallOption) (*AuthChallengeResponse, error)
+	AuthVerify(ctx context.Context, in *AuthVerifyRequest, opts ...grpc.CallOption) (*AuthVerifyResponse, error)
+}
+
+type pIRClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPIRClient(cc grpc.ClientConnInterface) PIRClient {
+	return &pIRClient{cc}
+}
+
+func (c *pIRClient) RegisterPublicKey(ctx context.Context, in *RegisterPublicKeyRequest, opts ...grpc.CallOption) (*RegisterPublicKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterPublicKeyResponse)
+	err := c.cc.Invoke(ctx, PIR_RegisterPublicKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pIRClient) PrivateSecretSharedQuery(ctx context.Context, in *SecretSharedQueryRequest, opts ...grpc.CallOption) (*proto.SecretSharedQueryResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.SecretSharedQueryResult)
+	err := c.cc.Invoke(ctx, PIR_PrivateSecretSharedQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pIRClient) PrivateEncryptedQuery(ctx context.Context, in *EncryptedQueryRequest, opts ...grpc.CallOption) (*proto.EncryptedQueryResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.EncryptedQueryResult)
+	err := c.cc.Invoke(ctx, PIR_PrivateEncryptedQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pIRClient) PrivateDoublyEncryptedQuery(ctx context.Context, in *DoublyEncryptedQueryRequest, opts ...grpc.CallOption) (*proto.DoublyEncryptedQueryResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(proto.DoublyEncryptedQueryResult)
+	err := c.cc.Invoke(ctx, PIR_PrivateDoublyEncryptedQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pIRClient) AuthChallenge(ctx context.Context, in *AuthChallengeRequest, opts ...grpc.CallOption) (*AuthChallengeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthChallengeResponse)
+	err := c.cc.Invoke(ctx, PIR_AuthChallenge_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pIRClient) AuthVerify(ctx context.Context, in *AuthVerifyRequest, opts ...grpc.CallOption) (*AuthVerifyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthVerifyResponse)
+	err := c.cc.Invoke(ctx, PIR_AuthVerify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PIRServer is the server API for PIR service.
+// All implementations must embed UnimplementedPIRServer
+// for forward compatibility.
+type PIRServer interface {
+	RegisterPublicKey(context.Context, *RegisterPublicKeyRequest) (*RegisterPublicKeyResponse, error)
+	PrivateSecretSharedQuery(context.Context, *SecretSharedQueryRequest) (*proto.SecretSharedQueryResult, error)
+	PrivateEncryptedQuery(context.Context, *EncryptedQueryRequest) (*proto.EncryptedQueryResult, error)
+	PrivateDoublyEncryptedQuery(context.Context, *DoublyEncryptedQueryRequest) (*proto.DoublyEncryptedQueryResult, error)
+	// ASPIR: the server issues a challenge for the authenticated query,
+	// the client proves knowledge of the key (pir.AuthProve),
+	// and the server verifies the proof (pir.AuthCheck)
+	AuthChallenge(context.Context, *AuthChallengeRequest) (*AuthChallengeResponse, error)
+	AuthVerify(context.Context, *AuthVerifyRequest) (*AuthVerifyResponse, error)
+	mustEmbedUnimplementedPIRServer()
+}
+
+// UnimplementedPIRServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPIRServer struct{}
+
+func (UnimplementedPIRServer) RegisterPublicKey(context.Context, *RegisterPublicKeyRequest) (*RegisterPublicKeyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RegisterPublicKey not implemented")
+}
+func (UnimplementedPIRServer) PrivateSecretSharedQuery(context.Context, *SecretSharedQueryRequest) (*proto.SecretSharedQueryResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method PrivateSecretSharedQuery not implemented")
+}
+func (UnimplementedPIRServer) PrivateEncryptedQuery(context.Context, *EncryptedQueryRequest) (*proto.EncryptedQueryResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method PrivateEncryptedQuery not implemented")
+}
+func (UnimplementedPIRServer) PrivateDoublyEncryptedQuery(context.Context, *DoublyEncryptedQueryRequest) (*proto.DoublyEncryptedQueryResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method PrivateDoublyEncryptedQuery not implemented")
+}
+func (UnimplementedPIRServer) AuthChallenge(context.Context, *AuthChallengeRequest) (*AuthChallengeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AuthChallenge not implemented")
+}
+func (UnimplementedPIRServer) AuthVerify(context.Context, *AuthVerifyRequest) (*AuthVerifyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AuthVerify not implemented")
+}
+func (UnimplementedPIRServer) mustEmbedUnimplementedPIRServer() {}
+func (UnimplementedPIRServer) testEmbeddedByValue()             {}
+
+// UnsafePIRServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PIRServer will
+// result in compilation errors.
+type UnsafePIRServer interface {
+	mustEmbedUnimplementedPIRServer()
+}
+
+func RegisterPIRServer(s grpc.ServiceRegistrar, srv PIRServer) {
+	// If the following call panics, it indicates UnimplementedPIRServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PIR_ServiceDesc, srv)
+}
+
+func _PIR_RegisterPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterPublicKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PIRServer).RegisterPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PIR_RegisterPublicKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PIRServer).RegisterPublicKey(ctx, req.(*RegisterPublicKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PIR_PrivateSecretSharedQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretSharedQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PIRServer).PrivateSecretSharedQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PIR_PrivateSecretSharedQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PIRServer).PrivateSecretSharedQuery(ctx, req.(*SecretSharedQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PIR_PrivateEncryptedQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncryptedQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PIRServer).PrivateEncryptedQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PIR_PrivateEncryptedQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PIRServer).PrivateEncryptedQuery(ctx, req.(*EncryptedQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PIR_PrivateDoublyEncryptedQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DoublyEncryptedQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PIRServer).PrivateDoublyEncryptedQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PIR_PrivateDoublyEncryptedQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PIRServer).PrivateDoublyEncryptedQuery(ctx, req.(*DoublyEncryptedQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PIR_AuthChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PIRServer).AuthChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PIR_AuthChallenge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PIRServer).AuthChallenge(ctx, req.(*AuthChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PIR_AuthVerify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthVerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PIRServer).AuthVerify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PIR_AuthVerify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PIRServer).AuthVerify(ctx, req.(*AuthVerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PIR_ServiceDesc is the grpc.ServiceDesc for PIR service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PIR_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pir.rpc.PIR",
+	HandlerType: (*PIRServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterPublicKey",
+			Handler:    _PIR_RegisterPublicKey_Handler,
+		},
+		{
+			MethodName: "PrivateSecretSharedQuery",
+			Handler:    _PIR_PrivateSecretSharedQuery_Handler,
+		},
+		{
+			MethodName: "PrivateEncryptedQuery",
+			Handler:    _PIR_PrivateEncryptedQuery_Handler,
+		},
+		{
+			MethodName: "PrivateDoublyEncryptedQuery",
+			Handler:    _PIR_PrivateDoublyEncryptedQuery_Handler,
+		},
+		{
+			MethodName: "AuthChallenge",
+			Handler:    _PIR_AuthChallenge_Handler,
+		},
+		{
+			MethodName: "AuthVerify",
+			Handler:    _PIR_AuthVerify_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rpc/pir.proto",
+}
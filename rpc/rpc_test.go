@@ -0,0 +1,398 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir"
+	pirpb "github.com/sachaservan/pir/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// serve serves the service over an in-memory connection with a gRPC server
+// and returns a connection to it (closed at the end of the test)
+func serve(t *testing.T, srv *Server) *grpc.ClientConn {
+
+	lis := bufconn.Listen(1 << 20)
+
+	gs := grpc.NewServer()
+	srv.Register(gs)
+	go gs.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		gs.Stop()
+	})
+
+	return conn
+}
+
+func setupService(t *testing.T, db *pir.Database) (*Server, *Client) {
+
+	srv := NewServer(pir.NewServer(db), pir.NumProcsForQuery)
+	client := NewClient(serve(t, srv))
+
+	return srv, client
+}
+
+func TestSecretSharedQuery(t *testing.T) {
+
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	_, client := setupService(t, db)
+
+	for groupSize := pir.MinGroupSize; groupSize < pir.MaxGroupSize; groupSize++ {
+
+		index := rand.Intn(pir.TestDBSize)
+		shares := db.NewIndexQueryShares(index/groupSize, groupSize, 2)
+
+		results := make([]*pir.SecretSharedQueryResult, len(shares))
+		for i, share := range shares {
+			res, err := client.PrivateSecretSharedQuery(context.Background(), share)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results[i] = res
+		}
+
+		slots := pir.Recover(results)
+		if !slots[index%groupSize].Equal(db.Slots[index]) {
+			t.Fatalf("Incorrect slot retrieved over rpc (group size %v)\n", groupSize)
+		}
+	}
+}
+
+func TestEncryptedQuery(t *testing.T) {
+
+	sk, pk := paillier.KeyGen(128)
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	srv, client := setupService(t, db)
+
+	index := rand.Intn(pir.TestDBSize)
+	width := db.NewEncryptedQuery(pk, 1, 0).DBWidth
+	query := db.NewEncryptedQuery(pk, 1, index/width)
+	doubly := db.NewDoublyEncryptedQuery(pk, 1, index)
+
+	// the key must be registered first
+	_, err := client.PrivateEncryptedQuery(context.Background(), query)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Query under an unregistered key was not rejected: %v\n", err)
+	}
+
+	if err := client.RegisterPublicKey(context.Background(), pk); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.PrivateEncryptedQuery(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := pir.RecoverEncrypted(res, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slots[index%width].Equal(db.Slots[index]) {
+		t.Fatalf("Incorrect slot retrieved with an encrypted query over rpc\n")
+	}
+
+	doublyRes, err := client.PrivateDoublyEncryptedQuery(context.Background(), doubly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err = pir.RecoverDoublyEncrypted(doublyRes, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slots[0].Equal(db.Slots[index]) {
+		t.Fatalf("Incorrect slot retrieved with a doubly encrypted query over rpc\n")
+	}
+
+	// queries that violate the server's contract are rejected
+	srv.PIR.AllowShape(query.DBWidth+1, query.DBHeight, 1)
+	_, err = client.PrivateEncryptedQuery(context.Background(), query)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Query with a disallowed shape was not rejected: %v\n", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+
+	secbytes := pir.StatisticalSecurityBytes
+	sk, pk := paillier.KeyGen(128)
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	srv, client := setupService(t, db)
+
+	if err := client.RegisterPublicKey(context.Background(), pk); err != nil {
+		t.Fatal(err)
+	}
+
+	index := rand.Intn(pir.TestDBSize)
+	authQuery, state := db.NewAuthenticatedQuery(sk, 1, index, db.Slots[0])
+
+	// challenges are only issued with a key database
+	_, err := client.AuthChallenge(context.Background(), authQuery)
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("Challenge issued without a key database: %v\n", err)
+	}
+
+	srv.KeyDB = pir.BuildKeyDBFromData(db, secbytes)
+	srv.SecParam = secbytes
+
+	authQuery, state = db.NewAuthenticatedQuery(sk, 1, index, srv.KeyDB.Slots[index])
+	if err := client.Authenticate(context.Background(), authQuery, state); err != nil {
+		t.Fatal(err)
+	}
+
+	// a forged proof is rejected
+	chal, err := client.AuthChallenge(context.Background(), authQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofToken, err := pir.AuthProve(state, chal.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := *proofToken
+	forged.QBit = 1 - forged.QBit
+	ok, err := client.AuthVerify(context.Background(), chal.ID, &forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Fatalf("Forged proof accepted over rpc\n")
+	}
+
+	// each challenge is answered at most once
+	_, err = client.AuthVerify(context.Background(), chal.ID, proofToken)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Challenge answered twice: %v\n", err)
+	}
+}
+
+func TestStandardCodec(t *testing.T) {
+
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	client := NewPIRClient(serve(t, NewServer(pir.NewServer(db), pir.NumProcsForQuery)))
+
+	index := rand.Intn(pir.TestDBSize)
+	shares := db.NewIndexQueryShares(index, 1, 2)
+
+	// call the service with the generated client and messages,
+	// as a client generated from pir.proto in another language does
+	results := make([]*pir.SecretSharedQueryResult, len(shares))
+	for i, share := range shares {
+		data, err := share.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := &SecretSharedQueryRequest{Share: &pirpb.QueryShare{}}
+		if err := proto.Unmarshal(data, req.Share); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.PrivateSecretSharedQuery(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err = proto.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results[i] = &pir.SecretSharedQueryResult{}
+		if err := results[i].UnmarshalProto(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !pir.Recover(results)[0].Equal(db.Slots[index]) {
+		t.Fatalf("Incorrect slot retrieved with the standard codec\n")
+	}
+}
+
+func TestRegisterPublicKey(t *testing.T) {
+
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	srv, client := setupService(t, db)
+	srv.MaxPublicKeys = 2
+
+	keys := make([]*paillier.PublicKey, 3)
+	for i := range keys {
+		_, keys[i] = paillier.KeyGen(128)
+		if err := client.RegisterPublicKey(context.Background(), keys[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the least recently registered key is dropped when full
+	query := db.NewEncryptedQuery(keys[0], 1, 0)
+	_, err := client.PrivateEncryptedQuery(context.Background(), query)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Query under a dropped key was not rejected: %v\n", err)
+	}
+
+	for _, pk := range keys[1:] {
+		if _, err := client.PrivateEncryptedQuery(context.Background(), db.NewEncryptedQuery(pk, 1, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// registering the key again makes it the most recently registered key
+	if err := client.RegisterPublicKey(context.Background(), keys[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.RegisterPublicKey(context.Background(), keys[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.PrivateEncryptedQuery(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srv.keys) != srv.MaxPublicKeys || len(srv.keyOrder) != srv.MaxPublicKeys {
+		t.Fatalf("Server keeps %v keys; expected %v\n", len(srv.keys), srv.MaxPublicKeys)
+	}
+
+	// invalid moduli are rejected
+	for _, n := range []int64{0, 1, 4} {
+		err := client.RegisterPublicKey(context.Background(), &paillier.PublicKey{N: gmp.NewInt(n)})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Modulus %v was not rejected: %v\n", n, err)
+		}
+	}
+}
+
+func TestZeroValueServer(t *testing.T) {
+
+	sk, pk := paillier.KeyGen(128)
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+
+	srv := &Server{}
+	client := NewClient(serve(t, srv))
+
+	if err := client.RegisterPublicKey(context.Background(), pk); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server does not have a database
+	_, err := client.PrivateEncryptedQuery(context.Background(), db.NewEncryptedQuery(pk, 1, 0))
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Server without a database answered a query: %v\n", err)
+	}
+
+	// without a limit, the server keeps the default number of challenges
+	srv.KeyDB = pir.BuildKeyDBFromData(db, pir.StatisticalSecurityBytes)
+	srv.SecParam = pir.StatisticalSecurityBytes
+	srv.NumProcs = 1
+
+	index := rand.Intn(pir.TestDBSize)
+	authQuery, state := db.NewAuthenticatedQuery(sk, 1, index, srv.KeyDB.Slots[index])
+	if err := client.Authenticate(context.Background(), authQuery, state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+
+	for _, test := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{fmt.Errorf("%w: bad dimensions", pir.ErrInvalidQuery), codes.InvalidArgument},
+		{pir.ErrQueryShapeNotAllowed, codes.InvalidArgument},
+		{pir.ErrRateLimited, codes.ResourceExhausted},
+		{pir.ErrDatabaseClosed, codes.Unavailable},
+		{context.Canceled, codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("out of memory"), codes.Internal},
+	} {
+		if code := status.Code(statusError(test.err)); code != test.code {
+			t.Fatalf("Error %v has code %v; expected %v\n", test.err, code, test.code)
+		}
+	}
+
+	// the context of the call is passed to the database
+	_, pk := paillier.KeyGen(128)
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	srv := NewServer(pir.NewServer(db), 1)
+	srv.RegisterPublicKey(pk)
+
+	query, err := toMessage(&pirpb.EncryptedQuery{}, db.NewEncryptedQuery(pk, 1, 0).MarshalProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := &EncryptedQueryRequest{KeyFingerprint: pir.PublicKeyFingerprint(pk), Query: query}
+	if _, err := srv.privateEncryptedQuery(ctx, req); status.Code(err) != codes.Canceled {
+		t.Fatalf("Query with a cancelled context was not cancelled: %v\n", err)
+	}
+
+	// requests without a query are rejected
+	req = &EncryptedQueryRequest{KeyFingerprint: pir.PublicKeyFingerprint(pk)}
+	if _, err := srv.privateEncryptedQuery(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Request without a query was not rejected: %v\n", err)
+	}
+}
+
+func TestRateLimitSource(t *testing.T) {
+
+	db := pir.GenerateRandomDB(pir.TestDBSize, pir.SlotBytes)
+	srv, client := setupService(t, db)
+	srv.PIR.Limiter = pir.NewRateLimiter(1e-9, 1)
+
+	share := db.NewIndexQueryShares(0, 1, 2)[0]
+	if _, err := client.PrivateSecretSharedQuery(context.Background(), share); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.PrivateSecretSharedQuery(context.Background(), share); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Query over the rate limit was not rejected: %v\n", err)
+	}
+
+	// a query of the same shape from another client has its own bucket
+	msg, err := toMessage(&pirpb.QueryShare{}, share.MarshalProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 4242},
+	})
+
+	if _, err := srv.privateSecretSharedQuery(other, &SecretSharedQueryRequest{Share: msg}); err != nil {
+		t.Fatalf("Query from another client was rejected: %v\n", err)
+	}
+}
@@ -0,0 +1,448 @@
+// Package rpc exposes a pir.Server as a gRPC service (see pir.proto)
+// and provides a typed Go client for it (see Client).
+//
+// The service answers two-server (secret-shared) queries, single-server
+// encrypted queries, and the ASPIR challenge/verify rounds of an authenticated
+// query. Clients register their Paillier public key with the RegisterPublicKey
+// method (see Client.RegisterPublicKey) and reference it by fingerprint in their
+// queries, as the public key is not part of the messages of the pir package.
+//
+// The messages of the service are generated from pir.proto (see gen.go) and encoded
+// with the standard protobuf codec of gRPC, so clients generated from pir.proto in
+// any language can call the service.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir"
+	pirpb "github.com/sachaservan/pir/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ServiceName is the full name of the PIR service
+const ServiceName = "pir.rpc.PIR"
+
+// DefaultMaxPendingChallenges is the default number of ASPIR challenges
+// that a server keeps while waiting for the client's proof
+const DefaultMaxPendingChallenges = 1024
+
+// DefaultMaxPublicKeys is the default number of public keys that a server keeps registered
+const DefaultMaxPublicKeys = 1024
+
+// Server implements the PIR service over a pir.Server.
+// The zero value is a server without a database that only registers keys
+type Server struct {
+	PIR      *pir.Server
+	NumProcs int
+
+	// key database and security parameter of the ASPIR challenges
+	// (AuthChallenge is unimplemented if KeyDB is nil)
+	KeyDB    *pir.Database
+	SecParam int
+
+	// challenges awaiting a proof; the oldest challenge is dropped when full
+	// (DefaultMaxPendingChallenges if <= 0)
+	MaxPendingChallenges int
+
+	// registered public keys; the least recently registered key is dropped when full
+	// such that clients cannot exhaust the server's memory (DefaultMaxPublicKeys if <= 0)
+	MaxPublicKeys int
+
+	mu         sync.Mutex
+	keys       map[string]*paillier.PublicKey
+	keyOrder   []string
+	challenges map[uint64]*pendingChallenge
+	order      []uint64
+	nextID     uint64
+}
+
+// pendingChallenge is an ASPIR challenge issued by AuthChallenge
+type pendingChallenge struct {
+	pk    *paillier.PublicKey
+	query *pir.AuthenticatedEncryptedQuery
+	chal  *pir.ChalToken
+}
+
+// NewServer returns a server that answers queries with s using nprocs goroutines per query
+func NewServer(s *pir.Server, nprocs int) *Server {
+	return &Server{
+		PIR:                  s,
+		NumProcs:             nprocs,
+		MaxPendingChallenges: DefaultMaxPendingChallenges,
+		MaxPublicKeys:        DefaultMaxPublicKeys,
+	}
+}
+
+// Register registers the PIR service with the gRPC server
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	RegisterPIRServer(r, &service{s: s})
+}
+
+// RegisterPublicKey registers a client's public key such that
+// queries encrypted under the key are accepted
+// (clients register their key with the RegisterPublicKey method of the service)
+func (s *Server) RegisterPublicKey(pk *paillier.PublicKey) {
+
+	fingerprint := string(pir.PublicKeyFingerprint(pk))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keys == nil {
+		s.keys = make(map[string]*paillier.PublicKey)
+	}
+
+	// re-registering a key makes it the most recently registered key
+	if _, ok := s.keys[fingerprint]; ok {
+		for i, f := range s.keyOrder {
+			if f == fingerprint {
+				s.keyOrder = append(s.keyOrder[:i], s.keyOrder[i+1:]...)
+				break
+			}
+		}
+	}
+
+	s.keys[fingerprint] = pk
+	s.keyOrder = append(s.keyOrder, fingerprint)
+
+	// drop the least recently registered keys when full
+	for len(s.keyOrder) > orDefault(s.MaxPublicKeys, DefaultMaxPublicKeys) {
+		delete(s.keys, s.keyOrder[0])
+		s.keyOrder = s.keyOrder[1:]
+	}
+}
+
+// publicKey returns the registered public key with the fingerprint
+func (s *Server) publicKey(fingerprint []byte) (*paillier.PublicKey, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pk, ok := s.keys[string(fingerprint)]
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "public key is not registered")
+	}
+
+	return pk, nil
+}
+
+// pirServer returns the server that answers the queries
+func (s *Server) pirServer() (*pir.Server, error) {
+
+	if s.PIR == nil {
+		return nil, status.Error(codes.Unavailable, "server does not have a database")
+	}
+
+	return s.PIR, nil
+}
+
+func (s *Server) registerPublicKey(ctx context.Context, req *RegisterPublicKeyRequest) (*RegisterPublicKeyResponse, error) {
+
+	n := new(gmp.Int).SetBytes(req.Modulus)
+	if n.Cmp(gmp.NewInt(1)) <= 0 || n.Bit(0) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "modulus must be an odd integer greater than 1")
+	}
+
+	pk := &paillier.PublicKey{N: n}
+	s.RegisterPublicKey(pk)
+
+	return &RegisterPublicKeyResponse{KeyFingerprint: pir.PublicKeyFingerprint(pk)}, nil
+}
+
+func (s *Server) privateSecretSharedQuery(ctx context.Context, req *SecretSharedQueryRequest) (*pirpb.SecretSharedQueryResult, error) {
+
+	srv, err := s.pirServer()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := requestBytes(req.Share)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &pir.QueryShare{}
+	if err := query.UnmarshalProto(data); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res, err := srv.PrivateSecretSharedQueryContext(querySource(ctx), query, s.NumProcs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return marshalResult(&pirpb.SecretSharedQueryResult{}, res.MarshalProto)
+}
+
+func (s *Server) privateEncryptedQuery(ctx context.Context, req *EncryptedQueryRequest) (*pirpb.EncryptedQueryResult, error) {
+
+	srv, err := s.pirServer()
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := s.publicKey(req.KeyFingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := requestBytes(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := pir.UnmarshalEncryptedQueryProto(data, pk)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res, err := srv.PrivateEncryptedQueryContext(querySource(ctx), query, s.NumProcs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return marshalResult(&pirpb.EncryptedQueryResult{}, res.MarshalProto)
+}
+
+func (s *Server) privateDoublyEncryptedQuery(ctx context.Context, req *DoublyEncryptedQueryRequest) (*pirpb.DoublyEncryptedQueryResult, error) {
+
+	srv, err := s.pirServer()
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := s.publicKey(req.KeyFingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := requestBytes(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := pir.UnmarshalDoublyEncryptedQueryProto(data, pk)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res, err := srv.PrivateDoublyEncryptedQueryContext(querySource(ctx), query, s.NumProcs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return marshalResult(&pirpb.DoublyEncryptedQueryResult{}, res.MarshalProto)
+}
+
+// querySource returns a copy of ctx with the IP address of the client of the call
+// as the source of the query, such that the pir.Server rate limits each client
+// independently (see pir.WithQuerySource)
+func querySource(ctx context.Context) context.Context {
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ctx
+	}
+
+	source := p.Addr.String()
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+
+	return pir.WithQuerySource(ctx, source)
+}
+
+func (s *Server) authChallenge(ctx context.Context, req *AuthChallengeRequest) (*AuthChallengeResponse, error) {
+
+	if s.KeyDB == nil {
+		return nil, status.Error(codes.Unimplemented, "server does not issue ASPIR challenges")
+	}
+
+	pk, err := s.publicKey(req.KeyFingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := requestBytes(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := pir.UnmarshalAuthenticatedEncryptedQueryProto(data, pk)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the challenge is not cancellable, so do not start it for a cancelled call
+	if err := ctx.Err(); err != nil {
+		return nil, statusError(err)
+	}
+
+	chal, err := pir.GenerateAuthChalForQuery(s.SecParam, s.KeyDB, query, s.NumProcs)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	token, err := marshalResult(&pirpb.ChalToken{}, chal.MarshalProto)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.challenges == nil {
+		s.challenges = make(map[uint64]*pendingChallenge)
+	}
+
+	s.nextID++
+	id := s.nextID
+	s.challenges[id] = &pendingChallenge{pk: pk, query: query, chal: chal}
+	s.order = append(s.order, id)
+
+	// drop the oldest challenges when full
+	for len(s.order) > orDefault(s.MaxPendingChallenges, DefaultMaxPendingChallenges) {
+		delete(s.challenges, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	return &AuthChallengeResponse{ChallengeId: id, Challenge: token}, nil
+}
+
+func (s *Server) authVerify(ctx context.Context, req *AuthVerifyRequest) (*AuthVerifyResponse, error) {
+
+	data, err := requestBytes(req.Proof)
+	if err != nil {
+		return nil, err
+	}
+
+	proofToken := &pir.ProofToken{}
+	if err := proofToken.UnmarshalProto(data); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// each challenge is answered at most once
+	s.mu.Lock()
+	pending, ok := s.challenges[req.ChallengeId]
+	if ok {
+		delete(s.challenges, req.ChallengeId)
+		for i, id := range s.order {
+			if id == req.ChallengeId {
+				s.order = append(s.order[:i], s.order[i+1:]...)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown or expired challenge")
+	}
+
+	return &AuthVerifyResponse{
+		Ok: pir.AuthCheck(pending.pk, pending.query, pending.chal, proofToken),
+	}, nil
+}
+
+// requestBytes returns the protobuf encoding of a message of a request,
+// which the pir package decodes (e.g., with pir.UnmarshalEncryptedQueryProto)
+func requestBytes(m proto.Message) ([]byte, error) {
+
+	if !m.ProtoReflect().IsValid() {
+		return nil, status.Error(codes.InvalidArgument, "request is missing its query")
+	}
+
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return data, nil
+}
+
+// marshalResult converts a result of the pir package to the generated message res (see toMessage)
+func marshalResult[M proto.Message](res M, marshal func() ([]byte, error)) (M, error) {
+
+	res, err := toMessage(res, marshal)
+	if err != nil {
+		return res, status.Error(codes.Internal, err.Error())
+	}
+
+	return res, nil
+}
+
+// statusError converts an error returned by the pir package to a gRPC status:
+// malformed queries are rejected, rate limited queries and queries to a closed
+// database are retryable, and every other error is an internal error of the server
+func statusError(err error) error {
+
+	var code codes.Code
+	switch {
+	case errors.Is(err, pir.ErrInvalidQuery),
+		errors.Is(err, pir.ErrQueryShapeNotAllowed),
+		errors.Is(err, pir.ErrInvalidGroupSize),
+		errors.Is(err, pir.ErrKeywordOutOfDomain):
+		code = codes.InvalidArgument
+	case errors.Is(err, pir.ErrRateLimited):
+		code = codes.ResourceExhausted
+	case errors.Is(err, pir.ErrDatabaseClosed):
+		code = codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// orDefault returns v or def if v <= 0
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// service implements the generated PIRServer interface with the methods of a Server,
+// whose exported RegisterPublicKey method registers keys from Go
+type service struct {
+	UnimplementedPIRServer
+	s *Server
+}
+
+func (v *service) RegisterPublicKey(ctx context.Context, req *RegisterPublicKeyRequest) (*RegisterPublicKeyResponse, error) {
+	return v.s.registerPublicKey(ctx, req)
+}
+
+func (v *service) PrivateSecretSharedQuery(ctx context.Context, req *SecretSharedQueryRequest) (*pirpb.SecretSharedQueryResult, error) {
+	return v.s.privateSecretSharedQuery(ctx, req)
+}
+
+func (v *service) PrivateEncryptedQuery(ctx context.Context, req *EncryptedQueryRequest) (*pirpb.EncryptedQueryResult, error) {
+	return v.s.privateEncryptedQuery(ctx, req)
+}
+
+func (v *service) PrivateDoublyEncryptedQuery(ctx context.Context, req *DoublyEncryptedQueryRequest) (*pirpb.DoublyEncryptedQueryResult, error) {
+	return v.s.privateDoublyEncryptedQuery(ctx, req)
+}
+
+func (v *service) AuthChallenge(ctx context.Context, req *AuthChallengeRequest) (*AuthChallengeResponse, error) {
+	return v.s.authChallenge(ctx, req)
+}
+
+func (v *service) AuthVerify(ctx context.Context, req *AuthVerifyRequest) (*AuthVerifyResponse, error) {
+	return v.s.authVerify(ctx, req)
+}
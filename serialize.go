@@ -0,0 +1,508 @@
+package pir
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir/dpf"
+)
+
+// This file contains the binary encodings of queries and results
+// such that they can be sent over the network.
+// The Paillier public key is not part of the encoding of encrypted
+// queries and results; it is exchanged once and passed to the decoders.
+// Every encoding starts with the wire format version (see wireversion.go)
+
+var errMalformedEncoding = errors.New("malformed binary encoding")
+
+// ErrMissingDPFKey is returned when encoding a query share
+// that does not have the DPF key of its type (two-party or multi-party)
+var ErrMissingDPFKey = errors.New("query share does not have a DPF key")
+
+// queryShareWriter is implemented by the encodings (binary, CBOR, protobuf, and
+// canonical) that write the fields of a query share in the order of writeQueryShare
+type queryShareWriter interface {
+	writeBool(b bool)
+	writeUint(v uint64)
+	writeBytes(b []byte)
+	writeBytesList(bs [][]byte)
+	writeRegion(region *GroupRegion)
+}
+
+// writeQueryShare writes the fields of the query share in order and returns
+// ErrMissingDPFKey if the share does not have its DPF key (an empty key is written instead)
+func writeQueryShare(w queryShareWriter, query *QueryShare) error {
+
+	prfKeys := make([][]byte, len(query.PrfKeys))
+	for i, key := range query.PrfKeys {
+		prfKeys[i] = key.Bytes
+	}
+
+	dpfKey, err := query.dpfKeyBytes()
+
+	w.writeBool(query.IsTwoParty)
+	w.writeBool(query.IsKeywordBased)
+	w.writeUint(uint64(query.ShareNumber))
+	w.writeUint(uint64(query.GroupSize))
+	w.writeBytesList(prfKeys)
+	w.writeBytes(dpfKey)
+	w.writeRegion(query.Region)
+	w.writeUint(uint64(query.PrefixBits))
+
+	return err
+}
+
+// dpfKeyBytes returns the encoding of the DPF key of the share
+// (or ErrMissingDPFKey if the share does not have one)
+func (query *QueryShare) dpfKeyBytes() ([]byte, error) {
+
+	if query.IsTwoParty {
+		if query.KeyTwoParty == nil {
+			return nil, ErrMissingDPFKey
+		}
+		return query.KeyTwoParty.Bytes(), nil
+	}
+
+	if query.KeyMultiParty == nil {
+		return nil, ErrMissingDPFKey
+	}
+	return query.KeyMultiParty.Bytes(), nil
+}
+
+// setDPFKey decodes the DPF key of the share's type from its encoding
+func (query *QueryShare) setDPFKey(keyBytes []byte) error {
+
+	var err error
+	if query.IsTwoParty {
+		query.KeyTwoParty, err = dpf.Key2PFromBytes(keyBytes)
+	} else {
+		query.KeyMultiParty, err = dpf.KeyMPFromBytes(keyBytes)
+	}
+
+	return err
+}
+
+// MarshalBinary encodes the query share
+// in the current wire format version (see MarshalBinaryVersion)
+func (query *QueryShare) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryVersion(query, CurrentWireVersion)
+}
+
+func (query *QueryShare) encodeBinary(w *binaryWriter) {
+	if err := writeQueryShare(w, query); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// UnmarshalBinary decodes a query share encoded with MarshalBinary
+func (query *QueryShare) UnmarshalBinary(data []byte) error {
+
+	r := newBinaryReader(data)
+	res := &QueryShare{}
+	res.IsTwoParty = r.readBool()
+	res.IsKeywordBased = r.readBool()
+	res.ShareNumber = uint(r.readUint())
+	res.GroupSize = int(r.readUint())
+
+	numKeys := r.readLength()
+	res.PrfKeys = make([]*dpf.PrfKey, numKeys)
+	for i := range res.PrfKeys {
+		res.PrfKeys[i] = &dpf.PrfKey{Bytes: r.readBytes()}
+	}
+
+	keyBytes := r.readBytes()
+	if r.err != nil {
+		return r.err
+	}
+
+	if err := res.setDPFKey(keyBytes); err != nil {
+		return err
+	}
+
+	res.Region = r.readRegion()
+	res.PrefixBits = uint(r.readUint())
+	res.Compression = r.compression
+
+	if err := r.done(); err != nil {
+		return err
+	}
+
+	*query = *res
+	return nil
+}
+
+// MarshalBinary encodes the result share
+// in the current wire format version (see MarshalBinaryVersion)
+func (res *SecretSharedQueryResult) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryVersion(res, CurrentWireVersion)
+}
+
+func (res *SecretSharedQueryResult) encodeBinary(w *binaryWriter) {
+	w.writeUint(uint64(res.SlotBytes))
+	w.writeUint(uint64(len(res.Shares)))
+	for _, share := range res.Shares {
+		w.writeBytes(share.Data)
+	}
+}
+
+// UnmarshalBinary decodes a result share encoded with MarshalBinary
+func (res *SecretSharedQueryResult) UnmarshalBinary(data []byte) error {
+
+	r := newBinaryReader(data)
+	slotBytes := int(r.readUint())
+	shares := make([]*Slot, r.readLength())
+	for i := range shares {
+		shares[i] = NewSlot(r.readBytes())
+	}
+
+	if err := r.done(); err != nil {
+		return err
+	}
+
+	res.SlotBytes = slotBytes
+	res.Shares = shares
+	res.Compression = r.compression
+	return nil
+}
+
+// MarshalBinary encodes the encrypted query (without the public key and the query proof)
+// in the current wire format version (see MarshalBinaryVersion)
+func (query *EncryptedQuery) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryVersion(query, CurrentWireVersion)
+}
+
+func (query *EncryptedQuery) encodeBinary(w *binaryWriter) {
+	w.writeEncryptedQuery(query)
+}
+
+// UnmarshalEncryptedQuery decodes an encrypted query encoded with MarshalBinary
+// that is encrypted under pk
+func UnmarshalEncryptedQuery(data []byte, pk *paillier.PublicKey) (*EncryptedQuery, error) {
+
+	r := newBinaryReader(data)
+	query := r.readEncryptedQuery(pk)
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// MarshalBinary encodes the doubly encrypted query (without the public key)
+// in the current wire format version (see MarshalBinaryVersion)
+func (query *DoublyEncryptedQuery) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryVersion(query, CurrentWireVersion)
+}
+
+func (query *DoublyEncryptedQuery) encodeBinary(w *binaryWriter) {
+	w.writeEncryptedQuery(query.Row)
+	w.writeEncryptedQuery(query.Col)
+	w.writeColumnMask(query.ColumnMask)
+}
+
+// UnmarshalDoublyEncryptedQuery decodes a doubly encrypted query encoded with MarshalBinary
+// that is encrypted under pk
+func UnmarshalDoublyEncryptedQuery(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQuery, error) {
+
+	r := newBinaryReader(data)
+	query := &DoublyEncryptedQuery{}
+	query.Row = r.readEncryptedQuery(pk)
+	query.Col = r.readEncryptedQuery(pk)
+	query.ColumnMask = r.readColumnMask()
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// MarshalBinary encodes the encrypted result (without the public key)
+// in the current wire format version (see MarshalBinaryVersion)
+func (res *EncryptedQueryResult) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryVersion(res, CurrentWireVersion)
+}
+
+func (res *EncryptedQueryResult) encodeBinary(w *binaryWriter) {
+	w.writeUint(uint64(res.SlotBytes))
+	w.writeUint(uint64(res.NumBytesPerCiphertext))
+	w.writeUint(uint64(len(res.Slots)))
+	for _, eslot := range res.Slots {
+		w.writeCiphertexts(eslot.Cts)
+	}
+}
+
+// UnmarshalEncryptedQueryResult decodes an encrypted result encoded with MarshalBinary
+// that is encrypted under pk
+func UnmarshalEncryptedQueryResult(data []byte, pk *paillier.PublicKey) (*EncryptedQueryResult, error) {
+
+	r := newBinaryReader(data)
+	res := &EncryptedQueryResult{Pk: pk, Compression: r.compression}
+	res.SlotBytes = int(r.readUint())
+	res.NumBytesPerCiphertext = int(r.readUint())
+	res.Slots = make([]*EncryptedSlot, r.readLength())
+	for i := range res.Slots {
+		res.Slots[i] = &EncryptedSlot{Cts: r.readCiphertexts()}
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// MarshalBinary encodes the doubly encrypted result (without the public key)
+// in the current wire format version (see MarshalBinaryVersion)
+func (res *DoublyEncryptedQueryResult) MarshalBinary() ([]byte, error) {
+	return MarshalBinaryVersion(res, CurrentWireVersion)
+}
+
+func (res *DoublyEncryptedQueryResult) encodeBinary(w *binaryWriter) {
+	w.writeUint(uint64(res.SlotBytes))
+	w.writeUint(uint64(res.NumBytesPerCiphertext))
+	w.writeUint(uint64(res.GroupSize))
+	w.writeColumnMask(res.ColumnMask)
+	w.writeUint(uint64(len(res.Slots)))
+	for _, slot := range res.Slots {
+		w.writeCiphertexts(slot.Cts)
+	}
+}
+
+// UnmarshalDoublyEncryptedQueryResult decodes a doubly encrypted result encoded
+// with MarshalBinary that is encrypted under pk
+func UnmarshalDoublyEncryptedQueryResult(data []byte, pk *paillier.PublicKey) (*DoublyEncryptedQueryResult, error) {
+
+	r := newBinaryReader(data)
+	res := &DoublyEncryptedQueryResult{Pk: pk, Compression: r.compression}
+	res.SlotBytes = int(r.readUint())
+	res.NumBytesPerCiphertext = int(r.readUint())
+	res.GroupSize = int(r.readUint())
+	res.ColumnMask = r.readColumnMask()
+	res.Slots = make([]*DoublyEncryptedSlot, r.readLength())
+	for i := range res.Slots {
+		res.Slots[i] = &DoublyEncryptedSlot{Cts: r.readCiphertexts()}
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// binaryWriter appends length-prefixed fields to a buffer
+// in the layout of the given wire format version
+// and records the first error encountered
+type binaryWriter struct {
+	buf     []byte
+	version uint
+	err     error
+}
+
+func (w *binaryWriter) writeUint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *binaryWriter) writeBool(b bool) {
+	if b {
+		w.writeUint(1)
+	} else {
+		w.writeUint(0)
+	}
+}
+
+func (w *binaryWriter) writeBytes(b []byte) {
+	w.writeUint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *binaryWriter) writeBytesList(bs [][]byte) {
+	w.writeUint(uint64(len(bs)))
+	for _, b := range bs {
+		w.writeBytes(b)
+	}
+}
+
+func (w *binaryWriter) writeCiphertexts(cts []*paillier.Ciphertext) {
+	w.writeUint(uint64(len(cts)))
+	for _, ct := range cts {
+		w.writeUint(uint64(ct.Level))
+		w.writeBytes(ct.C.Bytes())
+	}
+}
+
+// writeEncryptedQuery writes the fields of the query (without the public key and the proof)
+func (w *binaryWriter) writeEncryptedQuery(query *EncryptedQuery) {
+	w.writeUint(uint64(query.GroupSize))
+	w.writeUint(uint64(query.DBWidth))
+	w.writeUint(uint64(query.DBHeight))
+	w.writeCiphertexts(query.EBits)
+
+	if w.version >= WireVersion3 {
+		w.writeRegion(query.Region)
+	} else if query.Region != nil && w.err == nil {
+		w.err = ErrFieldNotInWireVersion
+	}
+}
+
+// writeRegion writes the (optional) region of a query
+func (w *binaryWriter) writeRegion(region *GroupRegion) {
+	w.writeBool(region != nil)
+	if region != nil {
+		w.writeUint(uint64(region.Start))
+		w.writeUint(uint64(region.End))
+		w.writeUint(uint64(region.GroupSize))
+	}
+}
+
+// writeColumnMask writes the (optional) column mask
+func (w *binaryWriter) writeColumnMask(mask []bool) {
+	w.writeBool(mask != nil)
+	if mask != nil {
+		w.writeUint(uint64(len(mask)))
+		for _, b := range mask {
+			w.writeBool(b)
+		}
+	}
+}
+
+// binaryReader reads the fields written by binaryWriter
+// and records the first error encountered
+type binaryReader struct {
+	buf         []byte
+	err         error
+	version     uint        // wire format version of the encoding
+	compression Compression // compression of the encoding (decompressed by newBinaryReader)
+}
+
+// newBinaryReader returns a reader over the encoding after reading its
+// wire format version (ErrUnsupportedWireVersion if it is not supported)
+// and decompressing the rest of the encoding
+func newBinaryReader(data []byte) *binaryReader {
+
+	r := &binaryReader{buf: data}
+	r.version = uint(r.readUint())
+	if r.err == nil && !IsSupportedWireVersion(r.version) {
+		r.err = ErrUnsupportedWireVersion
+	}
+
+	if r.err == nil && r.version >= WireVersion2 {
+		r.compression = Compression(r.readUint())
+		if r.err == nil {
+			r.buf, r.err = decompress(r.compression, r.buf)
+		}
+	}
+
+	return r
+}
+
+func (r *binaryReader) readUint() uint64 {
+
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errMalformedEncoding
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+	return v
+}
+
+// readLength reads a number of elements that are each encoded with at least one byte
+func (r *binaryReader) readLength() int {
+
+	n := r.readUint()
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = errMalformedEncoding
+		return 0
+	}
+
+	return int(n)
+}
+
+func (r *binaryReader) readBool() bool {
+	return r.readUint() == 1
+}
+
+func (r *binaryReader) readBytes() []byte {
+
+	n := r.readLength()
+	if r.err != nil {
+		return nil
+	}
+
+	b := append([]byte{}, r.buf[:n]...)
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *binaryReader) readCiphertexts() []*paillier.Ciphertext {
+
+	cts := make([]*paillier.Ciphertext, r.readLength())
+	for i := range cts {
+		level := paillier.EncryptionLevel(r.readUint())
+		c := new(gmp.Int).SetBytes(r.readBytes())
+		cts[i] = &paillier.Ciphertext{C: c, Level: level}
+	}
+
+	return cts
+}
+
+func (r *binaryReader) readEncryptedQuery(pk *paillier.PublicKey) *EncryptedQuery {
+
+	query := &EncryptedQuery{Pk: pk}
+	query.GroupSize = int(r.readUint())
+	query.DBWidth = int(r.readUint())
+	query.DBHeight = int(r.readUint())
+	query.EBits = r.readCiphertexts()
+
+	if r.version >= WireVersion3 {
+		query.Region = r.readRegion()
+	}
+
+	return query
+}
+
+func (r *binaryReader) readRegion() *GroupRegion {
+
+	if !r.readBool() {
+		return nil
+	}
+
+	return &GroupRegion{
+		Start:     int(r.readUint()),
+		End:       int(r.readUint()),
+		GroupSize: int(r.readUint()),
+	}
+}
+
+func (r *binaryReader) readColumnMask() []bool {
+
+	if !r.readBool() {
+		return nil
+	}
+
+	mask := make([]bool, r.readLength())
+	for i := range mask {
+		mask[i] = r.readBool()
+	}
+
+	return mask
+}
+
+func (r *binaryReader) done() error {
+
+	if r.err == nil && len(r.buf) != 0 {
+		r.err = errMalformedEncoding
+	}
+
+	return r.err
+}
@@ -0,0 +1,333 @@
+package pir
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+// writeFrame sends a length-prefixed message over the connection
+func writeFrame(conn net.Conn, data []byte) error {
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	if _, err := conn.Write(header); err != nil {
+		return err
+	}
+
+	_, err := conn.Write(data)
+	return err
+}
+
+// readFrame receives a length-prefixed message from the connection
+func readFrame(conn net.Conn) ([]byte, error) {
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint32(header))
+	_, err := io.ReadFull(conn, data)
+	return data, err
+}
+
+// servePipe runs a server that answers a single query received over
+// the connection with handle and sends the response back
+func servePipe(conn net.Conn, handle func([]byte) ([]byte, error)) chan error {
+
+	errs := make(chan error, 1)
+	go func() {
+		defer conn.Close()
+
+		req, err := readFrame(conn)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		resp, err := handle(req)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		errs <- writeFrame(conn, resp)
+	}()
+
+	return errs
+}
+
+func TestSecretSharedQueryOverPipe(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		dimHeight := (TestDBSize + groupSize - 1) / groupSize
+		qIndex := rand.Intn(dimHeight)
+		shares := db.NewIndexQueryShares(qIndex, groupSize, 2)
+
+		results := make([]*SecretSharedQueryResult, len(shares))
+		for i, share := range shares {
+			client, server := net.Pipe()
+
+			errs := servePipe(server, func(req []byte) ([]byte, error) {
+				query := &QueryShare{}
+				if err := query.UnmarshalBinary(req); err != nil {
+					return nil, err
+				}
+
+				res, err := db.PrivateSecretSharedQuery(query, NumProcsForQuery)
+				if err != nil {
+					return nil, err
+				}
+
+				return res.MarshalBinary()
+			})
+
+			req, err := share.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := writeFrame(client, req); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := readFrame(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+
+			results[i] = &SecretSharedQueryResult{}
+			if err := results[i].UnmarshalBinary(resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		res := Recover(results)
+		for j := 0; j < groupSize; j++ {
+			index := qIndex*groupSize + j
+			if index < db.DBSize && !db.Slots[index].Equal(res[j]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[j])
+			}
+		}
+	}
+}
+
+func TestEncryptedQueryOverPipe(t *testing.T) {
+	setup()
+
+	// the public key is exchanged ahead of time
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+		rowIndex := rand.Intn(dimHeight)
+		query := db.NewEncryptedQuery(pk, groupSize, rowIndex)
+
+		client, server := net.Pipe()
+
+		errs := servePipe(server, func(req []byte) ([]byte, error) {
+			query, err := UnmarshalEncryptedQuery(req, pk)
+			if err != nil {
+				return nil, err
+			}
+
+			res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+			if err != nil {
+				return nil, err
+			}
+
+			return res.MarshalBinary()
+		})
+
+		req, err := query.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writeFrame(client, req); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := readFrame(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+
+		response, err := UnmarshalEncryptedQueryResult(resp, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := RecoverEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j := range res {
+			index := rowIndex*query.DBWidth + j
+			if index < db.DBSize && !db.Slots[index].Equal(res[j]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[j])
+			}
+		}
+	}
+}
+
+func TestDoublyEncryptedQueryOverPipe(t *testing.T) {
+	setup()
+
+	// the public key is exchanged ahead of time
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+
+		// every other group member (nil mask for group size 1)
+		var mask []bool
+		if groupSize > 1 {
+			mask = make([]bool, groupSize)
+			for j := range mask {
+				mask[j] = j%2 == 0
+			}
+		}
+
+		query := db.NewDoublyEncryptedQueryWithColumnMask(pk, groupSize, index, mask)
+
+		client, server := net.Pipe()
+
+		errs := servePipe(server, func(req []byte) ([]byte, error) {
+			query, err := UnmarshalDoublyEncryptedQuery(req, pk)
+			if err != nil {
+				return nil, err
+			}
+
+			res, err := db.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+			if err != nil {
+				return nil, err
+			}
+
+			return res.MarshalBinary()
+		})
+
+		req, err := query.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writeFrame(client, req); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := readFrame(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+
+		response, err := UnmarshalDoublyEncryptedQueryResult(resp, pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := RecoverDoublyEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// only the unmasked group members are returned (in order)
+		start := (index / groupSize) * groupSize
+		j := 0
+		for member := 0; member < groupSize; member++ {
+			if mask != nil && !mask[member] {
+				continue
+			}
+
+			expected := NewEmptySlot(SlotBytes)
+			if start+member < db.DBSize {
+				expected = db.Slots[start+member]
+			}
+
+			if !expected.Equal(res[j]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", expected, res[j])
+			}
+			j++
+		}
+
+		if j != len(res) {
+			t.Fatalf("Recovered %v slots, expected %v\n", len(res), j)
+		}
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	share := db.NewIndexQueryShares(0, 1, 2)[0]
+
+	data, err := share.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&QueryShare{}).UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("Decoded a truncated query share")
+	}
+
+	if err := (&QueryShare{}).UnmarshalBinary(append(data, 0)); err == nil {
+		t.Fatal("Decoded a query share with trailing bytes")
+	}
+}
+
+func TestMarshalShareWithoutKey(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	for _, isTwoParty := range []bool{true, false} {
+		share := db.NewIndexQueryShares(0, 1, 2)[0]
+		share.IsTwoParty = isTwoParty
+		share.KeyTwoParty = nil
+		share.KeyMultiParty = nil
+
+		if _, err := share.MarshalBinary(); err != ErrMissingDPFKey {
+			t.Fatalf("Binary encoding returned %v, expected ErrMissingDPFKey\n", err)
+		}
+
+		if _, err := share.MarshalCBOR(); err != ErrMissingDPFKey {
+			t.Fatalf("CBOR encoding returned %v, expected ErrMissingDPFKey\n", err)
+		}
+
+		if _, err := share.MarshalProto(); err != ErrMissingDPFKey {
+			t.Fatalf("Protobuf encoding returned %v, expected ErrMissingDPFKey\n", err)
+		}
+
+		if _, err := share.MarshalJSON(); err != ErrMissingDPFKey {
+			t.Fatalf("JSON encoding returned %v, expected ErrMissingDPFKey\n", err)
+		}
+
+		// the canonical encoding does not fail
+		share.CanonicalBytes()
+	}
+}
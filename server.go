@@ -0,0 +1,239 @@
+package pir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrQueryShapeNotAllowed is returned when a query does not match
+// any of the (width, height, groupSize) tuples allowed by the server
+var ErrQueryShapeNotAllowed = errors.New("query dimensions not allowed by server")
+
+// ErrInvalidQuery is returned (wrapped) when a query is malformed,
+// e.g., when its dimensions are inconsistent or do not match the database
+var ErrInvalidQuery = errors.New("invalid query")
+
+// QueryShape describes the dimensions that a query views the database as
+type QueryShape struct {
+	Width     int
+	Height    int
+	GroupSize int
+}
+
+// Server answers PIR queries over a database and enforces
+// a fixed contract on the dimensions of incoming queries
+// such that a client cannot waste resources (or read the wrong data)
+// by sending a query with arbitrary dimensions
+type Server struct {
+	DB            *Database
+	AllowedShapes map[QueryShape]bool // if empty, all shapes are allowed
+
+	// if set, encrypted queries must carry a valid QueryProof
+	RequireQueryProofs bool
+
+	// if set, queries are rate limited per query shape (see RateLimiter).
+	// Frontends that know the network source of a query can call
+	// Limiter.Allow with the source before passing the query to the server
+	Limiter *RateLimiter
+
+	// if set, queries do not return before MinResponseTime has elapsed
+	// such that the response time does not depend on the query (see padResponseTime)
+	MinResponseTime time.Duration
+
+	// null responses computed for the current database generation (see PrivateEncryptedNullQuery)
+	nullMu         sync.Mutex
+	nullGeneration uint64
+	nullResponses  map[nullResponseKey]*EncryptedQueryResult
+
+	// sessions opened by clients (see OpenSession)
+	sessions sessionStore
+}
+
+// NewServer returns a server for the database that accepts queries of any shape
+func NewServer(db *Database) *Server {
+	return &Server{
+		DB:            db,
+		AllowedShapes: make(map[QueryShape]bool),
+	}
+}
+
+// AllowShape adds (width, height, groupSize) to the set of allowed query shapes
+func (s *Server) AllowShape(width, height, groupSize int) {
+	checkGroupSize(groupSize)
+	s.AllowedShapes[QueryShape{width, height, groupSize}] = true
+}
+
+// AllowDefaultShapes allows the shapes produced by the default query constructors
+// (NewIndexQueryShares, NewEncryptedQuery, and NewDoublyEncryptedQuery) for each group size,
+// and, if the database has a GroupLayout, for the window and group size of each region
+func (s *Server) AllowDefaultShapes(groupSizes ...int) {
+
+	for _, groupSize := range groupSizes {
+		s.allowDefaultShapes(&s.DB.DBMetadata, groupSize)
+	}
+
+	if s.DB.Layout != nil {
+		for _, region := range s.DB.Layout.Regions {
+			regionMD := &DBMetadata{SlotBytes: s.DB.SlotBytes, DBSize: region.End - region.Start}
+			s.allowDefaultShapes(regionMD, region.GroupSize)
+		}
+	}
+}
+
+func (s *Server) allowDefaultShapes(dbmd *DBMetadata, groupSize int) {
+
+	// shape of secret shared queries
+	s.AllowShape(groupSize, int(math.Ceil(float64(dbmd.DBSize)/float64(groupSize))), groupSize)
+
+	// shape of (doubly) encrypted queries
+	height := int(math.Ceil(math.Sqrt(float64(dbmd.DBSize))))
+	width, height := dbmd.GetDimentionsForDatabase(height, groupSize)
+	s.AllowShape(width, height, groupSize)
+}
+
+// CheckShape returns an error if the shape is not allowed by the server
+func (s *Server) CheckShape(shape QueryShape) error {
+
+	if len(s.AllowedShapes) == 0 {
+		return nil
+	}
+
+	if !s.AllowedShapes[shape] {
+		return ErrQueryShapeNotAllowed
+	}
+
+	return nil
+}
+
+// checkQuery returns an error if the shape is not allowed by the server
+// or if the server's rate limit is exceeded for the shape and the source of the query
+// (see WithQuerySource)
+func (s *Server) checkQuery(ctx context.Context, shape QueryShape) error {
+
+	if err := s.CheckShape(shape); err != nil {
+		return err
+	}
+
+	if s.Limiter != nil {
+		return s.Limiter.Allow(RateLimitKey{Source: QuerySource(ctx), Shape: shape})
+	}
+
+	return nil
+}
+
+// PrivateSecretSharedQuery checks the query shape before processing the query
+func (s *Server) PrivateSecretSharedQuery(query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+	return s.PrivateSecretSharedQueryContext(context.Background(), query, nprocs)
+}
+
+// PrivateSecretSharedQueryContext is the same as PrivateSecretSharedQuery
+// but stops processing the query when the context is cancelled
+// and rate limits the query on the source of the context (see WithQuerySource)
+func (s *Server) PrivateSecretSharedQueryContext(ctx context.Context, query *QueryShare, nprocs int) (*SecretSharedQueryResult, error) {
+
+	defer s.padResponseTime(time.Now())
+
+	if query.GroupSize <= 0 {
+		return nil, fmt.Errorf("%w: invalid group size provided in query", ErrInvalidQuery)
+	}
+
+	dbSize := s.DB.DBSize
+	if query.Region != nil {
+		dbSize = query.Region.End - query.Region.Start
+	}
+
+	shape := QueryShape{
+		Width:     query.GroupSize,
+		Height:    int(math.Ceil(float64(dbSize) / float64(query.GroupSize))),
+		GroupSize: query.GroupSize,
+	}
+
+	if err := s.checkQuery(ctx, shape); err != nil {
+		return nil, err
+	}
+
+	return s.DB.PrivateSecretSharedQueryContext(ctx, query, nprocs)
+}
+
+// PrivateEncryptedQuery checks the query shape before processing the query
+func (s *Server) PrivateEncryptedQuery(query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, error) {
+	return s.PrivateEncryptedQueryContext(context.Background(), query, nprocs)
+}
+
+// PrivateEncryptedQueryContext is the same as PrivateEncryptedQuery
+// but stops processing the query when the context is cancelled
+// and rate limits the query on the source of the context (see WithQuerySource)
+func (s *Server) PrivateEncryptedQueryContext(ctx context.Context, query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, error) {
+
+	defer s.padResponseTime(time.Now())
+
+	if len(query.EBits) != query.DBHeight {
+		return nil, fmt.Errorf("%w: number of encrypted bits does not match query height", ErrInvalidQuery)
+	}
+
+	if err := s.checkQuery(ctx, QueryShape{query.DBWidth, query.DBHeight, query.GroupSize}); err != nil {
+		return nil, err
+	}
+
+	if s.RequireQueryProofs {
+		if err := query.VerifyQueryProof(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.DB.PrivateEncryptedQueryContext(ctx, query, nprocs)
+}
+
+// PrivateDoublyEncryptedQuery checks the query shape before processing the query
+func (s *Server) PrivateDoublyEncryptedQuery(query *DoublyEncryptedQuery, nprocs int) (*DoublyEncryptedQueryResult, error) {
+	return s.PrivateDoublyEncryptedQueryContext(context.Background(), query, nprocs)
+}
+
+// PrivateDoublyEncryptedQueryContext is the same as PrivateDoublyEncryptedQuery
+// but stops processing the query when the context is cancelled
+// and rate limits the query on the source of the context (see WithQuerySource)
+func (s *Server) PrivateDoublyEncryptedQueryContext(ctx context.Context, query *DoublyEncryptedQuery, nprocs int) (*DoublyEncryptedQueryResult, error) {
+
+	defer s.padResponseTime(time.Now())
+
+	if query == nil || query.Row == nil || query.Col == nil {
+		return nil, fmt.Errorf("%w: query is missing its row or column query", ErrInvalidQuery)
+	}
+
+	if len(query.Row.EBits) != query.Row.DBHeight {
+		return nil, fmt.Errorf("%w: number of encrypted bits does not match query height", ErrInvalidQuery)
+	}
+
+	if query.Col.GroupSize != query.Row.GroupSize || query.Col.DBWidth != query.Row.DBWidth {
+		return nil, fmt.Errorf("%w: row and column queries have inconsistent dimensions", ErrInvalidQuery)
+	}
+
+	if query.Col.GroupSize <= 0 || len(query.Col.EBits) != query.Col.DBWidth/query.Col.GroupSize {
+		return nil, fmt.Errorf("%w: number of encrypted bits does not match query width", ErrInvalidQuery)
+	}
+
+	if err := s.checkQuery(ctx, QueryShape{query.Row.DBWidth, query.Row.DBHeight, query.Row.GroupSize}); err != nil {
+		return nil, err
+	}
+
+	return s.DB.PrivateDoublyEncryptedQueryContext(ctx, query, nprocs)
+}
+
+// padResponseTime sleeps until MinResponseTime has elapsed since start.
+// Processing time can depend on the query (e.g., cache effects, the number of
+// non-zero bits, or rejected queries) and leak information through the response time.
+// Padding makes the response time uniform across queries as long as processing
+// takes less than MinResponseTime, at the cost of added latency for every query
+// (the floor should be set above the worst-case processing time for the allowed shapes)
+func (s *Server) padResponseTime(start time.Time) {
+
+	if s.MinResponseTime <= 0 {
+		return
+	}
+
+	time.Sleep(s.MinResponseTime - time.Since(start))
+}
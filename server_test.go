@@ -0,0 +1,305 @@
+package pir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+)
+
+func TestServerAllowedShapes(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	server := NewServer(db)
+	server.AllowDefaultShapes(1, 2)
+
+	for groupSize := 1; groupSize <= 2; groupSize++ {
+
+		// queries generated by the default constructors are accepted
+		shares := db.NewIndexQueryShares(0, groupSize, 2)
+		if _, err := server.PrivateSecretSharedQuery(shares[0], NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+
+		qIndex := rand.Intn(TestDBSize / groupSize)
+		query := db.NewDoublyEncryptedQuery(pk, groupSize, qIndex)
+		response, err := server.PrivateDoublyEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		width, height := db.GetDimentionsForDatabase(query.Row.DBHeight, groupSize)
+		rowIndex, colIndex := db.IndexToCoordinates(qIndex, width, height)
+
+		res, err := RecoverDoublyEncrypted(response, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index := rowIndex*width + (colIndex/groupSize)*groupSize
+		if !db.Slots[index].Equal(res[0]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[0])
+		}
+	}
+
+	// group size not in the whitelist
+	shares := db.NewIndexQueryShares(0, 3, 2)
+	if _, err := server.PrivateSecretSharedQuery(shares[0], NumProcsForQuery); err != ErrQueryShapeNotAllowed {
+		t.Fatalf("Expected %v, got %v\n", ErrQueryShapeNotAllowed, err)
+	}
+
+	// dimensions not in the whitelist
+	query := db.NewEncryptedQueryWithDimentions(pk, 8, TestDBSize/8, 1, 0)
+	if _, err := server.PrivateEncryptedQuery(query, NumProcsForQuery); err != ErrQueryShapeNotAllowed {
+		t.Fatalf("Expected %v, got %v\n", ErrQueryShapeNotAllowed, err)
+	}
+
+	// arbitrary dimensions are accepted once allowed
+	server.AllowShape(8, TestDBSize/8, 1)
+	if _, err := server.PrivateEncryptedQuery(query, NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+
+	// malformed query that claims an allowed shape
+	query = db.NewEncryptedQuery(pk, 1, 0)
+	query.EBits = query.EBits[:1]
+	if _, err := server.PrivateEncryptedQuery(query, NumProcsForQuery); err == nil {
+		t.Fatal("Server accepted a query with an incorrect number of encrypted bits")
+	}
+
+	// doubly encrypted queries without a row or column query
+	doubly := db.NewDoublyEncryptedQuery(pk, 1, 0)
+	for _, malformed := range []*DoublyEncryptedQuery{nil, {Row: doubly.Row}, {Col: doubly.Col}} {
+		if _, err := server.PrivateDoublyEncryptedQuery(malformed, NumProcsForQuery); !errors.Is(err, ErrInvalidQuery) {
+			t.Fatalf("Expected %v, got %v\n", ErrInvalidQuery, err)
+		}
+	}
+
+	// column query that does not match the row result
+	rowRes, err := db.PrivateEncryptedQuery(doubly.Row, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, col := range []*EncryptedQuery{
+		{Pk: pk, EBits: doubly.Col.EBits, GroupSize: len(rowRes.Slots) + 1},
+		{Pk: pk, EBits: doubly.Col.EBits[1:], GroupSize: 1},
+		{Pk: &paillier.PublicKey{N: gmp.NewInt(15)}, EBits: doubly.Col.EBits, GroupSize: 1},
+	} {
+		if _, err := db.PrivateEncryptedQueryOverEncryptedResult(col, rowRes, NumProcsForQuery); !errors.Is(err, ErrInvalidQuery) {
+			t.Fatalf("Expected %v, got %v\n", ErrInvalidQuery, err)
+		}
+	}
+}
+
+func TestServerCachedNullResponse(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	server := NewServer(db)
+
+	query := db.NewEncryptedQuery(pk, 1, -1)
+
+	res1, err := server.PrivateEncryptedNullQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second response is served from the cache
+	db.OpCounter = NewOpCounter()
+	res2, err := server.PrivateEncryptedNullQuery(query, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if adds, _ := db.OpCounter.Totals(); adds != 0 {
+		t.Fatalf("Cached null response performed %v homomorphic additions\n", adds)
+	}
+
+	for _, res := range []*EncryptedQueryResult{res1, res2} {
+		slots, err := RecoverEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, slot := range slots {
+			if !slot.IsEmpty() {
+				t.Fatalf("Null response contains a non-empty slot %v\n", slot)
+			}
+		}
+	}
+
+	// responses are re-randomized
+	for i := range res1.Slots {
+		for j := range res1.Slots[i].Cts {
+			if res1.Slots[i].Cts[j].C.Cmp(res2.Slots[i].Cts[j].C) == 0 {
+				t.Fatal("Cached null responses are identical")
+			}
+		}
+	}
+
+	// modifying the database invalidates the cache
+	if err := db.SetSlot(0, NewRandomSlot(SlotBytes)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := server.PrivateEncryptedNullQuery(query, NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+
+	if adds, _ := db.OpCounter.Totals(); adds == 0 {
+		t.Fatal("Null response was not recomputed for the new database generation")
+	}
+}
+
+func TestServerMinResponseTime(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	server := NewServer(db)
+	server.MinResponseTime = 50 * time.Millisecond
+
+	// a real query and a null query of the same dimensions
+	for _, index := range []int{0, -1} {
+		query := db.NewEncryptedQuery(pk, 1, index)
+
+		start := time.Now()
+		if _, err := server.PrivateEncryptedQuery(query, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+
+		if elapsed := time.Since(start); elapsed < server.MinResponseTime {
+			t.Fatalf("Query returned after %v, before the minimum response time\n", elapsed)
+		}
+	}
+
+	// rejected queries are padded too
+	query := db.NewEncryptedQuery(pk, 1, 0)
+	query.EBits = query.EBits[:1]
+
+	start := time.Now()
+	if _, err := server.PrivateEncryptedQuery(query, NumProcsForQuery); err == nil {
+		t.Fatal("Server accepted a malformed query")
+	}
+
+	if elapsed := time.Since(start); elapsed < server.MinResponseTime {
+		t.Fatalf("Rejected query returned after %v, before the minimum response time\n", elapsed)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	server := NewServer(db)
+	server.Limiter = NewRateLimiter(20, 2)
+
+	now := time.Unix(0, 0)
+	server.Limiter.now = func() time.Time { return now }
+
+	query := db.NewIndexQueryShares(0, 1, 2)[0]
+
+	// the burst is allowed and the next query is rejected
+	for i := 0; i < 2; i++ {
+		if _, err := server.PrivateSecretSharedQuery(query, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := server.PrivateSecretSharedQuery(query, NumProcsForQuery); err != ErrRateLimited {
+		t.Fatalf("Expected %v, got %v\n", ErrRateLimited, err)
+	}
+
+	// queries of another shape have their own bucket
+	if _, err := server.PrivateSecretSharedQuery(db.NewIndexQueryShares(0, 2, 2)[0], NumProcsForQuery); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bucket refills over time
+	now = now.Add(100 * time.Millisecond)
+
+	if _, err := server.PrivateSecretSharedQuery(query, NumProcsForQuery); err != nil {
+		t.Fatalf("Query was rejected after the limiter refilled: %v\n", err)
+	}
+
+	// sources are limited independently
+	key := RateLimitKey{Source: "192.0.2.1", Shape: QueryShape{1, TestDBSize, 1}}
+	if err := server.Limiter.Allow(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// queries of the same shape from two sources have their own buckets
+	ctxA := WithQuerySource(context.Background(), "192.0.2.10")
+	ctxB := WithQuerySource(context.Background(), "192.0.2.11")
+
+	for i := 0; i < 2; i++ {
+		if _, err := server.PrivateSecretSharedQueryContext(ctxA, query, NumProcsForQuery); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := server.PrivateSecretSharedQueryContext(ctxA, query, NumProcsForQuery); err != ErrRateLimited {
+		t.Fatalf("Expected %v, got %v\n", ErrRateLimited, err)
+	}
+
+	if _, err := server.PrivateSecretSharedQueryContext(ctxB, query, NumProcsForQuery); err != nil {
+		t.Fatalf("Query from another source was rejected: %v\n", err)
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+
+	now := time.Unix(0, 0)
+	rl := NewRateLimiter(1, 2)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		key := RateLimitKey{Source: fmt.Sprint(i), Shape: QueryShape{1, TestDBSize, 1}}
+		if err := rl.Allow(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(rl.buckets) != 100 {
+		t.Fatalf("Limiter holds %v buckets, expected 100\n", len(rl.buckets))
+	}
+
+	// after a refill period, the idle (full) buckets are evicted
+	now = now.Add(2500 * time.Millisecond)
+	busy := RateLimitKey{Source: "busy", Shape: QueryShape{1, TestDBSize, 1}}
+	for i := 0; i < 2; i++ {
+		if err := rl.Allow(busy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(rl.buckets) != 1 {
+		t.Fatalf("Limiter holds %v buckets after eviction, expected 1\n", len(rl.buckets))
+	}
+
+	// keys whose bucket was evicted are still limited
+	now = now.Add(2500 * time.Millisecond)
+	if err := rl.Allow(busy); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(100 * time.Millisecond)
+	if err := rl.Allow(busy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rl.Allow(busy); err != ErrRateLimited {
+		t.Fatalf("Expected %v, got %v\n", ErrRateLimited, err)
+	}
+}
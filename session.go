@@ -0,0 +1,155 @@
+package pir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"sync"
+
+	"github.com/sachaservan/paillier"
+)
+
+// ErrUnknownSession is returned for queries that reference a session the server does not have
+var ErrUnknownSession = errors.New("unknown query session")
+
+// QuerySession is the context shared by the encrypted queries of a client.
+// It is negotiated once with OpenSession such that subsequent queries only carry
+// the session ID and the encrypted bits (see CompactEncryptedQuery)
+// rather than the public key and dimensions
+type QuerySession struct {
+	ID            uint64
+	Pk            *paillier.PublicKey
+	Fingerprint   []byte // SHA-256 of the public key modulus
+	Width, Height int
+	GroupSize     int
+}
+
+// CompactEncryptedQuery is an encrypted query that references a QuerySession
+type CompactEncryptedQuery struct {
+	SessionID uint64
+	EBits     []*paillier.Ciphertext
+}
+
+// sessionStore holds the sessions opened on a server
+type sessionStore struct {
+	mu       sync.Mutex
+	nextID   uint64
+	sessions map[uint64]*QuerySession
+}
+
+// OpenSession registers the public key and query dimensions of a client
+// and returns the session that its compact queries reference
+func (s *Server) OpenSession(pk *paillier.PublicKey, width, height, groupSize int) (*QuerySession, error) {
+
+	if groupSize < 1 {
+		return nil, ErrInvalidGroupSize
+	}
+
+	if err := s.CheckShape(QueryShape{width, height, groupSize}); err != nil {
+		return nil, err
+	}
+
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	if s.sessions.sessions == nil {
+		s.sessions.sessions = make(map[uint64]*QuerySession)
+	}
+
+	s.sessions.nextID++
+	session := &QuerySession{
+		ID:          s.sessions.nextID,
+		Pk:          pk,
+		Fingerprint: PublicKeyFingerprint(pk),
+		Width:       width,
+		Height:      height,
+		GroupSize:   groupSize,
+	}
+
+	s.sessions.sessions[session.ID] = session
+
+	return session, nil
+}
+
+// CloseSession removes the session from the server
+func (s *Server) CloseSession(id uint64) {
+
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	delete(s.sessions.sessions, id)
+}
+
+// PrivateSessionQuery reconstructs the full query from the session
+// that the compact query references and processes it with PrivateEncryptedQuery
+func (s *Server) PrivateSessionQuery(query *CompactEncryptedQuery, nprocs int) (*EncryptedQueryResult, error) {
+
+	s.sessions.mu.Lock()
+	session, ok := s.sessions.sessions[query.SessionID]
+	s.sessions.mu.Unlock()
+
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+
+	return s.PrivateEncryptedQuery(session.Expand(query), nprocs)
+}
+
+// Compact returns the compact form of the query and returns an error
+// if the query does not match the key and dimensions of the session
+func (session *QuerySession) Compact(query *EncryptedQuery) (*CompactEncryptedQuery, error) {
+
+	if !bytes.Equal(PublicKeyFingerprint(query.Pk), session.Fingerprint) {
+		return nil, errors.New("query is not encrypted under the session key")
+	}
+
+	if query.DBWidth != session.Width || query.DBHeight != session.Height || query.GroupSize != session.GroupSize {
+		return nil, errors.New("query dimensions do not match the session")
+	}
+
+	return &CompactEncryptedQuery{SessionID: session.ID, EBits: query.EBits}, nil
+}
+
+// Expand returns the full query for a compact query of the session
+func (session *QuerySession) Expand(query *CompactEncryptedQuery) *EncryptedQuery {
+	return &EncryptedQuery{
+		Pk:        session.Pk,
+		EBits:     query.EBits,
+		GroupSize: session.GroupSize,
+		DBWidth:   session.Width,
+		DBHeight:  session.Height,
+	}
+}
+
+// MarshalBinary encodes the compact query
+func (query *CompactEncryptedQuery) MarshalBinary() ([]byte, error) {
+
+	w := &binaryWriter{}
+	w.writeUint(query.SessionID)
+	w.writeCiphertexts(query.EBits)
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes a compact query encoded with MarshalBinary
+func (query *CompactEncryptedQuery) UnmarshalBinary(data []byte) error {
+
+	r := &binaryReader{buf: data}
+	sessionID := r.readUint()
+	ebits := r.readCiphertexts()
+
+	if err := r.done(); err != nil {
+		return err
+	}
+
+	query.SessionID = sessionID
+	query.EBits = ebits
+	return nil
+}
+
+// PublicKeyFingerprint returns the SHA-256 digest of the modulus of the public key,
+// which identifies the key to servers that received it out of band (e.g., see QuerySession)
+func PublicKeyFingerprint(pk *paillier.PublicKey) []byte {
+	digest := sha256.Sum256(pk.N.Bytes())
+	return digest[:]
+}
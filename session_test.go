@@ -0,0 +1,73 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestQuerySession(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	server := NewServer(db)
+
+	groupSize := 2
+	template := db.NewEncryptedQuery(pk, groupSize, 0)
+
+	session, err := server.OpenSession(pk, template.DBWidth, template.DBHeight, groupSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		row := rand.Intn(template.DBHeight)
+		query := db.NewEncryptedQuery(pk, groupSize, row)
+
+		compact, err := session.Compact(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the compact query only carries the session ID and the encrypted bits
+		encoded, err := compact.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		received := &CompactEncryptedQuery{}
+		if err := received.UnmarshalBinary(encoded); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := server.PrivateSessionQuery(received, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots, err := RecoverEncrypted(res, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j, slot := range slots {
+			index := row*query.DBWidth + j
+			if index < db.DBSize && !slot.Equal(db.Slots[index]) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], slot)
+			}
+		}
+	}
+
+	// queries with other dimensions cannot use the session
+	if _, err := session.Compact(db.NewEncryptedQuery(pk, 1, 0)); err == nil {
+		t.Fatalf("Compacted a query that does not match the session\n")
+	}
+
+	server.CloseSession(session.ID)
+	if _, err := server.PrivateSessionQuery(&CompactEncryptedQuery{SessionID: session.ID}, NumProcsForQuery); err != ErrUnknownSession {
+		t.Fatalf("Expected %v, got %v\n", ErrUnknownSession, err)
+	}
+}
@@ -0,0 +1,88 @@
+package pir
+
+import (
+	"crypto/aes"
+	"errors"
+	"math"
+
+	"github.com/sachaservan/pir/dpf"
+)
+
+// QueryShareSize is the size of the encoding (see QueryShare.MarshalBinary)
+// of one share of a query over a DPF domain of NumBits bits split among NumShares servers
+type QueryShareSize struct {
+	NumShares  uint
+	NumBits    uint
+	ShareBytes int
+
+	// true if the size is computed from the shape of the multi-party key
+	// (the dpf package does not implement multi-party key generation)
+	Estimated bool
+}
+
+// MeasureQueryShareSizes returns the size of one query share for every
+// combination of the number of shares and the DPF domain size.
+// Two-party shares are generated and encoded. Multi-party keys cannot be generated
+// (dpf.GenerateMultiServer is not implemented), so their size is that of an encoded
+// key with the shape read by dpf.EvaluateMP: 2^(p-1) correction words of mu = 2^(n/2) * 2^((p-1)/2)
+// 32-bit words and 2^ceil(n/2) seeds of 2^(p-1) blocks, for p shares and an n-bit domain
+func MeasureQueryShareSizes(numShares []uint, numBits []uint) ([]*QueryShareSize, error) {
+
+	sizes := make([]*QueryShareSize, 0, len(numShares)*len(numBits))
+	for _, p := range numShares {
+		for _, n := range numBits {
+			if p < 2 || n == 0 || n > KeywordBits {
+				return nil, errors.New("invalid number of shares or domain size")
+			}
+
+			var share *QueryShare
+			if p == 2 {
+				share = newDPFQueryShares(0, n, 1, 2)[0]
+			} else {
+				share = &QueryShare{
+					PrfKeys:       dpf.ClientInitialize(n).PrfKeys,
+					KeyMultiParty: multiPartyKeyShape(p, n),
+					GroupSize:     1,
+				}
+			}
+
+			data, err := share.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+
+			sizes = append(sizes, &QueryShareSize{
+				NumShares:  p,
+				NumBits:    n,
+				ShareBytes: len(data),
+				Estimated:  p != 2,
+			})
+		}
+	}
+
+	return sizes, nil
+}
+
+// multiPartyKeyShape returns an all-zero multi-party key for numShares parties
+// over a numBits-bit domain with the dimensions read by dpf.EvaluateMP
+func multiPartyKeyShape(numShares, numBits uint) *dpf.KeyMP {
+
+	p2 := uint(1) << (numShares - 1)
+	mu := uint(math.Ceil(math.Pow(2, float64(numBits)/2) * math.Pow(2, float64(numShares-1)/2)))
+
+	key := &dpf.KeyMP{
+		NumParties: numShares,
+		CW:         make([][]uint32, p2),
+		Sigma:      make([][]byte, 1<<((numBits+1)/2)),
+	}
+
+	for i := range key.CW {
+		key.CW[i] = make([]uint32, mu)
+	}
+
+	for i := range key.Sigma {
+		key.Sigma[i] = make([]byte, p2*aes.BlockSize)
+	}
+
+	return key
+}
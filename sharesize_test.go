@@ -0,0 +1,42 @@
+package pir
+
+import (
+	"testing"
+)
+
+func TestMeasureQueryShareSizes(t *testing.T) {
+
+	numBits := []uint{8, 16, 20}
+	sizes, err := MeasureQueryShareSizes([]uint{2, 3, 4}, numBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 3*len(numBits) {
+		t.Fatalf("Expected %v sizes, got %v\n", 3*len(numBits), len(sizes))
+	}
+
+	bySize := make(map[[2]uint]int)
+	for _, size := range sizes {
+		if size.Estimated != (size.NumShares != 2) {
+			t.Fatalf("Only multi-party sizes should be estimated\n")
+		}
+		bySize[[2]uint{size.NumShares, size.NumBits}] = size.ShareBytes
+	}
+
+	for i, n := range numBits {
+		// two-party shares grow linearly with the domain bits and
+		// multi-party shares grow with sqrt of the domain size (and with the number of shares)
+		if bySize[[2]uint{3, n}] <= bySize[[2]uint{2, n}] || bySize[[2]uint{4, n}] <= bySize[[2]uint{3, n}] {
+			t.Fatalf("Multi-party shares are not larger than two-party shares for %v bits: %v\n", n, bySize)
+		}
+
+		if i > 0 && bySize[[2]uint{2, n}] <= bySize[[2]uint{2, numBits[i-1]}] {
+			t.Fatalf("Two-party shares do not grow with the domain size: %v\n", bySize)
+		}
+	}
+
+	if _, err := MeasureQueryShareSizes([]uint{1}, numBits); err == nil {
+		t.Fatalf("Expected an error for a single share\n")
+	}
+}
@@ -2,7 +2,6 @@ package pir
 
 import (
 	"bytes"
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"math"
@@ -17,14 +16,30 @@ type Slot struct {
 
 // XorSlots compute xor a and b storing result in a
 func XorSlots(a, b *Slot) {
+	xorBytes(a.Data, b.Data)
+}
+
+// XorSlotView computes xor of a and the view storing result in a
+// without copying the viewed slot (see Database.SlotView)
+func XorSlotView(a *Slot, view SlotView) {
+
+	if view.store != nil {
+		view.store.XorInto(a.Data, view.index)
+	} else {
+		xorBytes(a.Data, view.data)
+	}
+}
+
+// xorBytes computes xor of dst and src (up to the shorter of the two) storing result in dst
+func xorBytes(dst, src []byte) {
 
-	if len(a.Data) < len(b.Data) {
-		for j := 0; j < len(a.Data); j++ {
-			a.Data[j] ^= b.Data[j]
+	if len(dst) < len(src) {
+		for j := 0; j < len(dst); j++ {
+			dst[j] ^= src[j]
 		}
 	} else {
-		for j := 0; j < len(b.Data); j++ {
-			a.Data[j] ^= b.Data[j]
+		for j := 0; j < len(src); j++ {
+			dst[j] ^= src[j]
 		}
 	}
 }
@@ -49,6 +64,18 @@ func (slot *Slot) Equal(other *Slot) bool {
 	return true
 }
 
+// IsEmpty returns true if the slot is all zero
+func (slot *Slot) IsEmpty() bool {
+
+	for _, b := range slot.Data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Compare returns the comparison of the two byte arrays
 // 0 if slot == other
 // -1 if slot < other
@@ -96,10 +123,17 @@ func (slot *Slot) ToGmpIntArray(numChuncks int) ([]*gmp.Int, int, error) {
 // numBytes is the final size of the slot
 // numBytesPerInt the the number of bytes to extract from each int
 func NewSlotFromGmpIntArray(arr []*gmp.Int, numBytes int, numBytesPerInt int) *Slot {
+	return setSlotFromGmpIntArray(nil, arr, numBytes, numBytesPerInt)
+}
+
+// setSlotFromGmpIntArray is the same as NewSlotFromGmpIntArray
+// but reuses dst if it is a slot of numBytes bytes (see reuseSlots)
+func setSlotFromGmpIntArray(dst *Slot, arr []*gmp.Int, numBytes int, numBytesPerInt int) *Slot {
 
 	// each encrypted slot has an array of ciphertexts
 	// encoding the slot data
-	bytes := make([]byte, numBytes)
+	slot := reuseSlot(dst, numBytes)
+	bytes := slot.Data
 	nextByte := 0
 	for _, v := range arr {
 
@@ -130,7 +164,38 @@ func NewSlotFromGmpIntArray(arr []*gmp.Int, numBytes int, numBytesPerInt int) *S
 		}
 	}
 
-	return NewSlot(bytes)
+	return slot
+}
+
+// reuseSlot returns dst cleared to numBytes zero bytes
+// or a new empty slot if dst is nil or has a different size
+func reuseSlot(dst *Slot, numBytes int) *Slot {
+
+	if dst == nil || len(dst.Data) != numBytes {
+		return NewEmptySlot(numBytes)
+	}
+
+	for i := range dst.Data {
+		dst.Data[i] = 0
+	}
+
+	return dst
+}
+
+// reuseSlots returns numSlots empty slots of numBytes bytes
+// reusing the slice and the slots of dst where the sizes match
+func reuseSlots(dst []*Slot, numSlots, numBytes int) []*Slot {
+
+	if cap(dst) < numSlots {
+		dst = append(dst[:cap(dst)], make([]*Slot, numSlots-cap(dst))...)
+	}
+
+	dst = dst[:numSlots]
+	for i := range dst {
+		dst[i] = reuseSlot(dst[i], numBytes)
+	}
+
+	return dst
 }
 
 // NewSlotFromString converts a string to a slot type
@@ -158,17 +223,68 @@ func NewEmptySlot(numBytes int) *Slot {
 	}
 }
 
+// NewEmptySlotFill returns a slot with every byte set to fill
+func NewEmptySlotFill(numBytes int, fill byte) *Slot {
+
+	slot := NewEmptySlot(numBytes)
+	for i := range slot.Data {
+		slot.Data[i] = fill
+	}
+
+	return slot
+}
+
 // NewRandomSlot returns a slot filled with random bytes
 func NewRandomSlot(numBytes int) *Slot {
 	slotData := make([]byte, numBytes)
-	_, err := rand.Read(slotData)
-	if err != nil {
-		panic(fmt.Sprintf("Generating random bytes failed with %v\n", err))
-	}
+	randomBytes(slotData)
 
 	return &Slot{slotData}
 }
 
+// SplitRecord splits a wide record into groupSize slots of slotBytes bytes
+// (to be stored in adjacent slots of a group) and returns an error if the record
+// does not fit into the group. The last slots are padded with zeros
+func SplitRecord(record []byte, slotBytes, groupSize int) ([]*Slot, error) {
+
+	if groupSize < 1 {
+		return nil, ErrInvalidGroupSize
+	}
+
+	if len(record) > slotBytes*groupSize {
+		return nil, fmt.Errorf("record of %v bytes does not fit into %v slots of %v bytes", len(record), groupSize, slotBytes)
+	}
+
+	slots := make([]*Slot, groupSize)
+	for i := range slots {
+		slots[i] = NewEmptySlot(slotBytes)
+		if i*slotBytes < len(record) {
+			copy(slots[i].Data, record[i*slotBytes:])
+		}
+	}
+
+	return slots, nil
+}
+
+// ConcatGroup joins the slots of a recovered group (in order) into the wide record
+// they were split from (see SplitRecord). The slots do not record the length of the record,
+// so the zero padding of the last slots is kept; truncate the result to the length of the
+// record if it is known, or use ToString for records that are strings
+func ConcatGroup(slots []*Slot) *Slot {
+
+	size := 0
+	for _, slot := range slots {
+		size += len(slot.Data)
+	}
+
+	data := make([]byte, 0, size)
+	for _, slot := range slots {
+		data = append(data, slot.Data...)
+	}
+
+	return NewSlot(data)
+}
+
 // GetRequiredSlotSize returns the minimum number of
 // bytes required to represent each data point
 func GetRequiredSlotSize(data []string) int {
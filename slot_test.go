@@ -173,3 +173,89 @@ func TestXorSlots(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSlotView(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	columnar := GenerateEmptyDB(TestDBSize, SlotBytes)
+	copy(columnar.Slots, db.Slots)
+	columnar.BuildSlotStore()
+
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+
+		// views of the slots and of the store match the slot
+		for _, view := range []SlotView{db.SlotView(index), columnar.SlotView(index)} {
+			slotA := NewRandomSlot(SlotBytes)
+			slotB := NewSlot(append([]byte{}, slotA.Data...))
+
+			XorSlots(slotA, db.Slots[index])
+			XorSlotView(slotB, view)
+			if !slotA.Equal(slotB) {
+				t.Fatalf("Xor with slot view is incorrect. %v != %v\n", slotA, slotB)
+			}
+		}
+	}
+
+	// the views alias the backing storage (and are not copies of the slot)
+	views := []SlotView{db.SlotView(0), columnar.SlotView(0)}
+	db.Slots[0].Data[0] ^= 0xff
+	columnar.Store.Columns[0][0] ^= 0xff
+
+	for _, view := range views {
+		slot := NewEmptySlot(SlotBytes)
+		XorSlotView(slot, view)
+		if slot.Data[0] != db.Slots[0].Data[0] {
+			t.Fatalf("Slot view is a copy of the slot\n")
+		}
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+
+	if !NewEmptySlot(4).IsEmpty() {
+		t.Fail()
+	}
+
+	if NewSlot([]byte{0, 0, 1, 0}).IsEmpty() {
+		t.Fail()
+	}
+}
+
+func TestConcatGroup(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+
+		// a record that spans the whole group except for one byte of padding
+		record := make([]byte, groupSize*SlotBytes-1)
+		srand.Read(record)
+		record[len(record)-1] = 1
+
+		slots, err := SplitRecord(record, SlotBytes, groupSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		index := rand.Intn(TestDBSize/groupSize) * groupSize
+		for i, slot := range slots {
+			db.Slots[index+i] = slot
+		}
+
+		res := ConcatGroup(sharedQueryGroup(t, db, groupSize, index))
+		if len(res.Data) != groupSize*SlotBytes {
+			t.Fatalf("Joined record has %v bytes, expected %v\n", len(res.Data), groupSize*SlotBytes)
+		}
+
+		if !NewSlot(record).Equal(NewSlot(res.Data[:len(record)])) {
+			t.Fatalf("Joined record is incorrect. %v != %v\n", record, res.Data)
+		}
+
+		if _, err := SplitRecord(append(record, 0, 0), SlotBytes, groupSize); err == nil {
+			t.Fatalf("Expected an error for a record that does not fit into the group\n")
+		}
+	}
+}
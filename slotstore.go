@@ -0,0 +1,169 @@
+package pir
+
+import (
+	"math"
+
+	"github.com/ncw/gmp"
+)
+
+// SlotStore holds the slots of a database in a byte-columnar layout
+// where Columns[b][i] is byte b of slot i.
+// The AHE scan packs the bytes of each slot into several plaintexts; with the
+// columnar layout, the bytes packed into the j-th plaintext of every slot are
+// contiguous, so the scan can process one plaintext position at a time over all the rows
+// (see Database.BuildSlotStore)
+type SlotStore struct {
+	Columns  [][]byte
+	NumSlots int
+
+	// called with the index of every slot read by a scan (optional, used by tests)
+	onRead func(index int)
+}
+
+// NewSlotStore returns the columnar layout of the slots (each of slotBytes bytes)
+func NewSlotStore(slots []*Slot, slotBytes int) *SlotStore {
+
+	store := &SlotStore{
+		Columns:  make([][]byte, slotBytes),
+		NumSlots: len(slots),
+	}
+
+	for b := range store.Columns {
+		store.Columns[b] = make([]byte, len(slots))
+	}
+
+	for i, slot := range slots {
+		store.set(i, slot)
+	}
+
+	return store
+}
+
+// Slot returns a copy of the slot at index
+func (store *SlotStore) Slot(index int) *Slot {
+
+	data := make([]byte, len(store.Columns))
+	for b, column := range store.Columns {
+		data[b] = column[index]
+	}
+
+	return NewSlot(data)
+}
+
+// XorInto XORs the bytes of the slot at index into dst without allocating a slot
+// (as XorSlots with Slot(index))
+func (store *SlotStore) XorInto(dst []byte, index int) {
+
+	if store.onRead != nil {
+		store.onRead(index)
+	}
+
+	columns := store.Columns
+	if len(dst) < len(columns) {
+		columns = columns[:len(dst)]
+	}
+
+	for b, column := range columns {
+		dst[b] ^= column[index]
+	}
+}
+
+// View returns the store restricted to the slots [start, end).
+// The columns are shared with the store (not copied), so reading the view
+// only touches the bytes of the slots in the window
+func (store *SlotStore) View(start, end int) *SlotStore {
+
+	view := &SlotStore{
+		Columns:  make([][]byte, len(store.Columns)),
+		NumSlots: end - start,
+	}
+
+	for b, column := range store.Columns {
+		view.Columns[b] = column[start:end:end]
+	}
+
+	if store.onRead != nil {
+		view.onRead = func(index int) { store.onRead(start + index) }
+	}
+
+	return view
+}
+
+// readBytes copies the bytes [first, last) of the slot at index to buf
+func (store *SlotStore) readBytes(buf []byte, index, first, last int) {
+
+	if store.onRead != nil {
+		store.onRead(index)
+	}
+
+	for b := first; b < last; b++ {
+		buf[b-first] = store.Columns[b][index]
+	}
+}
+
+// set writes the slot at index (extending the store by one slot if index == NumSlots)
+func (store *SlotStore) set(index int, slot *Slot) {
+
+	if index == store.NumSlots {
+		for b := range store.Columns {
+			store.Columns[b] = append(store.Columns[b], 0)
+		}
+		store.NumSlots++
+	}
+
+	for b, column := range store.Columns {
+		column[index] = slot.Data[b]
+	}
+}
+
+// BuildSlotStore sets db.Store to the columnar layout of the slots
+// such that PrivateEncryptedQuery reads the slots from the store.
+// The store is kept up to date by SetSlot and Append.
+// Results are identical to the row-major scan
+func (db *Database) BuildSlotStore() {
+	db.Store = NewSlotStore(db.Slots, db.SlotBytes)
+}
+
+// accumulateColumnar adds the selected rows [start, end) of the columnar store
+// to the encrypted slots of res (see PrivateEncryptedQueryWithChunkSize)
+// and returns the number of homomorphic operations performed
+func (db *Database) accumulateColumnar(
+	query *EncryptedQuery,
+	start, end int,
+	numCiphertextsPerSlot int,
+	res []*EncryptedSlot) int {
+
+	dimWidth := query.DBWidth
+
+	// same packing as Slot.ToGmpIntArray
+	numBytesPerChunk := int(math.Max(1, math.Ceil(float64(db.SlotBytes)/float64(numCiphertextsPerSlot))))
+
+	buf := make([]byte, numBytesPerChunk)
+	numOps := 0
+
+	for j := 0; j < numCiphertextsPerSlot; j++ {
+		first := j * numBytesPerChunk
+		last := int(math.Min(float64(db.SlotBytes), float64(first+numBytesPerChunk)))
+
+		for row := start; row < end; row++ {
+			for col := 0; col < dimWidth; col++ {
+				slotIndex := row*dimWidth + col
+				if slotIndex >= db.Store.NumSlots || !db.isOccupied(slotIndex) {
+					continue
+				}
+
+				val := new(gmp.Int)
+				if first < last {
+					db.Store.readBytes(buf, slotIndex, first, last)
+					val.SetBytes(buf[:last-first])
+				}
+
+				sel := query.Pk.ConstMult(query.EBits[row], val)
+				res[col].Cts[j] = query.Pk.Add(res[col].Cts[j], sel)
+				numOps++
+			}
+		}
+	}
+
+	return numOps
+}
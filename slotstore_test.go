@@ -0,0 +1,254 @@
+package pir
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestSlotStoreEncryptedQuery(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	for _, slotBytes := range []int{SlotBytes, SlotBytes + SlotBytesStep, 40} {
+		db := GenerateRandomDB(TestDBSize, slotBytes)
+		columnar := GenerateEmptyDB(TestDBSize, slotBytes)
+		copy(columnar.Slots, db.Slots)
+		columnar.BuildSlotStore()
+
+		for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+			_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+			row := rand.Intn(dimHeight)
+			query := db.NewEncryptedQuery(pk, groupSize, row)
+
+			res, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resColumnar, err := columnar.PrivateEncryptedQuery(query, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the ciphertexts are identical to the row-major scan
+			for col := range res.Slots {
+				for j, ct := range res.Slots[col].Cts {
+					if ct.C.Cmp(resColumnar.Slots[col].Cts[j].C) != 0 {
+						t.Fatalf("Columnar scan result differs at column %v\n", col)
+					}
+				}
+			}
+
+			slots, err := RecoverEncrypted(resColumnar, sk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for j, slot := range slots {
+				slotIndex := row*query.DBWidth + j
+				if slotIndex < TestDBSize && !slot.Equal(db.Slots[slotIndex]) {
+					t.Fatalf("Recovered slot %v is incorrect\n", slotIndex)
+				}
+			}
+		}
+	}
+}
+
+func TestSlotStoreUpdates(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.BuildSlotStore()
+
+	index := rand.Intn(TestDBSize)
+	if err := db.SetSlot(index, NewRandomSlot(SlotBytes)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Append(NewRandomSlot(SlotBytes)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []int{index, TestDBSize} {
+		if !db.Store.Slot(i).Equal(db.Slots[i]) {
+			t.Fatalf("Slot store is out of date at index %v\n", i)
+		}
+	}
+}
+
+func BenchmarkEncryptedQueryAHERowMajor(b *testing.B) {
+	benchmarkEncryptedQueryAHELayout(b, false)
+}
+
+func BenchmarkEncryptedQueryAHEColumnar(b *testing.B) {
+	benchmarkEncryptedQueryAHELayout(b, true)
+}
+
+func benchmarkEncryptedQueryAHELayout(b *testing.B, columnar bool) {
+	setup()
+
+	_, pk := paillier.KeyGen(1024)
+	db := GenerateRandomDB(TestDBSize, 256)
+	if columnar {
+		db.BuildSlotStore()
+	}
+
+	query := db.NewEncryptedQuery(pk, 1, 0)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := db.PrivateEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestSlotStoreQueryInRange(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.BuildSlotStore()
+
+	// record the slots read from the store
+	var mu sync.Mutex
+	read := make(map[int]bool)
+	db.Store.onRead = func(index int) {
+		mu.Lock()
+		defer mu.Unlock()
+		read[index] = true
+	}
+
+	width := 4
+	startRow, endRow := 16, 48
+	row := startRow + rand.Intn(endRow-startRow)
+
+	query := db.NewEncryptedQueryWithDimentions(pk, width, endRow-startRow, 1, row-startRow)
+
+	res, err := db.PrivateEncryptedQueryInRange(query, startRow, endRow, NumProcsForQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slots, err := RecoverEncrypted(res, sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for col, slot := range slots {
+		if !slot.Equal(db.Slots[row*width+col]) {
+			t.Fatalf("Recovered slot %v is incorrect\n", row*width+col)
+		}
+	}
+
+	// exactly the slots of the range were read
+	if len(read) != (endRow-startRow)*width {
+		t.Fatalf("Read %v slots, expected %v for the range\n", len(read), (endRow-startRow)*width)
+	}
+
+	for index := range read {
+		if index < startRow*width || index >= endRow*width {
+			t.Fatalf("Read slot %v outside of the range\n", index)
+		}
+	}
+
+	// the store view only covers the range
+	sub, err := db.SubDatabase(startRow*width, endRow*width)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sub.Store.NumSlots != (endRow-startRow)*width || len(sub.Store.Columns[0]) != sub.Store.NumSlots {
+		t.Fatalf("Store view covers %v slots, expected %v\n", len(sub.Store.Columns[0]), (endRow-startRow)*width)
+	}
+
+	if _, err := db.PrivateEncryptedQueryInRange(query, startRow, endRow+1, NumProcsForQuery); err == nil {
+		t.Fatalf("Expected an error for a query that does not match the range\n")
+	}
+}
+
+func TestSlotStoreSecretSharedQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	columnar := GenerateEmptyDB(TestDBSize, SlotBytes)
+	copy(columnar.Slots, db.Slots)
+	columnar.BuildSlotStore()
+
+	// XorInto matches XORing the (allocated) slot
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+
+		dst := NewRandomSlot(SlotBytes)
+		expected := NewSlot(append([]byte{}, dst.Data...))
+
+		columnar.Store.XorInto(dst.Data, index)
+		XorSlots(expected, columnar.Store.Slot(index))
+
+		if !dst.Equal(expected) {
+			t.Fatalf("XorInto differs from XorSlots at index %v\n", index)
+		}
+	}
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+		shares := db.NewIndexQueryShares(index/groupSize, groupSize, 2)
+
+		for _, share := range shares {
+			res, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resColumnar, err := columnar.PrivateSecretSharedQuery(share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for col := range res.Shares {
+				if !res.Shares[col].Equal(resColumnar.Shares[col]) {
+					t.Fatalf("Columnar scan result differs at column %v\n", col)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSlotStoreXorSlot(b *testing.B) {
+	benchmarkSlotStoreXor(b, false)
+}
+
+func BenchmarkSlotStoreXorInto(b *testing.B) {
+	benchmarkSlotStoreXor(b, true)
+}
+
+// XORs every slot of the store into a result slot
+// either through an allocated copy of each slot or with XorInto
+func benchmarkSlotStoreXor(b *testing.B, xorInto bool) {
+	setup()
+
+	db := GenerateRandomDB(BenchmarkDBSize, SlotBytes)
+	db.BuildSlotStore()
+
+	res := NewEmptySlot(SlotBytes)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for index := 0; index < db.Store.NumSlots; index++ {
+			if xorInto {
+				db.Store.XorInto(res.Data, index)
+			} else {
+				XorSlots(res, db.Store.Slot(index))
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pir
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+// SortedDatabase is a database where the slots are laid out
+// in ascending order such that the index of a record is its rank.
+// This makes the database binary-search friendly: a client can
+// privately fetch the record at a given rank or run an oblivious
+// binary search consisting of dependent PIR queries
+type SortedDatabase struct {
+	*Database
+}
+
+// NewSortedDatabase returns an empty sorted database
+func NewSortedDatabase() *SortedDatabase {
+	return &SortedDatabase{NewDatabase()}
+}
+
+// BuildForData sorts (a copy of) the data in ascending order
+// and constructs a PIR database where each string gets a slot
+func (sdb *SortedDatabase) BuildForData(data []string) {
+
+	sorted := make([]string, len(data))
+	copy(sorted, data)
+	sort.Strings(sorted)
+
+	sdb.Database.BuildForData(sorted)
+}
+
+// SharedQueryFunc sends the query shares to the servers and
+// returns the result shares (one for each server)
+type SharedQueryFunc func(shares []*QueryShare) ([]*SecretSharedQueryResult, error)
+
+// NewRankQuery generates PIR query shares that retrieve the record
+// with the specified rank from a sorted database.
+// This is just an index query with group size 1 since, in a sorted database,
+// the rank of a record is its index
+func (dbmd *DBMetadata) NewRankQuery(rank int, numShares uint) []*QueryShare {
+	return dbmd.NewIndexQueryShares(rank, 1, numShares)
+}
+
+// ObliviousBinarySearch searches a sorted database for the smallest rank
+// such that the record at that rank is >= target and returns DBSize if
+// no such record exists.
+// Each step of the search issues a PIR query with freshly generated
+// DPF keys via query, so the servers cannot link the steps together.
+// The number of steps is always bits.Len(DBSize), regardless of where
+// the target is located, to avoid leaking the search path length
+func (dbmd *DBMetadata) ObliviousBinarySearch(target *Slot, numShares uint, query SharedQueryFunc) (int, error) {
+
+	if dbmd.DBSize <= 0 {
+		return 0, errors.New("cannot search an empty database")
+	}
+
+	lo := 0
+	hi := dbmd.DBSize
+
+	for step := 0; step < bits.Len(uint(dbmd.DBSize)); step++ {
+
+		// once the search has converged, keep issuing
+		// (dummy) queries so that every search has the same length
+		mid := lo + (hi-lo)/2
+		if mid >= dbmd.DBSize {
+			mid = dbmd.DBSize - 1
+		}
+
+		resShares, err := query(dbmd.NewRankQuery(mid, numShares))
+		if err != nil {
+			return 0, err
+		}
+
+		if lo >= hi {
+			continue
+		}
+
+		slot := Recover(resShares)[0]
+		if slot.Compare(target) < 0 {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
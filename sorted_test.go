@@ -0,0 +1,93 @@
+package pir
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestRankQuery(t *testing.T) {
+	setup()
+
+	data := generateStringsInSequence(rand.Intn(1<<8) + 100)
+
+	sdb := NewSortedDatabase()
+	sdb.BuildForData(data)
+
+	sort.Strings(data)
+
+	for i := 0; i < NumQueries; i++ {
+		rank := rand.Intn(sdb.DBSize)
+		shares := sdb.NewRankQuery(rank, 2)
+
+		resA, err := sdb.PrivateSecretSharedQuery(shares[0], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resB, err := sdb.PrivateSecretSharedQuery(shares[1], NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Recover([]*SecretSharedQueryResult{resA, resB})
+		if res[0].ToString() != data[rank] {
+			t.Fatalf("Incorrect record at rank %v. Expected %v, got %v\n", rank, data[rank], res[0].ToString())
+		}
+	}
+}
+
+// run with 'go test -v -run TestObliviousBinarySearch' to see log outputs.
+func TestObliviousBinarySearch(t *testing.T) {
+	setup()
+
+	for trial := 0; trial < NumTrials; trial++ {
+
+		data := generateStringsInSequence(rand.Intn(1<<8) + 1)
+
+		sdb := NewSortedDatabase()
+		sdb.BuildForData(data)
+
+		sort.Strings(data)
+
+		numQueries := 0
+		query := func(shares []*QueryShare) ([]*SecretSharedQueryResult, error) {
+			numQueries++
+
+			resShares := make([]*SecretSharedQueryResult, len(shares))
+			for i, share := range shares {
+				res, err := sdb.PrivateSecretSharedQuery(share, NumProcsForQuery)
+				if err != nil {
+					return nil, err
+				}
+				resShares[i] = res
+			}
+
+			return resShares, nil
+		}
+
+		expectedRank := rand.Intn(len(data))
+		target := NewSlotFromString(data[expectedRank], sdb.SlotBytes)
+
+		rank, err := sdb.ObliviousBinarySearch(target, 2, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rank != expectedRank {
+			t.Fatalf("Binary search returned rank %v, expected %v\n", rank, expectedRank)
+		}
+
+		// the number of queries must not depend on the target
+		expectedNumQueries := 0
+		for n := sdb.DBSize; n > 0; n >>= 1 {
+			expectedNumQueries++
+		}
+
+		if numQueries != expectedNumQueries {
+			t.Fatalf("Binary search issued %v queries, expected %v\n", numQueries, expectedNumQueries)
+		}
+
+		t.Logf("Found %v at rank %v using %v queries\n", data[rank], rank, numQueries)
+	}
+}
@@ -0,0 +1,133 @@
+package pir
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/sachaservan/pir/dpf"
+)
+
+// EvalFullDomainStream evaluates the query share on every row of the database
+// (in increasing order) and calls emit with the selection bit of the row,
+// i.e., the bit that ExpandSharedQuery would set at the same index,
+// without materializing the selection vector
+func (db *Database) EvalFullDomainStream(query *QueryShare, emit func(index int, bit bool)) {
+
+	dimHeight := int(math.Ceil(float64(db.DBSize) / float64(query.GroupSize)))
+
+	pf := dpf.ServerInitialize(query.PrfKeys, queryDomainBits(query, dimHeight))
+
+	// index queries walk the DPF tree over the rows
+	if query.IsTwoParty && !query.IsKeywordBased {
+		pf.EvalFullDomain2P(query.ShareNumber, query.KeyTwoParty, uint(dimHeight), func(x uint, res int) {
+			// IMPORTANT: take mod 2 of uint *before* casting to float64, otherwise there is an overflow edge case!
+			emit(int(x), int(math.Abs(float64(res%2))) == 0)
+		})
+		return
+	}
+
+	// keywords are not contiguous so each row is evaluated separately
+	for i := 0; i < dimHeight; i++ {
+		key := db.queryKey(query, i)
+
+		if query.IsTwoParty {
+			res := pf.Evaluate2P(query.ShareNumber, query.KeyTwoParty, key)
+			emit(i, int(math.Abs(float64(res%2))) == 0)
+		} else {
+			res := pf.EvaluateMP(query.KeyMultiParty, key)
+			emit(i, int(math.Abs(float64(res%2))) == 0)
+		}
+	}
+}
+
+// privateSecretSharedQueryStreamed answers the query by XORing the selected rows
+// as the query is expanded (see Database.StreamQueryExpansion)
+func (db *Database) privateSecretSharedQueryStreamed(query *QueryShare) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	dimWidth := query.GroupSize
+
+	results := make([]*Slot, dimWidth)
+	for col := 0; col < dimWidth; col++ {
+		results[col] = NewEmptySlot(db.SlotBytes)
+	}
+
+	db.EvalFullDomainStream(query, func(row int, bit bool) {
+		if !bit {
+			return
+		}
+
+		for col := 0; col < dimWidth; col++ {
+			slotIndex := row*dimWidth + col
+			if slotIndex >= len(db.Slots) {
+				break
+			}
+
+			if db.isOccupied(slotIndex) {
+				XorSlotView(results[col], db.SlotView(slotIndex))
+			}
+		}
+	})
+
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: results}, nil
+}
+
+// PrivateSecretSharedQueryStreaming answers the query share over slots that arrive
+// from an upstream source (e.g., the network or a decompressor) rather than from db.Slots,
+// XORing each selected slot into the result as it arrives without holding on to the slots.
+// Only the metadata of db (and its keywords for keyword queries) is used.
+//
+// The channel must deliver the slots of the database in index order (slot i is
+// the i-th slot received), each of db.SlotBytes bytes, and must be closed after the last slot.
+// The result is identical to PrivateSecretSharedQuery over the same slots.
+// An error is returned if the channel delivers fewer or more than db.DBSize slots
+// or a slot of the wrong size; the rest of the channel is not drained in that case
+func (db *Database) PrivateSecretSharedQueryStreaming(query *QueryShare, slots <-chan *Slot) (*SecretSharedQueryResult, error) {
+
+	if db.IsClosed() {
+		return nil, ErrDatabaseClosed
+	}
+
+	if query.GroupSize <= 0 {
+		return nil, errors.New("invalid group size provided in query")
+	}
+
+	if query.IsKeywordBased && len(db.Keywords) < db.DBSize {
+		return nil, errors.New("database does not have keywords")
+	}
+
+	dimWidth := query.GroupSize
+	bits := db.ExpandSharedQuery(query, 1)
+
+	results := make([]*Slot, dimWidth)
+	for col := 0; col < dimWidth; col++ {
+		results[col] = NewEmptySlot(db.SlotBytes)
+	}
+
+	index := 0
+	for slot := range slots {
+		if index >= db.DBSize {
+			return nil, errors.New("received more slots than the size of the database")
+		}
+
+		if len(slot.Data) != db.SlotBytes {
+			return nil, fmt.Errorf("slot %v has %v bytes, expected %v", index, len(slot.Data), db.SlotBytes)
+		}
+
+		if bits[index/dimWidth] {
+			XorSlots(results[index%dimWidth], slot)
+		}
+
+		index++
+	}
+
+	if index != db.DBSize {
+		return nil, fmt.Errorf("received %v slots, expected %v", index, db.DBSize)
+	}
+
+	return &SecretSharedQueryResult{SlotBytes: db.SlotBytes, Shares: results}, nil
+}
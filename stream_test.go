@@ -0,0 +1,124 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEvalFullDomainStream(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		dimHeight := (TestDBSize + groupSize - 1) / groupSize
+		shares := db.NewIndexQueryShares(rand.Intn(dimHeight), groupSize, 2)
+
+		for _, share := range shares {
+			bits := db.ExpandSharedQuery(share, NumProcsForQuery)
+
+			next := 0
+			db.EvalFullDomainStream(share, func(index int, bit bool) {
+				if index != next {
+					t.Fatalf("Streamed row %v, expected %v\n", index, next)
+				}
+				next++
+
+				if bit != bits[index] {
+					t.Fatalf("Streamed bit of row %v does not match the expanded query\n", index)
+				}
+			})
+
+			if next != len(bits) {
+				t.Fatalf("Streamed %v rows, expected %v\n", next, len(bits))
+			}
+		}
+	}
+}
+
+func TestStreamQueryExpansion(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	db.StreamQueryExpansion = true
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		index := rand.Intn(TestDBSize)
+		res := sharedQueryGroup(t, db, groupSize, index)
+		checkGroup(t, db, groupSize, index, res)
+	}
+}
+
+func BenchmarkQuerySecretSharesMaterialized(b *testing.B) {
+	benchmarkQuerySecretSharesExpansion(b, false)
+}
+
+func BenchmarkQuerySecretSharesStreamed(b *testing.B) {
+	benchmarkQuerySecretSharesExpansion(b, true)
+}
+
+func benchmarkQuerySecretSharesExpansion(b *testing.B, stream bool) {
+	setup()
+
+	db := GenerateEmptyDB(BenchmarkDBSize, SlotBytes)
+	db.StreamQueryExpansion = stream
+	queryA := db.NewIndexQueryShares(0, 1, 2)[0]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := db.PrivateSecretSharedQuery(queryA, NumProcsForQuery)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestPrivateSecretSharedQueryStreaming(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	// the streaming server only holds the metadata of the database
+	upstream := &Database{DBMetadata: db.DBMetadata}
+
+	feed := func(slots []*Slot) <-chan *Slot {
+		ch := make(chan *Slot)
+		go func() {
+			defer close(ch)
+			for _, slot := range slots {
+				ch <- slot
+			}
+		}()
+		return ch
+	}
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		dimHeight := (TestDBSize + groupSize - 1) / groupSize
+		shares := db.NewIndexQueryShares(rand.Intn(dimHeight), groupSize, 2)
+
+		for _, share := range shares {
+			expected, err := db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := upstream.PrivateSecretSharedQueryStreaming(share, feed(db.Slots))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for col := range expected.Shares {
+				if !expected.Shares[col].Equal(res.Shares[col]) {
+					t.Fatalf("Streaming result differs at column %v\n", col)
+				}
+			}
+		}
+	}
+
+	share := db.NewIndexQueryShares(0, 1, 2)[0]
+	if _, err := upstream.PrivateSecretSharedQueryStreaming(share, feed(db.Slots[1:])); err == nil {
+		t.Fatalf("Expected an error for a truncated stream\n")
+	}
+}
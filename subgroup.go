@@ -0,0 +1,58 @@
+package pir
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SubGroupSelection is a selection of Width slots at Offset within a group of
+// MaxGroupSize slots. The query always retrieves the entire group (the servers
+// only see MaxGroupSize) and the selection is applied by the client during recovery,
+// so the width of the record is hidden from the servers.
+// The bandwidth is that of a query for MaxGroupSize slots regardless of Width
+type SubGroupSelection struct {
+	MaxGroupSize  int
+	Offset, Width int
+}
+
+// NewSubGroupQueryShares generates query shares for the width slots starting at index
+// using a public group size of maxGroupSize and returns the private selection
+// to pass to RecoverSubGroup. The slots must be within a single group of maxGroupSize slots
+func (dbmd *DBMetadata) NewSubGroupQueryShares(index, width, maxGroupSize int, numShares uint) ([]*QueryShare, *SubGroupSelection, error) {
+
+	sel, err := newSubGroupSelection(index, width, maxGroupSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dbmd.NewIndexQueryShares(index/maxGroupSize, maxGroupSize, numShares), sel, nil
+}
+
+func newSubGroupSelection(index, width, maxGroupSize int) (*SubGroupSelection, error) {
+
+	if maxGroupSize <= 0 || width <= 0 {
+		return nil, errors.New("invalid group size")
+	}
+
+	offset := index % maxGroupSize
+	if offset+width > maxGroupSize {
+		return nil, fmt.Errorf("%v slots at index %v span more than one group of %v slots", width, index, maxGroupSize)
+	}
+
+	return &SubGroupSelection{MaxGroupSize: maxGroupSize, Offset: offset, Width: width}, nil
+}
+
+// RecoverSubGroup recovers the group from the result shares
+// and returns the slots of the private selection
+func RecoverSubGroup(resShares []*SecretSharedQueryResult, sel *SubGroupSelection) ([]*Slot, error) {
+	return sel.apply(Recover(resShares))
+}
+
+func (sel *SubGroupSelection) apply(group []*Slot) ([]*Slot, error) {
+
+	if len(group) < sel.Offset+sel.Width {
+		return nil, fmt.Errorf("expected a group of %v slots, got %v", sel.MaxGroupSize, len(group))
+	}
+
+	return group[sel.Offset : sel.Offset+sel.Width], nil
+}
@@ -0,0 +1,58 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSubGroupQuery(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	maxGroupSize := MaxGroupSize
+
+	for width := 1; width <= maxGroupSize; width++ {
+		for trial := 0; trial < 10; trial++ {
+			group := rand.Intn(TestDBSize / maxGroupSize)
+			index := group*maxGroupSize + rand.Intn(maxGroupSize-width+1)
+
+			shares, sel, err := db.NewSubGroupQueryShares(index, width, maxGroupSize, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the servers only see the max group size
+			resShares := make([]*SecretSharedQueryResult, len(shares))
+			for i, share := range shares {
+				if share.GroupSize != maxGroupSize {
+					t.Fatalf("Query reveals the group size %v\n", share.GroupSize)
+				}
+
+				resShares[i], err = db.PrivateSecretSharedQuery(share, NumProcsForQuery)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			slots, err := RecoverSubGroup(resShares, sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(slots) != width {
+				t.Fatalf("Recovered %v slots, expected %v\n", len(slots), width)
+			}
+
+			for j, slot := range slots {
+				if !slot.Equal(db.Slots[index+j]) {
+					t.Fatalf("Recovered slot %v is incorrect\n", index+j)
+				}
+			}
+		}
+	}
+
+	// selections spanning two groups are rejected
+	if _, _, err := db.NewSubGroupQueryShares(maxGroupSize-1, 2, maxGroupSize, 2); err == nil {
+		t.Fatalf("Generated a query for a selection spanning two groups\n")
+	}
+}
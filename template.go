@@ -0,0 +1,89 @@
+package pir
+
+import (
+	"sync"
+
+	"github.com/sachaservan/paillier"
+)
+
+// QueryTemplate precomputes the encryptions of zero of an encrypted query
+// such that generating a query for a given row only costs one encryption (of one).
+//
+// Reusing encryptions of zero across queries would let the server link the queries
+// and learn the queried rows by comparing ciphertexts, so the encryptions of zero
+// are handed to a single query and the template must be refreshed before the next one.
+// ForIndex refreshes the template if needed; calling Refresh ahead of time (e.g., in a
+// background goroutine while the client is idle) takes the cost off the critical path
+type QueryTemplate struct {
+	Pk                *paillier.PublicKey
+	GroupSize         int
+	DBWidth, DBHeight int
+
+	mu    sync.Mutex
+	zeros []*paillier.Ciphertext // fresh encryptions of zero (nil once used)
+}
+
+// NewEncryptedQueryTemplate returns a refreshed template for encrypted queries
+// with the same dimensions as NewEncryptedQuery
+func (dbmd *DBMetadata) NewEncryptedQueryTemplate(pk *paillier.PublicKey, groupSize int) *QueryTemplate {
+
+	width, height := dbmd.encryptedQueryDimentions(pk, groupSize)
+
+	template := &QueryTemplate{
+		Pk:        pk,
+		GroupSize: groupSize,
+		DBWidth:   width,
+		DBHeight:  height,
+	}
+	template.Refresh()
+
+	return template
+}
+
+// Refresh generates fresh encryptions of zero for the next query
+// (no-op if the current ones have not been used yet)
+func (template *QueryTemplate) Refresh() {
+
+	template.mu.Lock()
+	defer template.mu.Unlock()
+
+	template.refresh()
+}
+
+func (template *QueryTemplate) refresh() {
+
+	if template.zeros != nil {
+		return
+	}
+
+	zeros := make([]*paillier.Ciphertext, template.DBHeight)
+	for i := range zeros {
+		zeros[i] = template.Pk.EncryptZero()
+	}
+
+	template.zeros = zeros
+}
+
+// ForIndex returns an encrypted query for the row at index (as NewEncryptedQuery)
+// using the precomputed encryptions of zero, which are then discarded.
+// As with NewEncryptedQuery, an index of -1 returns a null query
+func (template *QueryTemplate) ForIndex(index int) *EncryptedQuery {
+
+	template.mu.Lock()
+	template.refresh()
+	ebits := template.zeros
+	template.zeros = nil
+	template.mu.Unlock()
+
+	if index >= 0 && index < len(ebits) {
+		ebits[index] = template.Pk.EncryptOne()
+	}
+
+	return &EncryptedQuery{
+		Pk:        template.Pk,
+		EBits:     ebits,
+		GroupSize: template.GroupSize,
+		DBWidth:   template.DBWidth,
+		DBHeight:  template.DBHeight,
+	}
+}
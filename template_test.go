@@ -0,0 +1,75 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestQueryTemplate(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		template := db.NewEncryptedQueryTemplate(pk, groupSize)
+
+		var prev *EncryptedQuery
+		for i := 0; i < 5; i++ {
+			row := rand.Intn(template.DBHeight)
+			query := template.ForIndex(row)
+
+			if err := query.SelfCheck(sk); err != nil {
+				t.Fatal(err)
+			}
+
+			encryptedQueryRowWithQuery(t, db, sk, query, row)
+
+			// encryptions of zero are never reused across queries
+			if prev != nil {
+				for j := range query.EBits {
+					if query.EBits[j].C.Cmp(prev.EBits[j].C) == 0 {
+						t.Fatalf("Ciphertext %v is reused across queries\n", j)
+					}
+				}
+			}
+			prev = query
+
+			if i%2 == 0 {
+				template.Refresh()
+			}
+		}
+	}
+}
+
+func BenchmarkNewEncryptedQuery(b *testing.B) {
+	setup()
+
+	_, pk := paillier.KeyGen(1024)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.NewEncryptedQuery(pk, 1, i%TestDBHeight)
+	}
+}
+
+func BenchmarkQueryTemplate(b *testing.B) {
+	setup()
+
+	_, pk := paillier.KeyGen(1024)
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	template := db.NewEncryptedQueryTemplate(pk, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		template.ForIndex(i % template.DBHeight)
+
+		// refreshing is done ahead of time (off the critical path)
+		b.StopTimer()
+		template.Refresh()
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,427 @@
+package pir
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ncw/gmp"
+	"github.com/sachaservan/paillier"
+	"github.com/sachaservan/pir/dpf"
+)
+
+// TestVectorConfig is the configuration of a set of test vectors (see GenerateTestVectors)
+type TestVectorConfig struct {
+	Seed      []byte
+	DBSize    int
+	SlotBytes int
+	GroupSize int
+	Index     int // index of the slot retrieved by the queries
+	KeyBits   int // size of the Paillier modulus in bits
+}
+
+// TestVectors are deterministic inputs and outputs of the package that implementations
+// in other languages can check themselves against: a database, a two-server DPF query
+// with the responses of both servers, and an encrypted query with its randomness
+// and the response of the server. The queries and responses are given both as JSON
+// (see json.go) and as binary encodings (see serialize.go).
+//
+// All the randomness, including the primes of the Paillier key (see seededPaillierKey),
+// is read from the seeded stream (see newSeededStream), so the same configuration always
+// gives the same vectors. The primes are part of the vectors such that implementations
+// can decrypt the response to the encrypted query
+type TestVectors struct {
+	Seed      []byte   `json:"seed"`
+	DBSize    int      `json:"db_size"`
+	SlotBytes int      `json:"slot_bytes"`
+	GroupSize int      `json:"group_size"`
+	Index     int      `json:"index"`
+	DB        [][]byte `json:"db"`
+
+	// two-server DPF query for the group of Index (QueryShares[i] is sent to server i)
+	QueryShares         []*QueryShare              `json:"query_shares"`
+	QuerySharesBinary   [][]byte                   `json:"query_shares_binary"`
+	SharedResults       []*SecretSharedQueryResult `json:"shared_results"`
+	SharedResultsBinary [][]byte                   `json:"shared_results_binary"`
+	ExpectedGroup       [][]byte                   `json:"expected_group"`
+
+	// encrypted query for the row of Index; ciphertext i encrypts bit i
+	// with the Paillier randomness EncryptedQueryRandomness[i]
+	PaillierN                []byte                `json:"paillier_n"`
+	PaillierP                []byte                `json:"paillier_p"`
+	PaillierQ                []byte                `json:"paillier_q"`
+	EncryptedQuery           *EncryptedQuery       `json:"encrypted_query"`
+	EncryptedQueryRandomness [][]byte              `json:"encrypted_query_randomness"`
+	EncryptedQueryBinary     []byte                `json:"encrypted_query_binary"`
+	EncryptedResult          *EncryptedQueryResult `json:"encrypted_result"`
+	EncryptedResultBinary    []byte                `json:"encrypted_result_binary"`
+	ExpectedRow              [][]byte              `json:"expected_row"`
+}
+
+// GenerateTestVectors generates the test vectors of the configuration (see TestVectors)
+func GenerateTestVectors(config *TestVectorConfig) (*TestVectors, error) {
+
+	if config.DBSize <= 0 || config.SlotBytes <= 0 {
+		return nil, errors.New("invalid database size")
+	}
+
+	if config.GroupSize < 1 {
+		return nil, ErrInvalidGroupSize
+	}
+
+	if config.Index < 0 || config.Index >= config.DBSize {
+		return nil, errors.New("index out of range")
+	}
+
+	if config.KeyBits < 64 {
+		return nil, errors.New("invalid Paillier key size")
+	}
+
+	stream := newSeededStream(config.Seed)
+
+	p, q, err := seededPaillierKey(stream, config.KeyBits)
+	if err != nil {
+		return nil, err
+	}
+	pk := &paillier.PublicKey{N: new(gmp.Int).Mul(p, q)}
+
+	tv := &TestVectors{
+		Seed:      config.Seed,
+		DBSize:    config.DBSize,
+		SlotBytes: config.SlotBytes,
+		GroupSize: config.GroupSize,
+		Index:     config.Index,
+		DB:        make([][]byte, config.DBSize),
+		PaillierN: pk.N.Bytes(),
+		PaillierP: p.Bytes(),
+		PaillierQ: q.Bytes(),
+	}
+
+	db := NewDatabase()
+	db.DBSize = config.DBSize
+	db.SlotBytes = config.SlotBytes
+	db.Slots = make([]*Slot, config.DBSize)
+	for i := range db.Slots {
+		data := make([]byte, config.SlotBytes)
+		if _, err := io.ReadFull(stream, data); err != nil {
+			return nil, err
+		}
+		db.Slots[i] = NewSlot(data)
+		tv.DB[i] = data
+	}
+
+	// two-server DPF query
+	group := config.Index / config.GroupSize
+	_, numBits := db.indexQueryDomain(config.GroupSize)
+	tv.QueryShares = newDPFQuerySharesFrom(
+		dpf.ClientInitializeWithRandomness(numBits, stream), uint(group), config.GroupSize, 2)
+
+	for _, share := range tv.QueryShares {
+		res, err := db.PrivateSecretSharedQuery(share, 1)
+		if err != nil {
+			return nil, err
+		}
+		tv.SharedResults = append(tv.SharedResults, res)
+	}
+
+	for _, slot := range Recover(tv.SharedResults) {
+		tv.ExpectedGroup = append(tv.ExpectedGroup, slot.Data)
+	}
+
+	// encrypted query
+	width, height := db.encryptedQueryDimentions(pk, config.GroupSize)
+	row, _ := db.IndexToCoordinates(config.Index, width, height)
+
+	qr := &QueryRandomness{
+		Bits:  make([]int, height),
+		Rands: make([]*gmp.Int, height),
+		Level: paillier.EncLevelOne,
+	}
+	qr.Bits[row] = 1
+
+	max := new(big.Int).SetBytes(pk.N.Bytes())
+	max.Sub(max, big.NewInt(1))
+	for i := range qr.Rands {
+		r, err := rand.Int(stream, max)
+		if err != nil {
+			return nil, err
+		}
+		qr.Rands[i] = new(gmp.Int).SetBytes(r.Add(r, big.NewInt(1)).Bytes())
+		tv.EncryptedQueryRandomness = append(tv.EncryptedQueryRandomness, qr.Rands[i].Bytes())
+	}
+
+	tv.EncryptedQuery = &EncryptedQuery{
+		Pk:        pk,
+		EBits:     qr.Encrypt(pk),
+		GroupSize: config.GroupSize,
+		DBWidth:   width,
+		DBHeight:  height,
+	}
+
+	tv.EncryptedResult, err = db.PrivateEncryptedQuery(tv.EncryptedQuery, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for j := 0; j < width; j++ {
+		slot := db.EmptySlot()
+		if row*width+j < db.DBSize {
+			slot = db.Slots[row*width+j]
+		}
+		tv.ExpectedRow = append(tv.ExpectedRow, slot.Data)
+	}
+
+	if err := tv.encodeBinary(); err != nil {
+		return nil, err
+	}
+
+	return tv, nil
+}
+
+// encodeBinary sets the binary encodings of the queries and results
+func (tv *TestVectors) encodeBinary() error {
+
+	tv.QuerySharesBinary = nil
+	for _, share := range tv.QueryShares {
+		data, err := share.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		tv.QuerySharesBinary = append(tv.QuerySharesBinary, data)
+	}
+
+	tv.SharedResultsBinary = nil
+	for _, res := range tv.SharedResults {
+		data, err := res.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		tv.SharedResultsBinary = append(tv.SharedResultsBinary, data)
+	}
+
+	var err error
+	if tv.EncryptedQueryBinary, err = tv.EncryptedQuery.MarshalBinary(); err != nil {
+		return err
+	}
+
+	tv.EncryptedResultBinary, err = tv.EncryptedResult.MarshalBinary()
+	return err
+}
+
+// WriteTestVectors writes the test vectors to w as indented JSON
+func WriteTestVectors(w io.Writer, tv *TestVectors) error {
+
+	data, err := json.MarshalIndent(tv, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadTestVectors reads test vectors written by WriteTestVectors from r
+func ReadTestVectors(r io.Reader) (*TestVectors, error) {
+
+	tv := &TestVectors{}
+	if err := json.NewDecoder(r).Decode(tv); err != nil {
+		return nil, err
+	}
+
+	if tv.EncryptedQuery == nil || tv.EncryptedResult == nil || len(tv.QueryShares) != 2 {
+		return nil, errors.New("incomplete test vectors")
+	}
+
+	pk := &paillier.PublicKey{N: new(gmp.Int).SetBytes(tv.PaillierN)}
+	tv.EncryptedQuery.Pk = pk
+	tv.EncryptedResult.Pk = pk
+
+	return tv, nil
+}
+
+// CheckTestVectors recomputes the responses of the queries of the test vectors
+// over their database (as an implementation of the server in another language would),
+// decrypts the response to the encrypted query with the primes of the vectors,
+// and returns an error if a response, encoding, or recovered slot differs from the vectors
+func CheckTestVectors(tv *TestVectors) error {
+
+	db := NewDatabase()
+	db.DBSize = len(tv.DB)
+	db.SlotBytes = tv.SlotBytes
+	for _, data := range tv.DB {
+		db.Slots = append(db.Slots, NewSlot(data))
+	}
+
+	expected := &TestVectors{}
+	*expected = *tv
+
+	expected.SharedResults = nil
+	for _, share := range tv.QueryShares {
+		res, err := db.PrivateSecretSharedQuery(share, 1)
+		if err != nil {
+			return err
+		}
+		expected.SharedResults = append(expected.SharedResults, res)
+	}
+
+	res, err := db.PrivateEncryptedQuery(tv.EncryptedQuery, 1)
+	if err != nil {
+		return err
+	}
+	expected.EncryptedResult = res
+
+	if err := expected.encodeBinary(); err != nil {
+		return err
+	}
+
+	for i := range tv.QueryShares {
+		if !bytes.Equal(expected.QuerySharesBinary[i], tv.QuerySharesBinary[i]) {
+			return fmt.Errorf("binary encoding of query share %v differs", i)
+		}
+		if !bytes.Equal(expected.SharedResultsBinary[i], tv.SharedResultsBinary[i]) {
+			return fmt.Errorf("response of server %v differs", i)
+		}
+	}
+
+	if !bytes.Equal(expected.EncryptedQueryBinary, tv.EncryptedQueryBinary) {
+		return errors.New("binary encoding of the encrypted query differs")
+	}
+
+	if !bytes.Equal(expected.EncryptedResultBinary, tv.EncryptedResultBinary) {
+		return errors.New("response to the encrypted query differs")
+	}
+
+	for i, slot := range Recover(expected.SharedResults) {
+		if i >= len(tv.ExpectedGroup) || !bytes.Equal(slot.Data, tv.ExpectedGroup[i]) {
+			return fmt.Errorf("recovered slot %v of the group differs", i)
+		}
+	}
+
+	p := new(gmp.Int).SetBytes(tv.PaillierP)
+	q := new(gmp.Int).SetBytes(tv.PaillierQ)
+	if tv.EncryptedQuery.Pk == nil || new(gmp.Int).Mul(p, q).Cmp(tv.EncryptedQuery.Pk.N) != 0 {
+		return errors.New("Paillier primes do not match the modulus")
+	}
+
+	if len(res.Slots) != len(tv.ExpectedRow) {
+		return fmt.Errorf("response to the encrypted query has %v slots, expected %v", len(res.Slots), len(tv.ExpectedRow))
+	}
+
+	for i, eslot := range res.Slots {
+		arr := make([]*gmp.Int, len(eslot.Cts))
+		for j, ct := range eslot.Cts {
+			arr[j] = paillierDecrypt(p, q, ct)
+		}
+
+		slot := NewSlotFromGmpIntArray(arr, res.SlotBytes, res.NumBytesPerCiphertext)
+		if !bytes.Equal(slot.Data, tv.ExpectedRow[i]) {
+			return fmt.Errorf("decrypted slot %v of the row differs", i)
+		}
+	}
+
+	return nil
+}
+
+// seededPaillierKey reads the primes p and q of a Paillier modulus of the given size
+// from the stream: each prime is the first candidate of bits/2 bytes from the stream,
+// with its two most significant bits and its least significant bit set,
+// that passes the Baillie-PSW test (as in math/big's ProbablyPrime, which is deterministic)
+func seededPaillierKey(stream io.Reader, bits int) (*gmp.Int, *gmp.Int, error) {
+
+	prime := func() (*big.Int, error) {
+		buf := make([]byte, (bits/2+7)/8)
+		for {
+			if _, err := io.ReadFull(stream, buf); err != nil {
+				return nil, err
+			}
+
+			// clear the bits beyond bits/2 and set the top two bits
+			// such that the product of two primes has exactly bits bits
+			excess := uint(len(buf)*8 - bits/2)
+			buf[0] &= 0xff >> excess
+			top := uint(7 - excess)
+			buf[0] |= 1 << top
+			if top > 0 {
+				buf[0] |= 1 << (top - 1)
+			} else {
+				buf[1] |= 0x80
+			}
+			buf[len(buf)-1] |= 1
+
+			if c := new(big.Int).SetBytes(buf); c.ProbablyPrime(0) {
+				return c, nil
+			}
+		}
+	}
+
+	for {
+		p, err := prime()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		q, err := prime()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if p.Cmp(q) != 0 {
+			return new(gmp.Int).SetBytes(p.Bytes()), new(gmp.Int).SetBytes(q.Bytes()), nil
+		}
+	}
+}
+
+// paillierDecrypt decrypts the (level one) ciphertext ct under the modulus N = p*q,
+// i.e., returns L(c^lambda mod N^2) * lambda^-1 mod N with L(x) = (x-1)/N
+// and lambda = lcm(p-1, q-1) for the generator N+1
+func paillierDecrypt(p, q *gmp.Int, ct *paillier.Ciphertext) *gmp.Int {
+
+	one := gmp.NewInt(1)
+	n := new(gmp.Int).Mul(p, q)
+	n2 := new(gmp.Int).Mul(n, n)
+
+	p1 := new(gmp.Int).Sub(p, one)
+	q1 := new(gmp.Int).Sub(q, one)
+	lambda := new(gmp.Int).Mul(p1, q1)
+	lambda.Div(lambda, new(gmp.Int).GCD(nil, nil, p1, q1))
+
+	m := new(gmp.Int).Exp(ct.C, lambda, n2)
+	m.Sub(m, one).Div(m, n)
+	m.Mul(m, new(gmp.Int).ModInverse(lambda, n))
+
+	return m.Mod(m, n)
+}
+
+// newSeededStream returns the deterministic stream of the test vectors:
+// the AES-128-CTR keystream (with a zero IV) under the first 16 bytes
+// of the SHA-256 digest of the seed
+func newSeededStream(seed []byte) io.Reader {
+
+	key := sha256.Sum256(seed)
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		panic(err)
+	}
+
+	return &cipher.StreamReader{
+		S: cipher.NewCTR(block, make([]byte, aes.BlockSize)),
+		R: zeroReader{},
+	}
+}
+
+// zeroReader is an infinite stream of zeros
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
@@ -0,0 +1,112 @@
+package pir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ncw/gmp"
+)
+
+func TestTestVectors(t *testing.T) {
+	setup()
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		config := &TestVectorConfig{
+			Seed:      []byte("test vectors"),
+			DBSize:    TestDBSize,
+			SlotBytes: SlotBytes,
+			GroupSize: groupSize,
+			Index:     TestDBSize / 3,
+			KeyBits:   128,
+		}
+
+		tv, err := GenerateTestVectors(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the same configuration and key give the same vectors
+		var first, second bytes.Buffer
+		if err := WriteTestVectors(&first, tv); err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := GenerateTestVectors(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteTestVectors(&second, again); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Fatalf("Test vectors are not deterministic\n")
+		}
+
+		// the recovered slots are the slots of the queried group and row
+		start := (config.Index / groupSize) * groupSize
+		for i, data := range tv.ExpectedGroup {
+			if start+i < TestDBSize && !bytes.Equal(data, tv.DB[start+i]) {
+				t.Fatalf("Expected group slot %v does not match the database\n", i)
+			}
+		}
+
+		// the vectors check out after a round trip through JSON
+		decoded, err := ReadTestVectors(bytes.NewReader(first.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := CheckTestVectors(decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		// the key is derived from the seed
+		if new(gmp.Int).SetBytes(tv.PaillierN).BitLen() != config.KeyBits {
+			t.Fatalf("Paillier modulus has %v bits; expected %v\n", new(gmp.Int).SetBytes(tv.PaillierN).BitLen(), config.KeyBits)
+		}
+
+		// a wrong response is detected
+		decoded.SharedResultsBinary[0][len(decoded.SharedResultsBinary[0])-1] ^= 1
+		if err := CheckTestVectors(decoded); err == nil {
+			t.Fatalf("Modified test vectors passed the check\n")
+		}
+
+		// a wrong expected row is detected
+		decoded, err = ReadTestVectors(bytes.NewReader(first.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded.ExpectedRow[0][0] ^= 1
+		if err := CheckTestVectors(decoded); err == nil {
+			t.Fatalf("Test vectors with a wrong expected row passed the check\n")
+		}
+
+		// the primes must match the modulus
+		decoded, err = ReadTestVectors(bytes.NewReader(first.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded.PaillierP, decoded.PaillierQ = decoded.PaillierQ, decoded.PaillierP[1:]
+		if err := CheckTestVectors(decoded); err == nil {
+			t.Fatalf("Test vectors with wrong primes passed the check\n")
+		}
+	}
+
+	// another seed gives other vectors
+	a, err := GenerateTestVectors(&TestVectorConfig{Seed: []byte("a"), DBSize: TestDBSize, SlotBytes: SlotBytes, GroupSize: 1, KeyBits: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := GenerateTestVectors(&TestVectorConfig{Seed: []byte("b"), DBSize: TestDBSize, SlotBytes: SlotBytes, GroupSize: 1, KeyBits: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(a.PaillierN, b.PaillierN) || bytes.Equal(a.EncryptedQueryBinary, b.EncryptedQueryBinary) || bytes.Equal(a.QuerySharesBinary[0], b.QuerySharesBinary[0]) {
+		t.Fatalf("Different seeds give the same vectors\n")
+	}
+}
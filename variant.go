@@ -0,0 +1,212 @@
+package pir
+
+import (
+	"crypto/aes"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+// QueryVariant identifies one of the PIR query variants supported by the library
+type QueryVariant int
+
+const (
+	// SecretSharedVariant uses DPF query shares and requires two non-colluding servers
+	SecretSharedVariant QueryVariant = iota
+	// EncryptedVariant uses a single server and returns a row of the database
+	EncryptedVariant
+	// DoublyEncryptedVariant uses a single server and returns a single group
+	DoublyEncryptedVariant
+)
+
+// CostModelKeyBits is the Paillier modulus size assumed when estimating query costs
+const CostModelKeyBits = 2048
+
+// relative cost of a modular exponentiation compared to
+// XORing a slot or evaluating the DPF at one point
+const expCost = 1000
+
+// CostModelOpTime is the nominal time of one server operation
+// (XORing a slot or evaluating the DPF at one point; see QueryCost.ServerOps)
+// used to convert estimated costs into latencies
+const CostModelOpTime = 10 * time.Nanosecond
+
+// maxPlanningDBSize is the largest database size considered by MaxPracticalDBSize
+// (the size of the keyword domain)
+const maxPlanningDBSize = 1 << KeywordBits
+
+// String returns the name of the variant
+func (v QueryVariant) String() string {
+	switch v {
+	case SecretSharedVariant:
+		return "secret-shared"
+	case EncryptedVariant:
+		return "encrypted"
+	case DoublyEncryptedVariant:
+		return "doubly-encrypted"
+	default:
+		return "unknown"
+	}
+}
+
+// QueryCost is the estimated cost of retrieving one slot using a query variant
+type QueryCost struct {
+	UploadBytes   int // total size of the query sent to the server(s)
+	DownloadBytes int // total size of the response(s)
+	ServerOps     int // work done by each server (in units of one slot XOR)
+}
+
+// EstimateQueryCost estimates the cost of retrieving one slot from a database
+// of dbSize slots of size slotBytes using the default (sqrt) dimensions
+// and Paillier keys of size keyBits (ignored for the secret-shared variant)
+func EstimateQueryCost(variant QueryVariant, dbSize, slotBytes, keyBits int) QueryCost {
+
+	dbmd := &DBMetadata{SlotBytes: slotBytes, DBSize: dbSize}
+
+	// size of level one and level two ciphertexts
+	keyBytes := keyBits / 8
+	ctBytes := 2 * keyBytes
+	ctBytesLevelTwo := 3 * keyBytes
+	numCiphertextsPerSlot := int(math.Ceil(float64(slotBytes) / float64(keyBytes-2)))
+
+	height := int(math.Ceil(math.Sqrt(float64(dbSize))))
+	width, height := dbmd.GetDimentionsForDatabase(height, 1)
+
+	switch variant {
+	case SecretSharedVariant:
+		// seed, correction words, final correction word, and PRF keys (per server)
+		numBits := int(math.Log2(float64(dbSize)) + 1)
+		dpfKeyBytes := aes.BlockSize + 1 + numBits*(aes.BlockSize+2) + 8 + 4*aes.BlockSize
+
+		return QueryCost{
+			UploadBytes:   2 * dpfKeyBytes,
+			DownloadBytes: 2 * slotBytes,
+			ServerOps:     2 * dbSize, // DPF expansion and scan
+		}
+
+	case EncryptedVariant:
+		return QueryCost{
+			UploadBytes:   height * ctBytes,
+			DownloadBytes: width * numCiphertextsPerSlot * ctBytes,
+			ServerOps:     dbSize * numCiphertextsPerSlot * expCost,
+		}
+
+	default:
+		// the column selection operates over level two ciphertexts
+		return QueryCost{
+			UploadBytes:   height*ctBytes + width*ctBytesLevelTwo,
+			DownloadBytes: numCiphertextsPerSlot * ctBytesLevelTwo,
+			ServerOps:     (dbSize + 2*width) * numCiphertextsPerSlot * expCost,
+		}
+	}
+}
+
+// RecommendVariant returns the query variant with the lowest estimated server time
+// (work divided among nprocs cores) among the variants whose query and response
+// fit within maxUploadBytes and maxDownloadBytes, assuming two non-colluding servers are available.
+// Returns an error if no variant fits
+func RecommendVariant(dbSize, slotBytes, nprocs int, maxUploadBytes, maxDownloadBytes int) (QueryVariant, error) {
+	return RecommendVariantForServers(2, dbSize, slotBytes, nprocs, maxUploadBytes, maxDownloadBytes)
+}
+
+// RecommendVariantForServers is the same as RecommendVariant given the number of
+// available non-colluding servers (the secret-shared variant requires at least two)
+func RecommendVariantForServers(numServers, dbSize, slotBytes, nprocs int, maxUploadBytes, maxDownloadBytes int) (QueryVariant, error) {
+
+	if dbSize <= 0 || slotBytes <= 0 {
+		return 0, errors.New("invalid database size")
+	}
+
+	if nprocs <= 0 {
+		nprocs = 1
+	}
+
+	variants := []QueryVariant{EncryptedVariant, DoublyEncryptedVariant}
+	if numServers >= 2 {
+		variants = append([]QueryVariant{SecretSharedVariant}, variants...)
+	}
+
+	best := QueryVariant(-1)
+	bestTime := math.Inf(1)
+
+	for _, variant := range variants {
+		cost := EstimateQueryCost(variant, dbSize, slotBytes, CostModelKeyBits)
+		if cost.UploadBytes > maxUploadBytes || cost.DownloadBytes > maxDownloadBytes {
+			continue
+		}
+
+		time := float64(cost.ServerOps) / float64(nprocs)
+		if time < bestTime {
+			best = variant
+			bestTime = time
+		}
+	}
+
+	if best < 0 {
+		return 0, errors.New("no query variant fits within the bandwidth budget")
+	}
+
+	return best, nil
+}
+
+// EstimateQueryLatency estimates the server time to answer a query
+// with the work divided among nprocs cores (see EstimateQueryCost)
+func EstimateQueryLatency(variant QueryVariant, dbSize, slotBytes, keyBits, nprocs int) time.Duration {
+
+	if nprocs <= 0 {
+		nprocs = 1
+	}
+
+	cost := EstimateQueryCost(variant, dbSize, slotBytes, keyBits)
+	return time.Duration(float64(cost.ServerOps) / float64(nprocs) * float64(CostModelOpTime))
+}
+
+// MaxPracticalDBSize returns the largest database size (in slots of slotBytes bytes)
+// for which the estimated latency of the variant with the key pk (ignored for the
+// secret-shared variant) and nprocs cores is within latencyBudget.
+// The bound accounts for the number of ciphertexts needed to pack a slot under pk
+// and is capped at the size of the keyword domain. Returns 0 if no database fits.
+// The estimate uses the nominal CostModelOpTime and is meant for capacity planning only
+func MaxPracticalDBSize(variant QueryVariant, pk *paillier.PublicKey, slotBytes int, latencyBudget time.Duration, nprocs int) int {
+
+	if slotBytes <= 0 {
+		return 0
+	}
+
+	keyBits := CostModelKeyBits
+	if pk != nil {
+		keyBits = pk.N.BitLen()
+	}
+
+	// a slot must be packed into at least one ciphertext
+	if variant != SecretSharedVariant && keyBits/8 <= 2 {
+		return 0
+	}
+
+	fits := func(dbSize int) bool {
+		return EstimateQueryLatency(variant, dbSize, slotBytes, keyBits, nprocs) <= latencyBudget
+	}
+
+	if !fits(1) {
+		return 0
+	}
+
+	// the latency grows with the database size
+	low, high := 1, maxPlanningDBSize
+	if fits(high) {
+		return high
+	}
+
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		if fits(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return low
+}
@@ -0,0 +1,91 @@
+package pir
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestRecommendVariant(t *testing.T) {
+
+	dbSize := BenchmarkDBSize
+	slotBytes := 32
+
+	// very tight bandwidth: only the DPF based variant fits
+	variant, err := RecommendVariant(dbSize, slotBytes, NumProcsForQuery, 2<<10, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if variant != SecretSharedVariant {
+		t.Fatalf("Recommended %v variant, expected %v\n", variant, SecretSharedVariant)
+	}
+
+	// only one server available
+	variant, err = RecommendVariantForServers(1, dbSize, slotBytes, NumProcsForQuery, 1<<30, 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if variant != EncryptedVariant {
+		t.Fatalf("Recommended %v variant, expected %v\n", variant, EncryptedVariant)
+	}
+
+	// only one server and a tight download budget
+	variant, err = RecommendVariantForServers(1, dbSize, slotBytes, NumProcsForQuery, 1<<30, 1<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if variant != DoublyEncryptedVariant {
+		t.Fatalf("Recommended %v variant, expected %v\n", variant, DoublyEncryptedVariant)
+	}
+
+	// nothing fits
+	if _, err := RecommendVariantForServers(1, dbSize, slotBytes, NumProcsForQuery, 2<<10, 1<<10); err == nil {
+		t.Fatal("Recommended a variant that does not fit the bandwidth budget")
+	}
+}
+
+func TestEstimateQueryCost(t *testing.T) {
+
+	for _, variant := range []QueryVariant{SecretSharedVariant, EncryptedVariant, DoublyEncryptedVariant} {
+		small := EstimateQueryCost(variant, TestDBSize, SlotBytes, CostModelKeyBits)
+		large := EstimateQueryCost(variant, BenchmarkDBSize, SlotBytes, CostModelKeyBits)
+
+		if small.UploadBytes > large.UploadBytes || small.ServerOps > large.ServerOps {
+			t.Fatalf("Cost of the %v variant decreased with the database size\n", variant)
+		}
+
+		if small.UploadBytes <= 0 || small.DownloadBytes <= 0 || small.ServerOps <= 0 {
+			t.Fatalf("Invalid cost estimate for the %v variant: %v\n", variant, small)
+		}
+	}
+}
+
+func TestMaxPracticalDBSize(t *testing.T) {
+
+	_, pk := paillier.KeyGen(256)
+
+	for _, variant := range []QueryVariant{SecretSharedVariant, EncryptedVariant, DoublyEncryptedVariant} {
+		prev := math.MaxInt64
+		for _, budget := range []time.Duration{time.Minute, time.Second, 100 * time.Millisecond, time.Millisecond} {
+			size := MaxPracticalDBSize(variant, pk, 32, budget, NumProcsForQuery)
+			if size > prev {
+				t.Fatalf("Bound for %v grew from %v to %v when tightening the budget to %v\n", variant, prev, size, budget)
+			}
+
+			if size > 0 && EstimateQueryLatency(variant, size, 32, pk.N.BitLen(), NumProcsForQuery) > budget {
+				t.Fatalf("Database of %v slots exceeds the budget of %v\n", size, budget)
+			}
+
+			prev = size
+		}
+
+		if MaxPracticalDBSize(variant, pk, 32, time.Millisecond, NumProcsForQuery) >= MaxPracticalDBSize(variant, pk, 32, time.Second, NumProcsForQuery) {
+			t.Fatalf("Bound for %v does not shrink with the budget\n", variant)
+		}
+	}
+}
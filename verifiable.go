@@ -0,0 +1,246 @@
+package pir
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sachaservan/paillier"
+)
+
+// ResultProof accompanies an EncryptedQueryResult in verifiable mode.
+//
+// The server commits to the database with Digest, the root of a Merkle tree
+// whose leaves are the rows of the width x height database layout.
+// Along with the result, the server runs the same encrypted query over an auxiliary
+// database in which row i holds the Merkle authentication path of row i.
+// The homomorphic sum therefore selects the authentication path of the
+// queried row without revealing which row it is, and the client checks that the
+// decrypted row together with the decrypted path hashes to the committed digest.
+//
+// Soundness model: the digest must be obtained over an authenticated channel
+// (e.g., published by the server ahead of time) and the client must check the
+// result against the row it queried. A server that returns any row other than the
+// committed row at the queried index must then find a collision in SHA-256.
+// Note that the server can still mount a selective failure attack (e.g., corrupt
+// the result only if the queried row is some row j) so the client
+// should not reveal to the server whether verification succeeded
+type ResultProof struct {
+	Path *EncryptedQueryResult // encrypted authentication path of the queried row
+}
+
+const (
+	merkleLeafPrefix byte = 0
+	merkleNodePrefix byte = 1
+)
+
+// Digest returns the Merkle root committing to the database
+// when viewed as a width x height grid (as in NewEncryptedQueryWithDimentions)
+func (db *Database) Digest(width, height int) []byte {
+	levels := db.merkleLevels(width, height)
+	return levels[len(levels)-1][0]
+}
+
+// PrivateVerifiableEncryptedQuery is the same as PrivateEncryptedQuery but also
+// returns a proof that the result is the queried row of the database
+// committed to by Digest (see ResultProof)
+func (db *Database) PrivateVerifiableEncryptedQuery(query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, *ResultProof, error) {
+
+	start := time.Now()
+	res, proof, err := db.privateVerifiableEncryptedQuery(query, nprocs)
+
+	db.logQuery(&QueryEvent{
+		Variant:   EncryptedVariant,
+		DBWidth:   query.DBWidth,
+		DBHeight:  query.DBHeight,
+		GroupSize: query.GroupSize,
+		NumProcs:  nprocs,
+	}, start, err)
+
+	return res, proof, err
+}
+
+func (db *Database) privateVerifiableEncryptedQuery(query *EncryptedQuery, nprocs int) (*EncryptedQueryResult, *ResultProof, error) {
+
+	res, err := db.privateEncryptedQuery(context.Background(), query, nprocs, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pathDB := db.merklePathDatabase(query.DBWidth, query.DBHeight)
+	pathQuery := &EncryptedQuery{
+		Pk:        query.Pk,
+		EBits:     query.EBits,
+		GroupSize: 1,
+		DBWidth:   1,
+		DBHeight:  query.DBHeight,
+	}
+
+	pathRes, err := pathDB.privateEncryptedQuery(context.Background(), pathQuery, nprocs, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, &ResultProof{Path: pathRes}, nil
+}
+
+// VerifyResult decrypts the result and the proof of a verifiable query for the given row
+// and returns the slots of the row if they are consistent with the digest
+func VerifyResult(
+	sk *paillier.SecretKey,
+	digest []byte,
+	query *EncryptedQuery,
+	row int,
+	res *EncryptedQueryResult,
+	proof *ResultProof) ([]*Slot, error) {
+
+	if row < 0 || row >= query.DBHeight {
+		return nil, errors.New("row outside of the query dimensions")
+	}
+
+	if len(res.Slots) != query.DBWidth {
+		return nil, fmt.Errorf("result has %v slots, expected %v", len(res.Slots), query.DBWidth)
+	}
+
+	if proof == nil || proof.Path == nil || len(proof.Path.Slots) != 1 {
+		return nil, errors.New("missing authentication path")
+	}
+
+	depth := merkleDepth(query.DBHeight)
+	if proof.Path.SlotBytes != depth*sha256.Size {
+		return nil, errors.New("authentication path has an incorrect length")
+	}
+
+	slots, err := RecoverEncrypted(res, sk)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := RecoverEncrypted(proof.Path, sk)
+	if err != nil {
+		return nil, err
+	}
+
+	node := merkleLeaf(row, slots)
+	index := row
+	for level := 0; level < depth; level++ {
+		sibling := path[0].Data[level*sha256.Size : (level+1)*sha256.Size]
+		if index%2 == 0 {
+			node = merkleNode(node, sibling)
+		} else {
+			node = merkleNode(sibling, node)
+		}
+		index /= 2
+	}
+
+	if !bytes.Equal(node, digest) {
+		return nil, errors.New("result does not match the database digest")
+	}
+
+	return slots, nil
+}
+
+// merkleLevels returns all levels of the Merkle tree over the rows
+// starting with the leaves and ending with the root
+func (db *Database) merkleLevels(width, height int) [][][]byte {
+
+	depth := merkleDepth(height)
+	leaves := make([][]byte, 1<<depth)
+	for row := range leaves {
+		if row >= height {
+			leaves[row] = make([]byte, sha256.Size)
+			continue
+		}
+
+		slots := make([]*Slot, width)
+		for col := 0; col < width; col++ {
+			slotIndex := row*width + col
+			if slotIndex < len(db.Slots) {
+				slots[col] = db.Slots[slotIndex]
+			} else {
+				slots[col] = NewEmptySlot(db.SlotBytes)
+			}
+		}
+
+		leaves[row] = merkleLeaf(row, slots)
+	}
+
+	levels := [][][]byte{leaves}
+	for len(levels[len(levels)-1]) > 1 {
+		prev := levels[len(levels)-1]
+		next := make([][]byte, len(prev)/2)
+		for i := range next {
+			next[i] = merkleNode(prev[2*i], prev[2*i+1])
+		}
+		levels = append(levels, next)
+	}
+
+	return levels
+}
+
+// merklePathDatabase returns a database with one slot per row
+// containing the concatenated sibling hashes from the row's leaf to the root
+func (db *Database) merklePathDatabase(width, height int) *Database {
+
+	levels := db.merkleLevels(width, height)
+	depth := len(levels) - 1
+
+	pathDB := &Database{}
+	pathDB.SlotBytes = depth * sha256.Size
+	pathDB.DBSize = height
+	pathDB.Slots = make([]*Slot, height)
+
+	for row := 0; row < height; row++ {
+		path := make([]byte, 0, pathDB.SlotBytes)
+		index := row
+		for level := 0; level < depth; level++ {
+			path = append(path, levels[level][index^1]...)
+			index /= 2
+		}
+		pathDB.Slots[row] = NewSlot(path)
+	}
+
+	return pathDB
+}
+
+// merkleDepth returns the depth of the Merkle tree over height rows
+// (at least one such that authentication paths are never empty)
+func merkleDepth(height int) int {
+
+	depth := 1
+	for (1 << depth) < height {
+		depth++
+	}
+
+	return depth
+}
+
+func merkleLeaf(row int, slots []*Slot) []byte {
+
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+
+	rowBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(rowBytes, uint64(row))
+	h.Write(rowBytes)
+
+	for _, slot := range slots {
+		h.Write(slot.Data)
+	}
+
+	return h.Sum(nil)
+}
+
+func merkleNode(left, right []byte) []byte {
+
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+
+	return h.Sum(nil)
+}
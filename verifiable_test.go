@@ -0,0 +1,52 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestVerifiableEncryptedQuery(t *testing.T) {
+	setup()
+
+	sk, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+
+	for groupSize := MinGroupSize; groupSize < MaxGroupSize; groupSize++ {
+		_, dimHeight := db.GetDimentionsForDatabase(TestDBHeight, groupSize)
+		rowIndex := rand.Intn(dimHeight)
+
+		query := db.NewEncryptedQuery(pk, groupSize, rowIndex)
+		digest := db.Digest(query.DBWidth, query.DBHeight)
+
+		res, proof, err := db.PrivateVerifiableEncryptedQuery(query, NumProcsForQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		slots, err := VerifyResult(sk, digest, query, rowIndex, res, proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for col, slot := range slots {
+			index := rowIndex*query.DBWidth + col
+			if index < db.DBSize && !db.Slots[index].Equal(slot) {
+				t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], slot)
+			}
+		}
+
+		// the result does not verify for another row
+		if _, err := VerifyResult(sk, digest, query, (rowIndex+1)%dimHeight, res, proof); err == nil {
+			t.Fatal("Result verified for a row that was not queried")
+		}
+
+		// a server that tampers with a slot of the result fails verification
+		res.Slots[0].Cts[0] = pk.Add(res.Slots[0].Cts[0], pk.EncryptOne())
+		if _, err := VerifyResult(sk, digest, query, rowIndex, res, proof); err == nil {
+			t.Fatal("Tampered result passed verification")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package pir
+
+import "errors"
+
+// SetSlot replaces the slot at index and bumps the version of that slot
+// to the new generation of the database
+func (db *Database) SetSlot(index int, slot *Slot) error {
+
+	if index < 0 || index >= db.DBSize {
+		return errors.New("slot index out of range")
+	}
+
+	if len(slot.Data) != db.SlotBytes {
+		return errors.New("slot size does not match the database")
+	}
+
+	if len(db.Versions) != db.DBSize {
+		versions := make([]uint64, db.DBSize)
+		copy(versions, db.Versions)
+		db.Versions = versions
+	}
+
+	db.Generation++
+	db.Slots[index] = slot
+	db.Versions[index] = db.Generation
+	db.setOccupied(index, slot)
+
+	if db.Store != nil {
+		db.Store.set(index, slot)
+	}
+
+	return nil
+}
+
+// ClearSlot marks the slot at index as absent by replacing it with EmptySlot
+func (db *Database) ClearSlot(index int) error {
+	return db.SetSlot(index, db.EmptySlot())
+}
+
+// Append adds the slot to the end of the database and sets
+// the version of the new slot to the new generation of the database
+func (db *Database) Append(slot *Slot) error {
+
+	if db.DBSize == 0 && db.SlotBytes == 0 {
+		db.SlotBytes = len(slot.Data)
+	}
+
+	if len(slot.Data) != db.SlotBytes {
+		return errors.New("slot size does not match the database")
+	}
+
+	if len(db.Versions) != db.DBSize {
+		versions := make([]uint64, db.DBSize)
+		copy(versions, db.Versions)
+		db.Versions = versions
+	}
+
+	db.Generation++
+	db.Slots = append(db.Slots, slot)
+	db.Versions = append(db.Versions, db.Generation)
+	db.setOccupied(db.DBSize, slot)
+
+	if db.Store != nil {
+		db.Store.set(db.DBSize, slot)
+	}
+
+	db.DBSize++
+
+	return nil
+}
+
+// VersionVector returns a copy of the per-slot versions.
+// The version vector is public metadata that is small compared to the database
+// (one counter per slot) such that a client can download it in full and
+// detect stale cached slots locally without revealing which slots it cached
+func (db *Database) VersionVector() []uint64 {
+
+	versions := make([]uint64, db.DBSize)
+	copy(versions, db.Versions)
+
+	return versions
+}
+
+// ChangedSlots returns the indices of the slots whose version
+// differs between the old and new version vectors
+// (slots that only exist in the new vector are considered changed)
+func ChangedSlots(oldVersions, newVersions []uint64) []int {
+
+	changed := make([]int, 0)
+	for i, v := range newVersions {
+		if i >= len(oldVersions) || oldVersions[i] != v {
+			changed = append(changed, i)
+		}
+	}
+
+	return changed
+}
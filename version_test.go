@@ -0,0 +1,49 @@
+package pir
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestVersionVector(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	cached := db.VersionVector()
+
+	for i := 0; i < NumQueries; i++ {
+		index := rand.Intn(TestDBSize)
+
+		err := db.SetSlot(index, NewRandomSlot(SlotBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		versions := db.VersionVector()
+		changed := ChangedSlots(cached, versions)
+
+		if len(changed) != 1 || changed[0] != index {
+			t.Fatalf("Expected only slot %v to change, got %v\n", index, changed)
+		}
+
+		if versions[index] != db.Generation {
+			t.Fatalf("Slot version %v does not match the generation %v\n", versions[index], db.Generation)
+		}
+
+		// the retrieved slot reflects the change
+		res := sharedQueryGroup(t, db, 1, index)
+		if !db.Slots[index].Equal(res[0]) {
+			t.Fatalf("Query result is incorrect. %v != %v\n", db.Slots[index], res[0])
+		}
+
+		cached = versions
+	}
+
+	if err := db.SetSlot(TestDBSize, NewRandomSlot(SlotBytes)); err == nil {
+		t.Fatal("Set a slot outside of the database")
+	}
+
+	if err := db.SetSlot(0, NewRandomSlot(SlotBytes+1)); err == nil {
+		t.Fatal("Set a slot of the wrong size")
+	}
+}
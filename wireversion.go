@@ -0,0 +1,159 @@
+package pir
+
+import "errors"
+
+// Wire format versions of the binary and CBOR encodings of queries and results.
+// The version is the first field of every encoding such that a server can keep
+// decoding (and answering in) the format of older clients when the layout of
+// the structs changes: new fields are written and read only for versions that
+// have them (see binaryWriter.version and binaryReader.version).
+//
+// The protobuf and JSON encodings are not versioned since their fields are
+// numbered (or named) and unknown fields are ignored
+const (
+	WireVersion1 uint = 1
+
+	// WireVersion2 adds the compression of the binary encodings (see Compression):
+	// the version is followed by the compression of the rest of the encoding.
+	// The CBOR layout is the same as in WireVersion1
+	WireVersion2 uint = 2
+
+	// WireVersion3 adds the region of encrypted queries (see GroupLayout)
+	// to the binary and CBOR encodings
+	WireVersion3 uint = 3
+
+	// MinWireVersion and CurrentWireVersion bound the versions that this package
+	// decodes; encodings use CurrentWireVersion unless another one is negotiated
+	MinWireVersion     = WireVersion1
+	CurrentWireVersion = WireVersion3
+)
+
+// ErrUnsupportedWireVersion is returned when decoding (or encoding) a wire format
+// version outside of [MinWireVersion, CurrentWireVersion]
+var ErrUnsupportedWireVersion = errors.New("unsupported wire format version")
+
+// ErrFieldNotInWireVersion is returned when encoding a message that has a field
+// the wire format version cannot represent (e.g., the region of an encrypted
+// query before WireVersion3), instead of silently dropping the field
+var ErrFieldNotInWireVersion = errors.New("message has a field that is not in the wire format version")
+
+// ErrNoCommonWireVersion is returned by NegotiateWireVersion when the peers do not
+// support a common version
+var ErrNoCommonWireVersion = errors.New("no common wire format version")
+
+// WireMessage is a query or result with versioned binary and CBOR encodings
+type WireMessage interface {
+	encodeBinary(w *binaryWriter)
+	encodeCBOR(w *cborWriter)
+}
+
+// IsSupportedWireVersion returns true if the package encodes and decodes the version
+func IsSupportedWireVersion(version uint) bool {
+	return version >= MinWireVersion && version <= CurrentWireVersion
+}
+
+// SupportedWireVersions returns the versions supported by the package
+// (e.g., for a client to announce to the server)
+func SupportedWireVersions() []uint {
+
+	versions := make([]uint, 0, CurrentWireVersion-MinWireVersion+1)
+	for v := MinWireVersion; v <= CurrentWireVersion; v++ {
+		versions = append(versions, v)
+	}
+
+	return versions
+}
+
+// NegotiateWireVersion returns the highest version supported by both
+// the package and the peer (given the versions announced by the peer)
+func NegotiateWireVersion(peerVersions []uint) (uint, error) {
+
+	best := uint(0)
+	for _, v := range peerVersions {
+		if IsSupportedWireVersion(v) && v > best {
+			best = v
+		}
+	}
+
+	if best == 0 {
+		return 0, ErrNoCommonWireVersion
+	}
+
+	return best, nil
+}
+
+// WireVersionOf returns the wire format version of a binary encoding
+// without decoding the rest of the message
+func WireVersionOf(data []byte) (uint, error) {
+
+	r := &binaryReader{buf: data}
+	version := uint(r.readUint())
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	return version, nil
+}
+
+// MarshalBinaryVersion encodes the message in the binary layout of the version
+// (e.g., the version negotiated with the peer or, for a server, that of the query)
+func MarshalBinaryVersion(msg WireMessage, version uint) ([]byte, error) {
+
+	if !IsSupportedWireVersion(version) {
+		return nil, ErrUnsupportedWireVersion
+	}
+
+	body := &binaryWriter{version: version}
+	msg.encodeBinary(body)
+	if body.err != nil {
+		return nil, body.err
+	}
+
+	w := &binaryWriter{version: version}
+	w.writeUint(uint64(version))
+	if version < WireVersion2 {
+		return append(w.buf, body.buf...), nil
+	}
+
+	c := compressionOf(msg)
+	w.writeUint(uint64(c))
+
+	compressed, err := compress(c, body.buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(w.buf, compressed...), nil
+}
+
+// MarshalCBORVersion encodes the message in the CBOR layout of the version
+func MarshalCBORVersion(msg WireMessage, version uint) ([]byte, error) {
+
+	if !IsSupportedWireVersion(version) {
+		return nil, ErrUnsupportedWireVersion
+	}
+
+	w := &cborWriter{version: version}
+	w.writeArray(2)
+	w.writeUint(uint64(version))
+	msg.encodeCBOR(w)
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return w.buf, nil
+}
+
+// WireVersionOfCBOR returns the wire format version of a CBOR encoding
+// without decoding the rest of the message
+func WireVersionOfCBOR(data []byte) (uint, error) {
+
+	r := &cborReader{buf: data}
+	r.readArrayOf(2)
+	version := uint(r.readUint())
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,159 @@
+package pir
+
+import (
+	"testing"
+
+	"github.com/sachaservan/paillier"
+)
+
+func TestNegotiateWireVersion(t *testing.T) {
+
+	version, err := NegotiateWireVersion(SupportedWireVersions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != CurrentWireVersion {
+		t.Fatalf("Negotiated version %v, expected %v\n", version, CurrentWireVersion)
+	}
+
+	// a newer peer falls back to the highest version known to the package
+	version, err = NegotiateWireVersion([]uint{CurrentWireVersion + 2, CurrentWireVersion + 1, CurrentWireVersion, MinWireVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != CurrentWireVersion {
+		t.Fatalf("Negotiated version %v, expected %v\n", version, CurrentWireVersion)
+	}
+
+	// an older peer gets its own version
+	version, err = NegotiateWireVersion([]uint{MinWireVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != MinWireVersion {
+		t.Fatalf("Negotiated version %v, expected %v\n", version, MinWireVersion)
+	}
+
+	if _, err := NegotiateWireVersion([]uint{0, CurrentWireVersion + 1}); err != ErrNoCommonWireVersion {
+		t.Fatalf("Expected ErrNoCommonWireVersion, got %v\n", err)
+	}
+}
+
+func TestWireVersionRoundTrip(t *testing.T) {
+	setup()
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	share := db.NewIndexQueryShares(0, 1, 2)[0]
+
+	for _, version := range SupportedWireVersions() {
+
+		data, err := MarshalBinaryVersion(share, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := WireVersionOf(data); err != nil || v != version {
+			t.Fatalf("Binary encoding has version %v (%v), expected %v\n", v, err, version)
+		}
+
+		if err := (&QueryShare{}).UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err = MarshalCBORVersion(share, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := WireVersionOfCBOR(data); err != nil || v != version {
+			t.Fatalf("CBOR encoding has version %v (%v), expected %v\n", v, err, version)
+		}
+
+		if err := (&QueryShare{}).UnmarshalCBOR(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := MarshalBinaryVersion(share, CurrentWireVersion+1); err != ErrUnsupportedWireVersion {
+		t.Fatalf("Expected ErrUnsupportedWireVersion, got %v\n", err)
+	}
+
+	// encodings from a newer peer are rejected rather than misparsed
+	data, err := share.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] = byte(CurrentWireVersion + 1)
+
+	if err := (&QueryShare{}).UnmarshalBinary(data); err != ErrUnsupportedWireVersion {
+		t.Fatalf("Expected ErrUnsupportedWireVersion, got %v\n", err)
+	}
+
+	data, err = share.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[1] = byte(CurrentWireVersion + 1)
+
+	if err := (&QueryShare{}).UnmarshalCBOR(data); err != ErrUnsupportedWireVersion {
+		t.Fatalf("Expected ErrUnsupportedWireVersion, got %v\n", err)
+	}
+}
+
+func TestWireVersionRegion(t *testing.T) {
+	setup()
+
+	_, pk := paillier.KeyGen(128)
+
+	db := GenerateRandomDB(TestDBSize, SlotBytes)
+	layout, err := NewGroupLayout(
+		TestDBSize,
+		&GroupRegion{Start: 0, End: TestDBSize / 2, GroupSize: 2},
+		&GroupRegion{Start: TestDBSize / 2, End: TestDBSize, GroupSize: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Layout = layout
+
+	query := db.NewEncryptedQuery(pk, 1, TestDBSize-1)
+	if query.Region == nil {
+		t.Fatalf("Query does not have a region\n")
+	}
+
+	for _, version := range SupportedWireVersions() {
+
+		// versions before WireVersion3 cannot represent the region
+		_, err := MarshalBinaryVersion(query, version)
+		if (version < WireVersion3) != (err == ErrFieldNotInWireVersion) {
+			t.Fatalf("Binary encoding in version %v returned %v\n", version, err)
+		}
+
+		_, err = MarshalCBORVersion(query, version)
+		if (version < WireVersion3) != (err == ErrFieldNotInWireVersion) {
+			t.Fatalf("CBOR encoding in version %v returned %v\n", version, err)
+		}
+	}
+
+	// queries without a region are encoded in every version
+	db.Layout = nil
+	query = db.NewEncryptedQuery(pk, 1, 0)
+	for _, version := range SupportedWireVersions() {
+
+		data, err := MarshalBinaryVersion(query, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := UnmarshalEncryptedQuery(data, pk); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := MarshalCBORVersion(query, version); err != nil {
+			t.Fatal(err)
+		}
+	}
+}